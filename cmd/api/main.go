@@ -3,18 +3,23 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	_ "github.com/lib/pq"
 
 	"uniwish.com/internal/api"
 	"uniwish.com/internal/api/config"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/blob"
+	"uniwish.com/internal/migrator"
 	"uniwish.com/internal/scrapers"
+	"uniwish.com/internal/tracing"
 )
 
 func main() {
@@ -26,11 +31,16 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	cfg, err := config.Load()
 
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
 	if err != nil {
 		logger.Error("configuration load fail", "err", err)
 		os.Exit(1)
 	}
 
+	logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.LogLevel}))
+
 	db, err := sql.Open("postgres", cfg.DBURL)
 
 	if err != nil {
@@ -40,6 +50,11 @@ func main() {
 
 	defer db.Close()
 
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
 	ctx, stop := signal.NotifyContext(
 		context.Background(),
 		os.Interrupt,
@@ -52,26 +67,95 @@ func main() {
 		os.Exit(1)
 	}
 
-	server := api.NewServer(cfg, logger, db, scrapers.DefaultScraperRegistry)
+	if cfg.AutoMigrate {
+		if err := migrate(ctx, db); err != nil {
+			logger.Error("auto migrate failed", "err", err)
+			os.Exit(1)
+		}
+		logger.Info("migrations applied")
+	}
+
+	// a schema this build could corrupt or misread is refused, one merely behind fails readiness instead
+	if err := migrator.Check(ctx, repository.NewPostgresSchemaReader(db), repository.SchemaVersion); err != nil {
+		logger.Error("schema check failed", "err", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, "uniwish-api")
+	if err != nil {
+		logger.Error("tracing setup failed", "err", err)
+		os.Exit(1)
+	}
+
+	defer shutdownTracing(context.Background())
+
+	queueFactory, queueCloser, err := repository.NewQueueFactory(cfg.QueueBackend, cfg.RedisURL, cfg.QueueLease)
+	if err != nil {
+		logger.Error("queue setup failed", "err", err)
+		os.Exit(1)
+	}
+
+	defer queueCloser.Close()
+
+	imageStore, err := blob.OpenImageStore(cfg)
+	if err != nil {
+		logger.Error("image store setup failed", "err", err)
+		os.Exit(1)
+	}
+
+	registry, err := scrapers.NewConfiguredRegistry(cfg)
+	if err != nil {
+		logger.Error("scraper registry setup failed", "err", err)
+		os.Exit(1)
+	}
+
+	server, err := api.NewServer(cfg, logger, db, queueFactory(db), registry, imageStore)
+	if err != nil {
+		logger.Error("server setup failed", "err", err)
+		os.Exit(1)
+	}
 
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
+	go func() {
+		if err := server.ServeGRPC(); err != nil {
+			errCh <- err
+		}
+	}()
 
 	select {
 	case <-ctx.Done():
 		logger.Info("shutdown signal received")
+		// a second signal cuts the drain short
+		stop()
+		drainCtx, cancelDrain := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		server.Drain(drainCtx, cfg.HTTPDrainDelay)
+		cancelDrain()
 	case err := <-errCh:
 		logger.Error("server error", "err", err)
 	}
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		logger.Error("graceful shutdown failed", "err", err)
 	}
 }
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	// replicas starting together are serialized by migrate's advisory lock
+	runner, err := migrator.New(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer runner.Close()
+
+	stop := context.AfterFunc(ctx, runner.Stop)
+	defer stop()
+	return runner.Up()
+}
@@ -0,0 +1,41 @@
+/*
+uniwish.com/interal/cmd/uniwishctl/config
+
+prints the effective config, what the api and worker would run with given the same file, env and flags
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+)
+
+func runConfig(_ context.Context, env *env, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(env.stderr, "usage: uniwishctl config print [-sources]")
+		return errUsage
+	}
+
+	fs := newFlagSet(env, "config print", "config print [-sources]")
+	sources := fs.Bool("sources", false, "list every setting with its env var and the layer it was set by instead of YAML")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	// secrets are redacted either way, the output is meant to be pasted into tickets
+	if !*sources {
+		return env.cfg.WriteYAML(env.stdout)
+	}
+
+	tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tENV\tVALUE\tSOURCE")
+	for _, v := range env.cfg.Effective() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", v.Key, v.Env, v.Value, v.Source)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,49 @@
+/*
+uniwish.com/interal/cmd/uniwishctl/enqueue
+
+queues urls through the same service the api uses, so they are validated and scheduled alike
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+func runEnqueue(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "enqueue", "enqueue [-priority bulk|interactive] [-owner id] <url>...")
+	priority := fs.String("priority", services.PriorityBulk, "scheduling priority, interactive or bulk")
+	owner := fs.String("owner", "", "user the requests are scheduled fairly against")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	db, err := env.openDB(ctx)
+	if err != nil {
+		return err
+	}
+	queueFactory, queueCloser, err := repository.NewQueueFactory(env.cfg.QueueBackend, env.cfg.RedisURL, env.cfg.QueueLease)
+	if err != nil {
+		return fmt.Errorf("queue setup failed: %w", err)
+	}
+	defer queueCloser.Close()
+
+	// operators are not held to the daily quota, the service only applies it when one is set
+	service := services.NewScrapeRequestService(queueFactory(db), env.registry)
+
+	for _, url := range fs.Args() {
+		id, err := service.Request(ctx, services.ScrapeRequestInput{URL: url, OwnerID: *owner, Priority: *priority})
+		if err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+		fmt.Fprintf(env.stdout, "%s\t%s\n", id, url)
+	}
+	return nil
+}
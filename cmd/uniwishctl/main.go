@@ -0,0 +1,179 @@
+/*
+uniwish.com/interal/cmd/uniwishctl/main
+
+operator cli, enqueuing and dry-run scraping urls, managing the scrape queue, listing products and running migrations
+
+it reads the same config file and environment as the api and worker, DATABASE_URL above all
+*/
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/lib/pq"
+
+	"uniwish.com/internal/api/config"
+	"uniwish.com/internal/scrapers"
+)
+
+// errUsage reports a malformed command line, its usage has already been printed
+var errUsage = errors.New("usage")
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []command{
+	{name: "enqueue", summary: "queue urls for the workers to scrape", run: runEnqueue},
+	{name: "scrape", summary: "scrape a url locally and print what was parsed, nothing is stored", run: runScrape},
+	{name: "queue", summary: "inspect, retry, cancel and purge scrape requests", run: runQueue},
+	{name: "products", summary: "list tracked products", run: runProducts},
+	{name: "migrate", summary: "apply, roll back and inspect the embedded database migrations", run: runMigrate},
+	{name: "config", summary: "print the effective config, secrets redacted", run: runConfig},
+}
+
+// env carries what commands share, the database is only opened by the commands needing it
+type env struct {
+	stdout   io.Writer
+	stderr   io.Writer
+	cfg      *config.Config
+	registry scrapers.Registry
+	db       *sql.DB
+}
+
+func (e *env) openDB(ctx context.Context) (*sql.DB, error) {
+	if e.db != nil {
+		return e.db, nil
+	}
+	if e.cfg.DBURL == "" {
+		return nil, config.ErrNoDatabaseURL
+	}
+
+	db, err := sql.Open("postgres", e.cfg.DBURL)
+	if err != nil {
+		return nil, fmt.Errorf("db open failed: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("db ping failed: %w", err)
+	}
+	e.db = db
+	return db, nil
+}
+
+func (e *env) close() {
+	if e.db != nil {
+		e.db.Close()
+	}
+}
+
+func (e *env) printJSON(v any) error {
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	global := flag.NewFlagSet("uniwishctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { usage(stderr) }
+	configFile := global.String("config", "", "YAML config file, CONFIG_FILE otherwise")
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+
+	args = global.Args()
+	if len(args) == 0 || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	// DATABASE_URL stays optional, commands needing it say so once they run
+	cfg, err := config.Parse(config.Options{File: *configFile})
+	if err != nil {
+		fmt.Fprintf(stderr, "configuration load failed: %v\n", err)
+		return 1
+	}
+
+	registry, err := scrapers.NewConfiguredRegistry(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "configuration load failed: %v\n", err)
+		return 1
+	}
+
+	env := &env{stdout: stdout, stderr: stderr, cfg: cfg, registry: registry}
+	defer env.close()
+
+	if err := cmd.run(ctx, env, args[1:]); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "%s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: uniwishctl [-config file] <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run uniwishctl <command> -h for a command's flags")
+}
+
+// newFlagSet reports its errors to the command's stderr rather than exiting
+func newFlagSet(env *env, name string, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.stderr, "usage: uniwishctl %s\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags reads args into fs, its own errors have been reported by the flag set
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+uniwish.com/interal/cmd/uniwishctl/main_test
+
+tests command dispatch and the commands running without a database
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/config"
+	"uniwish.com/internal/currency"
+	"uniwish.com/internal/domain"
+	"uniwish.com/internal/scrapers"
+)
+
+type FakeScraper struct{}
+
+func (FakeScraper) Scrape(_ context.Context, url string) (*domain.ProductRecord, error) {
+	product := &domain.ProductSnapshot{ID: uuid.New(), URL: url, Name: "jacket", Store: "store.com"}
+	offers := []domain.Offer{{ID: uuid.New(), ProductID: product.ID, Price: currency.Amount{Minor: 2995, Currency: "EUR"}}}
+	return &domain.ProductRecord{Product: product, Offers: &offers}, nil
+}
+
+func (FakeScraper) Fetch(context.Context, string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (FakeScraper) ParseProduct(io.Reader) (*domain.ProductSnapshot, *[]domain.Offer, error) {
+	return nil, nil, nil
+}
+
+func newTestEnv() (*env, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	registry := scrapers.NewScraperRegistry(map[string]scrapers.ScraperFactory{
+		"store.com": func() scrapers.Scraper { return FakeScraper{} },
+	})
+	return &env{stdout: stdout, stderr: stderr, cfg: &config.Config{QueueAdmin: true}, registry: registry}, stdout, stderr
+}
+
+func TestRun_Dispatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		expectedCode   int
+		expectedStderr string
+	}{
+		{name: "no_command", args: nil, expectedCode: 2, expectedStderr: "usage: uniwishctl"},
+		{name: "help", args: []string{"help"}, expectedCode: 2, expectedStderr: "commands:"},
+		{name: "unknown_command", args: []string{"deploy"}, expectedCode: 2, expectedStderr: `unknown command "deploy"`},
+		{name: "missing_argument", args: []string{"scrape"}, expectedCode: 2, expectedStderr: "usage: uniwishctl scrape"},
+		{name: "bad_flag", args: []string{"enqueue", "-nope"}, expectedCode: 2, expectedStderr: "flag provided but not defined"},
+		{name: "unknown_queue_subcommand", args: []string{"queue", "drain"}, expectedCode: 2, expectedStderr: "usage: uniwishctl queue"},
+		{name: "unsupported_store", args: []string{"scrape", "http://nowhere.com/a"}, expectedCode: 1, expectedStderr: "no scraper available"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+			code := run(context.Background(), tt.args, stdout, stderr)
+			if code != tt.expectedCode {
+				t.Fatalf("expected exit code %d, received %d\nstderr: %s", tt.expectedCode, code, stderr)
+			}
+			if !strings.Contains(stderr.String(), tt.expectedStderr) {
+				t.Fatalf("expected stderr to contain %q, received %q", tt.expectedStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestRunScrape_PrintsRecord(t *testing.T) {
+	env, stdout, _ := newTestEnv()
+
+	if err := runScrape(context.Background(), env, []string{"http://store.com/jacket"}); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	var record domain.ProductRecord
+	if err := json.Unmarshal(stdout.Bytes(), &record); err != nil {
+		t.Fatalf("expected json output, received %v\n%s", err, stdout)
+	}
+	if record.Product.Name != "jacket" || len(*record.Offers) != 1 || (*record.Offers)[0].Price.Minor != 2995 {
+		t.Fatalf("unexpected record %s", stdout)
+	}
+}
+
+func TestRunCommands_RequireDatabase(t *testing.T) {
+	commands := [][]string{
+		{"enqueue", "http://store.com/a"},
+		{"queue", "list"},
+		{"products", "list"},
+		{"migrate", "version"},
+	}
+
+	for _, args := range commands {
+		t.Run(strings.Join(args, "_"), func(t *testing.T) {
+			env, _, _ := newTestEnv()
+
+			err := findCommand(args[0]).run(context.Background(), env, args[1:])
+			if err == nil || !strings.Contains(err.Error(), "DATABASE_URL") {
+				t.Fatalf("expected a missing DATABASE_URL error, received %v", err)
+			}
+		})
+	}
+}
+
+func TestRunConfigPrint(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "hunter2")
+	t.Setenv("APP_PORT", "8001")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "yaml", args: []string{"config", "print"}, expected: []string{"port: 8001", "token: '[redacted]'"}},
+		{name: "sources", args: []string{"config", "print", "-sources"}, expected: []string{"http.port", "APP_PORT", "env", "admin.token"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+			if code := run(context.Background(), tt.args, stdout, stderr); code != 0 {
+				t.Fatalf("expected exit code 0, received %d\nstderr: %s", code, stderr)
+			}
+			if strings.Contains(stdout.String(), "hunter2") {
+				t.Fatalf("printed config leaks a secret\n%s", stdout)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Fatalf("expected output to contain %q\n%s", expected, stdout)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	t.Setenv("WORKER_CONCURRENCY", "none")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	code := run(context.Background(), []string{"config", "print"}, stdout, stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "worker.concurrency") {
+		t.Fatalf("expected the invalid setting to be reported, received %d %q", code, stderr)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+uniwish.com/interal/cmd/uniwishctl/migrate
+
+applies and inspects the migrations embedded in the binary
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/migrator"
+)
+
+func runMigrate(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "migrate", "migrate [-all] <up [n] | down <n> | down -all | status | version | force <version>>")
+	all := fs.Bool("all", false, "with down, roll back every migration")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	db, err := env.openDB(ctx)
+	if err != nil {
+		return err
+	}
+	runner, err := migrator.New(ctx, db)
+	if err != nil {
+		return fmt.Errorf("migrate setup failed: %w", err)
+	}
+	defer runner.Close()
+
+	// an interrupt stops between migrations rather than mid statement
+	stop := context.AfterFunc(ctx, runner.Stop)
+	defer stop()
+
+	action, rest := fs.Arg(0), fs.Args()[1:]
+	switch {
+	case action == "up" && len(rest) == 0:
+		err = runner.Up()
+	case action == "up" && len(rest) == 1:
+		err = steps(runner, rest[0], 1)
+	case action == "down" && len(rest) == 1:
+		err = steps(runner, rest[0], -1)
+	case action == "down" && len(rest) == 0 && *all:
+		err = runner.Down()
+	case action == "force" && len(rest) == 1:
+		version, convErr := strconv.Atoi(rest[0])
+		if convErr != nil {
+			return fmt.Errorf("invalid version %q", rest[0])
+		}
+		err = runner.Force(version)
+	case action == "status" && len(rest) == 0:
+		return printStatus(env, runner)
+	case action == "version" && len(rest) == 0:
+		// reported below
+	default:
+		fs.Usage()
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+	return printVersion(env, runner)
+}
+
+func steps(runner *migrator.Runner, raw string, direction int) error {
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid step count %q", raw)
+	}
+	return runner.Steps(direction * n)
+}
+
+func printVersion(env *env, runner *migrator.Runner) error {
+	state, err := runner.Version()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.stdout, "version %d", state.Version)
+	if state.Dirty {
+		fmt.Fprint(env.stdout, " (dirty, fix the failed migration by hand then force its version)")
+	}
+	fmt.Fprintf(env.stdout, ", this build expects version %d\n", repository.SchemaVersion)
+	return nil
+}
+
+func printStatus(env *env, runner *migrator.Runner) error {
+	status, err := runner.Status()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+	for _, migration := range status {
+		fmt.Fprintf(tw, "%d\t%s\t%t\n", migration.Version, migration.Name, migration.Applied)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return printVersion(env, runner)
+}
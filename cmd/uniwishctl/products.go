@@ -0,0 +1,60 @@
+/*
+uniwish.com/interal/cmd/uniwishctl/products
+
+lists tracked products as GET /products reports them
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+func runProducts(ctx context.Context, env *env, args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(env.stderr, "usage: uniwishctl products list [-currency code] [-json]")
+		return errUsage
+	}
+
+	fs := newFlagSet(env, "products list", "products list [-currency code] [-json]")
+	displayCurrency := fs.String("currency", "", "also report prices in this ISO 4217 currency")
+	asJSON := fs.Bool("json", false, "print json rather than a table")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	db, err := env.openDB(ctx)
+	if err != nil {
+		return err
+	}
+	service := services.NewDefaultProductReaderService(
+		repository.NewDefaultProductReader(db),
+		repository.NewPostgresFXRateRepository(db),
+	)
+	list, err := service.List(ctx, *displayCurrency)
+	if err != nil {
+		return describe(err)
+	}
+
+	if *asJSON {
+		return env.printJSON(list)
+	}
+
+	tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STORE\tPRICE\tDISPLAY PRICE\tNAME")
+	for _, product := range list.Products {
+		display := ""
+		if product.DisplayPrice != nil {
+			display = strconv.FormatFloat(*product.DisplayPrice, 'f', 2, 64) + " " + product.DisplayCurrency
+		}
+		fmt.Fprintf(tw, "%s\t%s %s\t%s\t%s\n",
+			product.Store, strconv.FormatFloat(product.LastPrice, 'f', 2, 64), product.Currency, display, product.Name,
+		)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,227 @@
+/*
+uniwish.com/interal/cmd/uniwishctl/queue
+
+scrape queue operations, the cli side of the /admin/scrape-requests endpoints
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+type queueSubcommand struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+var queueSubcommands = []queueSubcommand{
+	{name: "list", summary: "list scrape requests, newest first", run: runQueueList},
+	{name: "stats", summary: "count requests by status", run: runQueueStats},
+	{name: "retry", summary: "re-pend failed requests", run: runQueueRetry},
+	{name: "cancel", summary: "cancel pending requests", run: runQueueCancel},
+	{name: "purge", summary: "delete old done requests", run: runQueuePurge},
+}
+
+func runQueue(ctx context.Context, env *env, args []string) error {
+	if len(args) > 0 {
+		for _, sub := range queueSubcommands {
+			if sub.name == args[0] {
+				return sub.run(ctx, env, args[1:])
+			}
+		}
+	}
+
+	fmt.Fprintln(env.stderr, "usage: uniwishctl queue <list|stats|retry|cancel|purge> [flags]")
+	for _, sub := range queueSubcommands {
+		fmt.Fprintf(env.stderr, "  %-8s %s\n", sub.name, sub.summary)
+	}
+	return errUsage
+}
+
+// queueAdmin builds the service the admin endpoints use, refusing like them while queue.admin is off
+func queueAdmin(ctx context.Context, env *env) (services.QueueAdministrator, error) {
+	if !env.cfg.QueueAdmin {
+		return services.DisabledQueueAdmin{}, nil
+	}
+	db, err := env.openDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewQueueAdminService(repository.NewPostgresScrapeRequestRepository(db)), nil
+}
+
+func runQueueList(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "queue list", "queue list [-status s] [-store s] [-error-kind k] [-limit n] [-offset n] [-json]")
+	status := fs.String("status", "", "pending, processing, done, failed or cancelled")
+	store := fs.String("store", "", "only requests for this store")
+	errorKind := fs.String("error-kind", "", "only requests that failed this way, like scrape_failed")
+	limit := fs.Int("limit", services.DefaultAdminListLimit, "requests listed")
+	offset := fs.Int("offset", 0, "requests skipped")
+	asJSON := fs.Bool("json", false, "print json rather than a table")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	service, err := queueAdmin(ctx, env)
+	if err != nil {
+		return err
+	}
+	items, err := service.List(ctx, services.ListScrapeRequestsInput{
+		Status:    *status,
+		Store:     *store,
+		ErrorKind: *errorKind,
+		Limit:     strconv.Itoa(*limit),
+		Offset:    strconv.Itoa(*offset),
+	})
+	if err != nil {
+		return describe(err)
+	}
+
+	if *asJSON {
+		return env.printJSON(items)
+	}
+	return printScrapeRequests(env.stdout, items)
+}
+
+func printScrapeRequests(w io.Writer, items []repository.ScrapeRequestListItem) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tSTORE\tPRIORITY\tERROR KIND\tCREATED\tURL")
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			item.ID, item.Status, item.Store, services.PriorityName(item.Priority),
+			item.ErrorKind, item.CreatedAt.Format(time.RFC3339), item.URL,
+		)
+	}
+	return tw.Flush()
+}
+
+func runQueueStats(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "queue stats", "queue stats")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	db, err := env.openDB(ctx)
+	if err != nil {
+		return err
+	}
+	queueFactory, queueCloser, err := repository.NewQueueFactory(env.cfg.QueueBackend, env.cfg.RedisURL, env.cfg.QueueLease)
+	if err != nil {
+		return fmt.Errorf("queue setup failed: %w", err)
+	}
+	defer queueCloser.Close()
+
+	inspector, ok := queueFactory(db).(repository.QueueInspector)
+	if !ok {
+		return fmt.Errorf("%s queue does not report stats", env.cfg.QueueBackend)
+	}
+	stats, err := inspector.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]string, 0, len(stats.Depth))
+	for status := range stats.Depth {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+
+	tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	for _, status := range statuses {
+		fmt.Fprintf(tw, "%s\t%d\n", status, stats.Depth[status])
+	}
+	fmt.Fprintf(tw, "oldest pending\t%s\n", stats.OldestPendingAge.Round(time.Second))
+	return tw.Flush()
+}
+
+// parseSelection reads the ids and filters retry and cancel act on
+func parseSelection(env *env, name string, args []string) (services.ScrapeRequestSelection, error) {
+	fs := newFlagSet(env, "queue "+name, "queue "+name+" [-store s] [-error-kind k] [-all] [id...]")
+	store := fs.String("store", "", "only requests for this store")
+	errorKind := fs.String("error-kind", "", "only requests that failed this way")
+	all := fs.Bool("all", false, "select every request, required when neither ids nor filters are given")
+	if err := parseFlags(fs, args); err != nil {
+		return services.ScrapeRequestSelection{}, err
+	}
+	return services.ScrapeRequestSelection{IDs: fs.Args(), Store: *store, ErrorKind: *errorKind, All: *all}, nil
+}
+
+func runQueueRetry(ctx context.Context, env *env, args []string) error {
+	selection, err := parseSelection(env, "retry", args)
+	if err != nil {
+		return err
+	}
+
+	service, err := queueAdmin(ctx, env)
+	if err != nil {
+		return err
+	}
+	retried, err := service.Retry(ctx, selection)
+	if err != nil {
+		return describe(err)
+	}
+	fmt.Fprintf(env.stdout, "%d requests retried\n", retried)
+	return nil
+}
+
+func runQueueCancel(ctx context.Context, env *env, args []string) error {
+	selection, err := parseSelection(env, "cancel", args)
+	if err != nil {
+		return err
+	}
+
+	service, err := queueAdmin(ctx, env)
+	if err != nil {
+		return err
+	}
+	cancelled, err := service.Cancel(ctx, selection)
+	if err != nil {
+		return describe(err)
+	}
+	fmt.Fprintf(env.stdout, "%d requests cancelled\n", cancelled)
+	return nil
+}
+
+func runQueuePurge(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "queue purge", "queue purge [-older-than-days n]")
+	olderThanDays := fs.Int("older-than-days", int(services.DefaultPurgeAge/(24*time.Hour)), "age of the done requests deleted")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	service, err := queueAdmin(ctx, env)
+	if err != nil {
+		return err
+	}
+	purged, err := service.Purge(ctx, services.PurgeInput{OlderThanDays: *olderThanDays})
+	if err != nil {
+		return describe(err)
+	}
+	fmt.Fprintf(env.stdout, "%d requests purged\n", purged)
+	return nil
+}
+
+// describe spells out validation failures, their error alone only says the input was invalid
+func describe(err error) error {
+	var validationErr *apiErrors.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	msg := err.Error()
+	for _, field := range validationErr.Fields {
+		msg += ", " + field.Message
+	}
+	return errors.New(msg)
+}
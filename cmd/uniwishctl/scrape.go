@@ -0,0 +1,34 @@
+/*
+uniwish.com/interal/cmd/uniwishctl/scrape
+
+dry-run scraping, handy to check a store's pages still parse without touching the database
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func runScrape(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet(env, "scrape", "scrape <url>")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	url := fs.Arg(0)
+	scraper, err := env.registry.NewScraperFor(url)
+	if err != nil {
+		return fmt.Errorf("%s: %w", url, err)
+	}
+
+	record, err := scraper.Scrape(ctx, url)
+	if err != nil {
+		return fmt.Errorf("%s: %w", url, err)
+	}
+	return env.printJSON(record)
+}
@@ -8,16 +8,26 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	_ "github.com/lib/pq"
 
 	"uniwish.com/internal/api/config"
+	"uniwish.com/internal/api/handlers"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+	"uniwish.com/internal/blob"
+	"uniwish.com/internal/metrics"
 	"uniwish.com/internal/scrapers"
+	"uniwish.com/internal/tracing"
 	"uniwish.com/internal/worker"
 )
 
@@ -30,11 +40,16 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	cfg, err := config.Load()
 
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
 	if err != nil {
 		logger.Error("configuration load fail", "err", err)
 		os.Exit(1)
 	}
 
+	logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.LogLevel}))
+
 	db, err := sql.Open("postgres", cfg.DBURL)
 
 	if err != nil {
@@ -44,6 +59,11 @@ func main() {
 
 	defer db.Close()
 
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
 	ctx, stop := signal.NotifyContext(
 		context.Background(),
 		os.Interrupt,
@@ -56,19 +76,104 @@ func main() {
 		os.Exit(1)
 	}
 
-	workerRepo := worker.NewWorkerRepo(db)
-	newWorker := worker.NewWorker(workerRepo, scrapers.DefaultScraperRegistry)
+	shutdownTracing, err := tracing.Setup(ctx, "uniwish-worker")
+	if err != nil {
+		logger.Error("tracing setup failed", "err", err)
+		os.Exit(1)
+	}
+
+	defer shutdownTracing(context.Background())
+
+	queueFactory, queueCloser, err := repository.NewQueueFactory(cfg.QueueBackend, cfg.RedisURL, cfg.QueueLease)
+	if err != nil {
+		logger.Error("queue setup failed", "err", err)
+		os.Exit(1)
+	}
+
+	defer queueCloser.Close()
+
+	metricsRegistry := metrics.NewRegistry(db)
+	if inspector, ok := queueFactory(db).(repository.QueueInspector); ok {
+		metricsRegistry.MustRegister(metrics.NewQueueCollector(inspector))
+	}
+
+	registry, err := scrapers.NewConfiguredRegistry(cfg)
+	if err != nil {
+		logger.Error("scraper registry setup failed", "err", err)
+		os.Exit(1)
+	}
+
+	workerRepo := worker.NewWorkerRepo(db, queueFactory)
+	newWorker := worker.NewWorker(workerRepo, registry)
+	newWorker.LeaseHeartbeat = cfg.QueueLease / 3
+	newWorker.Metrics = worker.NewMetrics(metricsRegistry)
+
+	imageStore, err := blob.OpenImageStore(cfg)
+	if err != nil {
+		logger.Error("image store setup failed", "err", err)
+		os.Exit(1)
+	}
+	if imageStore != nil {
+		newWorker.Images = worker.NewImageMirror(imageStore, 10*time.Second)
+	}
 	workerSupervisor := worker.WorkerSupervisor{
 		Worker:           newWorker,
 		PollInterval:     cfg.WorkerPollInterval,
 		FailureTolerance: cfg.WorkerFailureTolerance,
+		Concurrency:      cfg.WorkerConcurrency,
 		Sleep:            time.Sleep,
 		OnFatal:          stop,
 		Logger:           logger,
 	}
 
+	// a loop running longer than a lease means the job it holds is up for grabs anyway
+	healthService := services.NewHealthService(map[string]services.HealthCheckFunc{
+		"database": services.DatabaseCheck(db),
+		"schema":   services.SchemaCheck(repository.NewPostgresSchemaReader(db), repository.SchemaVersion),
+		"loop":     services.RecencyCheck(workerSupervisor.LastSuccess, cfg.QueueLease),
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler(metricsRegistry))
+	mux.Handle("GET /livez", handlers.NewHealthHandler(healthService))
+	mux.Handle("GET /readyz", handlers.NewReadinessHandler(healthService))
+	httpServer := &http.Server{
+		Addr:    ":" + strconv.Itoa(cfg.WorkerHTTPPort),
+		Handler: mux,
+	}
+
+	go func() {
+		logger.Info("worker http server started", "addr", httpServer.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("worker http server error", "err", err)
+		}
+	}()
+
 	go workerSupervisor.Run(ctx)
 
+	if cfg.FXRatesSource != "" {
+		fxRefresher := &worker.FXRefresher{
+			Source:   cfg.FXRatesSource,
+			Interval: cfg.FXRatesRefresh,
+			Repo:     repository.NewPostgresFXRateRepository(db),
+			Logger:   logger,
+		}
+		go fxRefresher.Run(ctx)
+	}
+
+	webhookDispatcher := worker.NewWebhookDispatcher(repository.NewPostgresWebhookRepository(db), cfg.WebhookTimeout, logger)
+	webhookDispatcher.Interval = cfg.WebhookPollInterval
+	webhookDispatcher.MaxAttempts = cfg.WebhookMaxAttempts
+	webhookDispatcher.RetryBackoff = cfg.WebhookRetryBackoff
+	go webhookDispatcher.Run(ctx)
+
 	<-ctx.Done()
 	logger.Info("shutdown signal received")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("worker http server shutdown failed", "err", err)
+	}
 }
@@ -0,0 +1,116 @@
+/*
+uniwish.com/internal/api/certs
+
+serves the TLS certificate from disk, picking up renewals without a restart
+*/
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval is how often handshakes look for a renewed certificate
+const certCheckInterval = 30 * time.Second
+
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// NewCertReloader loads the key pair once, failing when it cannot be served at all
+func NewCertReloader(certFile, keyFile string, logger *slog.Logger) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile, logger: logger, now: time.Now}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload swaps in the key pair on disk, the served one is kept when it does not load
+func (c *CertReloader) Reload() error {
+	modTime, err := c.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls key pair: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.checked = c.now()
+	return nil
+}
+
+// GetCertificate plugs into tls.Config, reloading first when the files changed since the last look
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if c.due() {
+		c.reloadIfChanged()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+func (c *CertReloader) due() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.now().Sub(c.checked) < certCheckInterval {
+		return false
+	}
+	// claimed here so concurrent handshakes do not all stat the files
+	c.checked = c.now()
+	return true
+}
+
+func (c *CertReloader) reloadIfChanged() {
+	modTime, err := c.lastModified()
+	if err != nil {
+		c.logger.Warn("tls certificate check failed", "err", err)
+		return
+	}
+
+	c.mu.RLock()
+	changed := modTime.After(c.modTime)
+	c.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	// a renewal caught half written fails to load, the next check picks it up complete
+	if err := c.Reload(); err != nil {
+		c.logger.Warn("tls certificate reload failed, serving the previous one", "err", err)
+		return
+	}
+	c.logger.Info("tls certificate reloaded", "cert_file", c.certFile)
+}
+
+func (c *CertReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat tls file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,111 @@
+/*
+uniwish.com/internal/api/certs_test
+
+tests certificates are reloaded once renewed on disk
+*/
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeKeyPair(t *testing.T, dir string, serial int64, modTime time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der, modTime)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER, modTime)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, kind string, der []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("chtimes %s: %v", path, err)
+	}
+}
+
+func servedSerial(t *testing.T, c *CertReloader) int64 {
+	t.Helper()
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse served certificate: %v", err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile := writeKeyPair(t, dir, 1, start)
+
+	reloader, err := NewCertReloader(certFile, keyFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	writeKeyPair(t, dir, 2, start.Add(time.Minute))
+	if serial := servedSerial(t, reloader); serial != 1 {
+		t.Fatalf("expected the renewal to wait for the next check, received serial %d", serial)
+	}
+
+	now = now.Add(certCheckInterval)
+	if serial := servedSerial(t, reloader); serial != 2 {
+		t.Fatalf("expected the renewed certificate, received serial %d", serial)
+	}
+
+	// a broken renewal leaves the served certificate in place
+	writePEM(t, certFile, "CERTIFICATE", []byte("garbage"), start.Add(2*time.Minute))
+	now = now.Add(certCheckInterval)
+	if serial := servedSerial(t, reloader); serial != 2 {
+		t.Fatalf("expected the previous certificate, received serial %d", serial)
+	}
+}
+
+func TestNewCertReloader_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err == nil {
+		t.Fatal("expected an error for missing files")
+	}
+}
@@ -2,20 +2,155 @@
 uniwish.com/interal/api/config
 
 centralizes our config specification
+
+every field is a setting of settings.go, read from the YAML file, the environment
+and the command line in that order, the comments name the env var and default
 */
 package config
 
-import "time"
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
 
 type Config struct {
 	// APP_ENV, dev
 	Env string
+	// LOG_LEVEL, info (debug | info | warn | error)
+	LogLevel slog.Level
 	// APP_PORT, 8080
 	Port int
+	// HTTP_READ_HEADER_TIMEOUT, 5s, time allowed to read request headers
+	HTTPReadHeaderTimeout time.Duration
+	// HTTP_READ_TIMEOUT, 15s, time allowed to read a whole request
+	HTTPReadTimeout time.Duration
+	// HTTP_WRITE_TIMEOUT, 30s, time allowed to write a response
+	HTTPWriteTimeout time.Duration
+	// HTTP_IDLE_TIMEOUT, 2m, keep-alive connections idle longer are closed
+	HTTPIdleTimeout time.Duration
+	// HTTP_SHUTDOWN_TIMEOUT, 10s, time in-flight requests get to finish on shutdown
+	HTTPShutdownTimeout time.Duration
+	// HTTP_DRAIN_DELAY, 0s, time readiness fails before shutdown starts so load balancers stop routing to us
+	HTTPDrainDelay time.Duration
+	// HTTP_MAX_HEADER_BYTES, 65536
+	HTTPMaxHeaderBytes int
+	// HTTP_MAX_BODY_BYTES, 1048576, request bodies above are refused with 413
+	HTTPMaxBodyBytes int
+	// TLS_CERT_FILE and TLS_KEY_FILE, PEM files served over https when both are set, reloaded when they change
+	TLSCertFile string
+	TLSKeyFile  string
+	// GRPC_PORT, 0, port the gRPC api listens on alongside the REST one, 0 disables it
+	GRPCPort int
+	// CORS_ALLOWED_ORIGINS, comma separated origins allowed to call the api from a browser, chrome-extension://<id>
+	CORSAllowedOrigins []string
+	// TRUSTED_PROXIES, comma separated IPs or CIDRs of the proxies in front of the api, whose X-Forwarded-For
+	// and Forwarded headers are believed for the client's address, empty believes none
+	TrustedProxies []string
 	// DATABASE_URL,
 	DBURL string
-	// WORKER_POLL_INTERVAL int, 1
+	// DB_MAX_OPEN_CONNS, 25, 0 is unlimited
+	DBMaxOpenConns int
+	// DB_MAX_IDLE_CONNS, 5
+	DBMaxIdleConns int
+	// DB_CONN_MAX_LIFETIME, 30m, 0 keeps connections forever
+	DBConnMaxLifetime time.Duration
+	// DB_CONN_MAX_IDLE_TIME, 5m, 0 keeps idle connections forever
+	DBConnMaxIdleTime time.Duration
+	// WORKER_POLL_INTERVAL, 1s, a bare int counts seconds
 	WorkerPollInterval time.Duration
 	// WORKER_FAILURE_TOLERANCE, 10
 	WorkerFailureTolerance int
+	// WORKER_HTTP_PORT, 9090, serves worker metrics and probes
+	WorkerHTTPPort int
+	// WORKER_CONCURRENCY, 1, jobs a worker process scrapes at once
+	WorkerConcurrency int
+	// QUEUE_BACKEND, postgres (postgres | redis)
+	QueueBackend string
+	// REDIS_URL, required when QUEUE_BACKEND is redis
+	RedisURL string
+	// QUEUE_ADMIN, true, serves the /admin/scrape-requests endpoints, which the redis backend does not support
+	QueueAdmin bool
+	// QUEUE_LEASE, 5m, a bare int counts seconds
+	QueueLease time.Duration
+	// RATE_LIMIT_PER_MINUTE, 30, scrape requests refilled per client IP and per API token, 0 disables
+	RateLimitPerMinute int
+	// RATE_LIMIT_BURST, 10
+	RateLimitBurst int
+	// SCRAPE_REQUEST_DAILY_QUOTA, 500, scrape requests per client IP and per user, and UTC day, 0 disables
+	ScrapeRequestDailyQuota int
+	// SCRAPE_REQUEST_BATCH_LIMIT, 500, urls accepted per batch, at most 10922 fit one insert
+	ScrapeRequestBatchLimit int
+	// PRODUCT_DISCONTINUED_GRACE, 168h, a bare int counts hours
+	ProductDiscontinuedGrace time.Duration
+	// FX_RATES_SOURCE, file path or http(s) url of an ECB format rates feed, empty disables refreshing
+	FXRatesSource string
+	// FX_RATES_REFRESH, 24h, a bare int counts hours
+	FXRatesRefresh time.Duration
+	// WEBHOOK_POLL_INTERVAL, 5s, a bare int counts seconds
+	WebhookPollInterval time.Duration
+	// WEBHOOK_TIMEOUT, 10s, time a receiver gets to respond
+	WebhookTimeout time.Duration
+	// WEBHOOK_MAX_ATTEMPTS, 8, attempts before a delivery fails for good
+	WebhookMaxAttempts int
+	// WEBHOOK_RETRY_BACKOFF, 30s, pause before the first retry, doubling with each further one
+	WebhookRetryBackoff time.Duration
+	// IMAGE_STORE, "" (filesystem | s3), where product images are mirrored, empty disables mirroring
+	ImageStore string
+	// IMAGE_STORE_DIR, data/images, root of the filesystem image store
+	ImageStoreDir string
+	// S3_ENDPOINT, host[:port] of S3 or MinIO, required when IMAGE_STORE is s3
+	S3Endpoint string
+	// S3_BUCKET, required when IMAGE_STORE is s3
+	S3Bucket string
+	// S3_REGION,
+	S3Region string
+	// S3_ACCESS_KEY,
+	S3AccessKey string
+	// S3_SECRET_KEY,
+	S3SecretKey string
+	// S3_USE_SSL, true
+	S3UseSSL bool
+	// ADMIN_TOKEN, bearer token guarding the /admin endpoints, empty rejects every admin request
+	AdminToken string
+	// AUTO_MIGRATE, false, apply the embedded migrations when the api starts
+	AutoMigrate bool
+	// SCRAPER_TIMEOUT, 10s, timeout of a store's scraper unless overridden in Scrapers
+	ScraperTimeout time.Duration
+	// SCRAPER_<STORE>_TIMEOUT and SCRAPER_<STORE>_ENABLED, per store overrides keyed by StoreKey
+	Scrapers map[string]ScraperConfig
+
+	// sources records where each setting was last set from, keyed like settings
+	sources map[string]Source
+}
+
+// ScraperConfig tunes the scraper of a single store
+type ScraperConfig struct {
+	// Timeout bounds a scrape, zero falls back to Config.ScraperTimeout
+	Timeout time.Duration
+	// Enabled false drops the store from the registry, its urls are then rejected
+	Enabled bool
+}
+
+// Scraper resolves the settings of a store, filling in the defaults it does not override
+func (c *Config) Scraper(store string) ScraperConfig {
+	scraper, ok := c.Scrapers[StoreKey(store)]
+	if !ok {
+		scraper = ScraperConfig{Enabled: true}
+	}
+	if scraper.Timeout == 0 {
+		scraper.Timeout = c.ScraperTimeout
+	}
+	return scraper
+}
+
+// StoreKey names a store in config, its host with every other character than letters and digits as underscores
+// so zara.com is zara_com in the file and SCRAPER_ZARA_COM_TIMEOUT in the environment
+func StoreKey(store string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(store))
 }
@@ -0,0 +1,266 @@
+/*
+uniwish.com/interal/api/config/config_test
+
+tests config layering, validation and redaction
+*/
+package config
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestParse_Defaults(t *testing.T) {
+	cfg, err := Parse(Options{Environ: []string{}})
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	if cfg.Port != 8080 || cfg.QueueBackend != "postgres" || cfg.WorkerConcurrency != 1 {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+	if cfg.QueueLease != 5*time.Minute || cfg.FXRatesRefresh != 24*time.Hour || cfg.DBConnMaxLifetime != 30*time.Minute {
+		t.Fatalf("unexpected duration defaults %+v", cfg)
+	}
+	if cfg.LogLevel != slog.LevelInfo || !cfg.S3UseSSL {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+	if cfg.Source("http.port") != SourceDefault {
+		t.Fatalf("expected default source, received %s", cfg.Source("http.port"))
+	}
+}
+
+func TestParse_Layers(t *testing.T) {
+	file := writeFile(t, `
+http:
+  port: 8000
+  read_timeout: 5s
+database:
+  max_open_conns: 40
+worker:
+  concurrency: 2
+log:
+  level: debug
+scrapers:
+  zara.com:
+    timeout: 20s
+`)
+
+	cfg, err := Parse(Options{
+		File:    file,
+		Environ: []string{"APP_PORT=8001", "WORKER_CONCURRENCY=3", "QUEUE_LEASE=60", "SCRAPER_ZARA_COM_ENABLED=false"},
+		Args:    []string{"-http.port", "8002", "-scraper", "zara.com.timeout=30s"},
+	})
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	tests := []struct {
+		key            string
+		expectedSource Source
+		ok             bool
+	}{
+		{"http.port", SourceFlag, cfg.Port == 8002},
+		{"http.read_timeout", SourceFile, cfg.HTTPReadTimeout == 5*time.Second},
+		{"database.max_open_conns", SourceFile, cfg.DBMaxOpenConns == 40},
+		{"worker.concurrency", SourceEnv, cfg.WorkerConcurrency == 3},
+		{"queue.lease", SourceEnv, cfg.QueueLease == time.Minute},
+		{"log.level", SourceFile, cfg.LogLevel == slog.LevelDebug},
+		{"scrapers.zara_com.timeout", SourceFlag, cfg.Scraper("zara.com").Timeout == 30*time.Second},
+		{"scrapers.zara_com.enabled", SourceEnv, !cfg.Scraper("zara.com").Enabled},
+		{"worker.http_port", SourceDefault, cfg.WorkerHTTPPort == 9090},
+	}
+
+	for _, tt := range tests {
+		if !tt.ok {
+			t.Fatalf("%s: unexpected value in %+v", tt.key, cfg)
+		}
+		if source := cfg.Source(tt.key); source != tt.expectedSource {
+			t.Fatalf("%s: expected source %s, received %s", tt.key, tt.expectedSource, source)
+		}
+	}
+}
+
+func TestParse_Lists(t *testing.T) {
+	file := writeFile(t, "http:\n  cors_origins:\n    - chrome-extension://abc\n    - https://uniwish.com\n")
+
+	cfg, err := Parse(Options{File: file, Environ: []string{}})
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	if len(cfg.CORSAllowedOrigins) != 2 || cfg.CORSAllowedOrigins[1] != "https://uniwish.com" {
+		t.Fatalf("unexpected origins %q", cfg.CORSAllowedOrigins)
+	}
+
+	cfg, err = Parse(Options{Environ: []string{"CORS_ALLOWED_ORIGINS=chrome-extension://abc, ,*"}})
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	if len(cfg.CORSAllowedOrigins) != 2 || cfg.CORSAllowedOrigins[1] != "*" {
+		t.Fatalf("unexpected origins %q", cfg.CORSAllowedOrigins)
+	}
+}
+
+func TestConfig_Scraper(t *testing.T) {
+	cfg, err := Parse(Options{Environ: []string{"SCRAPER_TIMEOUT=5s", "SCRAPER_ZARA_COM_ENABLED=false"}})
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	zara := cfg.Scraper("zara.com")
+	if zara.Enabled || zara.Timeout != 5*time.Second {
+		t.Fatalf("unexpected zara settings %+v", zara)
+	}
+	other := cfg.Scraper("mango.com")
+	if !other.Enabled || other.Timeout != 5*time.Second {
+		t.Fatalf("unexpected default settings %+v", other)
+	}
+}
+
+func TestParse_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectedKey string
+		expectedErr error
+	}{
+		{name: "bad_int_env", opts: Options{Environ: []string{"APP_PORT=eighty"}}, expectedKey: "http.port", expectedErr: ErrInvalidValue},
+		{name: "bad_duration_flag", opts: Options{Args: []string{"-http.read_timeout", "soon"}}, expectedKey: "http.read_timeout", expectedErr: ErrInvalidValue},
+		{name: "bad_level", opts: Options{Environ: []string{"LOG_LEVEL=loud"}}, expectedKey: "log.level", expectedErr: ErrInvalidValue},
+		{name: "port_range", opts: Options{Environ: []string{"APP_PORT=70000"}}, expectedKey: "http.port", expectedErr: ErrOutOfRange},
+		{name: "grpc_port_range", opts: Options{Environ: []string{"GRPC_PORT=-1"}}, expectedKey: "grpc.port", expectedErr: ErrOutOfRange},
+		{name: "grpc_port_taken", opts: Options{Environ: []string{"GRPC_PORT=8080"}}, expectedKey: "grpc.port", expectedErr: ErrInvalidValue},
+		{name: "negative_pool", opts: Options{Environ: []string{"DB_MAX_OPEN_CONNS=-1"}}, expectedKey: "database.max_open_conns", expectedErr: ErrOutOfRange},
+		{name: "no_concurrency", opts: Options{Environ: []string{"WORKER_CONCURRENCY=0"}}, expectedKey: "worker.concurrency", expectedErr: ErrOutOfRange},
+		{name: "unknown_backend", opts: Options{Environ: []string{"QUEUE_BACKEND=kafka"}}, expectedKey: "queue.backend", expectedErr: ErrInvalidValue},
+		{name: "redis_without_url", opts: Options{Environ: []string{"QUEUE_BACKEND=redis", "QUEUE_ADMIN=false"}}, expectedKey: "queue.redis_url", expectedErr: ErrRequired},
+		{name: "invalid_trusted_proxy", opts: Options{Environ: []string{"TRUSTED_PROXIES=10.0.0.0/8,proxy.internal"}}, expectedKey: "http.trusted_proxies", expectedErr: ErrInvalidValue},
+		{name: "redis_queue_admin", opts: Options{Environ: []string{"QUEUE_BACKEND=redis", "REDIS_URL=redis://redis:6379/0"}}, expectedKey: "queue.admin", expectedErr: ErrInvalidValue},
+		{name: "s3_without_bucket", opts: Options{Environ: []string{"IMAGE_STORE=s3", "S3_ENDPOINT=minio:9000"}}, expectedKey: "images.s3.bucket", expectedErr: ErrRequired},
+		{name: "database_required", opts: Options{RequireDatabase: true}, expectedKey: "database.url", expectedErr: ErrNoDatabaseURL},
+		{name: "cert_without_key", opts: Options{Environ: []string{"TLS_CERT_FILE=cert.pem"}}, expectedKey: "http.tls.key_file", expectedErr: ErrRequired},
+		{name: "no_body", opts: Options{Environ: []string{"HTTP_MAX_BODY_BYTES=0"}}, expectedKey: "http.max_body_bytes", expectedErr: ErrOutOfRange},
+		{name: "batch_limit_range", opts: Options{Environ: []string{"SCRAPE_REQUEST_BATCH_LIMIT=20000"}}, expectedKey: "scrape_requests.batch_limit", expectedErr: ErrOutOfRange},
+		{name: "bad_store_timeout", opts: Options{Environ: []string{"SCRAPER_ZARA_COM_TIMEOUT=fast"}}, expectedKey: "scrapers.zara_com.timeout", expectedErr: ErrInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opts.Environ == nil {
+				tt.opts.Environ = []string{}
+			}
+
+			_, err := Parse(tt.opts)
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected a ValidationError, received %v", err)
+			}
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected %v, received %v", tt.expectedErr, err)
+			}
+			if len(ve.Fields) != 1 || ve.Fields[0].Key != tt.expectedKey {
+				t.Fatalf("expected a single %s error, received %v", tt.expectedKey, err)
+			}
+		})
+	}
+}
+
+func TestParse_UnknownFileKey(t *testing.T) {
+	file := writeFile(t, "http:\n  prot: 8080\nscrapers:\n  zara.com:\n    retries: 3\n")
+
+	_, err := Parse(Options{File: file, Environ: []string{}})
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) || !errors.Is(err, ErrUnknownKey) || len(ve.Fields) != 2 {
+		t.Fatalf("expected two unknown keys, received %v", err)
+	}
+	if ve.Fields[0].Source != SourceFile || ve.Fields[0].Name != file {
+		t.Fatalf("expected the file to be reported, received %+v", ve.Fields[0])
+	}
+}
+
+func TestParse_ErrorsOmitValues(t *testing.T) {
+	_, err := Parse(Options{Environ: []string{"QUEUE_BACKEND=redis", "APP_PORT=x"}})
+
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if !strings.Contains(err.Error(), "env APP_PORT") || !strings.Contains(err.Error(), "queue.redis_url") {
+		t.Fatalf("expected both fields and their sources, received %v", err)
+	}
+}
+
+func TestConfig_EffectiveRedactsSecrets(t *testing.T) {
+	cfg, err := Parse(Options{Environ: []string{
+		"DATABASE_URL=postgres://uniwish:hunter2@db:5432/uniwish?sslmode=disable",
+		"REDIS_URL=redis://redis:6379/0?password=hunter2",
+		"QUEUE_BACKEND=redis",
+		"QUEUE_ADMIN=false",
+		"S3_SECRET_KEY=hunter2",
+		"ADMIN_TOKEN=hunter2",
+		"SCRAPER_ZARA_COM_TIMEOUT=15s",
+	}})
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	values := map[string]Value{}
+	for _, v := range cfg.Effective() {
+		if strings.Contains(v.Value, "hunter2") {
+			t.Fatalf("%s leaks its secret: %s", v.Key, v.Value)
+		}
+		values[v.Key] = v
+	}
+
+	if v := values["database.url"].Value; v != "postgres://uniwish:xxxxx@db:5432/uniwish?sslmode=disable" {
+		t.Fatalf("expected the database url without its password, received %s", v)
+	}
+	if v := values["admin.token"]; v.Value != Redacted || v.Source != SourceEnv || v.Env != "ADMIN_TOKEN" {
+		t.Fatalf("unexpected admin token %+v", v)
+	}
+	if v := values["images.s3.access_key"].Value; v != "" {
+		t.Fatalf("expected unset secrets to stay empty, received %s", v)
+	}
+	if v := values["scrapers.zara_com.timeout"]; v.Value != "15s" || v.Env != "SCRAPER_ZARA_COM_TIMEOUT" {
+		t.Fatalf("unexpected store override %+v", v)
+	}
+}
+
+func TestConfig_WriteYAMLRoundTrips(t *testing.T) {
+	cfg, err := Parse(Options{Environ: []string{"APP_PORT=8001", "SCRAPER_ZARA_COM_ENABLED=false", "ADMIN_TOKEN=hunter2"}})
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := cfg.WriteYAML(&out); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Fatalf("printed config leaks a secret\n%s", out.String())
+	}
+
+	reread, err := Parse(Options{File: writeFile(t, out.String()), Environ: []string{}})
+	if err != nil {
+		t.Fatalf("expected the printed config to parse, received %v\n%s", err, out.String())
+	}
+	if reread.Port != 8001 || reread.Scraper("zara.com").Enabled || reread.QueueLease != cfg.QueueLease {
+		t.Fatalf("unexpected round trip %+v\n%s", reread, out.String())
+	}
+}
@@ -0,0 +1,74 @@
+/*
+uniwish.com/interal/api/config/errors
+
+typed errors reporting which setting is wrong and where its value came from
+*/
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidValue = errors.New("invalid value")
+	ErrRequired     = errors.New("required")
+	ErrOutOfRange   = errors.New("out of range")
+	ErrUnknownKey   = errors.New("unknown key")
+)
+
+// ErrNoDatabaseURL is reported when a process needing its database has no DATABASE_URL
+var ErrNoDatabaseURL = fmt.Errorf("DATABASE_URL %w", ErrRequired)
+
+// Source is the layer a setting was read from, later layers override earlier ones
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// FieldError reports a single setting, values are left out as they may be secrets
+type FieldError struct {
+	// Key is the setting's dotted key, as written in the config file
+	Key    string
+	Source Source
+	// Name is what set the value in its source, the env var, the flag or the file path
+	Name string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %v (%s %s)", e.Key, e.Err, e.Source, e.Name)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError gathers every invalid setting so they can be fixed in one go
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Error()
+	}
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, field := range e.Fields {
+		errs[i] = field
+	}
+	return errs
+}
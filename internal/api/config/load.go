@@ -0,0 +1,235 @@
+/*
+uniwish.com/interal/config/load
+
+module dedicated to logic around loading config
+
+settings are layered, defaults then the YAML file then the environment then flags,
+every layer only overriding what it sets
+*/
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type Options struct {
+	// Args are command line flags, each setting has one named after its key, -http.port 8081
+	Args []string
+	// Environ is the environment as KEY=value pairs, os.Environ when nil
+	Environ []string
+	// File is a YAML config file, when empty -config or CONFIG_FILE name one
+	File string
+	// RequireDatabase fails validation without a DATABASE_URL
+	RequireDatabase bool
+	// Output receives flag usage, os.Stderr when nil
+	Output io.Writer
+}
+
+// Load reads the config of a process that cannot run without its database, flags come from os.Args
+func Load() (*Config, error) {
+	return Parse(Options{Args: os.Args[1:], RequireDatabase: true})
+}
+
+// Parse layers the config described by opts and validates the result
+func Parse(opts Options) (*Config, error) {
+	if opts.Environ == nil {
+		opts.Environ = os.Environ()
+	}
+	env := make(map[string]string, len(opts.Environ))
+	for _, kv := range opts.Environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	flags, file, err := parseFlags(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.File != "" {
+		file = opts.File
+	}
+	if file == "" {
+		file = env["CONFIG_FILE"]
+	}
+
+	cfg := &Config{Scrapers: map[string]ScraperConfig{}, sources: map[string]Source{}}
+	var errs []*FieldError
+
+	for _, s := range settings {
+		if err := s.set(cfg, s.def); err != nil {
+			// defaults are ours, one failing to parse is a bug in the table
+			panic(fmt.Sprintf("config: default of %s: %v", s.key, err))
+		}
+		cfg.sources[s.key] = SourceDefault
+	}
+
+	if file != "" {
+		values, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			errs = appendErr(errs, cfg.apply(v.key, v.value, SourceFile, file))
+		}
+	}
+
+	for _, s := range settings {
+		// empty env vars count as unset, as they always have
+		if v := env[s.env]; v != "" {
+			errs = appendErr(errs, cfg.apply(s.key, v, SourceEnv, s.env))
+		}
+	}
+	for _, v := range storeEnvValues(env) {
+		errs = appendErr(errs, cfg.apply(v.key, v.value, SourceEnv, v.name))
+	}
+
+	for _, v := range flags {
+		errs = appendErr(errs, cfg.apply(v.key, v.value, SourceFlag, "-"+v.name))
+	}
+
+	errs = append(errs, cfg.validate(opts.RequireDatabase)...)
+	if len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+	return cfg, nil
+}
+
+type rawValue struct {
+	key   string
+	name  string
+	value string
+}
+
+func appendErr(errs []*FieldError, err *FieldError) []*FieldError {
+	if err == nil {
+		return errs
+	}
+	return append(errs, err)
+}
+
+func (c *Config) apply(key, raw string, source Source, name string) *FieldError {
+	if s, ok := lookupSetting(key); ok {
+		if err := s.set(c, raw); err != nil {
+			return &FieldError{Key: key, Source: source, Name: name, Err: err}
+		}
+		c.sources[key] = source
+		return nil
+	}
+
+	store, field, ok := storeSetting(key)
+	if !ok {
+		return &FieldError{Key: key, Source: source, Name: name, Err: ErrUnknownKey}
+	}
+	scraper, exists := c.Scrapers[store]
+	if !exists {
+		scraper = ScraperConfig{Enabled: true}
+	}
+	if err := storeFields[field](&scraper, raw); err != nil {
+		return &FieldError{Key: storeKey(store, field), Source: source, Name: name, Err: err}
+	}
+	c.Scrapers[store] = scraper
+	c.sources[storeKey(store, field)] = source
+	return nil
+}
+
+func parseFlags(opts Options) ([]rawValue, string, error) {
+	fs := flag.NewFlagSet("uniwish", flag.ContinueOnError)
+	if opts.Output != nil {
+		fs.SetOutput(opts.Output)
+	}
+
+	var values []rawValue
+	var file string
+	fs.StringVar(&file, "config", "", "YAML config file (CONFIG_FILE)")
+	for _, s := range settings {
+		fs.Func(s.key, fmt.Sprintf("%s (%s, default %q)", s.usage, s.env, s.def), func(v string) error {
+			values = append(values, rawValue{key: s.key, name: s.key, value: v})
+			return nil
+		})
+	}
+	fs.Func("scraper", "store override as store.field=value, zara_com.timeout=15s, repeatable", func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return errors.New("expected store.field=value")
+		}
+		values = append(values, rawValue{key: "scrapers." + key, name: "scraper", value: value})
+		return nil
+	})
+
+	if err := fs.Parse(opts.Args); err != nil {
+		return nil, "", err
+	}
+	if fs.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return values, file, nil
+}
+
+// storeEnvValues picks SCRAPER_<STORE>_<FIELD> vars out of the environment, sorted for stable errors
+func storeEnvValues(env map[string]string) []rawValue {
+	var values []rawValue
+	for name, v := range env {
+		rest, ok := strings.CutPrefix(name, "SCRAPER_")
+		if !ok || v == "" {
+			continue
+		}
+		for field := range storeFields {
+			store, ok := strings.CutSuffix(rest, "_"+strings.ToUpper(field))
+			if ok && store != "" {
+				values = append(values, rawValue{key: storeKey(StoreKey(store), field), name: name, value: v})
+			}
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].name < values[j].name })
+	return values
+}
+
+// readFile flattens the YAML file into dotted keys
+func readFile(path string) ([]rawValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	var values []rawValue
+	flatten("", root, &values)
+	sort.Slice(values, func(i, j int) bool { return values[i].key < values[j].key })
+	return values, nil
+}
+
+func flatten(prefix string, node map[string]any, values *[]rawValue) {
+	for k, v := range node {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(key, v, values)
+		case nil:
+			*values = append(*values, rawValue{key: key, value: ""})
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			*values = append(*values, rawValue{key: key, value: strings.Join(items, ",")})
+		default:
+			*values = append(*values, rawValue{key: key, value: fmt.Sprint(v)})
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+uniwish.com/interal/api/config/print
+
+renders the effective config with secrets redacted, for operators checking what a process runs with
+*/
+package config
+
+import (
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Redacted replaces secrets, urls keep everything but their password which reads xxxxx
+const Redacted = "[redacted]"
+
+// Value is a setting as the process sees it
+type Value struct {
+	Key    string
+	Env    string
+	Value  string
+	Source Source
+}
+
+// Effective lists every setting then every store override, secrets redacted
+func (c *Config) Effective() []Value {
+	values := make([]Value, 0, len(settings)+2*len(c.Scrapers))
+	for _, s := range settings {
+		v := s.get(c)
+		if s.secret {
+			v = redact(v)
+		}
+		values = append(values, Value{Key: s.key, Env: s.env, Value: v, Source: c.Source(s.key)})
+	}
+
+	stores := make([]string, 0, len(c.Scrapers))
+	for store := range c.Scrapers {
+		stores = append(stores, store)
+	}
+	sort.Strings(stores)
+	for _, store := range stores {
+		scraper := c.Scrapers[store]
+		for _, field := range []struct{ name, value string }{
+			{"enabled", strconv.FormatBool(scraper.Enabled)},
+			{"timeout", c.Scraper(store).Timeout.String()},
+		} {
+			key := storeKey(store, field.name)
+			values = append(values, Value{Key: key, Env: storeEnvName(store, field.name), Value: field.value, Source: c.Source(key)})
+		}
+	}
+	return values
+}
+
+// WriteYAML writes the effective config as a file Parse reads back, secrets aside
+func (c *Config) WriteYAML(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, v := range c.Effective() {
+		// store keys never hold dots so every key splits back into its path
+		setNode(root, strings.Split(v.Key, "."), v.Value)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func setNode(node *yaml.Node, path []string, value string) {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == path[0] {
+			setNode(node.Content[i+1], path[1:], value)
+			return
+		}
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}
+	var child *yaml.Node
+	if len(path) == 1 {
+		child = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	} else {
+		child = &yaml.Node{Kind: yaml.MappingNode}
+		setNode(child, path[1:], value)
+	}
+	node.Content = append(node.Content, key, child)
+}
+
+func redact(v string) string {
+	if v == "" {
+		return ""
+	}
+	if u, err := url.Parse(v); err == nil && u.Scheme != "" && u.Host != "" {
+		// postgres also takes the password as a query parameter
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.Redacted()
+	}
+	return Redacted
+}
@@ -0,0 +1,257 @@
+/*
+uniwish.com/interal/api/config/settings
+
+the table of every setting, its file key, env var, default and how it is parsed
+
+file keys are dotted paths into the YAML file and double as flag names
+*/
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type setting struct {
+	key    string
+	env    string
+	def    string
+	usage  string
+	secret bool
+	set    func(cfg *Config, raw string) error
+	get    func(cfg *Config) string
+}
+
+// redacted marks a setting whose value is masked when the effective config is printed
+func (s setting) redacted() setting {
+	s.secret = true
+	return s
+}
+
+var settings = []setting{
+	stringSetting("app.env", "APP_ENV", "dev", "deployment environment", func(c *Config) *string { return &c.Env }),
+	levelSetting("log.level", "LOG_LEVEL", "info", "minimum log level (debug | info | warn | error)", func(c *Config) *slog.Level { return &c.LogLevel }),
+
+	intSetting("http.port", "APP_PORT", "8080", "port the api listens on", func(c *Config) *int { return &c.Port }),
+	durationSetting("http.read_header_timeout", "HTTP_READ_HEADER_TIMEOUT", "5s", 0, "time allowed to read request headers", func(c *Config) *time.Duration { return &c.HTTPReadHeaderTimeout }),
+	durationSetting("http.read_timeout", "HTTP_READ_TIMEOUT", "15s", 0, "time allowed to read a whole request", func(c *Config) *time.Duration { return &c.HTTPReadTimeout }),
+	durationSetting("http.write_timeout", "HTTP_WRITE_TIMEOUT", "30s", 0, "time allowed to write a response", func(c *Config) *time.Duration { return &c.HTTPWriteTimeout }),
+	durationSetting("http.idle_timeout", "HTTP_IDLE_TIMEOUT", "2m", 0, "keep-alive connections idle longer are closed", func(c *Config) *time.Duration { return &c.HTTPIdleTimeout }),
+	durationSetting("http.shutdown_timeout", "HTTP_SHUTDOWN_TIMEOUT", "10s", 0, "time in-flight requests get to finish on shutdown", func(c *Config) *time.Duration { return &c.HTTPShutdownTimeout }),
+	durationSetting("http.drain_delay", "HTTP_DRAIN_DELAY", "0s", 0, "time readiness fails before shutdown starts", func(c *Config) *time.Duration { return &c.HTTPDrainDelay }),
+	intSetting("http.max_header_bytes", "HTTP_MAX_HEADER_BYTES", "65536", "request header size cap", func(c *Config) *int { return &c.HTTPMaxHeaderBytes }),
+	intSetting("http.max_body_bytes", "HTTP_MAX_BODY_BYTES", "1048576", "request body size cap, larger bodies are refused with 413", func(c *Config) *int { return &c.HTTPMaxBodyBytes }),
+	stringSetting("http.tls.cert_file", "TLS_CERT_FILE", "", "PEM certificate served over https, reloaded when it changes", func(c *Config) *string { return &c.TLSCertFile }),
+	stringSetting("http.tls.key_file", "TLS_KEY_FILE", "", "PEM private key of the certificate", func(c *Config) *string { return &c.TLSKeyFile }),
+	listSetting("http.cors_origins", "CORS_ALLOWED_ORIGINS", "", "comma separated origins allowed to call the api from a browser, * allows any", func(c *Config) *[]string { return &c.CORSAllowedOrigins }),
+	listSetting("http.trusted_proxies", "TRUSTED_PROXIES", "", "comma separated IPs or CIDRs of proxies whose X-Forwarded-For and Forwarded headers are believed", func(c *Config) *[]string { return &c.TrustedProxies }),
+
+	intSetting("grpc.port", "GRPC_PORT", "0", "port the gRPC api listens on, 0 disables it", func(c *Config) *int { return &c.GRPCPort }),
+
+	stringSetting("database.url", "DATABASE_URL", "", "postgres connection url", func(c *Config) *string { return &c.DBURL }).redacted(),
+	intSetting("database.max_open_conns", "DB_MAX_OPEN_CONNS", "25", "open connections cap, 0 is unlimited", func(c *Config) *int { return &c.DBMaxOpenConns }),
+	intSetting("database.max_idle_conns", "DB_MAX_IDLE_CONNS", "5", "idle connections kept in the pool", func(c *Config) *int { return &c.DBMaxIdleConns }),
+	durationSetting("database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", "30m", 0, "connections older are recycled, 0 keeps them", func(c *Config) *time.Duration { return &c.DBConnMaxLifetime }),
+	durationSetting("database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME", "5m", 0, "connections idle longer are closed, 0 keeps them", func(c *Config) *time.Duration { return &c.DBConnMaxIdleTime }),
+	boolSetting("database.auto_migrate", "AUTO_MIGRATE", "false", "apply the embedded migrations when the api starts", func(c *Config) *bool { return &c.AutoMigrate }),
+
+	durationSetting("worker.poll_interval", "WORKER_POLL_INTERVAL", "1s", time.Second, "pause between claims, a bare int counts seconds", func(c *Config) *time.Duration { return &c.WorkerPollInterval }),
+	intSetting("worker.failure_tolerance", "WORKER_FAILURE_TOLERANCE", "10", "consecutive worker errors before it exits", func(c *Config) *int { return &c.WorkerFailureTolerance }),
+	intSetting("worker.http_port", "WORKER_HTTP_PORT", "9090", "port serving worker metrics and probes", func(c *Config) *int { return &c.WorkerHTTPPort }),
+	intSetting("worker.concurrency", "WORKER_CONCURRENCY", "1", "jobs a worker process scrapes at once", func(c *Config) *int { return &c.WorkerConcurrency }),
+
+	stringSetting("queue.backend", "QUEUE_BACKEND", "postgres", "scrape queue backend (postgres | redis)", func(c *Config) *string { return &c.QueueBackend }),
+	stringSetting("queue.redis_url", "REDIS_URL", "", "redis url, required by the redis backend", func(c *Config) *string { return &c.RedisURL }).redacted(),
+	boolSetting("queue.admin", "QUEUE_ADMIN", "true", "serve the /admin/scrape-requests endpoints, must be false with the redis backend", func(c *Config) *bool { return &c.QueueAdmin }),
+	durationSetting("queue.lease", "QUEUE_LEASE", "5m", time.Second, "time a claimed job is held, a bare int counts seconds", func(c *Config) *time.Duration { return &c.QueueLease }),
+
+	intSetting("rate_limit.per_minute", "RATE_LIMIT_PER_MINUTE", "30", "scrape requests refilled per client IP and per API token, 0 disables", func(c *Config) *int { return &c.RateLimitPerMinute }),
+	intSetting("rate_limit.burst", "RATE_LIMIT_BURST", "10", "scrape requests allowed at once", func(c *Config) *int { return &c.RateLimitBurst }),
+
+	intSetting("scrape_requests.daily_quota", "SCRAPE_REQUEST_DAILY_QUOTA", "500", "scrape requests per client IP and per user, and UTC day, 0 disables", func(c *Config) *int { return &c.ScrapeRequestDailyQuota }),
+	intSetting("scrape_requests.batch_limit", "SCRAPE_REQUEST_BATCH_LIMIT", "500", "urls accepted per batch", func(c *Config) *int { return &c.ScrapeRequestBatchLimit }),
+	durationSetting("products.discontinued_grace", "PRODUCT_DISCONTINUED_GRACE", "168h", time.Hour, "time a discontinued product's url is still scraped, a bare int counts hours", func(c *Config) *time.Duration { return &c.ProductDiscontinuedGrace }),
+
+	stringSetting("fx_rates.source", "FX_RATES_SOURCE", "", "file path or http(s) url of an ECB format rates feed, empty disables refreshing", func(c *Config) *string { return &c.FXRatesSource }),
+	durationSetting("fx_rates.refresh", "FX_RATES_REFRESH", "24h", time.Hour, "pause between rate refreshes, a bare int counts hours", func(c *Config) *time.Duration { return &c.FXRatesRefresh }),
+
+	durationSetting("webhooks.poll_interval", "WEBHOOK_POLL_INTERVAL", "5s", time.Second, "pause between polls for due deliveries, a bare int counts seconds", func(c *Config) *time.Duration { return &c.WebhookPollInterval }),
+	durationSetting("webhooks.timeout", "WEBHOOK_TIMEOUT", "10s", 0, "time a receiver gets to respond", func(c *Config) *time.Duration { return &c.WebhookTimeout }),
+	intSetting("webhooks.max_attempts", "WEBHOOK_MAX_ATTEMPTS", "8", "attempts before a delivery fails for good", func(c *Config) *int { return &c.WebhookMaxAttempts }),
+	durationSetting("webhooks.retry_backoff", "WEBHOOK_RETRY_BACKOFF", "30s", 0, "pause before the first retry, doubling with each further one", func(c *Config) *time.Duration { return &c.WebhookRetryBackoff }),
+
+	stringSetting("images.store", "IMAGE_STORE", "", "where product images are mirrored (filesystem | s3), empty disables mirroring", func(c *Config) *string { return &c.ImageStore }),
+	stringSetting("images.dir", "IMAGE_STORE_DIR", "data/images", "root of the filesystem image store", func(c *Config) *string { return &c.ImageStoreDir }),
+	stringSetting("images.s3.endpoint", "S3_ENDPOINT", "", "host[:port] of S3 or MinIO", func(c *Config) *string { return &c.S3Endpoint }),
+	stringSetting("images.s3.bucket", "S3_BUCKET", "", "bucket images are stored in", func(c *Config) *string { return &c.S3Bucket }),
+	stringSetting("images.s3.region", "S3_REGION", "", "bucket region", func(c *Config) *string { return &c.S3Region }),
+	stringSetting("images.s3.access_key", "S3_ACCESS_KEY", "", "S3 access key", func(c *Config) *string { return &c.S3AccessKey }).redacted(),
+	stringSetting("images.s3.secret_key", "S3_SECRET_KEY", "", "S3 secret key", func(c *Config) *string { return &c.S3SecretKey }).redacted(),
+	boolSetting("images.s3.use_ssl", "S3_USE_SSL", "true", "talk to S3 over https", func(c *Config) *bool { return &c.S3UseSSL }),
+
+	stringSetting("admin.token", "ADMIN_TOKEN", "", "bearer token guarding the /admin endpoints, empty rejects every admin request", func(c *Config) *string { return &c.AdminToken }).redacted(),
+
+	durationSetting("scrapers.timeout", "SCRAPER_TIMEOUT", "10s", 0, "timeout of a store's scraper unless overridden", func(c *Config) *time.Duration { return &c.ScraperTimeout }),
+}
+
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func stringSetting(key, env, def, usage string, field func(*Config) *string) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage,
+		set: func(cfg *Config, raw string) error {
+			*field(cfg) = raw
+			return nil
+		},
+		get: func(cfg *Config) string { return *field(cfg) },
+	}
+}
+
+func intSetting(key, env, def, usage string, field func(*Config) *int) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage,
+		set: func(cfg *Config, raw string) error {
+			i, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("%w: %q is not an integer", ErrInvalidValue, raw)
+			}
+			*field(cfg) = i
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.Itoa(*field(cfg)) },
+	}
+}
+
+func boolSetting(key, env, def, usage string, field func(*Config) *bool) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage,
+		set: func(cfg *Config, raw string) error {
+			b, err := strconv.ParseBool(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("%w: %q is not a bool", ErrInvalidValue, raw)
+			}
+			*field(cfg) = b
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.FormatBool(*field(cfg)) },
+	}
+}
+
+// durationSetting parses go durations, and bare ints as counts of unit when it is set
+// which keeps the env vars predating durations, QUEUE_LEASE=300, working
+func durationSetting(key, env, def string, unit time.Duration, usage string, field func(*Config) *time.Duration) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage,
+		set: func(cfg *Config, raw string) error {
+			d, err := parseDuration(raw, unit)
+			if err != nil {
+				return err
+			}
+			*field(cfg) = d
+			return nil
+		},
+		get: func(cfg *Config) string { return field(cfg).String() },
+	}
+}
+
+// listSetting reads comma separated values, a YAML list is joined the same way
+func listSetting(key, env, def, usage string, field func(*Config) *[]string) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage,
+		set: func(cfg *Config, raw string) error {
+			var values []string
+			for _, v := range strings.Split(raw, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					values = append(values, v)
+				}
+			}
+			*field(cfg) = values
+			return nil
+		},
+		get: func(cfg *Config) string { return strings.Join(*field(cfg), ",") },
+	}
+}
+
+func levelSetting(key, env, def, usage string, field func(*Config) *slog.Level) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage,
+		set: func(cfg *Config, raw string) error {
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(strings.TrimSpace(raw))); err != nil {
+				return fmt.Errorf("%w: %q is not a log level", ErrInvalidValue, raw)
+			}
+			*field(cfg) = level
+			return nil
+		},
+		get: func(cfg *Config) string { return strings.ToLower(field(cfg).String()) },
+	}
+}
+
+func parseDuration(raw string, unit time.Duration) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if unit != 0 {
+		if n, err := strconv.Atoi(raw); err == nil {
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a duration", ErrInvalidValue, raw)
+	}
+	return d, nil
+}
+
+// store settings are the fields a store overrides under scrapers.<store>
+var storeFields = map[string]func(scraper *ScraperConfig, raw string) error{
+	"timeout": func(scraper *ScraperConfig, raw string) error {
+		d, err := parseDuration(raw, 0)
+		if err != nil {
+			return err
+		}
+		scraper.Timeout = d
+		return nil
+	},
+	"enabled": func(scraper *ScraperConfig, raw string) error {
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a bool", ErrInvalidValue, raw)
+		}
+		scraper.Enabled = b
+		return nil
+	},
+}
+
+// storeSetting splits scrapers.<store>.<field> keys, the store itself may be dotted like zara.com
+func storeSetting(key string) (store string, field string, ok bool) {
+	rest, ok := strings.CutPrefix(key, "scrapers.")
+	if !ok {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, ".")
+	if i <= 0 {
+		return "", "", false
+	}
+	store, field = StoreKey(rest[:i]), rest[i+1:]
+	if _, known := storeFields[field]; !known {
+		return "", "", false
+	}
+	return store, field, true
+}
+
+func storeKey(store, field string) string {
+	return "scrapers." + store + "." + field
+}
+
+func storeEnvName(store, field string) string {
+	return "SCRAPER_" + strings.ToUpper(store) + "_" + strings.ToUpper(field)
+}
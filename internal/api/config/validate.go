@@ -0,0 +1,176 @@
+/*
+uniwish.com/interal/api/config/validate
+
+checks the settings which parse fine on their own but not together or not in range
+*/
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"time"
+)
+
+var queueBackends = []string{"postgres", "redis"}
+var imageStores = []string{"", "filesystem", "s3"}
+
+const maxBatchLimit = 65535 / 6
+
+func (c *Config) validate(requireDatabase bool) []*FieldError {
+	var errs []*FieldError
+	fail := func(key string, err error) {
+		source := c.Source(key)
+		errs = append(errs, &FieldError{Key: key, Source: source, Name: c.sourceName(key, source), Err: err})
+	}
+
+	if requireDatabase && c.DBURL == "" {
+		fail("database.url", ErrNoDatabaseURL)
+	}
+
+	for key, port := range map[string]int{"http.port": c.Port, "worker.http_port": c.WorkerHTTPPort} {
+		if port < 1 || port > 65535 {
+			fail(key, fmt.Errorf("%w: %d is not a port", ErrOutOfRange, port))
+		}
+	}
+	// the gRPC api is optional, so 0 is allowed there
+	if c.GRPCPort < 0 || c.GRPCPort > 65535 {
+		fail("grpc.port", fmt.Errorf("%w: %d is not a port", ErrOutOfRange, c.GRPCPort))
+	} else if c.GRPCPort != 0 && c.GRPCPort == c.Port {
+		fail("grpc.port", fmt.Errorf("%w: %d is already the http port", ErrInvalidValue, c.GRPCPort))
+	}
+
+	nonNegative := map[string]int{
+		"http.max_header_bytes":       c.HTTPMaxHeaderBytes,
+		"database.max_open_conns":     c.DBMaxOpenConns,
+		"database.max_idle_conns":     c.DBMaxIdleConns,
+		"worker.failure_tolerance":    c.WorkerFailureTolerance,
+		"rate_limit.per_minute":       c.RateLimitPerMinute,
+		"rate_limit.burst":            c.RateLimitBurst,
+		"scrape_requests.daily_quota": c.ScrapeRequestDailyQuota,
+	}
+	for key, n := range nonNegative {
+		if n < 0 {
+			fail(key, fmt.Errorf("%w: must not be negative", ErrOutOfRange))
+		}
+	}
+	if c.HTTPMaxBodyBytes < 1 {
+		fail("http.max_body_bytes", fmt.Errorf("%w: must be at least 1", ErrOutOfRange))
+	}
+	// each batched request binds 6 insert parameters and postgres takes at most 65535 per statement,
+	// matching repository.MaxEnqueueBatch
+	if c.ScrapeRequestBatchLimit < 1 || c.ScrapeRequestBatchLimit > maxBatchLimit {
+		fail("scrape_requests.batch_limit", fmt.Errorf("%w: must be between 1 and %d", ErrOutOfRange, maxBatchLimit))
+	}
+	if c.WorkerConcurrency < 1 {
+		fail("worker.concurrency", fmt.Errorf("%w: must be at least 1", ErrOutOfRange))
+	}
+	if c.WebhookMaxAttempts < 1 {
+		fail("webhooks.max_attempts", fmt.Errorf("%w: must be at least 1", ErrOutOfRange))
+	}
+
+	nonNegativeDurations := map[string]time.Duration{
+		"http.read_header_timeout":    c.HTTPReadHeaderTimeout,
+		"http.read_timeout":           c.HTTPReadTimeout,
+		"http.write_timeout":          c.HTTPWriteTimeout,
+		"http.idle_timeout":           c.HTTPIdleTimeout,
+		"http.shutdown_timeout":       c.HTTPShutdownTimeout,
+		"http.drain_delay":            c.HTTPDrainDelay,
+		"database.conn_max_lifetime":  c.DBConnMaxLifetime,
+		"database.conn_max_idle_time": c.DBConnMaxIdleTime,
+		"worker.poll_interval":        c.WorkerPollInterval,
+		"products.discontinued_grace": c.ProductDiscontinuedGrace,
+	}
+	for key, d := range nonNegativeDurations {
+		if d < 0 {
+			fail(key, fmt.Errorf("%w: must not be negative", ErrOutOfRange))
+		}
+	}
+	positiveDurations := map[string]time.Duration{
+		"queue.lease":            c.QueueLease,
+		"fx_rates.refresh":       c.FXRatesRefresh,
+		"scrapers.timeout":       c.ScraperTimeout,
+		"webhooks.poll_interval": c.WebhookPollInterval,
+		"webhooks.timeout":       c.WebhookTimeout,
+		"webhooks.retry_backoff": c.WebhookRetryBackoff,
+	}
+	for key, d := range positiveDurations {
+		if d <= 0 {
+			fail(key, fmt.Errorf("%w: must be positive", ErrOutOfRange))
+		}
+	}
+	for store, scraper := range c.Scrapers {
+		if scraper.Timeout < 0 {
+			fail(storeKey(store, "timeout"), fmt.Errorf("%w: must not be negative", ErrOutOfRange))
+		}
+	}
+
+	// a certificate without its key, or the reverse, is a half done setup rather than plain http
+	if c.TLSCertFile != "" && c.TLSKeyFile == "" {
+		fail("http.tls.key_file", fmt.Errorf("%w with http.tls.cert_file", ErrRequired))
+	}
+	if c.TLSKeyFile != "" && c.TLSCertFile == "" {
+		fail("http.tls.cert_file", fmt.Errorf("%w with http.tls.key_file", ErrRequired))
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			fail("http.trusted_proxies", fmt.Errorf("%w: %q is not an IP or CIDR", ErrInvalidValue, proxy))
+			break
+		}
+	}
+
+	if !slices.Contains(queueBackends, c.QueueBackend) {
+		fail("queue.backend", fmt.Errorf("%w: %q is not one of postgres, redis", ErrInvalidValue, c.QueueBackend))
+	}
+	if c.QueueBackend == "redis" && c.RedisURL == "" {
+		fail("queue.redis_url", fmt.Errorf("%w for the redis queue backend", ErrRequired))
+	}
+	// listing, retrying, cancelling and purging requests are postgres queries, redis has nothing to run them on
+	if c.QueueBackend == "redis" && c.QueueAdmin {
+		fail("queue.admin", fmt.Errorf("%w: the redis queue backend has no admin endpoints, set it to false", ErrInvalidValue))
+	}
+
+	if !slices.Contains(imageStores, c.ImageStore) {
+		fail("images.store", fmt.Errorf("%w: %q is not one of filesystem, s3", ErrInvalidValue, c.ImageStore))
+	}
+	if c.ImageStore == "s3" {
+		if c.S3Endpoint == "" {
+			fail("images.s3.endpoint", fmt.Errorf("%w for the s3 image store", ErrRequired))
+		}
+		if c.S3Bucket == "" {
+			fail("images.s3.bucket", fmt.Errorf("%w for the s3 image store", ErrRequired))
+		}
+	}
+
+	// map iteration above is random, keep the report stable
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+	return errs
+}
+
+func (c *Config) sourceName(key string, source Source) string {
+	s, ok := lookupSetting(key)
+	switch {
+	case source == SourceFlag:
+		return "-" + key
+	case source == SourceEnv && ok:
+		return s.env
+	case source == SourceEnv:
+		if store, field, ok := storeSetting(key); ok {
+			return storeEnvName(store, field)
+		}
+	}
+	return ""
+}
+
+// Source tells where a setting was last set from, keys are those of the config file
+func (c *Config) Source(key string) Source {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
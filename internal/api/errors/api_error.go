@@ -0,0 +1,120 @@
+/*
+uniwish.com/internal/api/errors/api_error
+
+contains the JSON error envelope every endpoint responds with, and its mapping from domain errors
+
+	{"error": {"code": "invalid_input", "message": "...", "details": [{"field": "url", "code": "required", "message": "..."}]}}
+*/
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"uniwish.com/internal/logging"
+)
+
+// FieldError describes why a single input field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by services rejecting input, it matches ErrInputInvalid with errors.Is
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func Invalid(fields ...FieldError) *ValidationError {
+	return &ValidationError{Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	return ErrInputInvalid.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInputInvalid
+}
+
+type APIError struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+func NewAPIError(status int, code string, message string, details ...FieldError) *APIError {
+	return &APIError{Status: status, Code: code, Message: message, Details: details}
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+type envelope struct {
+	Error *APIError `json:"error"`
+}
+
+var ErrInternal = NewAPIError(http.StatusInternalServerError, "internal_error", "internal server error")
+var ErrNotReady = NewAPIError(http.StatusServiceUnavailable, "unavailable", "service unavailable")
+var ErrRateLimited = NewAPIError(http.StatusTooManyRequests, "rate_limited", "too many requests")
+var ErrInvalidJSON = NewAPIError(http.StatusBadRequest, "invalid_json", "request body is not valid JSON")
+var ErrBodyTooLarge = NewAPIError(http.StatusRequestEntityTooLarge, "body_too_large", "request body is too large")
+var ErrUnsupportedMediaType = NewAPIError(http.StatusUnsupportedMediaType, "unsupported_media_type", "request content type is not supported")
+var ErrUnauthorized = NewAPIError(http.StatusUnauthorized, "unauthorized", "missing or invalid credentials")
+
+// FromError maps domain errors to their API error, anything unknown is an internal error
+func FromError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return NewAPIError(http.StatusBadRequest, "invalid_input", "request input is invalid", validationErr.Fields...)
+	case errors.Is(err, ErrInputInvalid):
+		return NewAPIError(http.StatusBadRequest, "invalid_input", "request input is invalid")
+	case errors.Is(err, ErrStoreUnsupported):
+		return NewAPIError(http.StatusUnprocessableEntity, "unsupported_store", "store is not supported")
+	case errors.Is(err, ErrQuotaExceeded):
+		return NewAPIError(http.StatusTooManyRequests, "quota_exceeded", "daily scrape request quota exceeded")
+	case errors.Is(err, ErrNoProductFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "product not found")
+	case errors.Is(err, ErrProductDiscontinued):
+		return NewAPIError(http.StatusGone, "product_discontinued", "product is no longer sold by its store")
+	case errors.Is(err, ErrImageNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "image not found")
+	case errors.Is(err, ErrScrapeRequestNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "scrape request not found")
+	case errors.Is(err, ErrWebhookNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "webhook not found")
+	case errors.Is(err, ErrWebhookDeliveryNotFound):
+		return NewAPIError(http.StatusNotFound, "not_found", "webhook delivery not found")
+	case errors.Is(err, ErrQueueAdminUnsupported):
+		return NewAPIError(http.StatusNotImplemented, "not_implemented", "queue administration is disabled, it requires the postgres queue backend")
+	default:
+		return ErrInternal
+	}
+}
+
+// Write responds with err's envelope, logging errors that are not the caller's fault
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := FromError(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error("request failed", "error", err)
+	}
+	Respond(w, apiErr)
+}
+
+// Respond writes apiErr's envelope as is, for callers that have already reported the failure
+func Respond(w http.ResponseWriter, apiErr *APIError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(envelope{Error: apiErr})
+}
@@ -0,0 +1,86 @@
+/*
+uniwish.com/internal/api/errors/api_error_test
+
+tests error envelope mapping and writing
+*/
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFromError(t *testing.T) {
+	field := FieldError{Field: "url", Code: "required", Message: "url is required"}
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedFields []FieldError
+	}{
+		{name: "validation", err: Invalid(field), expectedStatus: 400, expectedCode: "invalid_input", expectedFields: []FieldError{field}},
+		{name: "wrapped_validation", err: fmt.Errorf("request: %w", Invalid(field)), expectedStatus: 400, expectedCode: "invalid_input", expectedFields: []FieldError{field}},
+		{name: "input_invalid", err: ErrInputInvalid, expectedStatus: 400, expectedCode: "invalid_input"},
+		{name: "store_unsupported", err: ErrStoreUnsupported, expectedStatus: 422, expectedCode: "unsupported_store"},
+		{name: "not_found", err: fmt.Errorf("get: %w", ErrNoProductFound), expectedStatus: 404, expectedCode: "not_found"},
+		{name: "image_not_found", err: ErrImageNotFound, expectedStatus: 404, expectedCode: "not_found"},
+		{name: "product_discontinued", err: ErrProductDiscontinued, expectedStatus: 410, expectedCode: "product_discontinued"},
+		{name: "webhook_not_found", err: fmt.Errorf("delete: %w", ErrWebhookNotFound), expectedStatus: 404, expectedCode: "not_found"},
+		{name: "scrape_request_not_found", err: ErrScrapeRequestNotFound, expectedStatus: 404, expectedCode: "not_found"},
+		{name: "queue_admin_unsupported", err: ErrQueueAdminUnsupported, expectedStatus: 501, expectedCode: "not_implemented"},
+		{name: "api_error", err: ErrInvalidJSON, expectedStatus: 400, expectedCode: "invalid_json"},
+		{name: "unknown", err: fmt.Errorf("boom"), expectedStatus: 500, expectedCode: "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := FromError(tt.err)
+
+			if apiErr.Status != tt.expectedStatus {
+				t.Fatalf("expected status %d, received %d", tt.expectedStatus, apiErr.Status)
+			}
+			if apiErr.Code != tt.expectedCode {
+				t.Fatalf("expected code %s, received %s", tt.expectedCode, apiErr.Code)
+			}
+			if !reflect.DeepEqual(apiErr.Details, tt.expectedFields) {
+				t.Fatalf("expected details %+v, received %+v", tt.expectedFields, apiErr.Details)
+			}
+		})
+	}
+}
+
+func TestWrite_Envelope(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rr := httptest.NewRecorder()
+
+	Write(rr, req, Invalid(FieldError{Field: "url", Code: "required", Message: "url is required"}))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json, got %q", ct)
+	}
+
+	var body struct {
+		Error struct {
+			Code    string       `json:"code"`
+			Message string       `json:"message"`
+			Details []FieldError `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if body.Error.Code != "invalid_input" || body.Error.Message == "" {
+		t.Fatalf("unexpected envelope %+v", body.Error)
+	}
+	if len(body.Error.Details) != 1 || body.Error.Details[0].Field != "url" {
+		t.Fatalf("expected url field detail, got %+v", body.Error.Details)
+	}
+}
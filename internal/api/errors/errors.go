@@ -12,3 +12,11 @@ var ErrStoreUnsupported = errors.New("store unsupported")
 var ErrInputInvalid = errors.New("input invalid")
 var ErrScrapeFailed = errors.New("scrape failed")
 var ErrNoProductFound = errors.New("no product found")
+var ErrQuotaExceeded = errors.New("quota exceeded")
+var ErrImageNotFound = errors.New("image not found")
+var ErrQueueAdminUnsupported = errors.New("queue admin unsupported")
+var ErrProductRemoved = errors.New("product removed from store")
+var ErrProductDiscontinued = errors.New("product discontinued")
+var ErrWebhookNotFound = errors.New("webhook not found")
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+var ErrScrapeRequestNotFound = errors.New("scrape request not found")
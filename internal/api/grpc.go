@@ -0,0 +1,30 @@
+/*
+uniwish.com/internal/api/grpc
+
+centralizes the services of the gRPC api, the counterpart of routes
+*/
+package api
+
+import (
+	"database/sql"
+
+	"google.golang.org/grpc"
+	"uniwish.com/internal/api/config"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/rpc"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+	"uniwish.com/internal/api/services"
+	"uniwish.com/internal/scrapers"
+)
+
+// drain may be nil when watches need not end ahead of shutdown
+func RegisterGRPCServices(server grpc.ServiceRegistrar, cfg *config.Config, db *sql.DB, queue repository.Queue, registry scrapers.Registry, drain *services.Drain) {
+	productService := newProductService(db)
+	scrapeRequestServer := rpc.NewScrapeRequestServer(newScrapeRequestService(cfg, db, queue, registry), newScrapeStatusService(cfg, db, queue, productService))
+	if drain != nil {
+		// open watches would hold shutdown up, clients watch again on a replica that is not draining
+		scrapeRequestServer.Done = drain.Done()
+	}
+	uniwishv1.RegisterScrapeRequestServiceServer(server, scrapeRequestServer)
+	uniwishv1.RegisterProductServiceServer(server, rpc.NewProductServer(productService))
+}
@@ -0,0 +1,43 @@
+/*
+uniwish.com/internal/api/grpc_test
+
+checks every service of the proto is registered on the gRPC server
+*/
+package api
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"uniwish.com/internal/api/config"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+)
+
+func TestRegisterGRPCServices_MatchProto(t *testing.T) {
+	server := grpc.NewServer()
+	RegisterGRPCServices(server, &config.Config{}, nil, nil, nil, nil)
+
+	services := uniwishv1.File_uniwish_proto.Services()
+	var declared []string
+	for i := range services.Len() {
+		service := services.Get(i)
+		for j := range service.Methods().Len() {
+			declared = append(declared, string(service.FullName())+"/"+string(service.Methods().Get(j).Name()))
+		}
+	}
+	var registered []string
+	for name, info := range server.GetServiceInfo() {
+		for _, method := range info.Methods {
+			registered = append(registered, name+"/"+method.Name)
+		}
+	}
+
+	sort.Strings(declared)
+	sort.Strings(registered)
+	if strings.Join(declared, "\n") != strings.Join(registered, "\n") {
+		t.Fatalf("registered services and proto differ\nregistered:\n%s\ndeclared:\n%s",
+			strings.Join(registered, "\n"), strings.Join(declared, "\n"))
+	}
+}
@@ -0,0 +1,377 @@
+/*
+uniwish.com/interal/api/handlers/contract_test
+
+validates real handler responses against the OpenAPI document so the two cannot drift
+*/
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/middleware"
+	"uniwish.com/internal/api/openapi"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+func loadDocument(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	doc, err := openapi3.NewLoader().LoadFromData(openapi.Document())
+	if err != nil {
+		t.Fatalf("invalid openapi document: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("invalid openapi document: %v", err)
+	}
+	// event streams are documented as plain strings, their events' data is not validated
+	openapi3filter.RegisterBodyDecoder("text/event-stream", openapi3filter.PlainBodyDecoder)
+	return doc
+}
+
+func validateAgainstDocument(t *testing.T, doc *openapi3.T, pattern string, req *http.Request, rr *httptest.ResponseRecorder) {
+	t.Helper()
+
+	method, path, _ := strings.Cut(pattern, " ")
+	pathItem := doc.Paths.Find(path)
+	if pathItem == nil {
+		t.Fatalf("path %s is not documented", path)
+	}
+	operation := pathItem.GetOperation(method)
+	if operation == nil {
+		t.Fatalf("%s is not documented", pattern)
+	}
+
+	pathParams := map[string]string{}
+	for _, param := range operation.Parameters {
+		if param.Value.In == openapi3.ParameterInPath {
+			pathParams[param.Value.Name] = req.PathValue(param.Value.Name)
+		}
+	}
+
+	err := openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route: &routers.Route{
+				Spec:      doc,
+				Path:      path,
+				PathItem:  pathItem,
+				Method:    method,
+				Operation: operation,
+			},
+		},
+		Status: rr.Code,
+		Header: rr.Header(),
+		Body:   io.NopCloser(bytes.NewReader(rr.Body.Bytes())),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s responded %d outside of the document: %v\nbody: %s", pattern, rr.Code, err, rr.Body.String())
+	}
+}
+
+func TestHandlers_MatchOpenAPIDocument(t *testing.T) {
+	doc := loadDocument(t)
+
+	readyReport := &services.ReadinessReport{
+		Status: services.CheckStatusOK,
+		Checks: map[string]services.CheckResult{"database": {Status: services.CheckStatusOK, DurationMS: 1}},
+	}
+	failedReport := &services.ReadinessReport{
+		Status: services.CheckStatusFail,
+		Checks: map[string]services.CheckResult{"database": {Status: services.CheckStatusFail, Error: "down", DurationMS: 1}},
+	}
+
+	imageStore := newFakeImageStore(t)
+
+	tests := []struct {
+		name    string
+		pattern string
+		handler http.Handler
+		method  string
+		target  string
+		body    string
+		header  map[string]string
+		status  int
+	}{
+		{name: "health_ok", pattern: "GET /health", handler: NewHealthHandler(&FakeHealthService{}), target: "/health", status: 200},
+		{name: "health_unavailable", pattern: "GET /health", handler: NewHealthHandler(&FakeHealthService{err: errors.ErrUnavailable}), target: "/health", status: 503},
+		{name: "livez_ok", pattern: "GET /livez", handler: NewHealthHandler(&FakeHealthService{}), target: "/livez", status: 200},
+		{name: "readyz_ok", pattern: "GET /readyz", handler: NewReadinessHandler(&FakeReadinessService{report: readyReport}), target: "/readyz", status: 200},
+		{name: "readyz_failing", pattern: "GET /readyz", handler: NewReadinessHandler(&FakeReadinessService{report: failedReport}), target: "/readyz", status: 503},
+		{name: "openapi", pattern: "GET /openapi.json", handler: openapi.Handler(), target: "/openapi.json", status: 200},
+		{name: "image", pattern: "GET /images/{kind}/{name}", handler: NewImageHandler(imageStore), target: "/images/thumbnails/abc.jpeg", status: 200},
+		{name: "image_not_found", pattern: "GET /images/{kind}/{name}", handler: NewImageHandler(imageStore), target: "/images/thumbnails/missing.jpeg", status: 404},
+		{
+			name: "scrape_request_accepted", pattern: "POST /scrape-requests",
+			handler: NewCreateItemHandler(&FakeScrapeRequester{id: fakeId}),
+			target:  "/scrape-requests", body: `{"url": "http://store.com/a", "priority": "bulk"}`, status: 202,
+		},
+		{
+			name: "scrape_request_invalid_json", pattern: "POST /scrape-requests",
+			handler: NewCreateItemHandler(&FakeScrapeRequester{}),
+			target:  "/scrape-requests", body: `{"url":}`, status: 400,
+		},
+		{
+			name: "scrape_request_invalid_input", pattern: "POST /scrape-requests",
+			handler: NewCreateItemHandler(&FakeScrapeRequester{err: errors.Invalid(errors.FieldError{Field: "url", Code: "required", Message: "url is required"})}),
+			target:  "/scrape-requests", body: `{"url": ""}`, status: 400,
+		},
+		{
+			name: "scrape_request_unsupported_store", pattern: "POST /scrape-requests",
+			handler: NewCreateItemHandler(&FakeScrapeRequester{err: errors.ErrStoreUnsupported}),
+			target:  "/scrape-requests", body: `{"url": "http://nowhere.com/a"}`, status: 422,
+		},
+		{
+			name: "scrape_request_quota_exceeded", pattern: "POST /scrape-requests",
+			handler: NewCreateItemHandler(&FakeScrapeRequester{err: errors.ErrQuotaExceeded}),
+			target:  "/scrape-requests", body: `{"url": "http://store.com/a"}`, status: 429,
+		},
+		{
+			name: "scrape_request_rate_limited", pattern: "POST /scrape-requests",
+			handler: middleware.RateLimit(middleware.NewRateLimiter(1, 0))(NewCreateItemHandler(&FakeScrapeRequester{})),
+			target:  "/scrape-requests", body: `{"url": "http://store.com/a"}`, status: 429,
+		},
+		{
+			name: "scrape_request_internal_error", pattern: "POST /scrape-requests",
+			handler: NewCreateItemHandler(&FakeScrapeRequester{err: errors.ErrUnavailable}),
+			target:  "/scrape-requests", body: `{"url": "http://store.com/a"}`, status: 500,
+		},
+		{
+			name: "scrape_request_batch_accepted", pattern: "POST /scrape-requests/batch",
+			handler: NewBatchScrapeRequestHandler(&FakeBatchScrapeRequester{}),
+			target:  "/scrape-requests/batch", body: `["http://store.com/a", "bad"]`, status: 202,
+		},
+		{
+			name: "scrape_request_batch_invalid_input", pattern: "POST /scrape-requests/batch",
+			handler: NewBatchScrapeRequestHandler(&FakeBatchScrapeRequester{err: errors.Invalid(errors.FieldError{Field: "urls", Code: "required", Message: "at least one url is required"})}),
+			target:  "/scrape-requests/batch", body: `[]`, status: 400,
+		},
+		{
+			name: "scrape_request_batch_quota_exceeded", pattern: "POST /scrape-requests/batch",
+			handler: NewBatchScrapeRequestHandler(&FakeBatchScrapeRequester{err: errors.ErrQuotaExceeded}),
+			target:  "/scrape-requests/batch", body: `["http://store.com/a"]`, status: 429,
+		},
+		{
+			name: "admin_scrape_requests", pattern: "GET /admin/scrape-requests",
+			handler: http.HandlerFunc(NewQueueAdminHandler(&FakeQueueAdministrator{}).ListScrapeRequests),
+			target:  "/admin/scrape-requests?status=failed", status: 200,
+		},
+		{
+			name: "admin_scrape_requests_invalid_input", pattern: "GET /admin/scrape-requests",
+			handler: http.HandlerFunc(NewQueueAdminHandler(&FakeQueueAdministrator{err: errors.Invalid(errors.FieldError{Field: "limit", Code: "invalid", Message: "limit must be between 1 and 500"})}).ListScrapeRequests),
+			target:  "/admin/scrape-requests?limit=0", status: 400,
+		},
+		{
+			name: "admin_scrape_requests_unauthorized", pattern: "GET /admin/scrape-requests",
+			handler: middleware.AdminAuth("secret")(http.HandlerFunc(NewQueueAdminHandler(&FakeQueueAdministrator{}).ListScrapeRequests)),
+			target:  "/admin/scrape-requests", status: 401,
+		},
+		{
+			name: "admin_scrape_requests_unsupported", pattern: "GET /admin/scrape-requests",
+			handler: http.HandlerFunc(NewQueueAdminHandler(services.DisabledQueueAdmin{}).ListScrapeRequests),
+			target:  "/admin/scrape-requests", status: 501,
+		},
+		{
+			name: "admin_retry", pattern: "POST /admin/scrape-requests/retry",
+			handler: http.HandlerFunc(NewQueueAdminHandler(&FakeQueueAdministrator{}).RetryScrapeRequests),
+			target:  "/admin/scrape-requests/retry", body: `{"store": "zara", "error_kind": "scrape_failed"}`, status: 200,
+		},
+		{
+			name: "admin_retry_invalid_input", pattern: "POST /admin/scrape-requests/retry",
+			handler: http.HandlerFunc(NewQueueAdminHandler(&FakeQueueAdministrator{err: errors.Invalid(errors.FieldError{Field: "ids", Code: "required", Message: "ids, store or error_kind is required"})}).RetryScrapeRequests),
+			target:  "/admin/scrape-requests/retry", body: `{}`, status: 400,
+		},
+		{
+			name: "admin_cancel", pattern: "POST /admin/scrape-requests/cancel",
+			handler: http.HandlerFunc(NewQueueAdminHandler(&FakeQueueAdministrator{}).CancelScrapeRequests),
+			target:  "/admin/scrape-requests/cancel", body: `{"all": true}`, status: 200,
+		},
+		{
+			name: "admin_purge", pattern: "POST /admin/scrape-requests/purge",
+			handler: http.HandlerFunc(NewQueueAdminHandler(&FakeQueueAdministrator{}).PurgeScrapeRequests),
+			target:  "/admin/scrape-requests/purge", body: `{"older_than_days": 30}`, status: 200,
+		},
+		{
+			name: "admin_webhooks", pattern: "GET /admin/webhooks",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{}).ListWebhooks),
+			target:  "/admin/webhooks", status: 200,
+		},
+		{
+			name: "admin_webhooks_unauthorized", pattern: "GET /admin/webhooks",
+			handler: middleware.AdminAuth("secret")(http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{}).ListWebhooks)),
+			target:  "/admin/webhooks", status: 401,
+		},
+		{
+			name: "admin_webhook_created", pattern: "POST /admin/webhooks",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{}).CreateWebhook),
+			target:  "/admin/webhooks", body: `{"url": "https://hooks.example.com", "event_types": ["price.changed"]}`, status: 201,
+		},
+		{
+			name: "admin_webhook_invalid_input", pattern: "POST /admin/webhooks",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{err: errors.Invalid(errors.FieldError{Field: "url", Code: "required", Message: "url is required"})}).CreateWebhook),
+			target:  "/admin/webhooks", body: `{"event_types": ["price.changed"]}`, status: 400,
+		},
+		{
+			name: "admin_webhook_deleted", pattern: "DELETE /admin/webhooks/{id}",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{}).DeleteWebhook),
+			target:  "/admin/webhooks/" + uuid.NewString(), status: 204,
+		},
+		{
+			name: "admin_webhook_not_found", pattern: "DELETE /admin/webhooks/{id}",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{err: errors.ErrWebhookNotFound}).DeleteWebhook),
+			target:  "/admin/webhooks/" + uuid.NewString(), status: 404,
+		},
+		{
+			name: "admin_webhook_deliveries", pattern: "GET /admin/webhooks/{id}/deliveries",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{}).ListDeliveries),
+			target:  "/admin/webhooks/" + uuid.NewString() + "/deliveries?status=pending", status: 200,
+		},
+		{
+			name: "admin_webhook_delivery", pattern: "GET /admin/webhooks/{id}/deliveries/{delivery_id}",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{}).GetDelivery),
+			target:  "/admin/webhooks/" + uuid.NewString() + "/deliveries/" + uuid.NewString(), status: 200,
+		},
+		{
+			name: "admin_webhook_delivery_invalid_id", pattern: "GET /admin/webhooks/{id}/deliveries/{delivery_id}",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{}).GetDelivery),
+			target:  "/admin/webhooks/" + uuid.NewString() + "/deliveries/abc", status: 400,
+		},
+		{
+			name: "admin_webhook_redeliver", pattern: "POST /admin/webhooks/{id}/deliveries/{delivery_id}/redeliver",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{}).Redeliver),
+			target:  "/admin/webhooks/" + uuid.NewString() + "/deliveries/" + uuid.NewString() + "/redeliver", status: 202,
+		},
+		{
+			name: "admin_webhook_redeliver_not_found", pattern: "POST /admin/webhooks/{id}/deliveries/{delivery_id}/redeliver",
+			handler: http.HandlerFunc(NewWebhookAdminHandler(&FakeWebhookAdministrator{err: errors.ErrWebhookDeliveryNotFound}).Redeliver),
+			target:  "/admin/webhooks/" + uuid.NewString() + "/deliveries/" + uuid.NewString() + "/redeliver", status: 404,
+		},
+		{
+			name: "products", pattern: "GET /products",
+			handler: http.HandlerFunc(NewDefaultProductHandler(services.NewDefaultProductReaderService(&SuccessfulRepo{}, nil)).ListProducts),
+			target:  "/products", status: 200,
+		},
+		{
+			name: "products_unsupported_currency", pattern: "GET /products",
+			handler: http.HandlerFunc(NewDefaultProductHandler(services.NewDefaultProductReaderService(&SuccessfulRepo{}, nil)).ListProducts),
+			target:  "/products?currency=EUR", status: 400,
+		},
+		{
+			name: "products_internal_error", pattern: "GET /products",
+			handler: http.HandlerFunc(NewDefaultProductHandler(services.NewDefaultProductReaderService(&FaultyRepo{}, nil)).ListProducts),
+			target:  "/products", status: 500,
+		},
+		{
+			name: "product", pattern: "GET /products/{id}",
+			handler: http.HandlerFunc(NewDefaultProductHandler(services.NewDefaultProductReaderService(&SuccessfulRepo{}, nil)).GetProduct),
+			target:  "/products/" + uuid.NewString(), status: 200,
+		},
+		{
+			name: "product_not_found", pattern: "GET /products/{id}",
+			handler: http.HandlerFunc(NewDefaultProductHandler(services.NewDefaultProductReaderService(&EmptyRepo{}, nil)).GetProduct),
+			target:  "/products/" + uuid.NewString(), status: 404,
+		},
+		{
+			name: "product_invalid_id", pattern: "GET /products/{id}",
+			handler: http.HandlerFunc(NewDefaultProductHandler(services.NewDefaultProductReaderService(&EmptyRepo{}, nil)).GetProduct),
+			target:  "/products/whatever", status: 400,
+		},
+		{
+			name: "product_internal_error", pattern: "GET /products/{id}",
+			handler: http.HandlerFunc(NewDefaultProductHandler(services.NewDefaultProductReaderService(&FaultyRepo{}, nil)).GetProduct),
+			target:  "/products/" + uuid.NewString(), status: 500,
+		},
+		{
+			name: "product_events", pattern: "GET /products/{id}/events",
+			handler: NewProductEventHandler(services.NewDefaultProductEventReaderService(&FakeProductEventRepo{})),
+			target:  "/products/" + uuid.NewString() + "/events?kind=price_changed&limit=10", status: 200,
+		},
+		{
+			name: "product_events_invalid_kind", pattern: "GET /products/{id}/events",
+			handler: NewProductEventHandler(services.NewDefaultProductEventReaderService(&FakeProductEventRepo{})),
+			target:  "/products/" + uuid.NewString() + "/events?kind=renamed", status: 400,
+		},
+		{
+			name: "product_events_not_found", pattern: "GET /products/{id}/events",
+			handler: NewProductEventHandler(services.NewDefaultProductEventReaderService(&FakeProductEventRepo{err: sql.ErrNoRows})),
+			target:  "/products/" + uuid.NewString() + "/events", status: 404,
+		},
+		{
+			name: "notifications", pattern: "GET /notifications",
+			handler: NewNotificationHandler(services.NewDefaultNotificationReaderService(&FakeNotificationRepo{})),
+			target:  "/notifications", header: map[string]string{UserIDHeader: "user-1"}, status: 200,
+		},
+		{
+			name: "notifications_anonymous", pattern: "GET /notifications",
+			handler: NewNotificationHandler(services.NewDefaultNotificationReaderService(&FakeNotificationRepo{})),
+			target:  "/notifications", status: 401,
+		},
+		{
+			name: "scrape_request_status_stream", pattern: "GET /scrape-requests/{id}/events",
+			handler: http.HandlerFunc(NewScrapeStatusHandler(&FakeScrapeStatusStreamer{
+				current: &repository.ScrapeStatus{Status: repository.StatusDone}, statuses: fakeStatuses(false),
+			}).StreamScrapeRequest),
+			target: "/scrape-requests/" + uuid.NewString() + "/events", status: 200,
+		},
+		{
+			name: "scrape_request_status_stream_not_found", pattern: "GET /scrape-requests/{id}/events",
+			handler: http.HandlerFunc(NewScrapeStatusHandler(&FakeScrapeStatusStreamer{err: errors.ErrScrapeRequestNotFound}).StreamScrapeRequest),
+			target:  "/scrape-requests/" + uuid.NewString() + "/events", status: 404,
+		},
+		{
+			name: "scrape_request_status_stream_invalid_id", pattern: "GET /scrape-requests/{id}/events",
+			handler: http.HandlerFunc(NewScrapeStatusHandler(&FakeScrapeStatusStreamer{}).StreamScrapeRequest),
+			target:  "/scrape-requests/abc/events", status: 400,
+		},
+		{
+			name: "scrape_status_stream", pattern: "GET /scrape-requests/events",
+			handler: http.HandlerFunc(NewScrapeStatusHandler(&FakeScrapeStatusStreamer{statuses: fakeStatuses(true, repository.StatusDone)}).StreamOwner),
+			target:  "/scrape-requests/events", header: map[string]string{UserIDHeader: "user-1"}, status: 200,
+		},
+		{
+			name: "scrape_status_stream_anonymous", pattern: "GET /scrape-requests/events",
+			handler: http.HandlerFunc(NewScrapeStatusHandler(&FakeScrapeStatusStreamer{}).StreamOwner),
+			target:  "/scrape-requests/events", status: 401,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle(tt.pattern, tt.handler)
+
+			method, _, _ := strings.Cut(tt.pattern, " ")
+			req := httptest.NewRequest(method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rr.Code)
+			}
+			validateAgainstDocument(t, doc, tt.pattern, req, rr)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+/*
+uniwish.com/interal/api/handlers/decode
+
+bounded JSON decoding of request bodies
+*/
+package handlers
+
+import (
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"uniwish.com/internal/api/errors"
+)
+
+// DefaultMaxBodyBytes bounds the body of handlers not given a limit of their own
+const DefaultMaxBodyBytes = 1 << 20
+
+// errEmptyBody is an invalid JSON body, told apart for handlers whose body is optional
+var errEmptyBody = fmt.Errorf("%w: empty body", errors.ErrInvalidJSON)
+
+// decodeJSON reads one JSON value into v from the first limit bytes of the body
+// a body running past limit is reported as errors.ErrBodyTooLarge, anything unreadable as errors.ErrInvalidJSON
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any, limit int64) error {
+	r.Body = limitBody(w, r, limit)
+	err := json.NewDecoder(r.Body).Decode(v)
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case err == nil:
+		return nil
+	case err == io.EOF:
+		return errEmptyBody
+	case stdErrors.As(err, &maxBytesErr):
+		return errors.ErrBodyTooLarge
+	default:
+		return errors.ErrInvalidJSON
+	}
+}
+
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) io.ReadCloser {
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	return http.MaxBytesReader(w, r.Body, limit)
+}
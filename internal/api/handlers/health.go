@@ -1,13 +1,17 @@
 /*
 uniwish.com/interal/api/handlers/health
 
-simple health endpoint
+liveness and readiness endpoints
 */
 package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/services"
 )
 
 type HealthChecker interface {
@@ -26,10 +30,36 @@ func NewHealthHandler(srv HealthChecker) *HealthHandler {
 func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	err := h.service.Check(r.Context())
 	if err != nil {
-		http.Error(w, "health check error", http.StatusServiceUnavailable)
+		apiErrors.Respond(w, apiErrors.ErrNotReady)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ok"))
 }
+
+type ReadinessChecker interface {
+	Ready(ctx context.Context) *services.ReadinessReport
+}
+
+type ReadinessHandler struct {
+	service ReadinessChecker
+}
+
+func NewReadinessHandler(srv ReadinessChecker) *ReadinessHandler {
+	return &ReadinessHandler{service: srv}
+}
+
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.service.Ready(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -7,11 +7,14 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/services"
 )
 
 type FakeHealthService struct {
@@ -57,3 +60,60 @@ func TestHealthHandler(t *testing.T) {
 		})
 	}
 }
+
+type FakeReadinessService struct {
+	report *services.ReadinessReport
+}
+
+func (s *FakeReadinessService) Ready(ctx context.Context) *services.ReadinessReport {
+	return s.report
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		report         *services.ReadinessReport
+		expectedStatus int
+	}{
+		{
+			name: "ready",
+			report: &services.ReadinessReport{
+				Status: services.CheckStatusOK,
+				Checks: map[string]services.CheckResult{"database": {Status: services.CheckStatusOK}},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "not_ready",
+			report: &services.ReadinessReport{
+				Status: services.CheckStatusFail,
+				Checks: map[string]services.CheckResult{"database": {Status: services.CheckStatusFail, Error: "down"}},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewReadinessHandler(&FakeReadinessService{report: tt.report})
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			var body services.ReadinessReport
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid json: %v", err)
+			}
+			if !reflect.DeepEqual(&body, tt.report) {
+				t.Fatalf("expected %+v, got %+v", tt.report, body)
+			}
+		})
+	}
+}
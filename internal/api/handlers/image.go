@@ -0,0 +1,63 @@
+/*
+uniwish.com/interal/api/handlers/image
+
+serves mirrored product images and thumbnails from the blob store
+*/
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/blob"
+)
+
+type ImageHandler struct {
+	store blob.Store
+}
+
+func NewImageHandler(store blob.Store) *ImageHandler {
+	return &ImageHandler{store: store}
+}
+
+func (h *ImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		apiErrors.Write(w, r, apiErrors.ErrImageNotFound)
+		return
+	}
+
+	// kind is originals or thumbnails, any other prefix simply is not in the store
+	key := r.PathValue("kind") + "/" + r.PathValue("name")
+	// keys are named after the image's hash, so a key never changes content
+	etag := `"` + r.PathValue("name") + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, info, err := h.store.Get(r.Context(), key)
+	if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+		apiErrors.Write(w, r, apiErrors.ErrImageNotFound)
+		return
+	}
+	if err != nil {
+		apiErrors.Write(w, r, err)
+		return
+	}
+	defer body.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, body)
+}
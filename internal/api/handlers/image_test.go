@@ -0,0 +1,73 @@
+/*
+uniwish.com/interal/api/handlers/image_test
+
+test image handler
+*/
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"uniwish.com/internal/blob"
+)
+
+func newFakeImageStore(t *testing.T) blob.Store {
+	t.Helper()
+	store, err := blob.NewFilesystemStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	err = store.Put(context.Background(), "thumbnails/abc.jpeg", strings.NewReader("jpeg"), 4, "image/jpeg")
+	if err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	return store
+}
+
+func TestImageHandler(t *testing.T) {
+	store := newFakeImageStore(t)
+
+	tests := []struct {
+		name           string
+		store          blob.Store
+		target         string
+		ifNoneMatch    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "served", store: store, target: "/images/thumbnails/abc.jpeg", expectedStatus: http.StatusOK, expectedBody: "jpeg"},
+		{name: "not_modified", store: store, target: "/images/thumbnails/abc.jpeg", ifNoneMatch: `"abc.jpeg"`, expectedStatus: http.StatusNotModified},
+		{name: "missing", store: store, target: "/images/thumbnails/missing.jpeg", expectedStatus: http.StatusNotFound},
+		{name: "escaping", store: store, target: "/images/thumbnails/..%2F..%2Fetc", expectedStatus: http.StatusNotFound},
+		{name: "mirroring_disabled", target: "/images/thumbnails/abc.jpeg", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("GET /images/{kind}/{name}", NewImageHandler(tt.store))
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Fatalf("expected body %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+			if rr.Code == http.StatusOK && rr.Header().Get("Content-Type") != "image/jpeg" {
+				t.Fatalf("expected image/jpeg, got %q", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+/*
+uniwish.com/interal/api/handlers/notification
+
+notifications endpoint listing what the calling user was told about their wishlist
+*/
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/services"
+)
+
+type NotificationHandler struct {
+	service services.NotificationReaderService
+}
+
+func NewNotificationHandler(service services.NotificationReaderService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+func (h *NotificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	notifications, err := h.service.List(r.Context(), services.ListNotificationsInput{
+		OwnerID: r.Header.Get(UserIDHeader),
+		Limit:   query.Get("limit"),
+		Offset:  query.Get("offset"),
+	})
+	if err != nil {
+		apiErrors.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notifications)
+}
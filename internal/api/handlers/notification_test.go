@@ -0,0 +1,75 @@
+/*
+uniwish.com/interal/api/handlers/notification_test
+
+tests for the notifications endpoint
+*/
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+type FakeNotificationRepo struct {
+	err error
+}
+
+func (r *FakeNotificationRepo) ListNotifications(_ context.Context, ownerID string, _ int, _ int) ([]repository.NotificationListItem, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []repository.NotificationListItem{
+		{ID: uuid.New(), Kind: "product_discontinued", ProductID: uuid.New(), ProductName: "jacket", ProductStore: "zara", CreatedAt: time.Now()},
+	}, nil
+}
+
+func TestListNotifications(t *testing.T) {
+	tests := []struct {
+		name               string
+		repo               repository.NotificationReader
+		userID             string
+		target             string
+		expectedStatusCode int
+	}{
+		{name: "success", repo: &FakeNotificationRepo{}, userID: "user-1", target: "/notifications", expectedStatusCode: 200},
+		{name: "anonymous", repo: &FakeNotificationRepo{}, target: "/notifications", expectedStatusCode: 401},
+		{name: "invalid_limit", repo: &FakeNotificationRepo{}, userID: "user-1", target: "/notifications?limit=0", expectedStatusCode: 400},
+		{name: "internal_error", repo: &FakeNotificationRepo{err: sql.ErrConnDone}, userID: "user-1", target: "/notifications", expectedStatusCode: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hdlr := NewNotificationHandler(services.NewDefaultNotificationReaderService(tt.repo))
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.userID != "" {
+				req.Header.Set(UserIDHeader, tt.userID)
+			}
+			rr := httptest.NewRecorder()
+
+			hdlr.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatusCode {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+			if rr.Code == http.StatusOK {
+				var resp services.NotificationListResponse
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatalf("invalid json: %v", err)
+				}
+				if len(resp.Notifications) != 1 || resp.Notifications[0].Kind != "product_discontinued" {
+					t.Fatalf("unexpected notifications %+v", resp.Notifications)
+				}
+			}
+		})
+	}
+}
@@ -7,7 +7,6 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -15,6 +14,9 @@ import (
 	"uniwish.com/internal/api/services"
 )
 
+// DisplayCurrencyParam names the query parameter prices are additionally converted to
+const DisplayCurrencyParam = "currency"
+
 type DefaultProductHandler struct {
 	service services.ProductReaderService
 }
@@ -23,49 +25,39 @@ func NewDefaultProductHandler(service services.ProductReaderService) *DefaultPro
 	return &DefaultProductHandler{service: service}
 }
 func (h *DefaultProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	rawId := r.PathValue("id")
 
-	if rawId == "" {
-		http.Error(w, `{"error": "invalid_path"}`, http.StatusBadRequest)
-		return
-	}
-
 	productId, err := uuid.Parse(rawId)
 	if err != nil {
-		http.Error(w, `{"error": "invalid_id"}`, http.StatusBadRequest)
+		apiErrors.Write(w, r, apiErrors.Invalid(apiErrors.FieldError{
+			Field:   "id",
+			Code:    "invalid",
+			Message: "id must be a uuid",
+		}))
 		return
 	}
 
-	product, err := h.service.Get(r.Context(), productId)
+	product, err := h.service.Get(r.Context(), productId, r.URL.Query().Get(DisplayCurrencyParam))
 
 	if err != nil {
-		switch {
-		case errors.Is(err, apiErrors.ErrNoProductFound):
-			http.Error(w, `{"error": "not_found"}`, http.StatusNotFound)
-		default:
-			http.Error(w, `{"error": "internal_error"}`, http.StatusInternalServerError)
-		}
+		apiErrors.Write(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(product)
 
 }
 func (h *DefaultProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	list, err := h.service.List(r.Context())
+	list, err := h.service.List(r.Context(), r.URL.Query().Get(DisplayCurrencyParam))
 
 	if err != nil {
-		switch {
-		default:
-			http.Error(w, `{"error": "internal_error"}`, http.StatusInternalServerError)
-		}
+		apiErrors.Write(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(list)
 }
@@ -0,0 +1,50 @@
+/*
+uniwish.com/interal/api/handlers/product_event
+
+product events endpoint listing the changes scrapes found on a product
+*/
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/services"
+)
+
+type ProductEventHandler struct {
+	service services.ProductEventReaderService
+}
+
+func NewProductEventHandler(service services.ProductEventReaderService) *ProductEventHandler {
+	return &ProductEventHandler{service: service}
+}
+
+func (h *ProductEventHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apiErrors.Write(w, r, apiErrors.Invalid(apiErrors.FieldError{
+			Field:   "id",
+			Code:    "invalid",
+			Message: "id must be a uuid",
+		}))
+		return
+	}
+
+	query := r.URL.Query()
+	events, err := h.service.List(r.Context(), productId, services.ListProductEventsInput{
+		Kinds:  query["kind"],
+		Limit:  query.Get("limit"),
+		Offset: query.Get("offset"),
+	})
+	if err != nil {
+		apiErrors.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
@@ -0,0 +1,75 @@
+/*
+uniwish.com/interal/api/handlers/product_event_test
+
+tests for the product events endpoint
+*/
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+type FakeProductEventRepo struct {
+	err error
+}
+
+func (r *FakeProductEventRepo) ListProductEvents(_ context.Context, _ uuid.UUID, _ repository.ProductEventFilter, _ int, _ int) ([]repository.ProductEventListItem, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	scrapeRequestId := uuid.New()
+	return []repository.ProductEventListItem{
+		{ID: uuid.New(), Kind: "availability_changed", Region: "es", Before: "OutOfStock", After: "InStock", ScrapeRequestID: &scrapeRequestId, OccurredAt: time.Now()},
+		{ID: uuid.New(), Kind: "name_changed", Before: "jacket", After: "cropped jacket", OccurredAt: time.Now().Add(-time.Hour)},
+	}, nil
+}
+
+func TestListProductEvents(t *testing.T) {
+	tests := []struct {
+		name               string
+		repo               repository.ProductEventReader
+		target             string
+		expectedStatusCode int
+	}{
+		{name: "success", repo: &FakeProductEventRepo{}, target: "/products/" + uuid.NewString() + "/events?kind=name_changed&kind=availability_changed", expectedStatusCode: 200},
+		{name: "not_found", repo: &FakeProductEventRepo{err: sql.ErrNoRows}, target: "/products/" + uuid.NewString() + "/events", expectedStatusCode: 404},
+		{name: "invalid_id", repo: &FakeProductEventRepo{}, target: "/products/whatever/events", expectedStatusCode: 400},
+		{name: "invalid_kind", repo: &FakeProductEventRepo{}, target: "/products/" + uuid.NewString() + "/events?kind=renamed", expectedStatusCode: 400},
+		{name: "internal_error", repo: &FakeProductEventRepo{err: sql.ErrConnDone}, target: "/products/" + uuid.NewString() + "/events", expectedStatusCode: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("GET /products/{id}/events", NewProductEventHandler(services.NewDefaultProductEventReaderService(tt.repo)))
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatusCode {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+			if rr.Code == http.StatusOK {
+				var resp services.ProductEventListResponse
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatalf("invalid json: %v", err)
+				}
+				if len(resp.Events) != 2 || resp.Events[0].ScrapeRequestID == nil || resp.Events[1].Region != "" {
+					t.Fatalf("unexpected events %+v", resp.Events)
+				}
+			}
+		})
+	}
+}
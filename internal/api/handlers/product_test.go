@@ -57,6 +57,27 @@ func (p *SuccessfulRepo) GetProduct(ctx context.Context, id uuid.UUID) (*reposit
 				UpdatedAt: time.Now().Add(-3 * time.Hour),
 			},
 		},
+		Regions: []repository.RegionListItem{
+			{
+				Region:    "es",
+				URL:       "fakestore.com/es/fakeproduct",
+				LastPrice: 34.42,
+				Currency:  "EUR",
+				UpdatedAt: time.Now(),
+			},
+		},
+		AlsoAvailableAt: []repository.MatchListItem{
+			{
+				ProductID:  uuid.New(),
+				Store:      "reseller",
+				Name:       "fake product",
+				URL:        "reseller.com/fakeproduct",
+				LastPrice:  31.99,
+				Currency:   "EUR",
+				Confidence: 0.99,
+				Method:     "gtin",
+			},
+		},
 	}, nil
 }
 
@@ -95,7 +116,7 @@ func TestListProducts(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt := tt
 			hdlr := &DefaultProductHandler{
-				service: services.NewDefaultProductReaderService(tt.repo),
+				service: services.NewDefaultProductReaderService(tt.repo, nil),
 			}
 
 			req := httptest.NewRequest(http.MethodGet, "/products", nil)
@@ -138,7 +159,7 @@ func TestGetProduct(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt := tt
 			hdlr := &DefaultProductHandler{
-				service: services.NewDefaultProductReaderService(tt.repo),
+				service: services.NewDefaultProductReaderService(tt.repo, nil),
 			}
 			mux := http.NewServeMux()
 			mux.HandleFunc("GET /products/{id}", hdlr.GetProduct)
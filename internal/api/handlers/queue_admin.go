@@ -0,0 +1,153 @@
+/*
+uniwish.com/interal/api/handlers/queue_admin
+
+operator endpoints over the scrape queue, listing requests and retrying, cancelling or purging them in bulk
+*/
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/services"
+)
+
+type QueueAdminHandler struct {
+	service services.QueueAdministrator
+	// MaxBodyBytes bounds request bodies, zero falls back to DefaultMaxBodyBytes
+	MaxBodyBytes int64
+}
+
+func NewQueueAdminHandler(srv services.QueueAdministrator) *QueueAdminHandler {
+	return &QueueAdminHandler{service: srv}
+}
+
+type scrapeRequestSelectionRequest struct {
+	IDs       []string `json:"ids"`
+	Store     string   `json:"store"`
+	ErrorKind string   `json:"error_kind"`
+	All       bool     `json:"all"`
+}
+
+type purgeScrapeRequestsRequest struct {
+	OlderThanDays int `json:"older_than_days"`
+}
+
+type adminScrapeRequestResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	URL        string     `json:"url"`
+	Status     string     `json:"status"`
+	Priority   string     `json:"priority"`
+	OwnerID    string     `json:"owner_id"`
+	Store      string     `json:"store"`
+	ErrorKind  string     `json:"error_kind,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type adminScrapeRequestListResponse struct {
+	ScrapeRequests []adminScrapeRequestResponse `json:"scrape_requests"`
+}
+
+type queueActionResponse struct {
+	Affected int64 `json:"affected"`
+}
+
+func (h *QueueAdminHandler) ListScrapeRequests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	items, err := h.service.List(r.Context(), services.ListScrapeRequestsInput{
+		Status:    query.Get("status"),
+		Store:     query.Get("store"),
+		ErrorKind: query.Get("error_kind"),
+		Limit:     query.Get("limit"),
+		Offset:    query.Get("offset"),
+	})
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	resp := adminScrapeRequestListResponse{ScrapeRequests: make([]adminScrapeRequestResponse, len(items))}
+	for i, item := range items {
+		resp.ScrapeRequests[i] = adminScrapeRequestResponse{
+			ID:         item.ID,
+			URL:        item.URL,
+			Status:     item.Status,
+			Priority:   services.PriorityName(item.Priority),
+			OwnerID:    item.OwnerID,
+			Store:      item.Store,
+			ErrorKind:  item.ErrorKind,
+			Error:      item.Error,
+			CreatedAt:  item.CreatedAt,
+			FinishedAt: item.FinishedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *QueueAdminHandler) RetryScrapeRequests(w http.ResponseWriter, r *http.Request) {
+	h.applyToSelection(w, r, h.service.Retry)
+}
+
+func (h *QueueAdminHandler) CancelScrapeRequests(w http.ResponseWriter, r *http.Request) {
+	h.applyToSelection(w, r, h.service.Cancel)
+}
+
+func (h *QueueAdminHandler) applyToSelection(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, selection services.ScrapeRequestSelection) (int64, error)) {
+	var req scrapeRequestSelectionRequest
+	if err := h.decodeBody(w, r, &req); err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	affected, err := action(r.Context(), services.ScrapeRequestSelection{
+		IDs:       req.IDs,
+		Store:     req.Store,
+		ErrorKind: req.ErrorKind,
+		All:       req.All,
+	})
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+	writeQueueAction(w, affected)
+}
+
+func (h *QueueAdminHandler) PurgeScrapeRequests(w http.ResponseWriter, r *http.Request) {
+	var req purgeScrapeRequestsRequest
+	if err := h.decodeBody(w, r, &req); err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	affected, err := h.service.Purge(r.Context(), services.PurgeInput{OlderThanDays: req.OlderThanDays})
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+	writeQueueAction(w, affected)
+}
+
+// decodeBody reads an optional JSON body into v, an empty body leaves v as is
+func (h *QueueAdminHandler) decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	err := decodeJSON(w, r, v, h.MaxBodyBytes)
+	if stdErrors.Is(err, errEmptyBody) {
+		return nil
+	}
+	return err
+}
+
+func writeQueueAction(w http.ResponseWriter, affected int64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(queueActionResponse{Affected: affected})
+}
@@ -0,0 +1,131 @@
+/*
+uniwish.com/interal/api/handlers/queue_admin_test
+
+test the queue admin handlers
+*/
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+type FakeQueueAdministrator struct {
+	listInput services.ListScrapeRequestsInput
+	selection services.ScrapeRequestSelection
+	purge     services.PurgeInput
+	err       error
+}
+
+func (s *FakeQueueAdministrator) List(_ context.Context, input services.ListScrapeRequestsInput) ([]repository.ScrapeRequestListItem, error) {
+	s.listInput = input
+	if s.err != nil {
+		return nil, s.err
+	}
+	finished := time.Date(2026, 1, 1, 0, 5, 0, 0, time.UTC)
+	return []repository.ScrapeRequestListItem{{
+		ID:         uuid.New(),
+		URL:        "http://store.com/a",
+		Status:     repository.StatusFailed,
+		Priority:   repository.PriorityBulk,
+		Store:      "store",
+		ErrorKind:  "scrape_failed",
+		Error:      "timeout",
+		CreatedAt:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		FinishedAt: &finished,
+	}}, nil
+}
+
+func (s *FakeQueueAdministrator) Retry(_ context.Context, selection services.ScrapeRequestSelection) (int64, error) {
+	s.selection = selection
+	return 3, s.err
+}
+
+func (s *FakeQueueAdministrator) Cancel(_ context.Context, selection services.ScrapeRequestSelection) (int64, error) {
+	s.selection = selection
+	return 2, s.err
+}
+
+func (s *FakeQueueAdministrator) Purge(_ context.Context, input services.PurgeInput) (int64, error) {
+	s.purge = input
+	return 1, s.err
+}
+
+func TestQueueAdminHandler_ListScrapeRequests(t *testing.T) {
+	service := &FakeQueueAdministrator{}
+	handler := NewQueueAdminHandler(service)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/scrape-requests?status=failed&store=zara&error_kind=scrape_failed&limit=10&offset=5", nil)
+	rr := httptest.NewRecorder()
+	handler.ListScrapeRequests(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, received %d", rr.Code)
+	}
+	expected := services.ListScrapeRequestsInput{Status: "failed", Store: "zara", ErrorKind: "scrape_failed", Limit: "10", Offset: "5"}
+	if service.listInput != expected {
+		t.Fatalf("expected input %+v, received %+v", expected, service.listInput)
+	}
+
+	var resp adminScrapeRequestListResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(resp.ScrapeRequests) != 1 || resp.ScrapeRequests[0].Priority != services.PriorityBulk || resp.ScrapeRequests[0].ErrorKind != "scrape_failed" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestQueueAdminHandler_Actions(t *testing.T) {
+	tests := []struct {
+		name             string
+		action           func(*QueueAdminHandler) http.HandlerFunc
+		body             string
+		err              error
+		expectedStatus   int
+		expectedAffected int64
+	}{
+		{name: "retry", action: func(h *QueueAdminHandler) http.HandlerFunc { return h.RetryScrapeRequests }, body: `{"store": "zara", "error_kind": "scrape_failed"}`, expectedStatus: 200, expectedAffected: 3},
+		{name: "cancel", action: func(h *QueueAdminHandler) http.HandlerFunc { return h.CancelScrapeRequests }, body: `{"ids": ["` + fakeId.String() + `"]}`, expectedStatus: 200, expectedAffected: 2},
+		{name: "purge", action: func(h *QueueAdminHandler) http.HandlerFunc { return h.PurgeScrapeRequests }, body: `{"older_than_days": 7}`, expectedStatus: 200, expectedAffected: 1},
+		{name: "purge_empty_body", action: func(h *QueueAdminHandler) http.HandlerFunc { return h.PurgeScrapeRequests }, expectedStatus: 200, expectedAffected: 1},
+		{name: "invalid_json", action: func(h *QueueAdminHandler) http.HandlerFunc { return h.RetryScrapeRequests }, body: `{"ids":`, expectedStatus: 400},
+		{name: "too_large", action: func(h *QueueAdminHandler) http.HandlerFunc { return h.RetryScrapeRequests }, body: `{"store": "` + strings.Repeat("a", DefaultMaxBodyBytes) + `"}`, expectedStatus: 413},
+		{name: "unsupported", action: func(h *QueueAdminHandler) http.HandlerFunc { return h.CancelScrapeRequests }, body: `{"all": true}`, err: errors.ErrQueueAdminUnsupported, expectedStatus: 501},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewQueueAdminHandler(&FakeQueueAdministrator{err: tt.err})
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/scrape-requests/"+tt.name, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			tt.action(handler)(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, received %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var resp queueActionResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if resp.Affected != tt.expectedAffected {
+				t.Fatalf("expected %d affected, received %d", tt.expectedAffected, resp.Affected)
+			}
+		})
+	}
+}
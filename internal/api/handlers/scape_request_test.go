@@ -16,16 +16,19 @@ import (
 
 	"github.com/google/uuid"
 	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/services"
 )
 
 var fakeId uuid.UUID = uuid.New()
 
 type FakeScrapeRequester struct {
-	id  uuid.UUID
-	err error
+	id    uuid.UUID
+	err   error
+	input services.ScrapeRequestInput
 }
 
-func (s *FakeScrapeRequester) Request(ctx context.Context, _ string) (uuid.UUID, error) {
+func (s *FakeScrapeRequester) Request(ctx context.Context, input services.ScrapeRequestInput) (uuid.UUID, error) {
+	s.input = input
 	return s.id, s.err
 }
 
@@ -94,6 +97,26 @@ func TestCreateScrapeRequester(t *testing.T) {
 			expectedStatus:       http.StatusBadRequest,
 			expectedJSONResponse: createScrapeRequestResponse{},
 		},
+		{
+			name: "empty_body",
+			service: FakeScrapeRequester{
+				id:  fakeId,
+				err: nil,
+			},
+			payload:              "",
+			expectedStatus:       http.StatusBadRequest,
+			expectedJSONResponse: createScrapeRequestResponse{},
+		},
+		{
+			name: "body_too_large",
+			service: FakeScrapeRequester{
+				id:  fakeId,
+				err: nil,
+			},
+			payload:              `{"url": "http://zara.com/` + strings.Repeat("a", DefaultMaxBodyBytes) + `"}`,
+			expectedStatus:       http.StatusRequestEntityTooLarge,
+			expectedJSONResponse: createScrapeRequestResponse{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -113,6 +136,24 @@ func TestCreateScrapeRequester(t *testing.T) {
 				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
 			}
 
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("expected application/json, got %q", ct)
+			}
+
+			if rr.Code != http.StatusAccepted {
+				var envelope struct {
+					Error struct {
+						Code string `json:"code"`
+					} `json:"error"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&envelope); err != nil {
+					t.Fatalf("invalid error envelope: %v", err)
+				}
+				if envelope.Error.Code == "" {
+					t.Fatal("expected error code in envelope")
+				}
+			}
+
 			if rr.Code == http.StatusAccepted {
 				var actualResp createScrapeRequestResponse
 				if err := json.NewDecoder(rr.Body).Decode(&actualResp); err != nil {
@@ -127,3 +168,27 @@ func TestCreateScrapeRequester(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateScrapeRequester_PassesScheduling(t *testing.T) {
+	srv := &FakeScrapeRequester{id: fakeId}
+	handler := NewCreateItemHandler(srv)
+
+	req := httptest.NewRequest(
+		http.MethodPost, "/scrape-requests",
+		strings.NewReader(`{"url": "fake.com", "priority": "bulk"}`),
+	)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(UserIDHeader, "user-1")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, rr.Code)
+	}
+
+	expected := services.ScrapeRequestInput{URL: "fake.com", OwnerID: "user-1", ClientIP: "192.0.2.1", Priority: "bulk"}
+	if srv.input != expected {
+		t.Fatalf("expected %+v, got %+v", expected, srv.input)
+	}
+}
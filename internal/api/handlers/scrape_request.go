@@ -11,19 +11,26 @@ import (
 
 	"github.com/google/uuid"
 	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/middleware"
 	"uniwish.com/internal/api/services"
 )
 
 type CreateScrapeRequestHandler struct {
 	service services.ScrapeRequester
+	// MaxBodyBytes bounds request bodies, zero falls back to DefaultMaxBodyBytes
+	MaxBodyBytes int64
 }
 
 func NewCreateItemHandler(srv services.ScrapeRequester) *CreateScrapeRequestHandler {
 	return &CreateScrapeRequestHandler{service: srv}
 }
 
+// until authentication lands, the extension identifies its user through this header
+const UserIDHeader = "X-User-ID"
+
 type createScrapeRequestRequest struct {
-	URL string `json:"url"`
+	URL      string `json:"url"`
+	Priority string `json:"priority,omitempty"`
 }
 
 type createScrapeRequestResponse struct {
@@ -33,24 +40,21 @@ type createScrapeRequestResponse struct {
 
 func (h *CreateScrapeRequestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var req createScrapeRequestRequest
-	w.Header().Set("Content-Type", "application/json")
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error": "invalid_json"}`, http.StatusBadRequest)
+	if err := decodeJSON(w, r, &req, h.MaxBodyBytes); err != nil {
+		errors.Write(w, r, err)
 		return
 	}
 
-	id, err := h.service.Request(r.Context(), req.URL)
+	id, err := h.service.Request(r.Context(), services.ScrapeRequestInput{
+		URL:      req.URL,
+		OwnerID:  r.Header.Get(UserIDHeader),
+		ClientIP: middleware.ClientIP(r),
+		Priority: req.Priority,
+	})
 
 	if err != nil {
-		switch err {
-		case errors.ErrInputInvalid:
-			http.Error(w, `{"error": "missing_or_invalid_url"}`, http.StatusBadRequest)
-		case errors.ErrStoreUnsupported:
-			http.Error(w, `{"error": "unsupported_store"}`, http.StatusUnprocessableEntity)
-		default:
-			http.Error(w, `{"error": "internal_error"}`, http.StatusInternalServerError)
-		}
+		errors.Write(w, r, err)
 		return
 	}
 
@@ -59,6 +63,7 @@ func (h *CreateScrapeRequestHandler) ServeHTTP(w http.ResponseWriter, r *http.Re
 		Status: "pending",
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(resp)
 }
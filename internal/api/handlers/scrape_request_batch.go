@@ -0,0 +1,215 @@
+/*
+uniwish.com/interal/api/handlers/scrape_request_batch
+
+bulk scrape request endpoint, importing wishlists exported by other tools
+
+the urls are read from a JSON array, a CSV (the url or link column, else the first one),
+plain text with one url per line, or any of those uploaded as the "file" field of a multipart form
+*/
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	stdErrors "errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/middleware"
+	"uniwish.com/internal/api/services"
+)
+
+type BatchScrapeRequestHandler struct {
+	service services.BatchScrapeRequester
+	// MaxBodyBytes bounds the uploaded list, zero falls back to DefaultMaxBodyBytes
+	MaxBodyBytes int64
+}
+
+func NewBatchScrapeRequestHandler(srv services.BatchScrapeRequester) *BatchScrapeRequestHandler {
+	return &BatchScrapeRequestHandler{service: srv}
+}
+
+type batchItemResponse struct {
+	URL    string     `json:"url"`
+	Status string     `json:"status"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+}
+
+type batchScrapeRequestResponse struct {
+	Accepted int                 `json:"accepted"`
+	Rejected int                 `json:"rejected"`
+	Results  []batchItemResponse `json:"results"`
+}
+
+func (h *BatchScrapeRequestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = limitBody(w, r, h.MaxBodyBytes)
+
+	urls, err := readBatchURLs(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if stdErrors.As(err, &maxBytesErr) {
+			err = errors.ErrBodyTooLarge
+		}
+		errors.Write(w, r, err)
+		return
+	}
+	// the rate limiter took one token for the request, every url past the first costs another
+	middleware.ChargeRateLimit(r, len(urls)-1)
+
+	results, err := h.service.RequestBatch(r.Context(), services.BatchScrapeRequestInput{
+		URLs:     urls,
+		OwnerID:  r.Header.Get(UserIDHeader),
+		ClientIP: middleware.ClientIP(r),
+		Priority: r.URL.Query().Get("priority"),
+	})
+
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	resp := batchScrapeRequestResponse{Results: make([]batchItemResponse, len(results))}
+	for i, result := range results {
+		resp.Results[i] = batchItemResponse{URL: result.URL, Status: result.Status}
+		if result.Status == services.BatchItemAccepted {
+			resp.Results[i].ID = &result.ID
+			resp.Accepted++
+		} else {
+			resp.Rejected++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func readBatchURLs(r *http.Request) ([]string, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, errors.ErrUnsupportedMediaType
+	}
+
+	if mediaType == "multipart/form-data" {
+		return readUploadedURLs(r)
+	}
+	return parseBatchURLs(mediaType, r.Body)
+}
+
+func readUploadedURLs(r *http.Request) ([]string, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.Invalid(errors.FieldError{Field: "file", Code: "invalid", Message: "malformed multipart body"})
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.Invalid(errors.FieldError{Field: "file", Code: "required", Message: "file is required"})
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return parseBatchURLs(uploadMediaType(part), part)
+		}
+	}
+}
+
+func uploadMediaType(part *multipart.Part) string {
+	// browsers often send uploads as application/octet-stream, the extension is more telling
+	switch strings.ToLower(filepath.Ext(part.FileName())) {
+	case ".json":
+		return "application/json"
+	case ".csv":
+		return "text/csv"
+	case ".txt":
+		return "text/plain"
+	}
+
+	mediaType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if err != nil || mediaType == "application/octet-stream" {
+		return "text/plain"
+	}
+	return mediaType
+}
+
+func parseBatchURLs(mediaType string, body io.Reader) ([]string, error) {
+	switch mediaType {
+	case "application/json":
+		var urls []string
+		if err := json.NewDecoder(body).Decode(&urls); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if stdErrors.As(err, &maxBytesErr) {
+				return nil, err
+			}
+			return nil, errors.ErrInvalidJSON
+		}
+		return urls, nil
+	case "text/csv":
+		return parseCSVURLs(body)
+	case "text/plain":
+		return parseTextURLs(body)
+	default:
+		return nil, errors.ErrUnsupportedMediaType
+	}
+}
+
+func parseCSVURLs(body io.Reader) ([]string, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if stdErrors.As(err, &maxBytesErr) {
+			return nil, err
+		}
+		return nil, errors.Invalid(errors.FieldError{Field: "body", Code: "invalid", Message: "malformed csv"})
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	column := 0
+	for i, cell := range records[0] {
+		if name := strings.ToLower(strings.TrimSpace(cell)); name == "url" || name == "link" {
+			column = i
+			records = records[1:]
+			break
+		}
+	}
+
+	urls := make([]string, 0, len(records))
+	for _, record := range records {
+		if column < len(record) {
+			if url := strings.TrimSpace(record[column]); url != "" {
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls, nil
+}
+
+func parseTextURLs(body io.Reader) ([]string, error) {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for line := range strings.Lines(string(raw)) {
+		// blank lines and # comments are skipped
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			urls = append(urls, line)
+		}
+	}
+	return urls, nil
+}
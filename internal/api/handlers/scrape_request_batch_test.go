@@ -0,0 +1,222 @@
+/*
+uniwish.com/interal/api/handlers/scrape_request_batch_test
+
+test bulk scrape request handler
+*/
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/middleware"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+type FakeBatchScrapeRequester struct {
+	input services.BatchScrapeRequestInput
+	err   error
+}
+
+func (s *FakeBatchScrapeRequester) RequestBatch(_ context.Context, input services.BatchScrapeRequestInput) ([]services.BatchItemResult, error) {
+	s.input = input
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	results := make([]services.BatchItemResult, len(input.URLs))
+	for i, url := range input.URLs {
+		results[i] = services.BatchItemResult{URL: url, Status: services.BatchItemAccepted, ID: uuid.New()}
+		if strings.HasPrefix(url, "bad") {
+			results[i] = services.BatchItemResult{URL: url, Status: services.BatchItemInvalid}
+		}
+	}
+	return results, nil
+}
+
+func multipartUpload(t *testing.T, filename string, content string) (string, *bytes.Buffer) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	writer.Close()
+	return writer.FormDataContentType(), body
+}
+
+func TestBatchScrapeRequestHandler_Formats(t *testing.T) {
+	uploadType, uploadBody := multipartUpload(t, "wishlist.csv", "name,link\njacket,http://a.com/1\nshoes,http://a.com/2\n")
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedURLs   []string
+	}{
+		{
+			name:           "json",
+			contentType:    "application/json",
+			body:           `["http://a.com/1", "http://a.com/2"]`,
+			expectedStatus: http.StatusAccepted,
+			expectedURLs:   []string{"http://a.com/1", "http://a.com/2"},
+		},
+		{
+			name:           "csv_with_header",
+			contentType:    "text/csv",
+			body:           "name,url\njacket,http://a.com/1\nshoes, http://a.com/2\n",
+			expectedStatus: http.StatusAccepted,
+			expectedURLs:   []string{"http://a.com/1", "http://a.com/2"},
+		},
+		{
+			name:           "csv_without_header",
+			contentType:    "text/csv; charset=utf-8",
+			body:           "http://a.com/1,jacket\nhttp://a.com/2,shoes\n",
+			expectedStatus: http.StatusAccepted,
+			expectedURLs:   []string{"http://a.com/1", "http://a.com/2"},
+		},
+		{
+			name:           "plain_text",
+			contentType:    "text/plain",
+			body:           "# my wishlist\nhttp://a.com/1\n\n  http://a.com/2  \n",
+			expectedStatus: http.StatusAccepted,
+			expectedURLs:   []string{"http://a.com/1", "http://a.com/2"},
+		},
+		{
+			name:           "multipart_upload",
+			contentType:    uploadType,
+			body:           uploadBody.String(),
+			expectedStatus: http.StatusAccepted,
+			expectedURLs:   []string{"http://a.com/1", "http://a.com/2"},
+		},
+		{
+			name:           "bad_json",
+			contentType:    "application/json",
+			body:           `["http://a.com/1",`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported_media_type",
+			contentType:    "application/xml",
+			body:           `<urls/>`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "too_large",
+			contentType:    "text/plain",
+			body:           strings.Repeat("http://a.com/1\n", DefaultMaxBodyBytes/10),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &FakeBatchScrapeRequester{}
+			handler := NewBatchScrapeRequestHandler(srv)
+
+			req := httptest.NewRequest(http.MethodPost, "/scrape-requests/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedURLs != nil && !reflect.DeepEqual(srv.input.URLs, tt.expectedURLs) {
+				t.Fatalf("expected urls %v, got %v", tt.expectedURLs, srv.input.URLs)
+			}
+		})
+	}
+}
+
+func TestBatchScrapeRequestHandler_Results(t *testing.T) {
+	srv := &FakeBatchScrapeRequester{}
+	handler := NewBatchScrapeRequestHandler(srv)
+
+	req := httptest.NewRequest(
+		http.MethodPost, "/scrape-requests/batch?priority=interactive",
+		strings.NewReader(`["http://a.com/1", "bad", "http://a.com/2"]`),
+	)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(UserIDHeader, "user-1")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, rr.Code)
+	}
+	if srv.input.OwnerID != "user-1" || srv.input.Priority != "interactive" {
+		t.Fatalf("expected owner and priority to be passed, got %+v", srv.input)
+	}
+
+	var resp batchScrapeRequestResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Accepted != 2 || resp.Rejected != 1 || len(resp.Results) != 3 {
+		t.Fatalf("expected 2 accepted and 1 rejected, got %+v", resp)
+	}
+	if resp.Results[1].Status != services.BatchItemInvalid || resp.Results[1].ID != nil {
+		t.Fatalf("expected invalid item without id, got %+v", resp.Results[1])
+	}
+	if resp.Results[0].ID == nil {
+		t.Fatal("expected accepted item to carry its id")
+	}
+}
+
+func TestBatchScrapeRequestHandler_TooManyForOneInsert(t *testing.T) {
+	// a limit configured past what one insert can bind is still capped, before the queue is reached
+	srv := services.NewScrapeRequestService(nil, nil)
+	srv.MaxBatchSize = repository.MaxEnqueueBatch + 100
+	handler := NewBatchScrapeRequestHandler(srv)
+
+	body := strings.Repeat("http://store.com/a\n", repository.MaxEnqueueBatch+1)
+	req := httptest.NewRequest(http.MethodPost, "/scrape-requests/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
+func TestBatchScrapeRequestHandler_ChargesRateLimitPerURL(t *testing.T) {
+	// a batch pays for each url, so it cannot be used to get around the limit on single requests
+	limiter := middleware.NewRateLimiter(1, 3)
+	handler := middleware.RateLimit(limiter)(NewBatchScrapeRequestHandler(&FakeBatchScrapeRequester{}))
+
+	for _, tt := range []struct {
+		body           string
+		expectedStatus int
+	}{
+		{body: "http://store.com/a\nhttp://store.com/b\nhttp://store.com/c\n", expectedStatus: http.StatusAccepted},
+		{body: "http://store.com/d\n", expectedStatus: http.StatusTooManyRequests},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/scrape-requests/batch", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "text/plain")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatus {
+			t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/*
+uniwish.com/interal/api/handlers/scrape_status
+
+server-sent event streams of scrape request statuses, so the extension learns a scrape finished without polling
+
+every transition is sent as a "status" event whose data is a ScrapeStatusResponse, idle streams get a comment
+every KeepAlive. A stream ending early, like when the api drains, is resumed by reconnecting
+*/
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+// DefaultStreamKeepAlive keeps proxies from closing streams that are quiet while a scrape runs
+const DefaultStreamKeepAlive = 15 * time.Second
+
+type ScrapeStatusHandler struct {
+	service services.ScrapeStatusStreamer
+	// KeepAlive is how often idle streams send a comment, zero falls back to DefaultStreamKeepAlive
+	KeepAlive time.Duration
+	// Done ends every open stream once closed, nil leaves them to their clients
+	Done <-chan struct{}
+}
+
+func NewScrapeStatusHandler(service services.ScrapeStatusStreamer) *ScrapeStatusHandler {
+	return &ScrapeStatusHandler{service: service}
+}
+
+// StreamScrapeRequest streams a request's current status then its transitions, ending once it is final
+func (h *ScrapeStatusHandler) StreamScrapeRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apiErrors.Write(w, r, apiErrors.Invalid(apiErrors.FieldError{Field: "id", Code: "invalid", Message: "id must be a uuid"}))
+		return
+	}
+
+	current, statuses, cancel, err := h.service.WatchScrapeRequest(r.Context(), id, r.Header.Get(UserIDHeader))
+	if err != nil {
+		apiErrors.Write(w, r, err)
+		return
+	}
+	defer cancel()
+	h.stream(w, r, current, statuses, true)
+}
+
+// StreamOwner streams the transitions of every request the calling user enqueues until they disconnect
+func (h *ScrapeStatusHandler) StreamOwner(w http.ResponseWriter, r *http.Request) {
+	statuses, cancel, err := h.service.WatchOwner(r.Context(), r.Header.Get(UserIDHeader))
+	if err != nil {
+		apiErrors.Write(w, r, err)
+		return
+	}
+	defer cancel()
+	h.stream(w, r, nil, statuses, false)
+}
+
+func (h *ScrapeStatusHandler) stream(w http.ResponseWriter, r *http.Request, current *repository.ScrapeStatus, statuses <-chan repository.ScrapeStatus, endOnFinal bool) {
+	ctx := r.Context()
+	displayCurrency := r.URL.Query().Get(DisplayCurrencyParam)
+	rc := http.NewResponseController(w)
+	// streams outlive the server's write timeout, which would otherwise cut them
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// keeps nginx style proxies from buffering the events
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	send := func(status repository.ScrapeStatus) (bool, error) {
+		resp := h.service.Describe(ctx, status, displayCurrency)
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return false, err
+		}
+		if _, err = fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+			return false, err
+		}
+		return endOnFinal && resp.Final(), rc.Flush()
+	}
+
+	if current != nil {
+		if final, err := send(*current); final || err != nil {
+			return
+		}
+	}
+
+	keepAlive := h.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultStreamKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.Done:
+			return
+		case status, ok := <-statuses:
+			if !ok {
+				// the watcher was dropped, the client reconnects and starts over from the current status
+				return
+			}
+			if final, err := send(status); final || err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
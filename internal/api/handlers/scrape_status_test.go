@@ -0,0 +1,194 @@
+/*
+uniwish.com/interal/api/handlers/scrape_status_test
+
+test the scrape status event streams
+*/
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+type FakeScrapeStatusStreamer struct {
+	current   *repository.ScrapeStatus
+	statuses  chan repository.ScrapeStatus
+	err       error
+	cancelled bool
+}
+
+func (s *FakeScrapeStatusStreamer) WatchScrapeRequest(context.Context, uuid.UUID, string) (*repository.ScrapeStatus, <-chan repository.ScrapeStatus, func(), error) {
+	if s.err != nil {
+		return nil, nil, nil, s.err
+	}
+	return s.current, s.statuses, func() { s.cancelled = true }, nil
+}
+
+func (s *FakeScrapeStatusStreamer) WatchOwner(_ context.Context, ownerID string) (<-chan repository.ScrapeStatus, func(), error) {
+	if ownerID == "" {
+		return nil, nil, errors.ErrUnauthorized
+	}
+	return s.statuses, func() { s.cancelled = true }, nil
+}
+
+func (s *FakeScrapeStatusStreamer) Describe(_ context.Context, status repository.ScrapeStatus, _ string) services.ScrapeStatusResponse {
+	return services.ScrapeStatusResponse{ScrapeRequestID: status.ScrapeRequestID, Status: status.Status, ProductID: status.ProductID}
+}
+
+// fakeStatuses returns a channel already holding statuses, closed as a dropped watcher's when closed is set
+func fakeStatuses(closed bool, statuses ...string) chan repository.ScrapeStatus {
+	c := make(chan repository.ScrapeStatus, len(statuses))
+	for _, status := range statuses {
+		c <- repository.ScrapeStatus{ScrapeRequestID: uuid.New(), Status: status}
+	}
+	if closed {
+		close(c)
+	}
+	return c
+}
+
+// readEvents parses the status events of an event stream body
+func readEvents(t *testing.T, body string) []services.ScrapeStatusResponse {
+	t.Helper()
+	var events []services.ScrapeStatusResponse
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event services.ScrapeStatusResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("invalid event data %q: %v", data, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func newScrapeStatusMux(handler *ScrapeStatusHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /scrape-requests/{id}/events", handler.StreamScrapeRequest)
+	mux.HandleFunc("GET /scrape-requests/events", handler.StreamOwner)
+	return mux
+}
+
+func TestScrapeStatusHandler_StreamScrapeRequest(t *testing.T) {
+	tests := []struct {
+		name             string
+		target           string
+		streamer         *FakeScrapeStatusStreamer
+		expectedStatus   int
+		expectedStatuses []string
+	}{
+		{
+			name: "ends_once_final", target: "/scrape-requests/" + uuid.NewString() + "/events",
+			streamer: &FakeScrapeStatusStreamer{
+				current:  &repository.ScrapeStatus{Status: repository.StatusPending},
+				statuses: fakeStatuses(false, repository.StatusProcessing, repository.StatusDone, repository.StatusProcessing),
+			},
+			expectedStatus: 200, expectedStatuses: []string{"pending", "processing", "done"},
+		},
+		{
+			name: "already_final", target: "/scrape-requests/" + uuid.NewString() + "/events",
+			streamer: &FakeScrapeStatusStreamer{
+				current:  &repository.ScrapeStatus{Status: repository.StatusFailed},
+				statuses: fakeStatuses(false),
+			},
+			expectedStatus: 200, expectedStatuses: []string{"failed"},
+		},
+		{
+			name: "watcher_dropped", target: "/scrape-requests/" + uuid.NewString() + "/events",
+			streamer:       &FakeScrapeStatusStreamer{statuses: fakeStatuses(true, repository.StatusProcessing)},
+			expectedStatus: 200, expectedStatuses: []string{"processing"},
+		},
+		{
+			name: "invalid_id", target: "/scrape-requests/abc/events",
+			streamer: &FakeScrapeStatusStreamer{}, expectedStatus: 400,
+		},
+		{
+			name: "not_found", target: "/scrape-requests/" + uuid.NewString() + "/events",
+			streamer: &FakeScrapeStatusStreamer{err: errors.ErrScrapeRequestNotFound}, expectedStatus: 404,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rr := httptest.NewRecorder()
+			newScrapeStatusMux(NewScrapeStatusHandler(tt.streamer)).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, received %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			if rr.Header().Get("Content-Type") != "text/event-stream" {
+				t.Fatalf("expected an event stream, received %q", rr.Header().Get("Content-Type"))
+			}
+			events := readEvents(t, rr.Body.String())
+			statuses := make([]string, len(events))
+			for i, event := range events {
+				statuses[i] = event.Status
+			}
+			if strings.Join(statuses, ",") != strings.Join(tt.expectedStatuses, ",") {
+				t.Fatalf("expected %q, received %q", tt.expectedStatuses, statuses)
+			}
+			if !tt.streamer.cancelled {
+				t.Fatal("expected the watch cancelled once the stream ended")
+			}
+		})
+	}
+}
+
+func TestScrapeStatusHandler_StreamOwner(t *testing.T) {
+	t.Run("anonymous", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/scrape-requests/events", nil)
+		rr := httptest.NewRecorder()
+		newScrapeStatusMux(NewScrapeStatusHandler(&FakeScrapeStatusStreamer{})).ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, received %d", rr.Code)
+		}
+	})
+
+	t.Run("keeps_streaming_past_final", func(t *testing.T) {
+		streamer := &FakeScrapeStatusStreamer{statuses: fakeStatuses(true, repository.StatusDone, repository.StatusFailed)}
+		req := httptest.NewRequest(http.MethodGet, "/scrape-requests/events", nil)
+		req.Header.Set(UserIDHeader, "user-1")
+		rr := httptest.NewRecorder()
+		newScrapeStatusMux(NewScrapeStatusHandler(streamer)).ServeHTTP(rr, req)
+
+		if events := readEvents(t, rr.Body.String()); len(events) != 2 {
+			t.Fatalf("expected every request's status, received %+v", events)
+		}
+	})
+}
+
+func TestScrapeStatusHandler_EndsOnDone(t *testing.T) {
+	done := make(chan struct{})
+	handler := NewScrapeStatusHandler(&FakeScrapeStatusStreamer{statuses: fakeStatuses(false)})
+	handler.KeepAlive = time.Millisecond
+	handler.Done = done
+	time.AfterFunc(20*time.Millisecond, func() { close(done) })
+
+	req := httptest.NewRequest(http.MethodGet, "/scrape-requests/events", nil)
+	req.Header.Set(UserIDHeader, "user-1")
+	rr := httptest.NewRecorder()
+	newScrapeStatusMux(handler).ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), ": keepalive\n\n") {
+		t.Fatalf("expected keepalives while idle, received %q", rr.Body.String())
+	}
+}
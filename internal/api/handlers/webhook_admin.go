@@ -0,0 +1,254 @@
+/*
+uniwish.com/interal/api/handlers/webhook_admin
+
+operator endpoints managing the webhook subscriptions of integrators, their delivery log and redeliveries
+*/
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+type WebhookAdminHandler struct {
+	service services.WebhookAdministrator
+	// MaxBodyBytes bounds request bodies, zero falls back to DefaultMaxBodyBytes
+	MaxBodyBytes int64
+}
+
+func NewWebhookAdminHandler(srv services.WebhookAdministrator) *WebhookAdminHandler {
+	return &WebhookAdminHandler{service: srv}
+}
+
+type createWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+type webhookResponse struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	// Secret is only shown by the response creating the webhook
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type webhookListResponse struct {
+	Webhooks []webhookResponse `json:"webhooks"`
+}
+
+type webhookDeliveryResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	EventID        uuid.UUID  `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+type webhookDeliveryListResponse struct {
+	Deliveries []webhookDeliveryResponse `json:"deliveries"`
+}
+
+type webhookAttemptResponse struct {
+	StatusCode  *int      `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMS  int64     `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+type webhookDeliveryDetailResponse struct {
+	webhookDeliveryResponse
+	Payload    json.RawMessage          `json:"payload"`
+	AttemptLog []webhookAttemptResponse `json:"attempt_log"`
+}
+
+func (h *WebhookAdminHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req createWebhookRequest
+	if err := decodeJSON(w, r, &req, h.MaxBodyBytes); err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	subscription, err := h.service.Create(r.Context(), services.CreateWebhookInput{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     req.Secret,
+	})
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	resp := newWebhookResponse(*subscription)
+	resp.Secret = subscription.Secret
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *WebhookAdminHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.service.List(r.Context())
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	resp := webhookListResponse{Webhooks: make([]webhookResponse, len(subscriptions))}
+	for i, subscription := range subscriptions {
+		resp.Webhooks[i] = newWebhookResponse(subscription)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *WebhookAdminHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookAdminHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	deliveries, err := h.service.ListDeliveries(r.Context(), id, services.ListWebhookDeliveriesInput{
+		Status: query.Get("status"),
+		Limit:  query.Get("limit"),
+		Offset: query.Get("offset"),
+	})
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	resp := webhookDeliveryListResponse{Deliveries: make([]webhookDeliveryResponse, len(deliveries))}
+	for i, delivery := range deliveries {
+		resp.Deliveries[i] = newWebhookDeliveryResponse(delivery)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *WebhookAdminHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID, err := deliveryPathIDs(r)
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	delivery, err := h.service.GetDelivery(r.Context(), id, deliveryID)
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	resp := webhookDeliveryDetailResponse{
+		webhookDeliveryResponse: newWebhookDeliveryResponse(delivery.WebhookDelivery),
+		Payload:                 delivery.Payload,
+		AttemptLog:              make([]webhookAttemptResponse, len(delivery.AttemptLog)),
+	}
+	for i, attempt := range delivery.AttemptLog {
+		resp.AttemptLog[i] = webhookAttemptResponse{
+			StatusCode:  attempt.StatusCode,
+			Error:       attempt.Error,
+			DurationMS:  attempt.Duration.Milliseconds(),
+			AttemptedAt: attempt.AttemptedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *WebhookAdminHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID, err := deliveryPathIDs(r)
+	if err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+
+	if err := h.service.Redeliver(r.Context(), id, deliveryID); err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+	// the worker sends it on its next poll
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func newWebhookResponse(subscription repository.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+func newWebhookDeliveryResponse(delivery repository.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+	// only pending deliveries have an attempt ahead of them
+	if delivery.Status == repository.WebhookDeliveryPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return resp
+}
+
+func pathID(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		return uuid.Nil, errors.Invalid(errors.FieldError{Field: name, Code: "invalid", Message: name + " must be a uuid"})
+	}
+	return id, nil
+}
+
+func deliveryPathIDs(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	deliveryID, err := pathID(r, "delivery_id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return id, deliveryID, nil
+}
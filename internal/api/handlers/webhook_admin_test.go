@@ -0,0 +1,186 @@
+/*
+uniwish.com/interal/api/handlers/webhook_admin_test
+
+test the webhook admin handlers
+*/
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/services"
+)
+
+type FakeWebhookAdministrator struct {
+	created services.CreateWebhookInput
+	err     error
+}
+
+func (s *FakeWebhookAdministrator) Create(_ context.Context, input services.CreateWebhookInput) (*repository.WebhookSubscription, error) {
+	s.created = input
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &repository.WebhookSubscription{
+		ID: uuid.New(), URL: input.URL, Secret: "generated-secret-0123", EventTypes: input.EventTypes, CreatedAt: time.Now(),
+	}, nil
+}
+
+func (s *FakeWebhookAdministrator) List(context.Context) ([]repository.WebhookSubscription, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []repository.WebhookSubscription{
+		{ID: uuid.New(), URL: "https://hooks.example.com", Secret: "generated-secret-0123", EventTypes: []string{"price.changed"}, CreatedAt: time.Now()},
+	}, nil
+}
+
+func (s *FakeWebhookAdministrator) Delete(context.Context, uuid.UUID) error {
+	return s.err
+}
+
+func (s *FakeWebhookAdministrator) ListDeliveries(_ context.Context, subscriptionID uuid.UUID, _ services.ListWebhookDeliveriesInput) ([]repository.WebhookDelivery, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	statusCode := 500
+	return []repository.WebhookDelivery{{
+		ID: uuid.New(), SubscriptionID: subscriptionID, EventID: uuid.New(), EventType: "price.changed",
+		Status: repository.WebhookDeliveryPending, Attempts: 1, NextAttemptAt: time.Now().Add(time.Minute),
+		LastStatusCode: &statusCode, LastError: "unexpected status 500", CreatedAt: time.Now(),
+	}}, nil
+}
+
+func (s *FakeWebhookAdministrator) GetDelivery(_ context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) (*repository.WebhookDeliveryDetail, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	statusCode := 200
+	delivered := time.Now()
+	return &repository.WebhookDeliveryDetail{
+		WebhookDelivery: repository.WebhookDelivery{
+			ID: deliveryID, SubscriptionID: subscriptionID, EventID: uuid.New(), EventType: "price.changed",
+			Status: repository.WebhookDeliveryDelivered, Attempts: 1, LastStatusCode: &statusCode, CreatedAt: time.Now(), DeliveredAt: &delivered,
+		},
+		Payload:    []byte(`{"id": "` + uuid.NewString() + `", "type": "price.changed", "occurred_at": "2026-01-02T03:04:05Z", "data": {}}`),
+		AttemptLog: []repository.WebhookDeliveryAttempt{{StatusCode: &statusCode, Duration: 40 * time.Millisecond, AttemptedAt: delivered}},
+	}, nil
+}
+
+func (s *FakeWebhookAdministrator) Redeliver(context.Context, uuid.UUID, uuid.UUID) error {
+	return s.err
+}
+
+func newWebhookAdminMux(service services.WebhookAdministrator) *http.ServeMux {
+	handler := NewWebhookAdminHandler(service)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /admin/webhooks", handler.CreateWebhook)
+	mux.HandleFunc("GET /admin/webhooks", handler.ListWebhooks)
+	mux.HandleFunc("DELETE /admin/webhooks/{id}", handler.DeleteWebhook)
+	mux.HandleFunc("GET /admin/webhooks/{id}/deliveries", handler.ListDeliveries)
+	mux.HandleFunc("GET /admin/webhooks/{id}/deliveries/{delivery_id}", handler.GetDelivery)
+	mux.HandleFunc("POST /admin/webhooks/{id}/deliveries/{delivery_id}/redeliver", handler.Redeliver)
+	return mux
+}
+
+func TestWebhookAdminHandler_CreateWebhook(t *testing.T) {
+	service := &FakeWebhookAdministrator{}
+	req := httptest.NewRequest(http.MethodPost, "/admin/webhooks", strings.NewReader(`{"url": "https://hooks.example.com", "event_types": ["price.changed"]}`))
+	rr := httptest.NewRecorder()
+	newWebhookAdminMux(service).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, received %d", rr.Code)
+	}
+	if service.created.URL != "https://hooks.example.com" || len(service.created.EventTypes) != 1 {
+		t.Fatalf("unexpected input %+v", service.created)
+	}
+	var resp webhookResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.Secret == "" {
+		t.Fatal("expected the secret shown on creation")
+	}
+}
+
+func TestWebhookAdminHandler_ListWebhooksHidesSecrets(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+	rr := httptest.NewRecorder()
+	newWebhookAdminMux(&FakeWebhookAdministrator{}).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, received %d", rr.Code)
+	}
+	if strings.Contains(rr.Body.String(), "generated-secret") {
+		t.Fatalf("expected secrets left out of listings, received %s", rr.Body.String())
+	}
+}
+
+func TestWebhookAdminHandler_Routes(t *testing.T) {
+	id, deliveryID := uuid.NewString(), uuid.NewString()
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "create_invalid_json", method: http.MethodPost, target: "/admin/webhooks", body: `{"url":`, expectedStatus: 400},
+		{name: "create_invalid_input", method: http.MethodPost, target: "/admin/webhooks", body: `{}`, err: errors.Invalid(), expectedStatus: 400},
+		{name: "delete", method: http.MethodDelete, target: "/admin/webhooks/" + id, expectedStatus: 204},
+		{name: "delete_invalid_id", method: http.MethodDelete, target: "/admin/webhooks/abc", expectedStatus: 400},
+		{name: "delete_unknown", method: http.MethodDelete, target: "/admin/webhooks/" + id, err: errors.ErrWebhookNotFound, expectedStatus: 404},
+		{name: "deliveries", method: http.MethodGet, target: "/admin/webhooks/" + id + "/deliveries?status=pending", expectedStatus: 200},
+		{name: "deliveries_unknown", method: http.MethodGet, target: "/admin/webhooks/" + id + "/deliveries", err: errors.ErrWebhookNotFound, expectedStatus: 404},
+		{name: "delivery", method: http.MethodGet, target: "/admin/webhooks/" + id + "/deliveries/" + deliveryID, expectedStatus: 200},
+		{name: "delivery_invalid_id", method: http.MethodGet, target: "/admin/webhooks/" + id + "/deliveries/abc", expectedStatus: 400},
+		{name: "redeliver", method: http.MethodPost, target: "/admin/webhooks/" + id + "/deliveries/" + deliveryID + "/redeliver", expectedStatus: 202},
+		{
+			name: "redeliver_unknown", method: http.MethodPost, target: "/admin/webhooks/" + id + "/deliveries/" + deliveryID + "/redeliver",
+			err: errors.ErrWebhookDeliveryNotFound, expectedStatus: 404,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			newWebhookAdminMux(&FakeWebhookAdministrator{err: tt.err}).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, received %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestWebhookAdminHandler_GetDelivery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/webhooks/"+uuid.NewString()+"/deliveries/"+uuid.NewString(), nil)
+	rr := httptest.NewRecorder()
+	newWebhookAdminMux(&FakeWebhookAdministrator{}).ServeHTTP(rr, req)
+
+	var resp struct {
+		Payload    map[string]any           `json:"payload"`
+		AttemptLog []webhookAttemptResponse `json:"attempt_log"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.Payload["type"] != "price.changed" {
+		t.Fatalf("expected the payload embedded as JSON, received %+v", resp.Payload)
+	}
+	if len(resp.AttemptLog) != 1 || resp.AttemptLog[0].DurationMS != 40 || *resp.AttemptLog[0].StatusCode != 200 {
+		t.Fatalf("expected the attempt logged, received %+v", resp.AttemptLog)
+	}
+}
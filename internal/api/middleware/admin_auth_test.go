@@ -0,0 +1,50 @@
+/*
+uniwish.com/internal/api/middleware/admin_auth_test
+
+tests admin token authentication
+*/
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "valid", token: "secret", authorization: "Bearer secret", expectedStatus: http.StatusOK},
+		{name: "scheme_case", token: "secret", authorization: "bearer secret", expectedStatus: http.StatusOK},
+		{name: "wrong_token", token: "secret", authorization: "Bearer guess", expectedStatus: http.StatusUnauthorized},
+		{name: "missing", token: "secret", expectedStatus: http.StatusUnauthorized},
+		{name: "basic_scheme", token: "secret", authorization: "Basic secret", expectedStatus: http.StatusUnauthorized},
+		{name: "unconfigured", token: "", authorization: "Bearer ", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := AdminAuth(tt.token)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/scrape-requests", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, received %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Fatal("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
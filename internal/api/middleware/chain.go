@@ -0,0 +1,17 @@
+/*
+uniwish.com/interal/api/middleware/chain
+
+composes middleware so the first listed is the outermost
+*/
+package middleware
+
+import "net/http"
+
+type Middleware func(http.Handler) http.Handler
+
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
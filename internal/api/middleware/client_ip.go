@@ -0,0 +1,136 @@
+/*
+uniwish.com/interal/api/middleware/client_ip
+
+resolves the address a request came from once, for logging, rate limits and quotas
+
+X-Forwarded-For and Forwarded are only believed when the connection comes from a trusted proxy, anyone
+else could set them to spread their requests over made up addresses. the hops are walked from the nearest
+one back, skipping trusted proxies, so addresses a client prepends itself are never reached
+*/
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// ClientIP is the address the request came from, as resolved by ClientAddress, or its peer's without it
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// ClientAddress resolves the client's address through the listed proxies, IPs or CIDRs, entries that do not
+// parse are ignored as config validation already rejects them. without proxies it is the peer's address
+func ClientAddress(trustedProxies []string) func(http.Handler) http.Handler {
+	trusted := parseProxies(trustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey{}, resolveClientIP(r, trusted))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func parseProxies(entries []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	remote := remoteIP(r)
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !isTrusted(addr, trusted) {
+		return remote
+	}
+
+	hops := forwardedHops(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			// unknown or obfuscated, nothing past it can be trusted
+			return remote
+		}
+		if !isTrusted(hop, trusted) {
+			return hop.Unmap().String()
+		}
+	}
+	// every hop is a proxy of ours, the furthest one is as close to the client as we get
+	if len(hops) > 0 {
+		if hop, err := netip.ParseAddr(hops[0]); err == nil {
+			return hop.Unmap().String()
+		}
+	}
+	return remote
+}
+
+// forwardedHops lists the addresses the proxies forwarded for, client first. Forwarded is preferred over
+// X-Forwarded-For when a proxy sets it
+func forwardedHops(header http.Header) []string {
+	var hops []string
+	for _, value := range header.Values("Forwarded") {
+		for element := range strings.SplitSeq(value, ",") {
+			for pair := range strings.SplitSeq(element, ";") {
+				key, node, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, forwardedNode(node))
+				}
+			}
+		}
+	}
+	if len(hops) > 0 {
+		return hops
+	}
+	for _, value := range header.Values("X-Forwarded-For") {
+		for hop := range strings.SplitSeq(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// forwardedNode strips a Forwarded node's quotes, brackets and port, "[2001:db8::1]:4711" is 2001:db8::1
+func forwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+	if strings.HasPrefix(node, "[") {
+		node, _, _ = strings.Cut(node[1:], "]")
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
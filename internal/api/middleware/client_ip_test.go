@@ -0,0 +1,67 @@
+/*
+uniwish.com/internal/api/middleware/client_ip_test
+
+tests resolving the client's address through trusted proxies
+*/
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientAddress(t *testing.T) {
+	proxies := []string{"10.0.0.0/8", "2001:db8::1"}
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{name: "no_proxies", remoteAddr: "203.0.113.7:4321", headers: map[string]string{"X-Forwarded-For": "198.51.100.1"}, expected: "203.0.113.7"},
+		{name: "untrusted_peer", proxies: proxies, remoteAddr: "203.0.113.7:4321", headers: map[string]string{"X-Forwarded-For": "198.51.100.1"}, expected: "203.0.113.7"},
+		{name: "trusted_peer", proxies: proxies, remoteAddr: "10.0.0.2:4321", headers: map[string]string{"X-Forwarded-For": "198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "spoofed_hop_skipped", proxies: proxies, remoteAddr: "10.0.0.2:4321", headers: map[string]string{"X-Forwarded-For": "192.0.2.9, 198.51.100.1, 10.0.0.3"}, expected: "198.51.100.1"},
+		{name: "only_proxies", proxies: proxies, remoteAddr: "10.0.0.2:4321", headers: map[string]string{"X-Forwarded-For": "10.0.0.4, 10.0.0.3"}, expected: "10.0.0.4"},
+		{name: "no_header", proxies: proxies, remoteAddr: "10.0.0.2:4321", expected: "10.0.0.2"},
+		{name: "malformed_hop", proxies: proxies, remoteAddr: "10.0.0.2:4321", headers: map[string]string{"X-Forwarded-For": "198.51.100.1, garbage"}, expected: "10.0.0.2"},
+		{name: "ipv6_peer", proxies: proxies, remoteAddr: "[2001:db8::1]:4321", headers: map[string]string{"X-Forwarded-For": "198.51.100.1"}, expected: "198.51.100.1"},
+		{
+			name: "forwarded_preferred", proxies: proxies, remoteAddr: "10.0.0.2:4321",
+			headers:  map[string]string{"Forwarded": `for=192.0.2.9, For="[2001:db8:cafe::17]:4711";proto=https`, "X-Forwarded-For": "198.51.100.1"},
+			expected: "2001:db8:cafe::17",
+		},
+		{name: "forwarded_unknown", proxies: proxies, remoteAddr: "10.0.0.2:4321", headers: map[string]string{"Forwarded": "for=unknown"}, expected: "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = ClientIP(r)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			ClientAddress(tt.proxies)(next).ServeHTTP(httptest.NewRecorder(), req)
+
+			if seen != tt.expected {
+				t.Fatalf("expected %q, received %q", tt.expected, seen)
+			}
+		})
+	}
+}
+
+func TestClientIP_WithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:4321"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if ip := ClientIP(req); ip != "203.0.113.7" {
+		t.Fatalf("expected the peer's address, received %q", ip)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+uniwish.com/interal/api/middleware/cors
+
+lets the browser extension, and any other allowed origin, call the api from a page
+
+preflights are answered here since the mux has no OPTIONS routes, other origins get no CORS headers
+and so are left to the browser's same-origin policy
+*/
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache a preflight, in seconds
+const corsMaxAge = "600"
+
+var (
+	corsAllowedMethods = strings.Join([]string{http.MethodGet, http.MethodPost}, ", ")
+	corsAllowedHeaders = strings.Join([]string{"Authorization", "Content-Type", RequestIDHeader, "X-User-ID"}, ", ")
+	corsExposedHeaders = strings.Join([]string{RequestIDHeader, "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"}, ", ")
+)
+
+// CORS allows the listed origins, chrome-extension://<id> for the extension, * for any
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	anyOrigin := slices.Contains(allowedOrigins, "*")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// responses differ per origin, caches must not hand one origin's to another
+			w.Header().Add("Vary", "Origin")
+			if !anyOrigin && !slices.Contains(allowedOrigins, origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
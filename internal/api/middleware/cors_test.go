@@ -0,0 +1,68 @@
+/*
+uniwish.com/internal/api/middleware/cors_test
+
+tests cross origin headers and preflights
+*/
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	const extension = "chrome-extension://abcdef"
+
+	tests := []struct {
+		name                string
+		allowed             []string
+		method              string
+		origin              string
+		preflight           bool
+		expectedStatus      int
+		expectedAllowOrigin string
+		expectedNextCalled  bool
+	}{
+		{name: "same_origin", allowed: []string{extension}, method: http.MethodGet, expectedStatus: http.StatusOK, expectedNextCalled: true},
+		{name: "allowed", allowed: []string{extension}, method: http.MethodGet, origin: extension, expectedStatus: http.StatusOK, expectedAllowOrigin: extension, expectedNextCalled: true},
+		{name: "not_allowed", allowed: []string{extension}, method: http.MethodGet, origin: "https://evil.com", expectedStatus: http.StatusOK, expectedNextCalled: true},
+		{name: "any_origin", allowed: []string{"*"}, method: http.MethodPost, origin: "https://evil.com", expectedStatus: http.StatusOK, expectedAllowOrigin: "https://evil.com", expectedNextCalled: true},
+		{name: "preflight", allowed: []string{extension}, method: http.MethodOptions, origin: extension, preflight: true, expectedStatus: http.StatusNoContent, expectedAllowOrigin: extension},
+		{name: "preflight_not_allowed", allowed: []string{extension}, method: http.MethodOptions, origin: "https://evil.com", preflight: true, expectedStatus: http.StatusOK, expectedNextCalled: true},
+		{name: "none_configured", allowed: nil, method: http.MethodGet, origin: extension, expectedStatus: http.StatusOK, expectedNextCalled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextCalled := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+			})
+
+			req := httptest.NewRequest(tt.method, "/scrape-requests", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rr := httptest.NewRecorder()
+
+			CORS(tt.allowed)(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, received %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedAllowOrigin {
+				t.Fatalf("expected allow origin %q, received %q", tt.expectedAllowOrigin, got)
+			}
+			if nextCalled != tt.expectedNextCalled {
+				t.Fatalf("expected next called %t, received %t", tt.expectedNextCalled, nextCalled)
+			}
+			if tt.preflight && tt.expectedAllowOrigin != "" && rr.Header().Get("Access-Control-Allow-Headers") == "" {
+				t.Fatal("expected allowed headers on the preflight")
+			}
+		})
+	}
+}
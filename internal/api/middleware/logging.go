@@ -2,6 +2,7 @@
 uniwish.com/interal/api/middleware/logging
 
 contains our logging middleware, wrapping http.Response as StatusWriter to capture status code, along other info
+each request gets a logger carrying its request and trace ids, reachable by handlers and services through logging.FromContext
 */
 package middleware
 
@@ -9,11 +10,16 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"uniwish.com/internal/logging"
 )
 
 type StatusWriter struct {
 	http.ResponseWriter
-	status int
+	status      int
+	bytes       int
+	wroteHeader bool
 }
 
 func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
@@ -23,22 +29,53 @@ func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
 	}
 }
 func (sw *StatusWriter) WriteHeader(code int) {
+	if sw.wroteHeader {
+		return
+	}
 	sw.status = code
+	sw.wroteHeader = true
 	sw.ResponseWriter.WriteHeader(code)
 }
 
+func (sw *StatusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+	return n, err
+}
+
+func (sw *StatusWriter) Unwrap() http.ResponseWriter {
+	// lets http.ResponseController reach the underlying writer for flushing and deadlines
+	return sw.ResponseWriter
+}
+
 func Logging(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestLogger := logger.With("request_id", RequestIDFrom(r.Context()))
+			if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+				requestLogger = requestLogger.With("trace_id", spanContext.TraceID().String())
+			}
+			r = r.WithContext(logging.WithLogger(r.Context(), requestLogger))
+
 			sw := NewStatusWriter(w)
 			start := time.Now()
 			next.ServeHTTP(sw, r)
 
-			logger.Info(
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+
+			requestLogger.Info(
 				"http request",
 				"method", r.Method,
 				"path", r.URL.Path,
+				"route", route,
 				"status", sw.status,
+				"bytes", sw.bytes,
+				"client_ip", ClientIP(r),
+				"user_agent", r.UserAgent(),
 				"duration_ms", time.Since(start).Milliseconds(),
 			)
 		})
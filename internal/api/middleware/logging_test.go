@@ -6,11 +6,15 @@ tests logging middleware
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"uniwish.com/internal/logging"
 )
 
 func TestLoggingMiddleware_PassesStatus(t *testing.T) {
@@ -29,3 +33,43 @@ func TestLoggingMiddleware_PassesStatus(t *testing.T) {
 		t.Fatalf("expected status 418, got %d", rr.Code)
 	}
 }
+
+func TestLoggingMiddleware_RequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("from handler")
+		w.Write([]byte("hello"))
+	})
+	handler := Chain(next, RequestID(), Logging(logger))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d", len(lines))
+	}
+
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("invalid log line %s: %v", line, err)
+		}
+		if entry["request_id"] != "abc-123" {
+			t.Fatalf("expected request_id abc-123, got %v", entry["request_id"])
+		}
+	}
+
+	var access map[string]any
+	json.Unmarshal(lines[1], &access)
+	if access["bytes"] != float64(5) {
+		t.Fatalf("expected 5 bytes, got %v", access["bytes"])
+	}
+	if access["route"] != "unmatched" {
+		t.Fatalf("expected unmatched route, got %v", access["route"])
+	}
+}
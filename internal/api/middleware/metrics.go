@@ -0,0 +1,58 @@
+/*
+uniwish.com/interal/api/middleware/metrics
+
+contains our prometheus middleware, counting requests and observing their latency per route
+*/
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewHTTPMetrics(reg prometheus.Registerer) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "uniwish",
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests handled by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "uniwish",
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+	}
+	reg.MustRegister(m.requests, m.duration)
+	return m
+}
+
+func Metrics(m *HTTPMetrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := NewStatusWriter(w)
+			start := time.Now()
+			next.ServeHTTP(sw, r)
+
+			// the mux records the matched pattern on the request, keeping label cardinality bounded by our routes
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+
+			m.requests.WithLabelValues(route, r.Method, strconv.Itoa(sw.status)).Inc()
+			m.duration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+uniwish.com/internal/api/middleware/metrics_test
+
+tests metrics middleware
+*/
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware_CountsByRoute(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewHTTPMetrics(reg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := Metrics(m)(mux)
+
+	for _, path := range []string{"/products/1", "/products/2", "/missing"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if count := testutil.ToFloat64(m.requests.WithLabelValues("GET /products/{id}", "GET", "404")); count != 2 {
+		t.Fatalf("expected 2 requests for route, received %v", count)
+	}
+	if count := testutil.ToFloat64(m.requests.WithLabelValues("unmatched", "GET", "404")); count != 1 {
+		t.Fatalf("expected 1 unmatched request, received %v", count)
+	}
+	if count := testutil.CollectAndCount(m.duration); count != 2 {
+		t.Fatalf("expected 2 latency series, received %d", count)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+uniwish.com/interal/api/middleware/rate_limit
+
+contains our rate limiting middleware, a token bucket per client IP and per API token
+
+every response carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers of the
+tightest bucket, rejected requests get a 429 with Retry-After. a request costs one token, handlers
+whose cost is only known once the body is read charge the rest with ChargeRateLimit
+*/
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apiErrors "uniwish.com/internal/api/errors"
+)
+
+// idle buckets are dropped this often, once refilled they hold no state worth keeping
+const rateLimitSweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type RateLimiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, zero when allowed
+	RetryAfter time.Duration
+}
+
+func NewRateLimiter(perMinute int, burst int) *RateLimiter {
+	return &RateLimiter{
+		perSecond: float64(perMinute) / 60,
+		burst:     burst,
+		buckets:   make(map[string]*bucket),
+		now:       time.Now,
+	}
+}
+
+func (l *RateLimiter) Allow(key string) RateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key)
+
+	result := RateLimitResult{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.refillTime(1 - b.tokens)
+	}
+
+	result.Remaining = max(int(b.tokens), 0)
+	result.Reset = l.refillTime(float64(l.burst) - b.tokens)
+	return result
+}
+
+// Charge takes n more tokens from key's bucket whatever is left, an overdrawn bucket refuses requests
+// until it refilled past the debt, so a large batch is paid for without being refused outright
+func (l *RateLimiter) Charge(key string, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(key).tokens -= float64(n)
+}
+
+func (l *RateLimiter) refill(key string) *bucket {
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.perSecond)
+	b.last = now
+	return b
+}
+
+func (l *RateLimiter) refillTime(tokens float64) time.Duration {
+	return time.Duration(tokens / l.perSecond * float64(time.Second))
+}
+
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.perSecond >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func rateLimitKeys(r *http.Request) []string {
+	keys := []string{"ip:" + ClientIP(r)}
+	if token := bearerToken(r); token != "" {
+		// tokens are hashed so the limiter never holds credentials
+		sum := sha256.Sum256([]byte(token))
+		keys = append(keys, "token:"+hex.EncodeToString(sum[:]))
+	}
+	return keys
+}
+
+type rateLimitChargeKey struct{}
+
+// ChargeRateLimit takes n more tokens from every bucket the request was limited by, a no-op for requests
+// that were not rate limited
+func ChargeRateLimit(r *http.Request, n int) {
+	if charge, ok := r.Context().Value(rateLimitChargeKey{}).(func(int)); ok && n > 0 {
+		charge(n)
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func RateLimit(limiter *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var tightest RateLimitResult
+			keys := rateLimitKeys(r)
+			for i, key := range keys {
+				result := limiter.Allow(key)
+				if i == 0 || !result.Allowed || (tightest.Allowed && result.Remaining < tightest.Remaining) {
+					tightest = result
+				}
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(tightest.Reset))
+
+			if !tightest.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(tightest.RetryAfter))
+				apiErrors.Respond(w, apiErrors.ErrRateLimited)
+				return
+			}
+
+			charge := func(n int) {
+				for _, key := range keys {
+					limiter.Charge(key, n)
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), rateLimitChargeKey{}, charge)))
+		})
+	}
+}
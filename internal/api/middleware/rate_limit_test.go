@@ -0,0 +1,126 @@
+/*
+uniwish.com/internal/api/middleware/rate_limit_test
+
+tests rate limiting middleware
+*/
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func fakeClockLimiter(perMinute, burst int) (*RateLimiter, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(perMinute, burst)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter, now := fakeClockLimiter(60, 2)
+
+	for i := range 2 {
+		if result := limiter.Allow("a"); !result.Allowed {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+
+	result := limiter.Allow("a")
+	if result.Allowed {
+		t.Fatal("expected burst to be exhausted")
+	}
+	if result.RetryAfter != time.Second {
+		t.Fatalf("expected retry after 1s, received %s", result.RetryAfter)
+	}
+
+	if !limiter.Allow("b").Allowed {
+		t.Fatal("expected another key to have its own bucket")
+	}
+
+	*now = now.Add(time.Second)
+	result = limiter.Allow("a")
+	if !result.Allowed {
+		t.Fatal("expected a token to be refilled")
+	}
+	if result.Remaining != 0 || result.Reset != 2*time.Second {
+		t.Fatalf("expected 0 remaining resetting in 2s, received %d in %s", result.Remaining, result.Reset)
+	}
+}
+
+func TestRateLimiter_Charge(t *testing.T) {
+	limiter, now := fakeClockLimiter(60, 2)
+
+	if !limiter.Allow("a").Allowed {
+		t.Fatal("expected the first request to be allowed")
+	}
+	// overdrawn by two, three seconds pass before the next token is whole
+	limiter.Charge("a", 3)
+
+	result := limiter.Allow("a")
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter != 3*time.Second {
+		t.Fatalf("expected refused with 0 remaining retrying after 3s, received %+v", result)
+	}
+
+	*now = now.Add(3 * time.Second)
+	if !limiter.Allow("a").Allowed {
+		t.Fatal("expected the debt to be refilled")
+	}
+}
+
+func TestRateLimiter_SweepsIdleBuckets(t *testing.T) {
+	limiter, now := fakeClockLimiter(60, 1)
+
+	limiter.Allow("a")
+	*now = now.Add(2 * rateLimitSweepInterval)
+	limiter.Allow("b")
+
+	if _, ok := limiter.buckets["a"]; ok {
+		t.Fatal("expected refilled bucket to be swept")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter, _ := fakeClockLimiter(60, 1)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	handler := RateLimit(limiter)(next)
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		token          string
+		expectedStatus int
+	}{
+		{name: "first", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusAccepted},
+		{name: "same_ip", remoteAddr: "10.0.0.1:4321", expectedStatus: http.StatusTooManyRequests},
+		{name: "other_ip", remoteAddr: "10.0.0.2:1234", token: "secret", expectedStatus: http.StatusAccepted},
+		{name: "same_token_other_ip", remoteAddr: "10.0.0.3:1234", token: "secret", expectedStatus: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/scrape-requests", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Header().Get("RateLimit-Limit") != "1" {
+				t.Fatalf("expected RateLimit-Limit 1, got %q", rr.Header().Get("RateLimit-Limit"))
+			}
+			if rr.Code == http.StatusTooManyRequests && rr.Header().Get("Retry-After") != "1" {
+				t.Fatalf("expected Retry-After 1, got %q", rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
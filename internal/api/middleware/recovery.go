@@ -0,0 +1,46 @@
+/*
+uniwish.com/interal/api/middleware/recovery
+
+contains our panic recovery middleware, turning handler panics into our internal error envelope
+*/
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/logging"
+)
+
+func Recovery() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := NewStatusWriter(w)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					// deliberate aborts keep net/http's handling
+					panic(rec)
+				}
+
+				logging.FromContext(r.Context()).Error(
+					"panic recovered",
+					"panic", rec,
+					"stack", string(debug.Stack()),
+				)
+
+				if sw.wroteHeader {
+					// too late to change the response, abort so the client sees a broken one rather than a truncated one
+					panic(http.ErrAbortHandler)
+				}
+				apiErrors.Respond(sw, apiErrors.ErrInternal)
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+/*
+uniwish.com/interal/api/middleware/request_id
+
+contains our request id middleware, propagating the caller's X-Request-ID or generating one
+*/
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// caller supplied ids longer than this are replaced rather than echoed into logs
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID carries the caller's id in ctx, or a generated one when it is missing or invalid
+func WithRequestID(ctx context.Context, id string) (context.Context, string) {
+	if !validRequestID(id) {
+		id = uuid.NewString()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id), id
+}
+
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, id := WithRequestID(r.Context(), r.Header.Get(RequestIDHeader))
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/*
+uniwish.com/internal/api/middleware/request_id_test
+
+tests request id middleware
+*/
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "generated", incoming: "", keep: false},
+		{name: "propagated", incoming: "abc-123", keep: true},
+		{name: "invalid_replaced", incoming: "abc 123", keep: false},
+		{name: "too_long_replaced", incoming: strings.Repeat("a", maxRequestIDLength+1), keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIDFrom(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+
+			RequestID()(next).ServeHTTP(rr, req)
+
+			returned := rr.Header().Get(RequestIDHeader)
+			if returned == "" {
+				t.Fatal("expected response to carry a request id")
+			}
+			if seen != returned {
+				t.Fatalf("expected context id %q to match response id %q", seen, returned)
+			}
+			if tt.keep && returned != tt.incoming {
+				t.Fatalf("expected %q, received %q", tt.incoming, returned)
+			}
+			if !tt.keep && returned == tt.incoming {
+				t.Fatalf("expected %q to be replaced", tt.incoming)
+			}
+		})
+	}
+}
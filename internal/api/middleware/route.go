@@ -0,0 +1,20 @@
+/*
+uniwish.com/interal/api/middleware/route
+
+resolves the route pattern before the mux runs
+
+the mux only records the pattern on the request it receives, which middlewares
+copying the request with WithContext never see, so it is set up-front instead
+*/
+package middleware
+
+import "net/http"
+
+func Route(mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, r.Pattern = mux.Handler(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
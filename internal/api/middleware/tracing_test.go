@@ -0,0 +1,44 @@
+/*
+uniwish.com/internal/api/middleware/tracing_test
+
+tests tracing middleware
+*/
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"uniwish.com/internal/testutil"
+)
+
+func TestTracingMiddleware_ContinuesTraceByRoute(t *testing.T) {
+	recorder := testutil.RecordSpans(t)
+
+	var handlerSpan trace.SpanContext
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+	})
+	handler := Tracing()(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, received %d", len(spans))
+	}
+	if spans[0].Name() != "GET /products/{id}" {
+		t.Fatalf("expected span named by route, received %s", spans[0].Name())
+	}
+	if spans[0].SpanContext().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Fatalf("expected incoming trace to continue, received %s", spans[0].SpanContext().TraceID())
+	}
+	if handlerSpan.SpanID() != spans[0].SpanContext().SpanID() {
+		t.Fatal("expected handler context to carry the server span")
+	}
+}
@@ -0,0 +1,26 @@
+/*
+uniwish.com/internal/api/openapi
+
+embeds the OpenAPI document describing every route in RegisterRoutes, served at /openapi.json
+routes and response shapes are checked against it by the routes and handler contract tests
+*/
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed openapi.json
+var document []byte
+
+func Document() []byte {
+	return document
+}
+
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document)
+	})
+}
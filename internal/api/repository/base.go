@@ -8,8 +8,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("uniwish.com/internal/api/repository")
+
 type DB interface {
 	// allows us to monkeypatch DB connection
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
@@ -0,0 +1,104 @@
+/*
+uniwish.com/interal/api/repository/fx_rate
+
+centralizes DB operations with exchange rates
+*/
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"uniwish.com/internal/currency"
+)
+
+type FXRateRepository interface {
+	// SaveRates stores a day of rates, replacing any already stored for that day
+	SaveRates(ctx context.Context, rates *currency.Rates) error
+	// LatestRates returns the most recent day of rates, nil when none were loaded yet
+	LatestRates(ctx context.Context) (*currency.Rates, error)
+}
+
+type PostgresFXRateRepository struct {
+	db DB
+}
+
+func NewPostgresFXRateRepository(db DB) FXRateRepository {
+	return &PostgresFXRateRepository{db: db}
+}
+
+func (r *PostgresFXRateRepository) SaveRates(ctx context.Context, rates *currency.Rates) error {
+	ctx, span := tracer.Start(ctx, "PostgresFXRateRepository.SaveRates")
+	defer span.End()
+
+	if len(rates.Rates) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(rates.Rates))
+	args := make([]any, 0, len(rates.Rates)*4)
+	date := rates.Date.Format(time.DateOnly)
+
+	for code, rate := range rates.Rates {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4))
+		args = append(args, rates.Base, code, date, rate)
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO fx_rates (base, currency, rate_date, rate)
+		VALUES `+strings.Join(values, ", ")+`
+		ON CONFLICT (base, currency, rate_date) DO UPDATE
+		SET rate = EXCLUDED.rate, loaded_at = now()
+		`,
+		args...,
+	)
+
+	return err
+}
+
+func (r *PostgresFXRateRepository) LatestRates(ctx context.Context) (*currency.Rates, error) {
+	ctx, span := tracer.Start(ctx, "PostgresFXRateRepository.LatestRates")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT base, currency, rate_date, rate
+		FROM fx_rates
+		WHERE rate_date = (SELECT max(rate_date) FROM fx_rates)
+		`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var rates *currency.Rates
+
+	for rows.Next() {
+		var (
+			base string
+			code string
+			date time.Time
+			rate float64
+		)
+		if err := rows.Scan(&base, &code, &date, &rate); err != nil {
+			return nil, err
+		}
+
+		if rates == nil {
+			rates = &currency.Rates{Base: base, Date: date, Rates: make(map[string]float64)}
+		}
+		rates.Rates[code] = rate
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
@@ -0,0 +1,53 @@
+/*
+uniwish.com/interal/api/repository/fx_rate_test
+
+testing for exchange rates
+*/
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"uniwish.com/internal/currency"
+	"uniwish.com/internal/testutil"
+)
+
+func TestFXRateRepo_LatestRates(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := NewPostgresFXRateRepository(tx)
+	ctx := context.Background()
+
+	empty, err := repo.LatestRates(ctx)
+	if err != nil {
+		t.Fatalf("latest rates failed: %v", err)
+	}
+	if empty != nil {
+		t.Fatalf("expected no rates, received %+v", empty)
+	}
+
+	older := &currency.Rates{Base: "EUR", Date: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), Rates: map[string]float64{"USD": 1.07}}
+	latest := &currency.Rates{Base: "EUR", Date: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), Rates: map[string]float64{"USD": 1.08, "GBP": 0.86}}
+
+	for _, rates := range []*currency.Rates{older, latest, latest} {
+		if err := repo.SaveRates(ctx, rates); err != nil {
+			t.Fatalf("save rates failed: %v", err)
+		}
+	}
+
+	received, err := repo.LatestRates(ctx)
+	if err != nil {
+		t.Fatalf("latest rates failed: %v", err)
+	}
+	if received == nil || !received.Date.Equal(latest.Date) || !reflect.DeepEqual(received.Rates, latest.Rates) {
+		t.Fatalf("expected %+v, received %+v", latest, received)
+	}
+}
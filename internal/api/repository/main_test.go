@@ -17,10 +17,14 @@ import (
 var testDB *sql.DB
 
 func TestMain(m *testing.M) {
-	testDB = testutil.OpenDB()
-
+	// opened only for integration runs so the redis conformance suite can run on its own
+	if os.Getenv("INTEGRATION_TESTS") != "" {
+		testDB = testutil.OpenDB()
+	}
 	code := m.Run()
 
-	testDB.Close()
+	if testDB != nil {
+		testDB.Close()
+	}
 	os.Exit(code)
 }
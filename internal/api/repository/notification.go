@@ -0,0 +1,72 @@
+/*
+uniwish.com/internal/api/repository/notification
+
+reads the notifications users receive about the products on their wishlist
+*/
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationListItem struct {
+	ID           uuid.UUID
+	Kind         string
+	ProductID    uuid.UUID
+	ProductName  string
+	ProductStore string
+	CreatedAt    time.Time
+}
+
+type NotificationReader interface {
+	// ListNotifications returns the owner's notifications newest first
+	ListNotifications(ctx context.Context, ownerID string, limit int, offset int) ([]NotificationListItem, error)
+}
+
+type PostgresNotificationRepository struct {
+	db DB
+}
+
+func NewPostgresNotificationRepository(db DB) *PostgresNotificationRepository {
+	return &PostgresNotificationRepository{db: db}
+}
+
+func (r *PostgresNotificationRepository) ListNotifications(ctx context.Context, ownerID string, limit int, offset int) ([]NotificationListItem, error) {
+	ctx, span := tracer.Start(ctx, "PostgresNotificationRepository.ListNotifications")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT n.id, n.kind, n.product_id, p.name, p.store, n.created_at
+		FROM notifications n
+		JOIN products p ON p.id = n.product_id
+		WHERE n.owner_id = $1
+		ORDER BY n.created_at DESC, n.id
+		LIMIT $2 OFFSET $3
+		`, ownerID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var items []NotificationListItem
+
+	for rows.Next() {
+		var item NotificationListItem
+		err := rows.Scan(&item.ID, &item.Kind, &item.ProductID, &item.ProductName, &item.ProductStore, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
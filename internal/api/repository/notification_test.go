@@ -0,0 +1,89 @@
+/*
+uniwish.com/internal/api/repository/notification_test
+
+testing for the notification reader and the discontinued product lookup
+*/
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/testutil"
+)
+
+func TestNotificationRepository_ListNotifications(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+	ctx := context.Background()
+
+	productId := uuid.New()
+	product := NewFakeProductDetail()
+	if err := insertFakeProductDetailItem(productId, product, ctx, tx); err != nil {
+		t.Fatalf("product insert failed: %v", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`
+		INSERT INTO notifications (id, owner_id, product_id, kind, created_at)
+		VALUES
+			($2, 'user-1', $1, 'product_discontinued', now() - interval '1 day'),
+			($3, 'user-1', $1, 'product_discontinued', now()),
+			($4, 'user-2', $1, 'product_discontinued', now())
+		`, productId, uuid.New(), uuid.New(), uuid.New(),
+	)
+	if err != nil {
+		t.Fatalf("notification insert failed: %v", err)
+	}
+
+	notifications, err := NewPostgresNotificationRepository(tx).ListNotifications(ctx, "user-1", 10, 0)
+	if err != nil {
+		t.Fatalf("error not nil: %v", err)
+	}
+	if len(notifications) != 2 || !notifications[0].CreatedAt.After(notifications[1].CreatedAt) {
+		t.Fatalf("expected user-1's two notifications newest first, received %+v", notifications)
+	}
+	if notifications[0].ProductName != product.Name || notifications[0].ProductStore != product.Store {
+		t.Fatalf("expected the product to be joined, received %+v", notifications[0])
+	}
+}
+
+func TestDiscontinuedProductReader_DiscontinuedSince(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+	ctx := context.Background()
+
+	productId := uuid.New()
+	product := NewFakeProductDetail()
+	if err := insertFakeProductDetailItem(productId, product, ctx, tx); err != nil {
+		t.Fatalf("product insert failed: %v", err)
+	}
+	reader := NewPostgresDiscontinuedProductReader(tx)
+	regionURL := product.Store + ".com/es/test"
+
+	since, err := reader.DiscontinuedSince(ctx, regionURL)
+	if err != nil || since != nil {
+		t.Fatalf("expected a listed product, received %v %v", since, err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE products SET discontinued_at = now() WHERE id = $1`, productId); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	since, err = reader.DiscontinuedSince(ctx, regionURL)
+	if err != nil || since == nil {
+		t.Fatalf("expected a discontinued product, received %v %v", since, err)
+	}
+
+	since, err = reader.DiscontinuedSince(ctx, "https://unknown.com/item")
+	if err != nil || since != nil {
+		t.Fatalf("expected an unknown url to read as listed, received %v %v", since, err)
+	}
+}
@@ -19,22 +19,53 @@ type ProductListItem struct {
 	ImageURL  string
 	LastPrice float64
 	Currency  string
+	// ImageKey and ThumbnailKey locate the mirrored image in the blob store, empty until it is mirrored
+	ImageKey     string
+	ThumbnailKey string
 }
 
 type ProductDetail struct {
-	Name     string
-	Store    string
-	ImageURL string
-	Offers   []OfferListItem
+	Name         string
+	Store        string
+	ImageURL     string
+	ImageKey     string
+	ThumbnailKey string
+	// DiscontinuedAt is when the store was found to have removed the product, nil while it is listed
+	DiscontinuedAt *time.Time
+	Offers         []OfferListItem
+	// Regions holds the latest price in every market the product was scraped in
+	Regions []RegionListItem
+	// AlsoAvailableAt lists the products other stores sell as the same item, most confident first
+	AlsoAvailableAt []MatchListItem
 }
 
 type OfferListItem struct {
 	Price        float64
 	Currency     string
+	Region       string
 	Availability string
 	UpdatedAt    time.Time
 }
 
+type RegionListItem struct {
+	Region    string
+	URL       string
+	LastPrice float64
+	Currency  string
+	UpdatedAt time.Time
+}
+
+type MatchListItem struct {
+	ProductID  uuid.UUID
+	Store      string
+	Name       string
+	URL        string
+	LastPrice  float64
+	Currency   string
+	Confidence float64
+	Method     string
+}
+
 type ProductReader interface {
 	ListProducts(ctx context.Context) ([]ProductListItem, error)
 	GetProduct(ctx context.Context, id uuid.UUID) (*ProductDetail, error)
@@ -49,13 +80,18 @@ func NewDefaultProductReader(db DB) ProductReader {
 }
 
 func (p *DefaultProductReader) ListProducts(ctx context.Context) ([]ProductListItem, error) {
+	ctx, span := tracer.Start(ctx, "DefaultProductReader.ListProducts")
+	defer span.End()
+
 	// TODO: add product_id, scraped_at, currency index to DB
 
 	rows, err := p.db.QueryContext(
 		ctx,
 		`
-		SELECT p.name, p.store, p.image_url, pr.price, pr.currency
+		SELECT p.name, p.store, p.image_url, pr.price, pr.currency,
+			COALESCE(pi.image_key, ''), COALESCE(pi.thumbnail_key, '')
 		FROM products p 
+		LEFT JOIN product_images pi ON pi.product_id = p.id
 		LEFT JOIN LATERAL (
 		SELECT price, currency
 		FROM prices 
@@ -76,7 +112,8 @@ func (p *DefaultProductReader) ListProducts(ctx context.Context) ([]ProductListI
 		var p ProductListItem
 		if err := rows.Scan(
 			&p.Name, &p.Store,
-			&p.ImageURL, &p.LastPrice, &p.Currency); err != nil {
+			&p.ImageURL, &p.LastPrice, &p.Currency,
+			&p.ImageKey, &p.ThumbnailKey); err != nil {
 			return nil, err
 		}
 
@@ -90,11 +127,16 @@ func (p *DefaultProductReader) ListProducts(ctx context.Context) ([]ProductListI
 }
 
 func (p *DefaultProductReader) GetProduct(ctx context.Context, id uuid.UUID) (*ProductDetail, error) {
+	ctx, span := tracer.Start(ctx, "DefaultProductReader.GetProduct")
+	defer span.End()
+
 	// TODO: use sql to grab basic info for product and each offer
 	rows, err := p.db.QueryContext(ctx,
 		`
-		SELECT p.name, p.store, p.image_url, pr.price, pr.currency, pr.scraped_at
+		SELECT p.name, p.store, p.image_url, COALESCE(pi.image_key, ''), COALESCE(pi.thumbnail_key, ''),
+			p.discontinued_at, pr.price, pr.currency, pr.region, pr.availability, pr.scraped_at
 		FROM products p JOIN prices pr ON p.id = pr.product_id
+		LEFT JOIN product_images pi ON pi.product_id = p.id
 		WHERE p.id = $1
 		ORDER BY pr.scraped_at ASC
 		`, id,
@@ -112,19 +154,27 @@ func (p *DefaultProductReader) GetProduct(ctx context.Context, id uuid.UUID) (*P
 	var pd *ProductDetail
 	for rows.Next() {
 		var (
-			name      string
-			store     string
-			image_url string
-			offer     OfferListItem
+			name          string
+			store         string
+			image_url     string
+			image_key     string
+			thumbnail_key string
+			discontinued  *time.Time
+			offer         OfferListItem
 		)
 		if err := rows.Scan(
-			&name, &store, &image_url, &offer.Price,
-			&offer.Currency, &offer.UpdatedAt); err != nil {
+			&name, &store, &image_url, &image_key, &thumbnail_key, &discontinued, &offer.Price,
+			&offer.Currency, &offer.Region, &offer.Availability, &offer.UpdatedAt); err != nil {
 			return nil, err
 		}
 
 		if pd == nil {
-			pd = &ProductDetail{Name: name, Store: store, ImageURL: image_url, Offers: make([]OfferListItem, 0)}
+			pd = &ProductDetail{
+				Name: name, Store: store, ImageURL: image_url,
+				ImageKey: image_key, ThumbnailKey: thumbnail_key,
+				DiscontinuedAt: discontinued,
+				Offers:         make([]OfferListItem, 0),
+			}
 		}
 
 		pd.Offers = append(pd.Offers, offer)
@@ -137,5 +187,126 @@ func (p *DefaultProductReader) GetProduct(ctx context.Context, id uuid.UUID) (*P
 	if pd == nil {
 		return nil, sql.ErrNoRows
 	}
+
+	if pd.Regions, err = p.listRegions(ctx, id); err != nil {
+		return nil, err
+	}
+	if pd.AlsoAvailableAt, err = p.listMatches(ctx, id); err != nil {
+		return nil, err
+	}
 	return pd, nil
 }
+
+func (p *DefaultProductReader) listRegions(ctx context.Context, id uuid.UUID) ([]RegionListItem, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`
+		SELECT r.region, r.url, pr.price, pr.currency, pr.scraped_at
+		FROM product_regions r
+		JOIN LATERAL (
+		SELECT price, currency, scraped_at
+		FROM prices
+		WHERE product_id = r.product_id AND region = r.region
+		ORDER BY scraped_at DESC
+		LIMIT 1
+		) pr ON TRUE
+		WHERE r.product_id = $1
+		ORDER BY r.region ASC
+		`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	regions := make([]RegionListItem, 0)
+	for rows.Next() {
+		var region RegionListItem
+		if err := rows.Scan(
+			&region.Region, &region.URL, &region.LastPrice,
+			&region.Currency, &region.UpdatedAt); err != nil {
+			return nil, err
+		}
+		regions = append(regions, region)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return regions, nil
+}
+
+func (p *DefaultProductReader) listMatches(ctx context.Context, id uuid.UUID) ([]MatchListItem, error) {
+	// a pair is only as certain as the weaker of the two memberships linking it
+	rows, err := p.db.QueryContext(ctx,
+		`
+		SELECT p.id, p.store, p.name, p.url, pr.price, pr.currency,
+			LEAST(me.confidence, other.confidence), other.method
+		FROM product_matches me
+		JOIN product_matches other
+			ON other.group_id = me.group_id AND other.product_id <> me.product_id
+		JOIN products p ON p.id = other.product_id
+		JOIN LATERAL (
+		SELECT price, currency
+		FROM prices
+		WHERE product_id = p.id
+		ORDER BY scraped_at DESC
+		LIMIT 1
+		) pr ON TRUE
+		WHERE me.product_id = $1
+		ORDER BY 7 DESC, p.store ASC
+		`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]MatchListItem, 0)
+	for rows.Next() {
+		var match MatchListItem
+		if err := rows.Scan(
+			&match.ProductID, &match.Store, &match.Name, &match.URL,
+			&match.LastPrice, &match.Currency, &match.Confidence, &match.Method); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// DiscontinuedProductReader looks up whether the product a url was scraped into is discontinued
+type DiscontinuedProductReader interface {
+	// DiscontinuedSince is when the product at url was discontinued, nil when it is listed or was never scraped
+	DiscontinuedSince(ctx context.Context, url string) (*time.Time, error)
+}
+
+type PostgresDiscontinuedProductReader struct {
+	db DB
+}
+
+func NewPostgresDiscontinuedProductReader(db DB) *PostgresDiscontinuedProductReader {
+	return &PostgresDiscontinuedProductReader{db: db}
+}
+
+func (r *PostgresDiscontinuedProductReader) DiscontinuedSince(ctx context.Context, url string) (*time.Time, error) {
+	ctx, span := tracer.Start(ctx, "PostgresDiscontinuedProductReader.DiscontinuedSince")
+	defer span.End()
+
+	// a url is only dead once every product it matched is, aggregates over no rows read as listed
+	var since *time.Time
+	err := r.db.QueryRowContext(ctx,
+		`
+		SELECT CASE WHEN bool_and(p.discontinued_at IS NOT NULL) THEN max(p.discontinued_at) END
+		FROM products p
+		WHERE p.url = $1 OR p.id IN (SELECT product_id FROM product_regions WHERE url = $1)
+		`, url,
+	).Scan(&since)
+	if err != nil {
+		return nil, err
+	}
+	return since, nil
+}
@@ -0,0 +1,92 @@
+/*
+uniwish.com/internal/api/repository/product_event
+
+reads the changes scrapes found on a product, the product's audit trail
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ProductEventListItem struct {
+	ID     uuid.UUID
+	Kind   string
+	Region string
+	Before string
+	After  string
+	// ScrapeRequestID is nil for changes recorded without a scrape request
+	ScrapeRequestID *uuid.UUID
+	OccurredAt      time.Time
+}
+
+// ProductEventFilter selects events, empty fields match everything
+type ProductEventFilter struct {
+	Kinds []string
+}
+
+type ProductEventReader interface {
+	// ListProductEvents returns the product's events newest first, sql.ErrNoRows when there is no such product
+	ListProductEvents(ctx context.Context, productID uuid.UUID, filter ProductEventFilter, limit int, offset int) ([]ProductEventListItem, error)
+}
+
+type PostgresProductEventRepository struct {
+	db DB
+}
+
+func NewPostgresProductEventRepository(db DB) *PostgresProductEventRepository {
+	return &PostgresProductEventRepository{db: db}
+}
+
+func (r *PostgresProductEventRepository) ListProductEvents(ctx context.Context, productID uuid.UUID, filter ProductEventFilter, limit int, offset int) ([]ProductEventListItem, error) {
+	ctx, span := tracer.Start(ctx, "PostgresProductEventRepository.ListProductEvents")
+	defer span.End()
+
+	// a product without events and no product at all both list nothing, only the latter is a 404
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, productID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT id, kind, region, before, after, scrape_request_id, occurred_at
+		FROM product_events
+		WHERE product_id = $1 AND (cardinality($2::text[]) = 0 OR kind = ANY($2::text[]))
+		ORDER BY occurred_at DESC, id
+		LIMIT $3 OFFSET $4
+		`, productID, pq.Array(filter.Kinds), limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var items []ProductEventListItem
+
+	for rows.Next() {
+		var item ProductEventListItem
+		err := rows.Scan(
+			&item.ID, &item.Kind, &item.Region, &item.Before, &item.After,
+			&item.ScrapeRequestID, &item.OccurredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
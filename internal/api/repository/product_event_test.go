@@ -0,0 +1,69 @@
+/*
+uniwish.com/internal/api/repository/product_event_test
+
+testing for the product event reader
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/testutil"
+)
+
+func TestProductEventRepository_ListProductEvents(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+	ctx := context.Background()
+
+	productId, jobId := uuid.New(), uuid.New()
+	if err := insertFakeProductDetailItem(productId, NewFakeProductDetail(), ctx, tx); err != nil {
+		t.Fatalf("product insert failed: %v", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`
+		INSERT INTO product_events (id, product_id, scrape_request_id, kind, region, before, after, occurred_at)
+		VALUES
+			($2, $1, $3, 'name_changed', '', 'jacket', 'cropped jacket', now() - interval '2 days'),
+			($4, $1, $3, 'price_changed', 'es', '29.95 EUR', '24.95 EUR', now() - interval '1 day'),
+			($5, $1, NULL, 'availability_changed', 'es', 'OutOfStock', 'InStock', now())
+		`, productId, uuid.New(), jobId, uuid.New(), uuid.New(),
+	)
+	if err != nil {
+		t.Fatalf("event insert failed: %v", err)
+	}
+
+	repo := NewPostgresProductEventRepository(tx)
+
+	events, err := repo.ListProductEvents(ctx, productId, ProductEventFilter{}, 2, 0)
+	if err != nil {
+		t.Fatalf("error not nil: %v", err)
+	}
+	if len(events) != 2 || events[0].Kind != "availability_changed" || events[1].Kind != "price_changed" {
+		t.Fatalf("expected the two latest events newest first, received %+v", events)
+	}
+	if events[0].ScrapeRequestID != nil || events[1].ScrapeRequestID == nil || *events[1].ScrapeRequestID != jobId {
+		t.Fatalf("unexpected scrape requests %+v", events)
+	}
+
+	events, err = repo.ListProductEvents(ctx, productId, ProductEventFilter{Kinds: []string{"name_changed"}}, 10, 0)
+	if err != nil {
+		t.Fatalf("error not nil: %v", err)
+	}
+	if len(events) != 1 || events[0].Before != "jacket" || events[0].After != "cropped jacket" {
+		t.Fatalf("expected the rename, received %+v", events)
+	}
+
+	_, err = repo.ListProductEvents(ctx, uuid.New(), ProductEventFilter{}, 10, 0)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for an unknown product, received %v", err)
+	}
+}
@@ -21,13 +21,16 @@ func NewFakeProductDetail() *ProductDetail {
 		ImageURL: "fakestore.com/fakeproduct.jpeg",
 		Offers: []OfferListItem{
 			{
-				Price:     34.42,
-				Currency:  "EUR",
-				UpdatedAt: time.Now(),
+				Price:        34.42,
+				Currency:     "EUR",
+				Region:       "es",
+				Availability: "InStock",
+				UpdatedAt:    time.Now(),
 			},
 			{
 				Price:     42.32,
-				Currency:  "EUR",
+				Currency:  "USD",
+				Region:    "us",
 				UpdatedAt: time.Now().Add(-3 * time.Hour),
 			},
 		},
@@ -52,9 +55,22 @@ func insertFakeProductDetailItem(productId uuid.UUID, pd *ProductDetail, ctx con
 			ctx,
 			`
 			INSERT INTO prices
-			(id, product_id, price, currency, scraped_at)
-			VALUES ($2, $1, $3, $4, $5)
-			`, productId, uuid.New(), o.Price, o.Currency, o.UpdatedAt,
+			(id, product_id, price, currency, region, availability, scraped_at)
+			VALUES ($2, $1, $3, $4, $5, $6, $7)
+			`, productId, uuid.New(), o.Price, o.Currency, o.Region, o.Availability, o.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.ExecContext(
+			ctx,
+			`
+			INSERT INTO product_regions
+			(product_id, region, url)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+			`, productId, o.Region, pd.Store+".com/"+o.Region+"/test",
 		)
 		if err != nil {
 			return err
@@ -128,4 +144,63 @@ func TestProductReader_GetProduct(t *testing.T) {
 	if len(result.Offers) != 2 {
 		t.Fatalf("Expected 2 offers, received %d", len(result.Offers))
 	}
+	// offers come oldest first, the es offer is the latest
+	if result.Offers[1].Availability != "InStock" {
+		t.Fatalf("expected the es offer in stock, received %+v", result.Offers[1])
+	}
+
+	if len(result.Regions) != 2 {
+		t.Fatalf("expected 2 regions, received %d", len(result.Regions))
+	}
+	if result.Regions[1].Region != "us" || result.Regions[1].Currency != "USD" {
+		t.Fatalf("expected us region priced in USD, received %+v", result.Regions[1])
+	}
+}
+
+func TestProductReader_GetProductAlsoAvailableAt(t *testing.T) {
+	testutil.RequireIntegration(t)
+	testutil.TruncateTables(t, testDB)
+	t.Cleanup(func() {
+		testutil.TruncateTables(t, testDB)
+	})
+	ctx := context.Background()
+
+	productId, resellerId := uuid.New(), uuid.New()
+	insertFakeProductDetailItem(productId, NewFakeProductDetail(), ctx, testDB)
+	reseller := NewFakeProductDetail()
+	reseller.Store = "reseller"
+	insertFakeProductDetailItem(resellerId, reseller, ctx, testDB)
+
+	groupId := uuid.New()
+	_, err := testDB.ExecContext(ctx, `INSERT INTO product_match_groups (id) VALUES ($1)`, groupId)
+	if err != nil {
+		t.Fatalf("group insert failed: %v", err)
+	}
+	_, err = testDB.ExecContext(ctx,
+		`
+		INSERT INTO product_matches (product_id, group_id, confidence, method)
+		VALUES ($1, $3, 0.99, 'gtin'), ($2, $3, 0.8, 'fuzzy')
+		`, productId, resellerId, groupId,
+	)
+	if err != nil {
+		t.Fatalf("match insert failed: %v", err)
+	}
+
+	pr := &DefaultProductReader{db: testDB}
+	result, err := pr.GetProduct(ctx, productId)
+	if err != nil {
+		t.Fatalf("error not nil: %v", err)
+	}
+
+	if len(result.AlsoAvailableAt) != 1 {
+		t.Fatalf("expected 1 match, received %d", len(result.AlsoAvailableAt))
+	}
+	match := result.AlsoAvailableAt[0]
+	if match.ProductID != resellerId || match.Store != "reseller" {
+		t.Fatalf("expected reseller product, received %+v", match)
+	}
+	// the weaker membership bounds the pair
+	if match.Confidence < 0.79 || match.Confidence > 0.81 || match.Method != "fuzzy" {
+		t.Fatalf("expected fuzzy match at 0.8, received %+v", match)
+	}
 }
@@ -0,0 +1,122 @@
+/*
+uniwish.com/interal/api/repository/queue
+
+centralizes the scrape queue interface and backend selection
+*/
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	QueueBackendPostgres = "postgres"
+	QueueBackendRedis    = "redis"
+)
+
+// DefaultLease is how long a claimed request stays invisible to other workers before it is reclaimable
+const DefaultLease = 5 * time.Minute
+
+// DefaultMaxAttempts is how many claims a request gets before it is dead lettered as retries_exhausted,
+// matching the scrape_requests.max_attempts column default
+const DefaultMaxAttempts = 5
+
+// MaxEnqueueBatch is the most requests EnqueueBatch takes, postgres binds 6 parameters per row
+// and a statement carries at most 65535
+const MaxEnqueueBatch = 65535 / 6
+
+var ErrLeaseLost = errors.New("lease lost")
+var ErrBatchTooLarge = errors.New("batch too large")
+var ErrUnknownQueueBackend = errors.New("unknown queue backend")
+
+type Queue interface {
+	// Enqueue adds a pending request, returning its id
+	Enqueue(ctx context.Context, req ScrapeRequest) (uuid.UUID, error)
+	// EnqueueBatch adds all requests atomically, returning their ids in order, at most MaxEnqueueBatch
+	EnqueueBatch(ctx context.Context, reqs []ScrapeRequest) ([]uuid.UUID, error)
+	// Claim leases the next request to the caller, returning nil when there is no work
+	Claim(ctx context.Context) (*ScrapeRequest, error)
+	// Ack marks a claimed request done, returning ErrLeaseLost when the lease is no longer held
+	Ack(ctx context.Context, lease Lease) error
+	// Nack marks a claimed request failed, recording why so operators can find and retry it.
+	// it returns ErrLeaseLost when the lease is no longer held
+	Nack(ctx context.Context, lease Lease, failure Failure) error
+	// ExtendLease renews a claim, returning ErrLeaseLost when the request is no longer held
+	ExtendLease(ctx context.Context, lease Lease) error
+}
+
+// Lease is one claim of a request. every claim gets a new token, so a worker whose lease lapsed
+// cannot finish or renew the request once another worker reclaimed it
+type Lease struct {
+	ID    uuid.UUID
+	Token uuid.UUID
+}
+
+// Failure records why a request was dead lettered
+type Failure struct {
+	// Kind groups failures with the same cause, like unsupported_store or scrape_failed
+	Kind    string
+	Message string
+}
+
+type QueueStats struct {
+	// Depth counts requests by status, backends report the statuses they track
+	Depth            map[string]int64
+	OldestPendingAge time.Duration
+}
+
+// QueueInspector is implemented by backends able to report on their backlog
+type QueueInspector interface {
+	Stats(ctx context.Context) (*QueueStats, error)
+}
+
+// ExternalQueue is implemented by backends living outside postgres, their acks and nacks cannot join a transaction
+// so callers must only send them once the transaction holding the request's results has committed
+type ExternalQueue interface {
+	Queue
+	External()
+}
+
+// QueueFactory binds a queue backend to a database handle so postgres queue operations can join a transaction,
+// backends living outside postgres ignore the handle
+type QueueFactory func(DB) Queue
+
+func PostgresQueueFactory(lease time.Duration) QueueFactory {
+	return func(db DB) Queue {
+		return &PostgresScrapeRequestRepository{db: db, lease: lease}
+	}
+}
+
+func RedisQueueFactory(queue *RedisQueue) QueueFactory {
+	return func(_ DB) Queue {
+		return queue
+	}
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func NewQueueFactory(backend string, redisURL string, lease time.Duration) (QueueFactory, io.Closer, error) {
+	// builds the configured backend, the returned closer releases any connections it holds
+	switch backend {
+	case QueueBackendPostgres:
+		return PostgresQueueFactory(lease), nopCloser{}, nil
+	case QueueBackendRedis:
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid redis url: %w", err)
+		}
+		client := redis.NewClient(opts)
+		return RedisQueueFactory(NewRedisQueue(client, "uniwish:scrape", lease)), client, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownQueueBackend, backend)
+	}
+}
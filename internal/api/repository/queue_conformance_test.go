@@ -0,0 +1,339 @@
+/*
+uniwish.com/interal/api/repository/queue_conformance_test
+
+behaviour every queue backend must share, run against postgres and redis
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/testutil"
+)
+
+type queueConstructor func(t *testing.T, lease time.Duration) Queue
+
+func runQueueConformance(t *testing.T, newQueue queueConstructor) {
+	ctx := context.Background()
+
+	t.Run("claim_empty", func(t *testing.T) {
+		q := newQueue(t, DefaultLease)
+
+		job, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("expected err to be nil, received %v", err)
+		}
+		if job != nil {
+			t.Fatalf("expected job to be nil, received %v", job)
+		}
+	})
+
+	t.Run("claim_returns_enqueued", func(t *testing.T) {
+		q := newQueue(t, DefaultLease)
+		expected := ScrapeRequest{
+			URL:          "http://store.com/a",
+			OwnerID:      "a",
+			Store:        "store.com",
+			Priority:     PriorityBulk,
+			TraceContext: map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		}
+
+		id, err := q.Enqueue(ctx, expected)
+		if err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		if id == uuid.Nil {
+			t.Fatal("expected non-nil id")
+		}
+
+		job, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+		if job == nil {
+			t.Fatal("expected job to be nonnil")
+		}
+
+		if job.ClaimToken == uuid.Nil {
+			t.Fatal("expected the claim to carry a token")
+		}
+		expected.ID = id
+		expected.Status = "processing"
+		expected.ClaimToken = job.ClaimToken
+		expected.Attempts = 1
+		expected.MaxAttempts = DefaultMaxAttempts
+		if !reflect.DeepEqual(*job, expected) {
+			t.Fatalf("expected %+v, received %+v", expected, *job)
+		}
+	})
+
+	t.Run("enqueue_batch", func(t *testing.T) {
+		q := newQueue(t, DefaultLease)
+
+		ids, err := q.EnqueueBatch(ctx, []ScrapeRequest{
+			{URL: "http://store.com/a", Priority: PriorityBulk},
+			{URL: "http://store.com/b", Priority: PriorityBulk},
+		})
+		if err != nil {
+			t.Fatalf("enqueue batch failed: %v", err)
+		}
+		if len(ids) != 2 || ids[0] == ids[1] {
+			t.Fatalf("expected 2 distinct ids, received %v", ids)
+		}
+
+		claimed := map[uuid.UUID]string{}
+		for range ids {
+			job, err := q.Claim(ctx)
+			if err != nil {
+				t.Fatalf("claim failed: %v", err)
+			}
+			if job == nil {
+				t.Fatal("expected job to be nonnil")
+			}
+			claimed[job.ID] = job.URL
+		}
+
+		if claimed[ids[0]] != "http://store.com/a" || claimed[ids[1]] != "http://store.com/b" {
+			t.Fatalf("expected ids to match requests in order, received %v", claimed)
+		}
+	})
+
+	t.Run("claimed_job_is_invisible", func(t *testing.T) {
+		q := newQueue(t, DefaultLease)
+
+		if _, err := q.Enqueue(ctx, ScrapeRequest{URL: "http://store.com/a"}); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		if _, err := q.Claim(ctx); err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+
+		job, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+		if job != nil {
+			t.Fatalf("expected job to be nil, received %v", job)
+		}
+	})
+
+	finishers := map[string]func(Queue, context.Context, Lease) error{
+		"ack": Queue.Ack,
+		"nack": func(q Queue, ctx context.Context, lease Lease) error {
+			return q.Nack(ctx, lease, Failure{Kind: "scrape_failed", Message: "boom"})
+		},
+	}
+	for name, finish := range finishers {
+		t.Run(name+"_releases_lease", func(t *testing.T) {
+			q := newQueue(t, 100*time.Millisecond)
+
+			if _, err := q.Enqueue(ctx, ScrapeRequest{URL: "http://store.com/a"}); err != nil {
+				t.Fatalf("enqueue failed: %v", err)
+			}
+			job, err := q.Claim(ctx)
+			if err != nil {
+				t.Fatalf("claim failed: %v", err)
+			}
+
+			if err := finish(q, ctx, job.Lease()); err != nil {
+				t.Fatalf("%s failed: %v", name, err)
+			}
+
+			if err := q.ExtendLease(ctx, job.Lease()); !errors.Is(err, ErrLeaseLost) {
+				t.Fatalf("expected %v, received %v", ErrLeaseLost, err)
+			}
+
+			// a finished job must not come back once its lease would have lapsed
+			time.Sleep(200 * time.Millisecond)
+			job, err = q.Claim(ctx)
+			if err != nil {
+				t.Fatalf("claim failed: %v", err)
+			}
+			if job != nil {
+				t.Fatalf("expected job to be nil, received %v", job)
+			}
+		})
+	}
+
+	for name, finish := range finishers {
+		t.Run(name+"_refuses_reclaimed_lease", func(t *testing.T) {
+			q := newQueue(t, 100*time.Millisecond)
+
+			if _, err := q.Enqueue(ctx, ScrapeRequest{URL: "http://store.com/a"}); err != nil {
+				t.Fatalf("enqueue failed: %v", err)
+			}
+			stale, err := q.Claim(ctx)
+			if err != nil {
+				t.Fatalf("claim failed: %v", err)
+			}
+			time.Sleep(250 * time.Millisecond)
+			current, err := q.Claim(ctx)
+			if err != nil || current == nil || current.ID != stale.ID {
+				t.Fatalf("expected the lapsed job reclaimed, received %v %v", current, err)
+			}
+
+			// the worker whose lease lapsed must not finish or renew the request it lost
+			if err := finish(q, ctx, stale.Lease()); !errors.Is(err, ErrLeaseLost) {
+				t.Fatalf("expected %v, received %v", ErrLeaseLost, err)
+			}
+			if err := q.ExtendLease(ctx, stale.Lease()); !errors.Is(err, ErrLeaseLost) {
+				t.Fatalf("expected %v, received %v", ErrLeaseLost, err)
+			}
+			if err := finish(q, ctx, current.Lease()); err != nil {
+				t.Fatalf("%s failed: %v", name, err)
+			}
+		})
+	}
+
+	t.Run("interactive_before_bulk", func(t *testing.T) {
+		q := newQueue(t, DefaultLease)
+
+		if _, err := q.Enqueue(ctx, ScrapeRequest{URL: "bulk", Priority: PriorityBulk}); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		if _, err := q.Enqueue(ctx, ScrapeRequest{URL: "interactive", Priority: PriorityInteractive}); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+
+		job, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+		if job.URL != "interactive" {
+			t.Fatalf("expected interactive job first, received %s", job.URL)
+		}
+	})
+
+	t.Run("lapsed_lease_is_reclaimed", func(t *testing.T) {
+		q := newQueue(t, 100*time.Millisecond)
+
+		id, err := q.Enqueue(ctx, ScrapeRequest{URL: "http://store.com/a"})
+		if err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		if _, err := q.Claim(ctx); err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+
+		time.Sleep(250 * time.Millisecond)
+
+		job, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+		if job == nil || job.ID != id {
+			t.Fatalf("expected job %s to be reclaimed, received %v", id, job)
+		}
+	})
+
+	t.Run("reclaims_count_attempts", func(t *testing.T) {
+		q := newQueue(t, 100*time.Millisecond)
+
+		if _, err := q.Enqueue(ctx, ScrapeRequest{URL: "http://store.com/a"}); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		for attempt := 1; attempt <= 3; attempt++ {
+			job, err := q.Claim(ctx)
+			if err != nil || job == nil {
+				t.Fatalf("expected a claim, received %v %v", job, err)
+			}
+			if job.Attempts != attempt {
+				t.Fatalf("expected attempt %d, received %d", attempt, job.Attempts)
+			}
+			time.Sleep(250 * time.Millisecond)
+		}
+	})
+
+	t.Run("status_read_back", func(t *testing.T) {
+		q := newQueue(t, DefaultLease)
+		reader, ok := q.(ScrapeStatusReader)
+		if !ok {
+			t.Fatal("expected the queue to read statuses back")
+		}
+
+		if _, err := reader.GetScrapeStatus(ctx, uuid.New()); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected %v for an unknown request, received %v", sql.ErrNoRows, err)
+		}
+
+		id, err := q.Enqueue(ctx, ScrapeRequest{URL: "http://store.com/a", OwnerID: "a"})
+		if err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		job, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+		if err := q.Nack(ctx, job.Lease(), Failure{Kind: "scrape_failed", Message: "boom"}); err != nil {
+			t.Fatalf("nack failed: %v", err)
+		}
+
+		status, err := reader.GetScrapeStatus(ctx, id)
+		if err != nil {
+			t.Fatalf("get status failed: %v", err)
+		}
+		if status.OwnerID != "a" || status.Status != StatusFailed || status.ErrorKind != "scrape_failed" || status.OccurredAt.IsZero() {
+			t.Fatalf("expected the failed request's status, received %+v", status)
+		}
+	})
+
+	t.Run("extended_lease_is_kept", func(t *testing.T) {
+		q := newQueue(t, 300*time.Millisecond)
+
+		if _, err := q.Enqueue(ctx, ScrapeRequest{URL: "http://store.com/a"}); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		job, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+
+		time.Sleep(200 * time.Millisecond)
+		if err := q.ExtendLease(ctx, job.Lease()); err != nil {
+			t.Fatalf("extend lease failed: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+
+		job, err = q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+		if job != nil {
+			t.Fatalf("expected job to be nil, received %v", job)
+		}
+	})
+}
+
+func TestPostgresQueue_Conformance(t *testing.T) {
+	testutil.RequireIntegration(t)
+
+	runQueueConformance(t, func(t *testing.T, lease time.Duration) Queue {
+		// leases are measured with now(), so these run outside a transaction
+		testutil.TruncateTables(t, testDB)
+		t.Cleanup(func() {
+			testutil.TruncateTables(t, testDB)
+		})
+		return PostgresQueueFactory(lease)(testDB)
+	})
+}
+
+func TestRedisQueue_Conformance(t *testing.T) {
+	client := testutil.RequireRedis(t)
+
+	runQueueConformance(t, func(t *testing.T, lease time.Duration) Queue {
+		prefix := "uniwish:test:" + uuid.NewString()
+		t.Cleanup(func() {
+			ctx := context.Background()
+			keys, _ := client.Keys(ctx, prefix+":*").Result()
+			if len(keys) > 0 {
+				client.Del(ctx, keys...)
+			}
+		})
+		return NewRedisQueue(client, prefix, lease)
+	})
+}
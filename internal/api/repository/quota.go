@@ -0,0 +1,58 @@
+/*
+uniwish.com/interal/api/repository/quota
+
+centralizes DB operations with daily scrape request quotas
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type QuotaRepository interface {
+	// Consume uses n of owner's requests for day, returning false without using any when fewer than n are left
+	Consume(ctx context.Context, ownerID string, day time.Time, n int, limit int) (bool, error)
+}
+
+type PostgresQuotaRepository struct {
+	db DB
+}
+
+func NewPostgresQuotaRepository(db DB) QuotaRepository {
+	return &PostgresQuotaRepository{db: db}
+}
+
+func (r *PostgresQuotaRepository) Consume(ctx context.Context, ownerID string, day time.Time, n int, limit int) (bool, error) {
+	// the conditional upsert counts and checks in one statement, so concurrent requests cannot overshoot the limit
+	ctx, span := tracer.Start(ctx, "PostgresQuotaRepository.Consume")
+	defer span.End()
+
+	if n > limit {
+		return false, nil
+	}
+
+	var used int
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO scrape_request_quotas (owner_id, day, used)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (owner_id, day) DO UPDATE
+		SET used = scrape_request_quotas.used + $3
+		WHERE scrape_request_quotas.used + $3 <= $4
+		RETURNING used
+		`,
+		ownerID, day.UTC().Format(time.DateOnly), n, limit,
+	).Scan(&used)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return used <= limit, nil
+}
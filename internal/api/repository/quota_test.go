@@ -0,0 +1,64 @@
+/*
+uniwish.com/interal/api/repository/quota_test
+
+testing for daily scrape request quotas
+*/
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"uniwish.com/internal/testutil"
+)
+
+func TestQuotaRepo_Consume(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := NewPostgresQuotaRepository(tx)
+	ctx := context.Background()
+	today := time.Now()
+
+	for i := range 2 {
+		ok, err := repo.Consume(ctx, "user-1", today, 1, 2)
+		if err != nil {
+			t.Fatalf("consume failed: %v", err)
+		}
+		if !ok {
+			t.Fatalf("expected request %d to be within quota", i+1)
+		}
+	}
+
+	ok, err := repo.Consume(ctx, "user-1", today, 1, 2)
+	if err != nil {
+		t.Fatalf("consume failed: %v", err)
+	}
+	if ok {
+		t.Fatal("expected third request to exceed quota")
+	}
+
+	// a batch larger than what is left uses nothing
+	if ok, _ := repo.Consume(ctx, "user-3", today, 2, 3); !ok {
+		t.Fatal("expected batch within quota")
+	}
+	if ok, _ := repo.Consume(ctx, "user-3", today, 2, 3); ok {
+		t.Fatal("expected batch exceeding quota to be refused")
+	}
+	if ok, _ := repo.Consume(ctx, "user-3", today, 1, 3); !ok {
+		t.Fatal("expected refused batch to leave the quota untouched")
+	}
+
+	// quotas are per owner and per day
+	if ok, _ := repo.Consume(ctx, "user-2", today, 1, 2); !ok {
+		t.Fatal("expected another owner to have its own quota")
+	}
+	if ok, _ := repo.Consume(ctx, "user-1", today.AddDate(0, 0, 1), 1, 2); !ok {
+		t.Fatal("expected quota to reset the next day")
+	}
+}
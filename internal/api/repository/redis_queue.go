@@ -0,0 +1,441 @@
+/*
+uniwish.com/interal/api/repository/redis_queue
+
+redis streams backed scrape queue
+
+each priority has its own stream read through a shared consumer group,
+interactive before bulk, while request state lives in a hash per request.
+unacknowledged entries idle longer than the lease are reclaimed with XAUTOCLAIM,
+their delivery count standing in for the postgres attempts column.
+unlike the postgres queue there is no round-robin across owners or stores,
+and no admin queries, config validation refuses queue.admin with this backend.
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const redisConsumerGroup = "workers"
+
+// finished requests are kept around this long so their status stays readable
+const redisFinishedTTL = 24 * time.Hour
+
+type RedisQueue struct {
+	client   *redis.Client
+	prefix   string
+	consumer string
+	lease    time.Duration
+	ready    atomic.Bool
+}
+
+func NewRedisQueue(client *redis.Client, prefix string, lease time.Duration) *RedisQueue {
+	hostname, _ := os.Hostname()
+	return &RedisQueue{
+		client:   client,
+		prefix:   prefix,
+		consumer: fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+		lease:    lease,
+	}
+}
+
+func (q *RedisQueue) External() {}
+
+func (q *RedisQueue) streams() []string {
+	// ordered by precedence
+	return []string{q.streamFor(PriorityInteractive), q.streamFor(PriorityBulk)}
+}
+
+func (q *RedisQueue) streamFor(p Priority) string {
+	if p >= PriorityInteractive {
+		return q.prefix + ":interactive"
+	}
+	return q.prefix + ":bulk"
+}
+
+func (q *RedisQueue) jobKey(id uuid.UUID) string {
+	return q.prefix + ":job:" + id.String()
+}
+
+func (q *RedisQueue) ensureGroups(ctx context.Context) error {
+	if q.ready.Load() {
+		return nil
+	}
+	for _, stream := range q.streams() {
+		err := q.client.XGroupCreateMkStream(ctx, stream, redisConsumerGroup, "0").Err()
+		// the group outliving a restart is expected, redis only promises the error code prefix
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return err
+		}
+	}
+	q.ready.Store(true)
+	return nil
+}
+
+func (q *RedisQueue) Enqueue(ctx context.Context, req ScrapeRequest) (uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "RedisQueue.Enqueue")
+	defer span.End()
+
+	if err := q.ensureGroups(ctx); err != nil {
+		return uuid.Nil, err
+	}
+
+	traceContext, err := marshalTraceContext(req.TraceContext)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	id := uuid.New()
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		q.add(ctx, pipe, id, req, traceContext)
+		return nil
+	})
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
+
+func (q *RedisQueue) EnqueueBatch(ctx context.Context, reqs []ScrapeRequest) ([]uuid.UUID, error) {
+	// one MULTI/EXEC, so the batch is stored entirely or not at all
+	ctx, span := tracer.Start(ctx, "RedisQueue.EnqueueBatch")
+	defer span.End()
+
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	if err := q.ensureGroups(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(reqs))
+	traceContexts := make([][]byte, len(reqs))
+	for i, req := range reqs {
+		traceContext, err := marshalTraceContext(req.TraceContext)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = uuid.New()
+		traceContexts[i] = traceContext
+	}
+
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, req := range reqs {
+			q.add(ctx, pipe, ids[i], req, traceContexts[i])
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (q *RedisQueue) add(ctx context.Context, pipe redis.Pipeliner, id uuid.UUID, req ScrapeRequest, traceContext []byte) {
+	pipe.HSet(ctx, q.jobKey(id),
+		"url", req.URL,
+		"trace_context", traceContext,
+		"status", "pending",
+		"priority", int(req.Priority),
+		"owner_id", req.OwnerID,
+		"store", req.Store,
+		"updated_at", time.Now().UnixMilli(),
+	)
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.streamFor(req.Priority),
+		Values: map[string]any{"id": id.String()},
+	})
+}
+
+func (q *RedisQueue) Claim(ctx context.Context) (*ScrapeRequest, error) {
+	ctx, span := tracer.Start(ctx, "RedisQueue.Claim")
+	defer span.End()
+
+	if err := q.ensureGroups(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, stream := range q.streams() {
+		// abandoned entries first so a crashed worker's requests are not starved by new ones
+		reclaimed, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    redisConsumerGroup,
+			Consumer: q.consumer,
+			MinIdle:  q.lease,
+			Start:    "0-0",
+			Count:    1,
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(reclaimed) > 0 {
+			// XAUTOCLAIM counted this delivery, the pending entry's count is the request's attempts
+			pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream: stream,
+				Group:  redisConsumerGroup,
+				Start:  reclaimed[0].ID,
+				End:    reclaimed[0].ID,
+				Count:  1,
+			}).Result()
+			if err != nil {
+				return nil, err
+			}
+			attempts := 1
+			if len(pending) > 0 {
+				attempts = int(pending[0].RetryCount)
+			}
+			return q.claimed(ctx, stream, reclaimed[0], attempts)
+		}
+
+		fresh, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    redisConsumerGroup,
+			Consumer: q.consumer,
+			Streams:  []string{stream, ">"},
+			Count:    1,
+			Block:    -1,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		if len(fresh) > 0 && len(fresh[0].Messages) > 0 {
+			return q.claimed(ctx, stream, fresh[0].Messages[0], 1)
+		}
+	}
+
+	// no work to do, lets return
+	return nil, nil
+}
+
+func (q *RedisQueue) claimed(ctx context.Context, stream string, msg redis.XMessage, attempts int) (*ScrapeRequest, error) {
+	rawId, _ := msg.Values["id"].(string)
+	id, err := uuid.Parse(rawId)
+	if err != nil {
+		return nil, fmt.Errorf("malformed stream entry %s: %w", msg.ID, err)
+	}
+
+	// a reclaimed entry gets a new token, the worker it was reclaimed from can no longer finish it
+	key := q.jobKey(id)
+	token := uuid.New()
+	err = q.client.HSet(ctx, key,
+		"status", "processing",
+		"stream", stream,
+		"message_id", msg.ID,
+		"claim_token", token.String(),
+		"updated_at", time.Now().UnixMilli(),
+	).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := q.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var traceContext map[string]string
+	if raw := fields["trace_context"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &traceContext); err != nil {
+			return nil, err
+		}
+	}
+
+	priority, _ := strconv.Atoi(fields["priority"])
+	return &ScrapeRequest{
+		ID:           id,
+		Status:       fields["status"],
+		URL:          fields["url"],
+		Priority:     Priority(priority),
+		OwnerID:      fields["owner_id"],
+		Store:        fields["store"],
+		TraceContext: traceContext,
+		ClaimToken:   token,
+		Attempts:     attempts,
+		MaxAttempts:  DefaultMaxAttempts,
+	}, nil
+}
+
+func (q *RedisQueue) entryFor(ctx context.Context, client redis.Cmdable, lease Lease) (string, string, error) {
+	entry, err := client.HMGet(ctx, q.jobKey(lease.ID), "stream", "message_id", "claim_token").Result()
+	if err != nil {
+		return "", "", err
+	}
+
+	stream, _ := entry[0].(string)
+	messageId, _ := entry[1].(string)
+	token, _ := entry[2].(string)
+	if stream == "" || messageId == "" || token != lease.Token.String() {
+		return "", "", ErrLeaseLost
+	}
+	return stream, messageId, nil
+}
+
+func (q *RedisQueue) finish(ctx context.Context, lease Lease, status string, failure Failure) error {
+	// watching the hash makes the token check and the finish atomic against a concurrent reclaim
+	key := q.jobKey(lease.ID)
+	err := q.client.Watch(ctx, func(tx *redis.Tx) error {
+		stream, messageId, err := q.entryFor(ctx, tx, lease)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.XAck(ctx, stream, redisConsumerGroup, messageId)
+			pipe.XDel(ctx, stream, messageId)
+			pipe.HSet(ctx, key, "status", status, "error_kind", failure.Kind, "error", failure.Message, "updated_at", time.Now().UnixMilli())
+			pipe.HDel(ctx, key, "stream", "message_id", "claim_token")
+			pipe.Expire(ctx, key, redisFinishedTTL)
+			return nil
+		})
+		return err
+	}, key)
+
+	if errors.Is(err, redis.TxFailedErr) {
+		// the hash changed under us, another worker reclaimed the request
+		return ErrLeaseLost
+	}
+	return err
+}
+
+func (q *RedisQueue) Ack(ctx context.Context, lease Lease) error {
+	ctx, span := tracer.Start(ctx, "RedisQueue.Ack")
+	defer span.End()
+
+	return q.finish(ctx, lease, "done", Failure{})
+}
+
+func (q *RedisQueue) Nack(ctx context.Context, lease Lease, failure Failure) error {
+	ctx, span := tracer.Start(ctx, "RedisQueue.Nack")
+	defer span.End()
+
+	return q.finish(ctx, lease, "failed", failure)
+}
+
+// checking the owner and re-claiming in one script, so a worker whose lease lapsed between the two
+// cannot take the entry back from the worker that reclaimed it
+var extendLeaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'claim_token') ~= ARGV[4] then
+	return 0
+end
+local pending = redis.call('XPENDING', KEYS[2], ARGV[1], ARGV[3], ARGV[3], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[2] then
+	return 0
+end
+return #redis.call('XCLAIM', KEYS[2], ARGV[1], ARGV[2], 0, ARGV[3], 'JUSTID')
+`)
+
+func (q *RedisQueue) ExtendLease(ctx context.Context, lease Lease) error {
+	ctx, span := tracer.Start(ctx, "RedisQueue.ExtendLease")
+	defer span.End()
+
+	// re-claiming an entry we hold resets its idle time, which is what the lease is measured against
+	stream, messageId, err := q.entryFor(ctx, q.client, lease)
+	if err != nil {
+		return err
+	}
+
+	claimed, err := extendLeaseScript.Run(ctx, q.client,
+		[]string{q.jobKey(lease.ID), stream},
+		redisConsumerGroup, q.consumer, messageId, lease.Token.String(),
+	).Int()
+	if err != nil {
+		return err
+	}
+
+	if claimed == 0 {
+		// acknowledged, or reclaimed by another worker after our lease lapsed
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (q *RedisQueue) GetScrapeStatus(ctx context.Context, id uuid.UUID) (*ScrapeStatus, error) {
+	ctx, span := tracer.Start(ctx, "RedisQueue.GetScrapeStatus")
+	defer span.End()
+
+	// the hash does not keep the product a done request scraped, only its streamed transition carries it
+	fields, err := q.client.HMGet(ctx, q.jobKey(id), "owner_id", "status", "error_kind", "updated_at").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	status, _ := fields[1].(string)
+	if status == "" {
+		// never enqueued, or finished longer than redisFinishedTTL ago
+		return nil, sql.ErrNoRows
+	}
+	ownerID, _ := fields[0].(string)
+	errorKind, _ := fields[2].(string)
+	updatedAt, _ := fields[3].(string)
+	millis, _ := strconv.ParseInt(updatedAt, 10, 64)
+	return &ScrapeStatus{
+		ScrapeRequestID: id,
+		OwnerID:         ownerID,
+		Status:          status,
+		ErrorKind:       errorKind,
+		OccurredAt:      time.UnixMilli(millis).UTC(),
+	}, nil
+}
+
+func (q *RedisQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	// finished requests leave the streams, so only pending (undelivered) and processing (unacked) are reported
+	if err := q.ensureGroups(ctx); err != nil {
+		return nil, err
+	}
+
+	stats := &QueueStats{Depth: map[string]int64{"pending": 0, "processing": 0}}
+	now := time.Now()
+
+	for _, stream := range q.streams() {
+		groups, err := q.client.XInfoGroups(ctx, stream).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range groups {
+			if group.Name != redisConsumerGroup {
+				continue
+			}
+			stats.Depth["pending"] += group.Lag
+			stats.Depth["processing"] += group.Pending
+
+			// the first entry after the group's cursor is the oldest one not yet delivered
+			next, err := q.client.XRangeN(ctx, stream, "("+group.LastDeliveredID, "+", 1).Result()
+			if err != nil {
+				return nil, err
+			}
+			if len(next) == 0 {
+				continue
+			}
+
+			if age := now.Sub(streamEntryTime(next[0].ID)); age > stats.OldestPendingAge {
+				stats.OldestPendingAge = age
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+func streamEntryTime(id string) time.Time {
+	// stream entry ids are <unix millis>-<sequence>
+	millis, _, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseInt(millis, 10, 64)
+	return time.UnixMilli(ms)
+}
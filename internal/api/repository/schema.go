@@ -0,0 +1,63 @@
+/*
+uniwish.com/interal/api/repository/schema
+
+reads the migration state recorded by golang-migrate
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion is the latest migration this build expects, bump it alongside new migrations
+const SchemaVersion = 18
+
+type SchemaState struct {
+	Version int64
+	Dirty   bool
+}
+
+type SchemaReader interface {
+	SchemaState(ctx context.Context) (*SchemaState, error)
+}
+
+type PostgresSchemaReader struct {
+	db DB
+}
+
+func NewPostgresSchemaReader(db DB) *PostgresSchemaReader {
+	return &PostgresSchemaReader{db: db}
+}
+
+func (r *PostgresSchemaReader) SchemaState(ctx context.Context) (*SchemaState, error) {
+	state := &SchemaState{}
+
+	var tracked bool
+	err := r.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&tracked)
+	if err != nil {
+		return nil, err
+	}
+	if !tracked {
+		// migrate creates its table on first use, a database it never touched is at version 0
+		return state, nil
+	}
+
+	err = r.db.QueryRowContext(
+		ctx,
+		`
+		SELECT version, dirty
+		FROM schema_migrations
+		LIMIT 1
+		`,
+	).Scan(&state.Version, &state.Dirty)
+
+	if err == sql.ErrNoRows {
+		// migrate tracks a single row, no row means nothing was applied yet
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
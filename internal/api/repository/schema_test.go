@@ -0,0 +1,29 @@
+/*
+uniwish.com/interal/api/repository/schema_test
+
+testing for migration state reads
+*/
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"uniwish.com/internal/testutil"
+)
+
+func TestSchemaReader_MatchesBuild(t *testing.T) {
+	testutil.RequireIntegration(t)
+
+	state, err := NewPostgresSchemaReader(testDB).SchemaState(context.Background())
+	if err != nil {
+		t.Fatalf("schema state failed: %v", err)
+	}
+
+	if state.Dirty {
+		t.Fatal("expected a clean schema")
+	}
+	if state.Version != SchemaVersion {
+		t.Fatalf("expected migrations at %d, received %d", SchemaVersion, state.Version)
+	}
+}
@@ -8,40 +8,87 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// Priority orders pending scrape requests, higher values are claimed first
+type Priority int16
+
+const (
+	PriorityBulk        Priority = 0
+	PriorityInteractive Priority = 10
+)
+
 type ScrapeRequest struct {
-	ID     uuid.UUID
-	Status string
-	URL    string
+	ID       uuid.UUID
+	Status   string
+	URL      string
+	Priority Priority
+	OwnerID  string
+	Store    string
+	// TraceContext carries the enqueuing request's propagated trace headers to the worker
+	TraceContext map[string]string
+	// ClaimToken is set by Claim, finishing or renewing the claim requires it
+	ClaimToken uuid.UUID
+	// Attempts counts the claims so far including the current one, a request whose lease keeps lapsing
+	// is dead lettered once it exceeds MaxAttempts
+	Attempts    int
+	MaxAttempts int
+}
+
+// RetriesExhausted reports whether the request was claimed more often than it may be
+func (r *ScrapeRequest) RetriesExhausted() bool {
+	return r.MaxAttempts > 0 && r.Attempts > r.MaxAttempts
+}
+
+func (r *ScrapeRequest) Lease() Lease {
+	return Lease{ID: r.ID, Token: r.ClaimToken}
+}
+
+func marshalTraceContext(carrier map[string]string) ([]byte, error) {
+	if carrier == nil {
+		carrier = map[string]string{}
+	}
+	return json.Marshal(carrier)
 }
 
 type ScrapeRequestRepository interface {
-	Insert(ctx context.Context, url string) (uuid.UUID, error)
-	Dequeue(ctx context.Context) (*ScrapeRequest, error)
-	MarkDone(ctx context.Context, id uuid.UUID) error
-	MarkFailed(ctx context.Context, id uuid.UUID) error
+	Queue
+	QueueAdmin
+	ScrapeStatusReader
 }
 
 type PostgresScrapeRequestRepository struct {
-	db DB
+	db    DB
+	lease time.Duration
 }
 
 func NewPostgresScrapeRequestRepository(db DB) ScrapeRequestRepository {
-	return &PostgresScrapeRequestRepository{db: db}
+	return &PostgresScrapeRequestRepository{db: db, lease: DefaultLease}
 }
 
-func (r *PostgresScrapeRequestRepository) Insert(ctx context.Context, url string) (uuid.UUID, error) {
+func (r *PostgresScrapeRequestRepository) Enqueue(ctx context.Context, req ScrapeRequest) (uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.Enqueue")
+	defer span.End()
+
+	traceContext, err := marshalTraceContext(req.TraceContext)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	id := uuid.New()
-	_, err := r.db.ExecContext(
+	_, err = r.db.ExecContext(
 		ctx,
 		`
-		INSERT INTO scrape_requests (id, url, status)
-		VALUES ($1, $2, 'pending')
+		INSERT INTO scrape_requests (id, url, status, priority, owner_id, store, trace_context)
+		VALUES ($1, $2, 'pending', $3, $4, $5, $6)
 		`,
-		id, url,
+		id, req.URL, req.Priority, req.OwnerID, req.Store, traceContext,
 	)
 
 	if err != nil {
@@ -51,22 +98,112 @@ func (r *PostgresScrapeRequestRepository) Insert(ctx context.Context, url string
 	return id, nil
 }
 
-func (r *PostgresScrapeRequestRepository) Dequeue(ctx context.Context) (*ScrapeRequest, error) {
-	// To prevent race conditions between read and update, dequeue must be called with a transaction backed repo.
+func (r *PostgresScrapeRequestRepository) EnqueueBatch(ctx context.Context, reqs []ScrapeRequest) ([]uuid.UUID, error) {
+	// a single multi-row insert, so the batch is stored entirely or not at all
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.EnqueueBatch")
+	defer span.End()
+
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	if len(reqs) > MaxEnqueueBatch {
+		return nil, fmt.Errorf("%w: %d requests, at most %d fit one insert", ErrBatchTooLarge, len(reqs), MaxEnqueueBatch)
+	}
+
+	ids := make([]uuid.UUID, len(reqs))
+	values := make([]string, len(reqs))
+	args := make([]any, 0, len(reqs)*6)
+
+	for i, req := range reqs {
+		traceContext, err := marshalTraceContext(req.TraceContext)
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = uuid.New()
+		n := len(args)
+		values[i] = fmt.Sprintf("($%d, $%d, 'pending', $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+		args = append(args, ids[i], req.URL, req.Priority, req.OwnerID, req.Store, traceContext)
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO scrape_requests (id, url, status, priority, owner_id, store, trace_context)
+		VALUES `+strings.Join(values, ", "),
+		args...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *PostgresScrapeRequestRepository) Claim(ctx context.Context) (*ScrapeRequest, error) {
+	// Selecting and leasing happen in one statement so claims are safe without a surrounding transaction.
+	// Pending requests and processing requests whose lease lapsed (crashed workers) are both claimable.
+	// They are served by priority first, then round-robin across owners and stores:
+	// whoever was least recently claimed for goes next, so one bulk import cannot starve everyone else.
+	// Only recent claims are considered to keep the fairness lookup bounded.
+	// Each claim records a fresh token, the previous holder of a reclaimed request can no longer finish it,
+	// and counts an attempt so a request that keeps crashing its worker is eventually dead lettered.
+
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.Claim")
+	defer span.End()
 
 	scrapeRequest := ScrapeRequest{}
+	var traceContext []byte
 
 	err := r.db.QueryRowContext(
 		ctx,
 		`
-		SELECT id, status, url
-		FROM scrape_requests
-		WHERE status = 'pending'
-		ORDER BY created_at
-		FOR UPDATE SKIP LOCKED
-		LIMIT 1
-		`,
-	).Scan(&scrapeRequest.ID, &scrapeRequest.Status, &scrapeRequest.URL)
+		WITH owner_turns AS (
+			SELECT owner_id, max(claimed_at) AS last_claimed_at
+			FROM scrape_requests
+			WHERE claimed_at > now() - interval '1 hour'
+			GROUP BY owner_id
+		), store_turns AS (
+			SELECT store, max(claimed_at) AS last_claimed_at
+			FROM scrape_requests
+			WHERE claimed_at > now() - interval '1 hour'
+			GROUP BY store
+		), next_request AS (
+			SELECT sr.id
+			FROM scrape_requests sr
+			LEFT JOIN owner_turns ot ON ot.owner_id = sr.owner_id
+			LEFT JOIN store_turns st ON st.store = sr.store
+			WHERE sr.status = 'pending'
+			OR (sr.status = 'processing' AND sr.lease_expires_at < now())
+			ORDER BY
+				sr.priority DESC,
+				ot.last_claimed_at ASC NULLS FIRST,
+				st.last_claimed_at ASC NULLS FIRST,
+				sr.created_at
+			FOR UPDATE OF sr SKIP LOCKED
+			LIMIT 1
+		)
+		UPDATE scrape_requests
+		SET status = 'processing',
+			claimed_at = now(),
+			lease_expires_at = now() + make_interval(secs => $1),
+			claim_token = $2,
+			attempts = scrape_requests.attempts + 1
+		FROM next_request
+		WHERE scrape_requests.id = next_request.id
+		RETURNING
+			scrape_requests.id, scrape_requests.status, scrape_requests.url,
+			scrape_requests.priority, scrape_requests.owner_id, scrape_requests.store,
+			scrape_requests.trace_context, scrape_requests.claim_token,
+			scrape_requests.attempts, scrape_requests.max_attempts
+		`, r.lease.Seconds(), uuid.New(),
+	).Scan(
+		&scrapeRequest.ID, &scrapeRequest.Status, &scrapeRequest.URL,
+		&scrapeRequest.Priority, &scrapeRequest.OwnerID, &scrapeRequest.Store,
+		&traceContext, &scrapeRequest.ClaimToken,
+		&scrapeRequest.Attempts, &scrapeRequest.MaxAttempts,
+	)
 
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
@@ -75,52 +212,133 @@ func (r *PostgresScrapeRequestRepository) Dequeue(ctx context.Context) (*ScrapeR
 		return nil, nil
 	}
 
-	_, err = r.db.ExecContext(
+	if err := json.Unmarshal(traceContext, &scrapeRequest.TraceContext); err != nil {
+		return nil, err
+	}
+	return &scrapeRequest, nil
+}
+
+func (r *PostgresScrapeRequestRepository) Ack(ctx context.Context, lease Lease) error {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.Ack")
+	defer span.End()
+
+	result, err := r.db.ExecContext(
 		ctx,
 		`
 		UPDATE scrape_requests
-		SET status = 'processing'
-		WHERE id = $1
-		`, scrapeRequest.ID,
+		SET status = 'done', lease_expires_at = NULL, finished_at = now()
+		WHERE id = $1 AND status = 'processing' AND claim_token = $2
+		`, lease.ID, lease.Token,
 	)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
-	scrapeRequest.Status = "processing"
-	return &scrapeRequest, nil
+
+	return leaseHeld(result)
 }
 
-func (r *PostgresScrapeRequestRepository) MarkDone(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.ExecContext(
+func (r *PostgresScrapeRequestRepository) Nack(ctx context.Context, lease Lease, failure Failure) error {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.Nack")
+	defer span.End()
+
+	result, err := r.db.ExecContext(
 		ctx,
 		`
 		UPDATE scrape_requests
-		SET status = 'done'
-		WHERE id = $1
-		`, id,
+		SET status = 'failed', lease_expires_at = NULL, finished_at = now(), error_kind = $3, error = $4
+		WHERE id = $1 AND status = 'processing' AND claim_token = $2
+		`, lease.ID, lease.Token, failure.Kind, failure.Message,
 	)
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return leaseHeld(result)
 }
 
-func (r *PostgresScrapeRequestRepository) MarkFailed(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.ExecContext(
+func (r *PostgresScrapeRequestRepository) ExtendLease(ctx context.Context, lease Lease) error {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.ExtendLease")
+	defer span.End()
+
+	result, err := r.db.ExecContext(
 		ctx,
 		`
 		UPDATE scrape_requests
-		SET status = 'failed'
-		WHERE id = $1
-		`, id,
+		SET lease_expires_at = now() + make_interval(secs => $3)
+		WHERE id = $1 AND status = 'processing' AND claim_token = $2
+		`, lease.ID, lease.Token, r.lease.Seconds(),
 	)
 
 	if err != nil {
 		return err
 	}
 
+	return leaseHeld(result)
+}
+
+// leaseHeld turns an update matching no row into ErrLeaseLost, the request was finished, cancelled or reclaimed
+func leaseHeld(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrLeaseLost
+	}
 	return nil
 }
+
+func (r *PostgresScrapeRequestRepository) Stats(ctx context.Context) (*QueueStats, error) {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.Stats")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT status, count(*)
+		FROM scrape_requests
+		GROUP BY status
+		`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	stats := &QueueStats{Depth: make(map[string]int64)}
+
+	for rows.Next() {
+		var (
+			status string
+			count  int64
+		)
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		stats.Depth[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var oldest sql.NullFloat64
+	err = r.db.QueryRowContext(
+		ctx,
+		`
+		SELECT EXTRACT(EPOCH FROM now() - min(created_at))
+		FROM scrape_requests
+		WHERE status = 'pending'
+		`,
+	).Scan(&oldest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats.OldestPendingAge = time.Duration(oldest.Float64 * float64(time.Second))
+	return stats, nil
+}
@@ -0,0 +1,191 @@
+/*
+uniwish.com/interal/api/repository/scrape_request_admin
+
+operator queries over the postgres scrape queue, listing requests and retrying, cancelling or purging them in bulk
+*/
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusDone       = "done"
+	StatusFailed     = "failed"
+	StatusCancelled  = "cancelled"
+)
+
+// ScrapeRequestFilter selects requests, empty fields match everything
+type ScrapeRequestFilter struct {
+	IDs       []uuid.UUID
+	Status    string
+	Store     string
+	ErrorKind string
+}
+
+type ScrapeRequestListItem struct {
+	ID         uuid.UUID
+	URL        string
+	Status     string
+	Priority   Priority
+	OwnerID    string
+	Store      string
+	ErrorKind  string
+	Error      string
+	CreatedAt  time.Time
+	FinishedAt *time.Time
+}
+
+type QueueAdmin interface {
+	// List returns the requests matching filter, newest first
+	List(ctx context.Context, filter ScrapeRequestFilter, limit int, offset int) ([]ScrapeRequestListItem, error)
+	// Retry re-pends the failed requests matching filter, returning how many were
+	Retry(ctx context.Context, filter ScrapeRequestFilter) (int64, error)
+	// Cancel stops the pending requests matching filter from being claimed, returning how many were
+	Cancel(ctx context.Context, filter ScrapeRequestFilter) (int64, error)
+	// Purge deletes done requests finished before cutoff, returning how many were
+	Purge(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// where renders filter as conditions on scrape_requests, numbering its placeholders after args
+func (f ScrapeRequestFilter) where(args []any) (string, []any) {
+	var conditions []string
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if len(f.IDs) > 0 {
+		add("id = ANY($%d::uuid[])", uuidStrings(f.IDs))
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.Store != "" {
+		add("store = $%d", f.Store)
+	}
+	if f.ErrorKind != "" {
+		add("error_kind = $%d", f.ErrorKind)
+	}
+
+	if len(conditions) == 0 {
+		return "TRUE", args
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+	return values
+}
+
+func (r *PostgresScrapeRequestRepository) List(ctx context.Context, filter ScrapeRequestFilter, limit int, offset int) ([]ScrapeRequestListItem, error) {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.List")
+	defer span.End()
+
+	where, args := filter.where([]any{limit, offset})
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT id, url, status, priority, owner_id, store, error_kind, error, created_at, finished_at
+		FROM scrape_requests
+		WHERE `+where+`
+		ORDER BY created_at DESC, id
+		LIMIT $1 OFFSET $2
+		`, args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var items []ScrapeRequestListItem
+
+	for rows.Next() {
+		var item ScrapeRequestListItem
+		err := rows.Scan(
+			&item.ID, &item.URL, &item.Status, &item.Priority, &item.OwnerID, &item.Store,
+			&item.ErrorKind, &item.Error, &item.CreatedAt, &item.FinishedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *PostgresScrapeRequestRepository) Retry(ctx context.Context, filter ScrapeRequestFilter) (int64, error) {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.Retry")
+	defer span.End()
+
+	// only dead lettered requests are retried, whatever status the filter asks for
+	filter.Status = StatusFailed
+	where, args := filter.where(nil)
+	// claimed_at is kept, it only feeds the fairness ordering
+	result, err := r.db.ExecContext(
+		ctx,
+		`
+		UPDATE scrape_requests
+		SET status = 'pending', error_kind = '', error = '', finished_at = NULL, lease_expires_at = NULL
+		WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *PostgresScrapeRequestRepository) Cancel(ctx context.Context, filter ScrapeRequestFilter) (int64, error) {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.Cancel")
+	defer span.End()
+
+	// requests already claimed run to completion, a worker would not notice them being cancelled
+	filter.Status = StatusPending
+	where, args := filter.where(nil)
+	result, err := r.db.ExecContext(
+		ctx,
+		`
+		UPDATE scrape_requests
+		SET status = 'cancelled', finished_at = now()
+		WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *PostgresScrapeRequestRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.Purge")
+	defer span.End()
+
+	// requests finished before finished_at was recorded fall back to their creation time
+	result, err := r.db.ExecContext(
+		ctx,
+		`
+		DELETE FROM scrape_requests
+		WHERE status = 'done' AND COALESCE(finished_at, created_at) < $1
+		`, cutoff,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,197 @@
+/*
+uniwish.com/interal/api/repository/scrape_request_admin_test
+
+testing for the operator queue queries
+*/
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/testutil"
+)
+
+// failRequests enqueues a request per store and dead letters it with kind
+func failRequests(t *testing.T, repo *PostgresScrapeRequestRepository, kind string, stores ...string) []uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+
+	var ids []uuid.UUID
+	for _, store := range stores {
+		id, err := repo.Enqueue(ctx, ScrapeRequest{URL: "http://" + store + ".com/a", Store: store})
+		if err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		job, err := repo.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+		if err := repo.Nack(ctx, job.Lease(), Failure{Kind: kind, Message: "boom"}); err != nil {
+			t.Fatalf("nack failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestScrapeRequestRepo_ListFilters(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := &PostgresScrapeRequestRepository{db: tx, lease: DefaultLease}
+	ctx := context.Background()
+
+	failRequests(t, repo, "scrape_failed", "zara", "zara")
+	failRequests(t, repo, "unsupported_store", "mango")
+	if _, err := repo.Enqueue(ctx, ScrapeRequest{URL: "http://zara.com/b", Store: "zara"}); err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   ScrapeRequestFilter
+		expected int
+	}{
+		{name: "all", filter: ScrapeRequestFilter{}, expected: 4},
+		{name: "status", filter: ScrapeRequestFilter{Status: StatusFailed}, expected: 3},
+		{name: "store", filter: ScrapeRequestFilter{Store: "zara"}, expected: 3},
+		{name: "error_kind", filter: ScrapeRequestFilter{ErrorKind: "scrape_failed"}, expected: 2},
+		{name: "combined", filter: ScrapeRequestFilter{Status: StatusFailed, Store: "mango"}, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := repo.List(ctx, tt.filter, 50, 0)
+			if err != nil {
+				t.Fatalf("list failed: %v", err)
+			}
+			if len(items) != tt.expected {
+				t.Fatalf("expected %d requests, received %d", tt.expected, len(items))
+			}
+		})
+	}
+}
+
+func TestScrapeRequestRepo_RetryRependsFailed(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := &PostgresScrapeRequestRepository{db: tx, lease: DefaultLease}
+	ctx := context.Background()
+
+	ids := failRequests(t, repo, "scrape_failed", "zara", "mango")
+
+	retried, err := repo.Retry(ctx, ScrapeRequestFilter{Store: "zara"})
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if retried != 1 {
+		t.Fatalf("expected 1 retried request, received %d", retried)
+	}
+
+	job, err := repo.Claim(ctx)
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if job == nil || job.ID != ids[0] {
+		t.Fatalf("expected the retried request %v to be claimable, received %v", ids[0], job)
+	}
+
+	var errorKind string
+	if err := tx.QueryRow(`SELECT error_kind FROM scrape_requests WHERE id = $1`, ids[0]).Scan(&errorKind); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if errorKind != "" {
+		t.Fatalf("expected the failure to be cleared, received %q", errorKind)
+	}
+}
+
+func TestScrapeRequestRepo_CancelSkipsClaimed(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := &PostgresScrapeRequestRepository{db: tx, lease: DefaultLease}
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := repo.Enqueue(ctx, ScrapeRequest{URL: "http://zara.com/a", Store: "zara"}); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	}
+	if _, err := repo.Claim(ctx); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+
+	cancelled, err := repo.Cancel(ctx, ScrapeRequestFilter{Store: "zara"})
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if cancelled != 1 {
+		t.Fatalf("expected 1 cancelled request, received %d", cancelled)
+	}
+
+	job, err := repo.Claim(ctx)
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if job != nil {
+		t.Fatalf("expected cancelled requests not to be claimed, received %v", job)
+	}
+}
+
+func TestScrapeRequestRepo_PurgeDone(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := &PostgresScrapeRequestRepository{db: tx, lease: DefaultLease}
+	ctx := context.Background()
+
+	_, err = repo.Enqueue(ctx, ScrapeRequest{URL: "http://zara.com/a"})
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	job, err := repo.Claim(ctx)
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if err := repo.Ack(ctx, job.Lease()); err != nil {
+		t.Fatalf("ack failed: %v", err)
+	}
+	failRequests(t, repo, "scrape_failed", "zara")
+
+	purged, err := repo.Purge(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if purged != 0 {
+		t.Fatalf("expected recent requests to be kept, purged %d", purged)
+	}
+
+	// a cutoff ahead of now covers the request just done
+	purged, err = repo.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected only the done request to be purged, purged %d", purged)
+	}
+}
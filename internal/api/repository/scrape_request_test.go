@@ -7,6 +7,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -14,7 +16,7 @@ import (
 	"uniwish.com/internal/testutil"
 )
 
-func TestScrapeRequestRepo_Insert(t *testing.T) {
+func TestScrapeRequestRepo_Enqueue(t *testing.T) {
 	testutil.RequireIntegration(t)
 	tx, err := testDB.BeginTx(context.Background(), nil)
 
@@ -24,10 +26,10 @@ func TestScrapeRequestRepo_Insert(t *testing.T) {
 	defer tx.Rollback()
 
 	repo := NewPostgresScrapeRequestRepository(tx)
-	id, err := repo.Insert(context.Background(), "whatever")
+	id, err := repo.Enqueue(context.Background(), ScrapeRequest{URL: "whatever"})
 
 	if err != nil {
-		t.Fatalf("insert failed: %v", err)
+		t.Fatalf("enqueue failed: %v", err)
 	}
 
 	if id == uuid.Nil {
@@ -35,6 +37,36 @@ func TestScrapeRequestRepo_Insert(t *testing.T) {
 	}
 }
 
+func TestScrapeRequestRepo_EnqueueBatchTooLarge(t *testing.T) {
+	// refused before reaching the database, postgres would reject the statement's parameter count
+	repo := NewPostgresScrapeRequestRepository(nil)
+	_, err := repo.EnqueueBatch(context.Background(), make([]ScrapeRequest, MaxEnqueueBatch+1))
+	if !errors.Is(err, ErrBatchTooLarge) {
+		t.Fatalf("expected ErrBatchTooLarge, received %v", err)
+	}
+}
+
+func TestScrapeRequest_RetriesExhausted(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      ScrapeRequest
+		expected bool
+	}{
+		{name: "first_claim", req: ScrapeRequest{Attempts: 1, MaxAttempts: 5}, expected: false},
+		{name: "last_claim", req: ScrapeRequest{Attempts: 5, MaxAttempts: 5}, expected: false},
+		{name: "beyond_cap", req: ScrapeRequest{Attempts: 6, MaxAttempts: 5}, expected: true},
+		{name: "uncapped", req: ScrapeRequest{Attempts: 6}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if exhausted := tt.req.RetriesExhausted(); exhausted != tt.expected {
+				t.Fatalf("expected %v, received %v", tt.expected, exhausted)
+			}
+		})
+	}
+}
+
 func TestScrapeRequestRepo_PersistsFields(t *testing.T) {
 	testutil.RequireIntegration(t)
 	tx, err := testDB.BeginTx(context.Background(), nil)
@@ -47,10 +79,10 @@ func TestScrapeRequestRepo_PersistsFields(t *testing.T) {
 	expectedUrl := "whatever"
 	ctx := context.Background()
 
-	id, err := repo.Insert(ctx, expectedUrl)
+	id, err := repo.Enqueue(ctx, ScrapeRequest{URL: expectedUrl})
 
 	if err != nil {
-		t.Fatalf("insert failed: %v", err)
+		t.Fatalf("enqueue failed: %v", err)
 	}
 
 	var (
@@ -82,7 +114,7 @@ func TestScrapeRequestRepo_PersistsFields(t *testing.T) {
 	}
 }
 
-func TestScrapeRequestRepo_DequeueEmpty(t *testing.T) {
+func TestScrapeRequestRepo_ClaimEmpty(t *testing.T) {
 	testutil.RequireIntegration(t)
 	tx, err := testDB.BeginTx(context.Background(), nil)
 	if err != nil {
@@ -91,7 +123,7 @@ func TestScrapeRequestRepo_DequeueEmpty(t *testing.T) {
 	defer tx.Rollback()
 
 	repo := NewPostgresScrapeRequestRepository(tx)
-	job, err := repo.Dequeue(context.Background())
+	job, err := repo.Claim(context.Background())
 
 	if err != nil {
 		t.Fatalf("expected err to be nil, received %v", err)
@@ -102,7 +134,7 @@ func TestScrapeRequestRepo_DequeueEmpty(t *testing.T) {
 	}
 }
 
-func TestScrapeRequestRepo_DequeueProcesses(t *testing.T) {
+func TestScrapeRequestRepo_ClaimProcesses(t *testing.T) {
 	testutil.RequireIntegration(t)
 	tx, err := testDB.BeginTx(context.Background(), nil)
 	if err != nil {
@@ -114,13 +146,13 @@ func TestScrapeRequestRepo_DequeueProcesses(t *testing.T) {
 	expectedUrl := "whatever"
 	ctx := context.Background()
 
-	_, err = repo.Insert(ctx, expectedUrl)
+	_, err = repo.Enqueue(ctx, ScrapeRequest{URL: expectedUrl})
 
 	if err != nil {
-		t.Fatalf("insert failed: %v", err)
+		t.Fatalf("enqueue failed: %v", err)
 	}
 
-	job, err := repo.Dequeue(context.Background())
+	job, err := repo.Claim(context.Background())
 	if err != nil {
 		t.Fatalf("expected err to be nil, received %v", err)
 	}
@@ -138,7 +170,7 @@ func TestScrapeRequestRepo_DequeueProcesses(t *testing.T) {
 	}
 }
 
-func TestScrapeRequestRepo_MarkDone(t *testing.T) {
+func TestScrapeRequestRepo_Ack(t *testing.T) {
 	testutil.RequireIntegration(t)
 	tx, err := testDB.BeginTx(context.Background(), nil)
 	if err != nil {
@@ -150,17 +182,17 @@ func TestScrapeRequestRepo_MarkDone(t *testing.T) {
 	expectedUrl := "whatever"
 	ctx := context.Background()
 
-	_, err = repo.Insert(ctx, expectedUrl)
+	_, err = repo.Enqueue(ctx, ScrapeRequest{URL: expectedUrl})
 
 	if err != nil {
-		t.Fatalf("insert failed: %v", err)
+		t.Fatalf("enqueue failed: %v", err)
 	}
 
-	job, err := repo.Dequeue(context.Background())
+	job, err := repo.Claim(context.Background())
 	if err != nil {
-		t.Fatalf("dequeue failed, %v", err)
+		t.Fatalf("claim failed, %v", err)
 	}
-	err = repo.MarkDone(ctx, job.ID)
+	err = repo.Ack(ctx, job.Lease())
 	if err != nil {
 		t.Fatalf("mark done failed, %v", err)
 	}
@@ -177,7 +209,7 @@ func TestScrapeRequestRepo_MarkDone(t *testing.T) {
 	}
 }
 
-func TestScrapeRequestRepo_MarkFailed(t *testing.T) {
+func TestScrapeRequestRepo_Nack(t *testing.T) {
 	testutil.RequireIntegration(t)
 	tx, err := testDB.BeginTx(context.Background(), nil)
 	if err != nil {
@@ -189,29 +221,131 @@ func TestScrapeRequestRepo_MarkFailed(t *testing.T) {
 	expectedUrl := "whatever"
 	ctx := context.Background()
 
-	_, err = repo.Insert(ctx, expectedUrl)
+	_, err = repo.Enqueue(ctx, ScrapeRequest{URL: expectedUrl})
 
 	if err != nil {
-		t.Fatalf("insert failed: %v", err)
+		t.Fatalf("enqueue failed: %v", err)
 	}
 
-	job, err := repo.Dequeue(context.Background())
+	job, err := repo.Claim(context.Background())
 	if err != nil {
-		t.Fatalf("dequeue failed, %v", err)
+		t.Fatalf("claim failed, %v", err)
 	}
-	err = repo.MarkFailed(ctx, job.ID)
+	err = repo.Nack(ctx, job.Lease(), Failure{Kind: "scrape_failed", Message: "timeout"})
 	if err != nil {
 		t.Fatalf("mark failed failed, %v", err)
 	}
-	var status string
+	var status, errorKind, message string
 
 	err = tx.QueryRow(`
-	SELECT status
+	SELECT status, error_kind, error
 	FROM scrape_requests 
 	WHERE id = $1
-	`, job.ID).Scan(&status)
+	`, job.ID).Scan(&status, &errorKind, &message)
 
 	if status != "failed" {
 		t.Fatalf("expected status to be 'failed', received, %s", status)
 	}
+	if errorKind != "scrape_failed" || message != "timeout" {
+		t.Fatalf("expected failure to be recorded, received %q %q", errorKind, message)
+	}
+}
+
+func TestScrapeRequestRepo_ClaimPrioritizesInteractive(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := NewPostgresScrapeRequestRepository(tx)
+	ctx := context.Background()
+
+	_, err = repo.Enqueue(ctx, ScrapeRequest{URL: "bulk", OwnerID: "a", Priority: PriorityBulk})
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	_, err = repo.Enqueue(ctx, ScrapeRequest{URL: "interactive", OwnerID: "a", Priority: PriorityInteractive})
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	job, err := repo.Claim(ctx)
+	if err != nil {
+		t.Fatalf("claim failed, %v", err)
+	}
+
+	if job.URL != "interactive" {
+		t.Fatalf("expected interactive job first, received %s", job.URL)
+	}
+}
+
+func TestScrapeRequestRepo_ClaimRoundRobinsOwners(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := NewPostgresScrapeRequestRepository(tx)
+	ctx := context.Background()
+
+	inserts := []ScrapeRequest{
+		{URL: "a1", OwnerID: "a", Store: "store.com", Priority: PriorityBulk},
+		{URL: "a2", OwnerID: "a", Store: "store.com", Priority: PriorityBulk},
+		{URL: "a3", OwnerID: "a", Store: "store.com", Priority: PriorityBulk},
+		{URL: "b1", OwnerID: "b", Store: "store.com", Priority: PriorityBulk},
+	}
+	for _, req := range inserts {
+		if _, err := repo.Enqueue(ctx, req); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	}
+
+	// rows created in one transaction share created_at, so only assert owners alternate
+	var owners []string
+	for range 2 {
+		job, err := repo.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim failed, %v", err)
+		}
+		owners = append(owners, job.OwnerID)
+	}
+
+	slices.Sort(owners)
+	if !slices.Equal(owners, []string{"a", "b"}) {
+		t.Fatalf("expected one job per owner, received owners %q", owners)
+	}
+}
+
+func TestScrapeRequestRepo_Stats(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := &PostgresScrapeRequestRepository{db: tx, lease: DefaultLease}
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := repo.Enqueue(ctx, ScrapeRequest{URL: "whatever"}); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	}
+	if _, err := repo.Claim(ctx); err != nil {
+		t.Fatalf("claim failed, %v", err)
+	}
+
+	stats, err := repo.Stats(ctx)
+	if err != nil {
+		t.Fatalf("stats failed, %v", err)
+	}
+
+	if stats.Depth["pending"] != 1 || stats.Depth["processing"] != 1 {
+		t.Fatalf("expected 1 pending and 1 processing, received %v", stats.Depth)
+	}
 }
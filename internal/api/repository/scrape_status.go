@@ -0,0 +1,108 @@
+/*
+uniwish.com/interal/api/repository/scrape_status
+
+reads scrape request statuses and listens for the transitions workers publish through postgres NOTIFY
+*/
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ScrapeStatusChannel is the NOTIFY channel workers publish status transitions on
+const ScrapeStatusChannel = "scrape_request_status"
+
+// listenerPingInterval keeps idle listening connections from being dropped unnoticed
+const listenerPingInterval = 90 * time.Second
+
+// ScrapeStatus is a scrape request's status, it is also the NOTIFY payload so must stay well below 8000 bytes
+type ScrapeStatus struct {
+	ScrapeRequestID uuid.UUID `json:"scrape_request_id"`
+	OwnerID         string    `json:"owner_id"`
+	Status          string    `json:"status"`
+	ErrorKind       string    `json:"error_kind,omitempty"`
+	// ProductID is the product a done request scraped
+	ProductID  *uuid.UUID `json:"product_id,omitempty"`
+	OccurredAt time.Time  `json:"occurred_at"`
+}
+
+type ScrapeStatusReader interface {
+	// GetScrapeStatus returns sql.ErrNoRows for unknown requests
+	GetScrapeStatus(ctx context.Context, id uuid.UUID) (*ScrapeStatus, error)
+}
+
+type ScrapeStatusListener interface {
+	// Listen hands every published status to notify until ctx ends, a nil status means the connection
+	// was re-established and statuses published in between were missed
+	Listen(ctx context.Context, notify func(*ScrapeStatus)) error
+}
+
+func (r *PostgresScrapeRequestRepository) GetScrapeStatus(ctx context.Context, id uuid.UUID) (*ScrapeStatus, error) {
+	ctx, span := tracer.Start(ctx, "PostgresScrapeRequestRepository.GetScrapeStatus")
+	defer span.End()
+
+	// requests do not keep the product they scraped, a done one is matched to it by the url it was scraped from
+	status := ScrapeStatus{ScrapeRequestID: id}
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		SELECT
+			sr.owner_id, sr.status, sr.error_kind,
+			COALESCE(sr.finished_at, sr.claimed_at, sr.created_at),
+			CASE WHEN sr.status = 'done' THEN (
+				SELECT pr.product_id FROM product_regions pr WHERE pr.url = sr.url LIMIT 1
+			) END
+		FROM scrape_requests sr
+		WHERE sr.id = $1
+		`, id,
+	).Scan(&status.OwnerID, &status.Status, &status.ErrorKind, &status.OccurredAt, &status.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+type PostgresScrapeStatusListener struct {
+	dsn string
+}
+
+// NewPostgresScrapeStatusListener listens on its own connection to dsn, LISTEN cannot share the pool
+func NewPostgresScrapeStatusListener(dsn string) *PostgresScrapeStatusListener {
+	return &PostgresScrapeStatusListener{dsn: dsn}
+}
+
+func (l *PostgresScrapeStatusListener) Listen(ctx context.Context, notify func(*ScrapeStatus)) error {
+	listener := pq.NewListener(l.dsn, time.Second, time.Minute, nil)
+	defer listener.Close()
+
+	if err := listener.Listen(ScrapeStatusChannel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-listener.Notify:
+			if n == nil {
+				notify(nil)
+				continue
+			}
+			var status ScrapeStatus
+			if err := json.Unmarshal([]byte(n.Extra), &status); err != nil {
+				// a payload this build cannot read is not worth dropping the listener over
+				continue
+			}
+			notify(&status)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
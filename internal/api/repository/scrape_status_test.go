@@ -0,0 +1,98 @@
+/*
+uniwish.com/interal/api/repository/scrape_status_test
+
+testing for reading scrape request statuses and listening for their transitions
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/testutil"
+)
+
+func TestScrapeRequestRepo_GetScrapeStatus(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	repo := &PostgresScrapeRequestRepository{db: tx, lease: DefaultLease}
+	ctx := context.Background()
+
+	pendingID, err := repo.Enqueue(ctx, ScrapeRequest{URL: "http://zara.com/pending", Store: "zara", OwnerID: "user-1"})
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	status, err := repo.GetScrapeStatus(ctx, pendingID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if status.Status != StatusPending || status.OwnerID != "user-1" || status.ProductID != nil {
+		t.Fatalf("expected a pending request without product, received %+v", status)
+	}
+
+	failedID := failRequests(t, repo, "scrape_failed", "zara")[0]
+	status, err = repo.GetScrapeStatus(ctx, failedID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if status.Status != StatusFailed || status.ErrorKind != "scrape_failed" {
+		t.Fatalf("expected the failure read back, received %+v", status)
+	}
+
+	if _, err := repo.GetScrapeStatus(ctx, uuid.New()); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, received %v", err)
+	}
+}
+
+func TestScrapeStatusListener_Listen(t *testing.T) {
+	testutil.RequireIntegration(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	received := make(chan *ScrapeStatus, 1)
+	listener := NewPostgresScrapeStatusListener(os.Getenv("DATABASE_URL"))
+	go listener.Listen(ctx, func(status *ScrapeStatus) {
+		if status == nil {
+			return
+		}
+		select {
+		case received <- status:
+		default:
+		}
+	})
+
+	sent := ScrapeStatus{ScrapeRequestID: uuid.New(), OwnerID: "user-1", Status: StatusDone, OccurredAt: time.Now()}
+	payload, err := json.Marshal(sent)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	// notifications sent before the listener is up are lost, so keep sending until one arrives
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case status := <-received:
+			if status.ScrapeRequestID != sent.ScrapeRequestID || status.Status != StatusDone {
+				t.Fatalf("expected the published status, received %+v", status)
+			}
+			return
+		case <-ticker.C:
+			if _, err := testDB.ExecContext(ctx, `SELECT pg_notify($1, $2)`, ScrapeStatusChannel, string(payload)); err != nil {
+				t.Fatalf("notify failed: %v", err)
+			}
+		case <-ctx.Done():
+			t.Fatal("status never received")
+		}
+	}
+}
@@ -0,0 +1,373 @@
+/*
+uniwish.com/internal/api/repository/webhook
+
+webhook subscriptions of integrators and the log of their deliveries, which the worker sends from an outbox
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type WebhookSubscription struct {
+	ID  uuid.UUID
+	URL string
+	// Secret keys the signature of every delivery
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Status         string
+	// Attempts counts the attempts since the delivery was created or last redelivered
+	Attempts      int
+	NextAttemptAt time.Time
+	// LastStatusCode is nil until a receiver responded
+	LastStatusCode *int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+type WebhookDeliveryAttempt struct {
+	// StatusCode is nil when no response was received
+	StatusCode  *int
+	Error       string
+	Duration    time.Duration
+	AttemptedAt time.Time
+}
+
+// WebhookDeliveryDetail is a delivery with the body it sends and its every attempt, oldest first
+type WebhookDeliveryDetail struct {
+	WebhookDelivery
+	Payload    []byte
+	AttemptLog []WebhookDeliveryAttempt
+}
+
+type WebhookAdmin interface {
+	CreateSubscription(ctx context.Context, subscription WebhookSubscription) error
+	// ListSubscriptions returns every subscription oldest first
+	ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	// DeleteSubscription removes the subscription with its deliveries, sql.ErrNoRows when there is no such subscription
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	// ListDeliveries returns the subscription's deliveries newest first, sql.ErrNoRows when there is no such subscription
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int, offset int) ([]WebhookDelivery, error)
+	// GetDelivery returns sql.ErrNoRows when the subscription has no such delivery
+	GetDelivery(ctx context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) (*WebhookDeliveryDetail, error)
+	// Redeliver pends the delivery again for an immediate attempt with a fresh retry budget,
+	// sql.ErrNoRows when the subscription has no such delivery
+	Redeliver(ctx context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) error
+}
+
+// PendingWebhookDelivery is a claimed delivery with what sending it takes
+type PendingWebhookDelivery struct {
+	ID        uuid.UUID
+	EventID   uuid.UUID
+	EventType string
+	Payload   []byte
+	// Attempts is how many attempts were made before this one
+	Attempts int
+	URL      string
+	Secret   string
+}
+
+type WebhookAttemptResult struct {
+	DeliveryID uuid.UUID
+	// StatusCode is zero when no response was received
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	// Status is the delivery's status after the attempt, pending deliveries are retried at NextAttemptAt
+	Status        string
+	NextAttemptAt time.Time
+}
+
+type WebhookOutbox interface {
+	// ClaimDeliveries leases up to limit due deliveries, other dispatchers skip them until lease runs out
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingWebhookDelivery, error)
+	RecordAttempt(ctx context.Context, result WebhookAttemptResult) error
+}
+
+type PostgresWebhookRepository struct {
+	db DB
+}
+
+func NewPostgresWebhookRepository(db DB) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{db: db}
+}
+
+func (r *PostgresWebhookRepository) CreateSubscription(ctx context.Context, subscription WebhookSubscription) error {
+	ctx, span := tracer.Start(ctx, "PostgresWebhookRepository.CreateSubscription")
+	defer span.End()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO webhook_subscriptions (id, url, secret, event_types, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		`, subscription.ID, subscription.URL, subscription.Secret, pq.Array(subscription.EventTypes), subscription.CreatedAt,
+	)
+	return err
+}
+
+func (r *PostgresWebhookRepository) ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "PostgresWebhookRepository.ListSubscriptions")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT id, url, secret, event_types, created_at
+		FROM webhook_subscriptions
+		ORDER BY created_at, id
+		`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var subscriptions []WebhookSubscription
+
+	for rows.Next() {
+		var s WebhookSubscription
+		if err := rows.Scan(&s.ID, &s.URL, &s.Secret, pq.Array(&s.EventTypes), &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *PostgresWebhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "PostgresWebhookRepository.DeleteSubscription")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresWebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int, offset int) ([]WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "PostgresWebhookRepository.ListDeliveries")
+	defer span.End()
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)`, subscriptionID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT id, subscription_id, event_id, event_type, status, attempts, next_attempt_at,
+			last_status_code, last_error, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+		`, subscriptionID, status, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var deliveries []WebhookDelivery
+
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(
+			&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *PostgresWebhookRepository) GetDelivery(ctx context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) (*WebhookDeliveryDetail, error) {
+	ctx, span := tracer.Start(ctx, "PostgresWebhookRepository.GetDelivery")
+	defer span.End()
+
+	var d WebhookDeliveryDetail
+	err := r.db.QueryRowContext(
+		ctx,
+		`
+		SELECT id, subscription_id, event_id, event_type, status, attempts, next_attempt_at,
+			last_status_code, last_error, created_at, delivered_at, payload
+		FROM webhook_deliveries
+		WHERE id = $1 AND subscription_id = $2
+		`, deliveryID, subscriptionID,
+	).Scan(
+		&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt, &d.Payload,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		SELECT status_code, error, duration_ms, attempted_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempted_at, id
+		`, deliveryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			a          WebhookDeliveryAttempt
+			durationMS int64
+		)
+		if err := rows.Scan(&a.StatusCode, &a.Error, &durationMS, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		a.Duration = time.Duration(durationMS) * time.Millisecond
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *PostgresWebhookRepository) Redeliver(ctx context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "PostgresWebhookRepository.Redeliver")
+	defer span.End()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND subscription_id = $2
+		`, deliveryID, subscriptionID,
+	)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingWebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "PostgresWebhookRepository.ClaimDeliveries")
+	defer span.End()
+
+	// claiming pushes the next attempt back by the lease, so a dispatcher dying mid send is retried once it lapses
+	rows, err := r.db.QueryContext(
+		ctx,
+		`
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + make_interval(secs => $2)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret
+		`, limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var deliveries []PendingWebhookDelivery
+
+	for rows.Next() {
+		var d PendingWebhookDelivery
+		if err := rows.Scan(&d.ID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *PostgresWebhookRepository) RecordAttempt(ctx context.Context, result WebhookAttemptResult) error {
+	ctx, span := tracer.Start(ctx, "PostgresWebhookRepository.RecordAttempt")
+	defer span.End()
+
+	// attempts are stamped with the wall clock, a transaction recording several would otherwise tie them
+	statusCode := sql.NullInt64{Int64: int64(result.StatusCode), Valid: result.StatusCode != 0}
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		WITH attempt AS (
+			INSERT INTO webhook_delivery_attempts (id, delivery_id, status_code, error, duration_ms, attempted_at)
+			VALUES (gen_random_uuid(), $1, $2, $3, $4, clock_timestamp())
+		)
+		UPDATE webhook_deliveries
+		SET status = $5::text,
+			attempts = attempts + 1,
+			next_attempt_at = $6,
+			last_status_code = $2,
+			last_error = $3,
+			delivered_at = CASE WHEN $5::text = 'delivered' THEN now() END
+		WHERE id = $1
+		`, result.DeliveryID, statusCode, result.Error, result.Duration.Milliseconds(), result.Status, result.NextAttemptAt,
+	)
+	return err
+}
@@ -0,0 +1,134 @@
+/*
+uniwish.com/internal/api/repository/webhook_test
+
+testing for webhook subscriptions, their delivery log and the outbox the worker sends from
+*/
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/testutil"
+)
+
+func insertFakeWebhookDelivery(ctx context.Context, db DB, subscriptionID uuid.UUID) (uuid.UUID, error) {
+	id := uuid.New()
+	_, err := db.ExecContext(ctx,
+		`
+		INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, 'price.changed', '{"type": "price.changed"}')
+		`, id, subscriptionID, uuid.New(),
+	)
+	return id, err
+}
+
+func TestWebhookRepository_Subscriptions(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+	ctx := context.Background()
+	repo := NewPostgresWebhookRepository(tx)
+
+	subscription := WebhookSubscription{
+		ID: uuid.New(), URL: "https://hooks.example.com", Secret: "s3cret", EventTypes: []string{"price.changed", "scrape.failed"}, CreatedAt: time.Now(),
+	}
+	if err := repo.CreateSubscription(ctx, subscription); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	subscriptions, err := repo.ListSubscriptions(ctx)
+	if err != nil || len(subscriptions) != 1 {
+		t.Fatalf("expected the subscription listed, received %+v, %v", subscriptions, err)
+	}
+	if subscriptions[0].Secret != "s3cret" || len(subscriptions[0].EventTypes) != 2 {
+		t.Fatalf("expected the subscription read back as stored, received %+v", subscriptions[0])
+	}
+
+	if _, err := insertFakeWebhookDelivery(ctx, tx, subscription.ID); err != nil {
+		t.Fatalf("delivery insert failed: %v", err)
+	}
+	if err := repo.DeleteSubscription(ctx, subscription.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := repo.DeleteSubscription(ctx, subscription.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected a second delete to find nothing, received %v", err)
+	}
+	if _, err := repo.ListDeliveries(ctx, subscription.ID, "", 10, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected deliveries of a deleted subscription to be missing, received %v", err)
+	}
+}
+
+func TestWebhookRepository_Outbox(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+	ctx := context.Background()
+	repo := NewPostgresWebhookRepository(tx)
+
+	subscription := WebhookSubscription{ID: uuid.New(), URL: "https://hooks.example.com", Secret: "s3cret", EventTypes: []string{"price.changed"}, CreatedAt: time.Now()}
+	if err := repo.CreateSubscription(ctx, subscription); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	deliveryID, err := insertFakeWebhookDelivery(ctx, tx, subscription.ID)
+	if err != nil {
+		t.Fatalf("delivery insert failed: %v", err)
+	}
+
+	claimed, err := repo.ClaimDeliveries(ctx, 10, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("expected the delivery claimed, received %+v, %v", claimed, err)
+	}
+	if claimed[0].ID != deliveryID || claimed[0].URL != subscription.URL || claimed[0].Secret != subscription.Secret {
+		t.Fatalf("expected the delivery joined with its subscription, received %+v", claimed[0])
+	}
+	if claimed, err = repo.ClaimDeliveries(ctx, 10, time.Minute); err != nil || len(claimed) != 0 {
+		t.Fatalf("expected a leased delivery to be skipped, received %+v, %v", claimed, err)
+	}
+
+	err = repo.RecordAttempt(ctx, WebhookAttemptResult{
+		DeliveryID: deliveryID, StatusCode: 500, Error: "unexpected status 500", Duration: 20 * time.Millisecond,
+		Status: WebhookDeliveryPending, NextAttemptAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	err = repo.RecordAttempt(ctx, WebhookAttemptResult{
+		DeliveryID: deliveryID, Error: "connection refused", Status: WebhookDeliveryFailed, NextAttemptAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	delivery, err := repo.GetDelivery(ctx, subscription.ID, deliveryID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if delivery.Status != WebhookDeliveryFailed || delivery.Attempts != 2 || delivery.LastStatusCode != nil {
+		t.Fatalf("expected a failed delivery after two attempts, received %+v", delivery.WebhookDelivery)
+	}
+	if len(delivery.AttemptLog) != 2 || *delivery.AttemptLog[0].StatusCode != 500 || delivery.AttemptLog[1].StatusCode != nil {
+		t.Fatalf("expected both attempts logged oldest first, received %+v", delivery.AttemptLog)
+	}
+
+	if err := repo.Redeliver(ctx, subscription.ID, deliveryID); err != nil {
+		t.Fatalf("redeliver failed: %v", err)
+	}
+	if err := repo.Redeliver(ctx, uuid.New(), deliveryID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected another subscription's delivery to be missing, received %v", err)
+	}
+	deliveries, err := repo.ListDeliveries(ctx, subscription.ID, WebhookDeliveryPending, 10, 0)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Attempts != 0 {
+		t.Fatalf("expected the delivery pending again with a fresh budget, received %+v, %v", deliveries, err)
+	}
+}
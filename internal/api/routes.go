@@ -9,27 +9,120 @@ import (
 	"database/sql"
 	"net/http"
 
+	"uniwish.com/internal/api/config"
 	"uniwish.com/internal/api/handlers"
+	"uniwish.com/internal/api/middleware"
+	"uniwish.com/internal/api/openapi"
 	"uniwish.com/internal/api/repository"
 	"uniwish.com/internal/api/services"
+	"uniwish.com/internal/blob"
 	"uniwish.com/internal/scrapers"
 )
 
-func RegisterRoutes(mux *http.ServeMux, db *sql.DB, registry scrapers.Registry) {
-	healthServce := services.NewHealthService()
+// Router is the subset of http.ServeMux routes are registered on, letting tests record them
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// images may be nil when mirroring is disabled, /images then answers 404
+// drain may be nil when readiness need not fail ahead of shutdown
+func RegisterRoutes(mux Router, cfg *config.Config, db *sql.DB, queue repository.Queue, registry scrapers.Registry, images blob.Store, drain *services.Drain) {
+	checks := map[string]services.HealthCheckFunc{
+		"database": services.DatabaseCheck(db),
+		"schema":   services.SchemaCheck(repository.NewPostgresSchemaReader(db), repository.SchemaVersion),
+	}
+	if drain != nil {
+		checks["drain"] = drain.Check
+	}
+	healthServce := services.NewHealthService(checks)
 	healthHandler := handlers.NewHealthHandler(healthServce)
 	mux.Handle("GET /health", healthHandler)
+	mux.Handle("GET /livez", healthHandler)
+	mux.Handle("GET /readyz", handlers.NewReadinessHandler(healthServce))
 
-	scrapeRepo := repository.NewPostgresScrapeRequestRepository(db)
+	scrapeRequestService := newScrapeRequestService(cfg, db, queue, registry)
+	createHandler := handlers.NewCreateItemHandler(scrapeRequestService)
+	createHandler.MaxBodyBytes = int64(cfg.HTTPMaxBodyBytes)
+	batchHandler := handlers.NewBatchScrapeRequestHandler(scrapeRequestService)
+	batchHandler.MaxBodyBytes = int64(cfg.HTTPMaxBodyBytes)
+	var scrapeRequestHandler http.Handler = createHandler
+	var batchScrapeRequestHandler http.Handler = batchHandler
+	if cfg.RateLimitPerMinute > 0 {
+		// scrape requests translate directly into load on the stores, so they are the ones limited
+		limiter := middleware.NewRateLimiter(cfg.RateLimitPerMinute, cfg.RateLimitBurst)
+		scrapeRequestHandler = middleware.RateLimit(limiter)(scrapeRequestHandler)
+		batchScrapeRequestHandler = middleware.RateLimit(limiter)(batchScrapeRequestHandler)
+	}
+	mux.Handle("POST /scrape-requests", scrapeRequestHandler)
+	mux.Handle("POST /scrape-requests/batch", batchScrapeRequestHandler)
 
-	scrapeRequestService := services.NewScrapeRequestService(scrapeRepo, registry)
-	scrapeRequestHandler := handlers.NewCreateItemHandler(scrapeRequestService)
-	mux.Handle("/scrape-requests", scrapeRequestHandler)
-
-	productRepo := repository.NewDefaultProductReader(db)
-	productService := services.NewDefaultProductReaderService(productRepo)
+	productService := newProductService(db)
 	productHandler := handlers.NewDefaultProductHandler(productService)
 	mux.HandleFunc("GET /products", productHandler.ListProducts)
 	mux.HandleFunc("GET /products/{id}", productHandler.GetProduct)
+	productEventService := services.NewDefaultProductEventReaderService(repository.NewPostgresProductEventRepository(db))
+	mux.Handle("GET /products/{id}/events", handlers.NewProductEventHandler(productEventService))
+	mux.Handle("GET /images/{kind}/{name}", handlers.NewImageHandler(images))
+	notificationService := services.NewDefaultNotificationReaderService(repository.NewPostgresNotificationRepository(db))
+	mux.Handle("GET /notifications", handlers.NewNotificationHandler(notificationService))
+
+	scrapeStatusHandler := handlers.NewScrapeStatusHandler(newScrapeStatusService(cfg, db, queue, productService))
+	if drain != nil {
+		// open streams would hold shutdown up, clients reconnect to a replica that is not draining
+		scrapeStatusHandler.Done = drain.Done()
+	}
+	mux.HandleFunc("GET /scrape-requests/events", scrapeStatusHandler.StreamOwner)
+	mux.HandleFunc("GET /scrape-requests/{id}/events", scrapeStatusHandler.StreamScrapeRequest)
+
+	// the admin endpoints manage the postgres queue, config validation keeps them off with the redis backend
+	var queueAdmin services.QueueAdministrator = services.DisabledQueueAdmin{}
+	if cfg.QueueAdmin {
+		queueAdmin = services.NewQueueAdminService(repository.NewPostgresScrapeRequestRepository(db))
+	}
+	queueAdminHandler := handlers.NewQueueAdminHandler(queueAdmin)
+	queueAdminHandler.MaxBodyBytes = int64(cfg.HTTPMaxBodyBytes)
+	adminAuth := middleware.AdminAuth(cfg.AdminToken)
+	mux.Handle("GET /admin/scrape-requests", adminAuth(http.HandlerFunc(queueAdminHandler.ListScrapeRequests)))
+	mux.Handle("POST /admin/scrape-requests/retry", adminAuth(http.HandlerFunc(queueAdminHandler.RetryScrapeRequests)))
+	mux.Handle("POST /admin/scrape-requests/cancel", adminAuth(http.HandlerFunc(queueAdminHandler.CancelScrapeRequests)))
+	mux.Handle("POST /admin/scrape-requests/purge", adminAuth(http.HandlerFunc(queueAdminHandler.PurgeScrapeRequests)))
+
+	webhookAdminHandler := handlers.NewWebhookAdminHandler(services.NewWebhookAdminService(repository.NewPostgresWebhookRepository(db)))
+	webhookAdminHandler.MaxBodyBytes = int64(cfg.HTTPMaxBodyBytes)
+	mux.Handle("GET /admin/webhooks", adminAuth(http.HandlerFunc(webhookAdminHandler.ListWebhooks)))
+	mux.Handle("POST /admin/webhooks", adminAuth(http.HandlerFunc(webhookAdminHandler.CreateWebhook)))
+	mux.Handle("DELETE /admin/webhooks/{id}", adminAuth(http.HandlerFunc(webhookAdminHandler.DeleteWebhook)))
+	mux.Handle("GET /admin/webhooks/{id}/deliveries", adminAuth(http.HandlerFunc(webhookAdminHandler.ListDeliveries)))
+	mux.Handle("GET /admin/webhooks/{id}/deliveries/{delivery_id}", adminAuth(http.HandlerFunc(webhookAdminHandler.GetDelivery)))
+	mux.Handle("POST /admin/webhooks/{id}/deliveries/{delivery_id}/redeliver", adminAuth(http.HandlerFunc(webhookAdminHandler.Redeliver)))
+
+	mux.Handle("GET /openapi.json", openapi.Handler())
+}
+
+// the services below back both the REST routes and the gRPC services, so both apis behave alike
+
+func newScrapeRequestService(cfg *config.Config, db *sql.DB, queue repository.Queue, registry scrapers.Registry) *services.ScrapeRequestService {
+	service := services.NewScrapeRequestService(queue, registry)
+	service.Quota = repository.NewPostgresQuotaRepository(db)
+	service.DailyQuota = cfg.ScrapeRequestDailyQuota
+	service.MaxBatchSize = cfg.ScrapeRequestBatchLimit
+	service.Discontinued = repository.NewPostgresDiscontinuedProductReader(db)
+	service.DiscontinuedGrace = cfg.ProductDiscontinuedGrace
+	return service
+}
+
+func newProductService(db *sql.DB) services.ProductReaderService {
+	return services.NewDefaultProductReaderService(repository.NewDefaultProductReader(db), repository.NewPostgresFXRateRepository(db))
+}
 
+// each scrape status service has its own broker, which listens on a connection of its own once something is watched
+func newScrapeStatusService(cfg *config.Config, db *sql.DB, queue repository.Queue, products services.ProductReaderService) *services.ScrapeStatusService {
+	// statuses are read back from wherever the queue keeps its requests
+	var scrapeStatuses repository.ScrapeStatusReader = repository.NewPostgresScrapeRequestRepository(db)
+	if reader, ok := queue.(repository.ScrapeStatusReader); ok {
+		scrapeStatuses = reader
+	}
+	broker := services.NewScrapeStatusBroker(repository.NewPostgresScrapeStatusListener(cfg.DBURL))
+	return services.NewScrapeStatusService(broker, scrapeStatuses, products)
 }
@@ -0,0 +1,58 @@
+/*
+uniwish.com/interal/api/routes_test
+
+checks every registered route is described by the OpenAPI document and vice versa
+*/
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"uniwish.com/internal/api/config"
+	"uniwish.com/internal/api/openapi"
+)
+
+type recordingRouter struct {
+	patterns []string
+}
+
+func (r *recordingRouter) Handle(pattern string, _ http.Handler) {
+	r.patterns = append(r.patterns, pattern)
+}
+
+func (r *recordingRouter) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	r.patterns = append(r.patterns, pattern)
+}
+
+func TestRegisterRoutes_MatchOpenAPIDocument(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(openapi.Document())
+	if err != nil {
+		t.Fatalf("invalid openapi document: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("invalid openapi document: %v", err)
+	}
+
+	var documented []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	router := &recordingRouter{}
+	RegisterRoutes(router, &config.Config{RateLimitPerMinute: 1, RateLimitBurst: 1}, nil, nil, nil, nil, nil)
+	registered := router.patterns
+
+	sort.Strings(documented)
+	sort.Strings(registered)
+	if strings.Join(documented, "\n") != strings.Join(registered, "\n") {
+		t.Fatalf("routes and openapi document differ\nregistered:\n%s\ndocumented:\n%s",
+			strings.Join(registered, "\n"), strings.Join(documented, "\n"))
+	}
+}
@@ -0,0 +1,109 @@
+/*
+uniwish.com/internal/api/rpc/convert
+
+turns the services' responses into their protobuf messages, field for field with the REST json
+*/
+package rpc
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+	"uniwish.com/internal/api/services"
+)
+
+// timestamp leaves zero times unset, as the json leaves them out
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func productDetailMessage(detail *services.ProductDetailResponse) *uniwishv1.ProductDetail {
+	msg := &uniwishv1.ProductDetail{
+		Product: &uniwishv1.Product{
+			Name:             detail.Product.Name,
+			Store:            detail.Product.Store,
+			ImageUrl:         detail.Product.ImageURL,
+			MirroredImageUrl: detail.Product.MirroredImageURL,
+			ThumbnailUrl:     detail.Product.ThumbnailURL,
+		},
+	}
+	if detail.Product.DiscontinuedAt != nil {
+		msg.Product.DiscontinuedAt = timestamppb.New(*detail.Product.DiscontinuedAt)
+	}
+	for _, offer := range detail.Offers {
+		msg.Offers = append(msg.Offers, &uniwishv1.Offer{
+			Price:           offer.Price,
+			Currency:        offer.Currency,
+			DisplayPrice:    offer.DisplayPrice,
+			DisplayCurrency: offer.DisplayCurrency,
+			Region:          offer.Region,
+			Availability:    offer.Availability,
+			UpdatedAt:       timestamp(offer.UpdatedAt),
+		})
+	}
+	for _, region := range detail.Regions {
+		msg.Regions = append(msg.Regions, &uniwishv1.RegionPrice{
+			Region:          region.Region,
+			Url:             region.URL,
+			Price:           region.Price,
+			Currency:        region.Currency,
+			DisplayPrice:    region.DisplayPrice,
+			DisplayCurrency: region.DisplayCurrency,
+			UpdatedAt:       timestamp(region.UpdatedAt),
+		})
+	}
+	for _, match := range detail.AlsoAvailableAt {
+		msg.AlsoAvailableAt = append(msg.AlsoAvailableAt, &uniwishv1.Match{
+			ProductId:       match.ProductID.String(),
+			Store:           match.Store,
+			Name:            match.Name,
+			Url:             match.URL,
+			Price:           match.Price,
+			Currency:        match.Currency,
+			DisplayPrice:    match.DisplayPrice,
+			DisplayCurrency: match.DisplayCurrency,
+			Confidence:      match.Confidence,
+			Method:          match.Method,
+		})
+	}
+	return msg
+}
+
+func productListMessage(list *services.ProductListResponse) *uniwishv1.ListProductsResponse {
+	msg := &uniwishv1.ListProductsResponse{}
+	for _, product := range list.Products {
+		msg.Products = append(msg.Products, &uniwishv1.ProductListItem{
+			Name:             product.Name,
+			Store:            product.Store,
+			ImageUrl:         product.ImageURL,
+			LastPrice:        product.LastPrice,
+			Currency:         product.Currency,
+			MirroredImageUrl: product.MirroredImageURL,
+			ThumbnailUrl:     product.ThumbnailURL,
+			DisplayPrice:     product.DisplayPrice,
+			DisplayCurrency:  product.DisplayCurrency,
+		})
+	}
+	return msg
+}
+
+func scrapeStatusMessage(resp services.ScrapeStatusResponse) *uniwishv1.ScrapeStatus {
+	msg := &uniwishv1.ScrapeStatus{
+		ScrapeRequestId: resp.ScrapeRequestID.String(),
+		Status:          resp.Status,
+		ErrorKind:       resp.ErrorKind,
+		OccurredAt:      timestamp(resp.OccurredAt),
+	}
+	if resp.ProductID != nil && *resp.ProductID != uuid.Nil {
+		msg.ProductId = resp.ProductID.String()
+	}
+	if resp.Product != nil {
+		msg.Product = productDetailMessage(resp.Product)
+	}
+	return msg
+}
@@ -0,0 +1,77 @@
+/*
+uniwish.com/internal/api/rpc/errors
+
+maps domain errors to gRPC statuses, going through errors.FromError so both apis fail alike
+
+the status carries an ErrorInfo whose reason is the REST envelope's code, and a BadRequest listing
+the rejected fields of invalid input
+*/
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/logging"
+)
+
+// ErrorDomain is the ErrorInfo domain of every status the api returns
+const ErrorDomain = "uniwish.com"
+
+// statusCodes picks the code of an error by the http status its REST response has
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusGone:                  codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.InvalidArgument,
+	http.StatusUnsupportedMediaType:  codes.InvalidArgument,
+	http.StatusUnprocessableEntity:   codes.InvalidArgument,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusNotImplemented:        codes.Unimplemented,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// Error turns err into a status error, logging errors that are not the caller's fault
+func Error(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	apiErr := apiErrors.FromError(err)
+	code, ok := statusCodes[apiErr.Status]
+	if !ok {
+		code = codes.Internal
+	}
+	if code == codes.Internal {
+		logging.FromContext(ctx).Error("grpc call failed", "error", err)
+	}
+
+	st, detailErr := status.New(code, apiErr.Message).WithDetails(&errdetails.ErrorInfo{Reason: apiErr.Code, Domain: ErrorDomain})
+	if detailErr != nil {
+		return status.Error(code, apiErr.Message)
+	}
+	if len(apiErr.Details) == 0 {
+		return st.Err()
+	}
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range apiErr.Details {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Reason:      field.Code,
+			Description: field.Message,
+		})
+	}
+	if withFields, err := st.WithDetails(badRequest); err == nil {
+		st = withFields
+	}
+	return st.Err()
+}
@@ -0,0 +1,76 @@
+/*
+uniwish.com/internal/api/rpc/errors_test
+
+tests mapping domain errors to gRPC statuses
+*/
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apiErrors "uniwish.com/internal/api/errors"
+)
+
+// hasFieldViolation reports whether err's status lists field among its bad request's violations
+func hasFieldViolation(err error, field string) bool {
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				if violation.GetField() == field {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func reason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedCode   codes.Code
+		expectedReason string
+		expectedField  string
+	}{
+		{name: "validation", err: apiErrors.Invalid(apiErrors.FieldError{Field: "url", Code: "required", Message: "url is required"}), expectedCode: codes.InvalidArgument, expectedReason: "invalid_input", expectedField: "url"},
+		{name: "unauthorized", err: apiErrors.ErrUnauthorized, expectedCode: codes.Unauthenticated, expectedReason: "unauthorized"},
+		{name: "not_found", err: fmt.Errorf("get: %w", apiErrors.ErrNoProductFound), expectedCode: codes.NotFound, expectedReason: "not_found"},
+		{name: "discontinued", err: apiErrors.ErrProductDiscontinued, expectedCode: codes.FailedPrecondition, expectedReason: "product_discontinued"},
+		{name: "rate_limited", err: apiErrors.ErrRateLimited, expectedCode: codes.ResourceExhausted, expectedReason: "rate_limited"},
+		{name: "not_implemented", err: apiErrors.ErrQueueAdminUnsupported, expectedCode: codes.Unimplemented, expectedReason: "not_implemented"},
+		{name: "unknown", err: errors.New("boom"), expectedCode: codes.Internal, expectedReason: "internal_error"},
+		{name: "cancelled", err: fmt.Errorf("query: %w", context.Canceled), expectedCode: codes.Canceled},
+		{name: "status", err: status.Error(codes.Aborted, "aborted"), expectedCode: codes.Aborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Error(context.Background(), tt.err)
+			if status.Code(err) != tt.expectedCode {
+				t.Fatalf("expected code %s, received %v", tt.expectedCode, err)
+			}
+			if reason(err) != tt.expectedReason {
+				t.Fatalf("expected reason %q, received %q", tt.expectedReason, reason(err))
+			}
+			if tt.expectedField != "" && !hasFieldViolation(err, tt.expectedField) {
+				t.Fatalf("expected a violation of %s, received %v", tt.expectedField, status.Convert(err).Details())
+			}
+		})
+	}
+}
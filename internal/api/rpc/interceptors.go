@@ -0,0 +1,129 @@
+/*
+uniwish.com/internal/api/rpc/interceptors
+
+the gRPC counterparts of the http middleware: request ids, logging, panic recovery and rate limiting
+
+each call gets a logger carrying its request id, reachable by the services through logging.FromContext
+*/
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/middleware"
+	"uniwish.com/internal/logging"
+)
+
+// metadata keys are lowercase in gRPC, these mirror the REST api's X-User-ID and X-Request-ID headers
+const (
+	UserIDKey    = "x-user-id"
+	RequestIDKey = "x-request-id"
+)
+
+// incoming returns the first value of key in the call's metadata
+func incoming(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ownerID identifies the calling user, empty for anonymous calls
+func ownerID(ctx context.Context) string {
+	return incoming(ctx, UserIDKey)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// begin sets up a call's context, its request id is echoed back in the response header
+func begin(ctx context.Context, logger *slog.Logger) (context.Context, *slog.Logger) {
+	ctx, id := middleware.WithRequestID(ctx, incoming(ctx, RequestIDKey))
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+	callLogger := logger.With("request_id", id)
+	return logging.WithLogger(ctx, callLogger), callLogger
+}
+
+// finish logs the call once handled, turning a panic into an internal error
+func finish(ctx context.Context, logger *slog.Logger, method string, start time.Time, rec any, err *error) {
+	if rec != nil {
+		logger.Error("panic recovered", "panic", rec, "stack", string(debug.Stack()))
+		*err = status.Error(codes.Internal, apiErrors.ErrInternal.Message)
+	}
+	logger.Info(
+		"grpc request",
+		"method", method,
+		"code", status.Code(*err).String(),
+		"client_ip", peerIP(ctx),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
+
+func UnaryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		ctx, callLogger := begin(ctx, logger)
+		start := time.Now()
+		defer func() { finish(ctx, callLogger, info.FullMethod, start, recover(), &err) }()
+		return handler(ctx, req)
+	}
+}
+
+// contextStream swaps the context of a stream for the one begin set up
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func StreamInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, callLogger := begin(ss.Context(), logger)
+		start := time.Now()
+		defer func() { finish(ctx, callLogger, info.FullMethod, start, recover(), &err) }()
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// RateLimit limits the calls to methods per client IP, the other methods pass through.
+// Rejected calls carry a retry-after header in seconds
+func RateLimit(limiter *middleware.RateLimiter, methods ...string) grpc.UnaryServerInterceptor {
+	limited := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		limited[method] = true
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !limited[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		result := limiter.Allow("ip:" + peerIP(ctx))
+		if !result.Allowed {
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))))
+			return nil, Error(ctx, apiErrors.ErrRateLimited)
+		}
+		return handler(ctx, req)
+	}
+}
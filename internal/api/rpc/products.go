@@ -0,0 +1,44 @@
+/*
+uniwish.com/internal/api/rpc/products
+
+the ProductService, reading products through the same service as GET /products and GET /products/{id}
+*/
+package rpc
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+	"uniwish.com/internal/api/services"
+)
+
+type ProductServer struct {
+	uniwishv1.UnimplementedProductServiceServer
+	products services.ProductReaderService
+}
+
+func NewProductServer(products services.ProductReaderService) *ProductServer {
+	return &ProductServer{products: products}
+}
+
+func (s *ProductServer) GetProduct(ctx context.Context, req *uniwishv1.GetProductRequest) (*uniwishv1.ProductDetail, error) {
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, Error(ctx, apiErrors.Invalid(apiErrors.FieldError{Field: "id", Code: "invalid", Message: "id must be a uuid"}))
+	}
+	product, err := s.products.Get(ctx, id, req.GetDisplayCurrency())
+	if err != nil {
+		return nil, Error(ctx, err)
+	}
+	return productDetailMessage(product), nil
+}
+
+func (s *ProductServer) ListProducts(ctx context.Context, req *uniwishv1.ListProductsRequest) (*uniwishv1.ListProductsResponse, error) {
+	list, err := s.products.List(ctx, req.GetDisplayCurrency())
+	if err != nil {
+		return nil, Error(ctx, err)
+	}
+	return productListMessage(list), nil
+}
@@ -0,0 +1,128 @@
+/*
+uniwish.com/internal/api/rpc/products_test
+
+tests reading products over gRPC
+*/
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+	"uniwish.com/internal/api/services"
+)
+
+type FakeProductReaderService struct {
+	detail *services.ProductDetailResponse
+	list   *services.ProductListResponse
+	err    error
+	panics bool
+	// displayCurrency records what the server passed on
+	displayCurrency string
+}
+
+func (s *FakeProductReaderService) Get(_ context.Context, _ uuid.UUID, displayCurrency string) (*services.ProductDetailResponse, error) {
+	s.displayCurrency = displayCurrency
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.detail, nil
+}
+
+func (s *FakeProductReaderService) List(_ context.Context, displayCurrency string) (*services.ProductListResponse, error) {
+	if s.panics {
+		panic("boom")
+	}
+	s.displayCurrency = displayCurrency
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.list == nil {
+		return &services.ProductListResponse{}, nil
+	}
+	return s.list, nil
+}
+
+func TestProductServer_GetProduct(t *testing.T) {
+	displayPrice := 27.5
+	updatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	matchID := uuid.New()
+	detail := &services.ProductDetailResponse{
+		Product: services.ProductItemResponse{Name: "jacket", Store: "zara.com", ImageURL: "https://static.zara.net/jacket.jpg"},
+		Offers: []services.OfferListResponse{
+			{Price: 29.95, Currency: "GBP", DisplayPrice: &displayPrice, DisplayCurrency: "EUR", Region: "gb", Availability: "in_stock", UpdatedAt: updatedAt},
+		},
+		Regions: []services.RegionPriceResponse{
+			{Region: "gb", URL: "https://www.zara.com/uk/jacket", Price: 29.95, Currency: "GBP", UpdatedAt: updatedAt},
+		},
+		AlsoAvailableAt: []services.MatchResponse{
+			{ProductID: matchID, Store: "hm.com", Name: "jacket", Price: 25, Currency: "EUR", Confidence: 0.9, Method: "gtin"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		id           string
+		service      *FakeProductReaderService
+		expectedCode codes.Code
+	}{
+		{name: "found", id: uuid.NewString(), service: &FakeProductReaderService{detail: detail}, expectedCode: codes.OK},
+		{name: "invalid_id", id: "abc", service: &FakeProductReaderService{}, expectedCode: codes.InvalidArgument},
+		{name: "not_found", id: uuid.NewString(), service: &FakeProductReaderService{err: apiErrors.ErrNoProductFound}, expectedCode: codes.NotFound},
+		{name: "invalid_currency", id: uuid.NewString(), service: &FakeProductReaderService{err: apiErrors.Invalid(apiErrors.FieldError{Field: "currency", Code: "invalid", Message: "currency is unknown"})}, expectedCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newTestServer(t, nil, nil, NewProductServer(tt.service))
+			product, err := uniwishv1.NewProductServiceClient(conn).GetProduct(context.Background(), &uniwishv1.GetProductRequest{Id: tt.id, DisplayCurrency: "EUR"})
+			if status.Code(err) != tt.expectedCode {
+				t.Fatalf("expected code %s, received %v", tt.expectedCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if tt.service.displayCurrency != "EUR" {
+				t.Fatalf("expected prices in EUR, received %q", tt.service.displayCurrency)
+			}
+			if product.GetProduct().GetName() != "jacket" || product.GetProduct().GetDiscontinuedAt() != nil {
+				t.Fatalf("unexpected product %+v", product.GetProduct())
+			}
+			offer := product.GetOffers()[0]
+			if offer.DisplayPrice == nil || offer.GetDisplayPrice() != 27.5 || !offer.GetUpdatedAt().AsTime().Equal(updatedAt) {
+				t.Fatalf("unexpected offer %+v", offer)
+			}
+			if region := product.GetRegions()[0]; region.DisplayPrice != nil || region.GetUrl() != "https://www.zara.com/uk/jacket" {
+				t.Fatalf("unexpected region %+v", region)
+			}
+			if match := product.GetAlsoAvailableAt()[0]; match.GetProductId() != matchID.String() || match.GetMethod() != "gtin" {
+				t.Fatalf("unexpected match %+v", match)
+			}
+		})
+	}
+}
+
+func TestProductServer_ListProducts(t *testing.T) {
+	service := &FakeProductReaderService{list: &services.ProductListResponse{Products: []services.ProductListItemResponse{
+		{Name: "jacket", Store: "zara.com", LastPrice: 29.95, Currency: "GBP"},
+		{Name: "coat", Store: "hm.com", LastPrice: 59, Currency: "EUR"},
+	}}}
+	conn := newTestServer(t, nil, nil, NewProductServer(service))
+
+	resp, err := uniwishv1.NewProductServiceClient(conn).ListProducts(context.Background(), &uniwishv1.ListProductsRequest{DisplayCurrency: "USD"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(resp.GetProducts()) != 2 || resp.GetProducts()[1].GetName() != "coat" || resp.GetProducts()[0].GetLastPrice() != 29.95 {
+		t.Fatalf("unexpected products %+v", resp.GetProducts())
+	}
+	if service.displayCurrency != "USD" {
+		t.Fatalf("expected prices in USD, received %q", service.displayCurrency)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+uniwish.com/internal/api/rpc/scrape_requests
+
+the ScrapeRequestService, submitting scrape requests and streaming their statuses through the same
+services as POST /scrape-requests and GET /scrape-requests/{id}/events
+*/
+package rpc
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+	"uniwish.com/internal/api/services"
+)
+
+type ScrapeRequestServer struct {
+	uniwishv1.UnimplementedScrapeRequestServiceServer
+	requester services.ScrapeRequester
+	statuses  services.ScrapeStatusStreamer
+	// Done ends every open watch once closed, nil leaves them to their clients
+	Done <-chan struct{}
+}
+
+func NewScrapeRequestServer(requester services.ScrapeRequester, statuses services.ScrapeStatusStreamer) *ScrapeRequestServer {
+	return &ScrapeRequestServer{requester: requester, statuses: statuses}
+}
+
+func (s *ScrapeRequestServer) CreateScrapeRequest(ctx context.Context, req *uniwishv1.CreateScrapeRequestRequest) (*uniwishv1.CreateScrapeRequestResponse, error) {
+	id, err := s.requester.Request(ctx, services.ScrapeRequestInput{
+		URL:      req.GetUrl(),
+		OwnerID:  ownerID(ctx),
+		ClientIP: peerIP(ctx),
+		Priority: req.GetPriority(),
+	})
+	if err != nil {
+		return nil, Error(ctx, err)
+	}
+	return &uniwishv1.CreateScrapeRequestResponse{Id: id.String(), Status: repository.StatusPending}, nil
+}
+
+func (s *ScrapeRequestServer) WatchScrapeRequest(req *uniwishv1.WatchScrapeRequestRequest, stream grpc.ServerStreamingServer[uniwishv1.ScrapeStatus]) error {
+	ctx := stream.Context()
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return Error(ctx, apiErrors.Invalid(apiErrors.FieldError{Field: "id", Code: "invalid", Message: "id must be a uuid"}))
+	}
+
+	current, statuses, cancel, err := s.statuses.WatchScrapeRequest(ctx, id, ownerID(ctx))
+	if err != nil {
+		return Error(ctx, err)
+	}
+	defer cancel()
+
+	send := func(status repository.ScrapeStatus) (bool, error) {
+		resp := s.statuses.Describe(ctx, status, req.GetDisplayCurrency())
+		return resp.Final(), stream.Send(scrapeStatusMessage(resp))
+	}
+	if current != nil {
+		if final, err := send(*current); final || err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return Error(ctx, ctx.Err())
+		case <-s.Done:
+			// the client watches again, reaching a replica that is not draining
+			return Error(ctx, apiErrors.ErrNotReady)
+		case status, ok := <-statuses:
+			if !ok {
+				// the watcher was dropped, watching again starts over from the current status
+				return Error(ctx, apiErrors.ErrNotReady)
+			}
+			if final, err := send(status); final || err != nil {
+				return err
+			}
+		}
+	}
+}
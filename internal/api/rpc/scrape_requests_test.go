@@ -0,0 +1,233 @@
+/*
+uniwish.com/internal/api/rpc/scrape_requests_test
+
+tests submitting and watching scrape requests over gRPC
+*/
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+	"uniwish.com/internal/api/services"
+)
+
+type FakeScrapeRequester struct {
+	id    uuid.UUID
+	err   error
+	input services.ScrapeRequestInput
+}
+
+func (r *FakeScrapeRequester) Request(_ context.Context, input services.ScrapeRequestInput) (uuid.UUID, error) {
+	r.input = input
+	return r.id, r.err
+}
+
+type FakeScrapeStatusStreamer struct {
+	current   *repository.ScrapeStatus
+	statuses  chan repository.ScrapeStatus
+	err       error
+	cancelled bool
+	// ownerID and displayCurrency record what the server passed on
+	ownerID         string
+	displayCurrency string
+}
+
+func (s *FakeScrapeStatusStreamer) WatchScrapeRequest(_ context.Context, _ uuid.UUID, ownerID string) (*repository.ScrapeStatus, <-chan repository.ScrapeStatus, func(), error) {
+	s.ownerID = ownerID
+	if s.err != nil {
+		return nil, nil, nil, s.err
+	}
+	return s.current, s.statuses, func() { s.cancelled = true }, nil
+}
+
+func (s *FakeScrapeStatusStreamer) WatchOwner(context.Context, string) (<-chan repository.ScrapeStatus, func(), error) {
+	return s.statuses, func() { s.cancelled = true }, nil
+}
+
+func (s *FakeScrapeStatusStreamer) Describe(_ context.Context, status repository.ScrapeStatus, displayCurrency string) services.ScrapeStatusResponse {
+	s.displayCurrency = displayCurrency
+	resp := services.ScrapeStatusResponse{ScrapeRequestID: status.ScrapeRequestID, Status: status.Status, ProductID: status.ProductID}
+	if status.ProductID != nil {
+		resp.Product = &services.ProductDetailResponse{Product: services.ProductItemResponse{Name: "jacket", Store: "zara.com"}}
+	}
+	return resp
+}
+
+// fakeStatuses returns a channel already holding statuses, closed as a dropped watcher's when closed is set
+func fakeStatuses(closed bool, statuses ...string) chan repository.ScrapeStatus {
+	c := make(chan repository.ScrapeStatus, len(statuses))
+	for _, status := range statuses {
+		c <- repository.ScrapeStatus{ScrapeRequestID: uuid.New(), Status: status}
+	}
+	if closed {
+		close(c)
+	}
+	return c
+}
+
+func TestScrapeRequestServer_CreateScrapeRequest(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name          string
+		requester     *FakeScrapeRequester
+		expectedCode  codes.Code
+		expectedField string
+	}{
+		{name: "created", requester: &FakeScrapeRequester{id: id}, expectedCode: codes.OK},
+		{name: "invalid_url", requester: &FakeScrapeRequester{err: apiErrors.Invalid(apiErrors.FieldError{Field: "url", Code: "invalid", Message: "url is invalid"})}, expectedCode: codes.InvalidArgument, expectedField: "url"},
+		{name: "unsupported_store", requester: &FakeScrapeRequester{err: apiErrors.ErrStoreUnsupported}, expectedCode: codes.InvalidArgument},
+		{name: "quota_exceeded", requester: &FakeScrapeRequester{err: apiErrors.ErrQuotaExceeded}, expectedCode: codes.ResourceExhausted},
+		{name: "discontinued", requester: &FakeScrapeRequester{err: apiErrors.ErrProductDiscontinued}, expectedCode: codes.FailedPrecondition},
+		{name: "queue_down", requester: &FakeScrapeRequester{err: errors.New("connection refused")}, expectedCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newTestServer(t, nil, NewScrapeRequestServer(tt.requester, &FakeScrapeStatusStreamer{}), nil)
+			ctx := metadata.AppendToOutgoingContext(context.Background(), UserIDKey, "user-1")
+			resp, err := uniwishv1.NewScrapeRequestServiceClient(conn).CreateScrapeRequest(ctx, &uniwishv1.CreateScrapeRequestRequest{
+				Url:      "https://www.zara.com/item",
+				Priority: services.PriorityBulk,
+			})
+
+			if status.Code(err) != tt.expectedCode {
+				t.Fatalf("expected code %s, received %v", tt.expectedCode, err)
+			}
+			if tt.expectedField != "" && !hasFieldViolation(err, tt.expectedField) {
+				t.Fatalf("expected a violation of %s, received %v", tt.expectedField, status.Convert(err).Details())
+			}
+			if err != nil {
+				return
+			}
+			if resp.GetId() != id.String() || resp.GetStatus() != repository.StatusPending {
+				t.Fatalf("unexpected response %+v", resp)
+			}
+			input := tt.requester.input
+			if input.URL != "https://www.zara.com/item" || input.OwnerID != "user-1" || input.Priority != services.PriorityBulk {
+				t.Fatalf("unexpected input %+v", input)
+			}
+		})
+	}
+}
+
+func TestScrapeRequestServer_WatchScrapeRequest(t *testing.T) {
+	productID := uuid.New()
+	tests := []struct {
+		name             string
+		id               string
+		streamer         *FakeScrapeStatusStreamer
+		expectedCode     codes.Code
+		expectedStatuses []string
+	}{
+		{
+			name: "ends_once_final", id: uuid.NewString(),
+			streamer: &FakeScrapeStatusStreamer{
+				current:  &repository.ScrapeStatus{Status: repository.StatusPending},
+				statuses: fakeStatuses(false, repository.StatusProcessing, repository.StatusFailed, repository.StatusProcessing),
+			},
+			expectedCode: codes.OK, expectedStatuses: []string{"pending", "processing", "failed"},
+		},
+		{
+			name: "already_final", id: uuid.NewString(),
+			streamer:     &FakeScrapeStatusStreamer{current: &repository.ScrapeStatus{Status: repository.StatusCancelled}, statuses: fakeStatuses(false)},
+			expectedCode: codes.OK, expectedStatuses: []string{"cancelled"},
+		},
+		{
+			name: "watcher_dropped", id: uuid.NewString(),
+			streamer:     &FakeScrapeStatusStreamer{statuses: fakeStatuses(true, repository.StatusProcessing)},
+			expectedCode: codes.Unavailable, expectedStatuses: []string{"processing"},
+		},
+		{name: "invalid_id", id: "abc", streamer: &FakeScrapeStatusStreamer{}, expectedCode: codes.InvalidArgument},
+		{name: "not_found", id: uuid.NewString(), streamer: &FakeScrapeStatusStreamer{err: apiErrors.ErrScrapeRequestNotFound}, expectedCode: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newTestServer(t, nil, NewScrapeRequestServer(&FakeScrapeRequester{}, tt.streamer), nil)
+			ctx := metadata.AppendToOutgoingContext(context.Background(), UserIDKey, "user-1")
+			stream, err := uniwishv1.NewScrapeRequestServiceClient(conn).WatchScrapeRequest(ctx, &uniwishv1.WatchScrapeRequestRequest{Id: tt.id})
+			if err != nil {
+				t.Fatalf("watch failed: %v", err)
+			}
+
+			var statuses []string
+			for {
+				msg, err := stream.Recv()
+				if err == io.EOF {
+					err = nil
+				}
+				if msg == nil {
+					if status.Code(err) != tt.expectedCode {
+						t.Fatalf("expected code %s, received %v", tt.expectedCode, err)
+					}
+					break
+				}
+				statuses = append(statuses, msg.GetStatus())
+			}
+			if strings.Join(statuses, ",") != strings.Join(tt.expectedStatuses, ",") {
+				t.Fatalf("expected %q, received %q", tt.expectedStatuses, statuses)
+			}
+			if tt.expectedCode == codes.InvalidArgument {
+				return
+			}
+			if tt.streamer.ownerID != "user-1" {
+				t.Fatalf("expected the caller's user, received %q", tt.streamer.ownerID)
+			}
+			if tt.expectedCode != codes.NotFound && !tt.streamer.cancelled {
+				t.Fatal("expected the watch cancelled once the stream ended")
+			}
+		})
+	}
+
+	t.Run("done_carries_product", func(t *testing.T) {
+		streamer := &FakeScrapeStatusStreamer{current: &repository.ScrapeStatus{ScrapeRequestID: uuid.New(), Status: repository.StatusDone, ProductID: &productID}}
+		conn := newTestServer(t, nil, NewScrapeRequestServer(&FakeScrapeRequester{}, streamer), nil)
+		stream, err := uniwishv1.NewScrapeRequestServiceClient(conn).WatchScrapeRequest(context.Background(), &uniwishv1.WatchScrapeRequestRequest{Id: uuid.NewString(), DisplayCurrency: "EUR"})
+		if err != nil {
+			t.Fatalf("watch failed: %v", err)
+		}
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("receive failed: %v", err)
+		}
+		if msg.GetProductId() != productID.String() || msg.GetProduct().GetProduct().GetName() != "jacket" {
+			t.Fatalf("expected the done request's product, received %+v", msg)
+		}
+		if streamer.displayCurrency != "EUR" {
+			t.Fatalf("expected the product priced in EUR, received %q", streamer.displayCurrency)
+		}
+		if _, err := stream.Recv(); err != io.EOF {
+			t.Fatalf("expected the stream ended once final, received %v", err)
+		}
+	})
+}
+
+func TestScrapeRequestServer_WatchEndsOnDone(t *testing.T) {
+	done := make(chan struct{})
+	server := NewScrapeRequestServer(&FakeScrapeRequester{}, &FakeScrapeStatusStreamer{statuses: fakeStatuses(false, repository.StatusProcessing)})
+	server.Done = done
+	conn := newTestServer(t, nil, server, nil)
+
+	stream, err := uniwishv1.NewScrapeRequestServiceClient(conn).WatchScrapeRequest(context.Background(), &uniwishv1.WatchScrapeRequestRequest{Id: uuid.NewString()})
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("receive failed: %v", err)
+	}
+	close(done)
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable once draining, received %v", err)
+	}
+}
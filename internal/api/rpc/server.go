@@ -0,0 +1,31 @@
+/*
+uniwish.com/internal/api/rpc/server
+
+contains the gRPC api served alongside the REST one, its messages and service stubs are generated from
+uniwishv1/uniwish.proto by protoc-gen-go and protoc-gen-go-grpc, go generate regenerates them
+*/
+package rpc
+
+//go:generate protoc -I uniwishv1 --go_out=uniwishv1 --go_opt=paths=source_relative --go-grpc_out=uniwishv1 --go-grpc_opt=paths=source_relative uniwish.proto
+
+import (
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"uniwish.com/internal/api/middleware"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+)
+
+// NewServer returns a server logging and recovering every call, with reflection registered so tools like grpcurl
+// can list its services. limiter limits CreateScrapeRequest when set, as the REST api limits POST /scrape-requests
+func NewServer(logger *slog.Logger, limiter *middleware.RateLimiter, opts ...grpc.ServerOption) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{UnaryInterceptor(logger)}
+	if limiter != nil {
+		unary = append(unary, RateLimit(limiter, uniwishv1.ScrapeRequestService_CreateScrapeRequest_FullMethodName))
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(StreamInterceptor(logger)))
+	server := grpc.NewServer(opts...)
+	reflection.Register(server)
+	return server
+}
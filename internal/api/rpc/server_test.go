@@ -0,0 +1,151 @@
+/*
+uniwish.com/internal/api/rpc/server_test
+
+tests the server's interceptors and reflection over an in-memory bufconn connection
+*/
+package rpc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"uniwish.com/internal/api/middleware"
+	"uniwish.com/internal/api/rpc/uniwishv1"
+)
+
+// dial serves server over bufconn, returning a client connection to it closed with the test
+func dial(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newTestServer registers the api's services backed by fakes, nil fakes are replaced by empty ones
+func newTestServer(t *testing.T, limiter *middleware.RateLimiter, scrapes *ScrapeRequestServer, products *ProductServer) *grpc.ClientConn {
+	t.Helper()
+	if scrapes == nil {
+		scrapes = NewScrapeRequestServer(&FakeScrapeRequester{}, &FakeScrapeStatusStreamer{})
+	}
+	if products == nil {
+		products = NewProductServer(&FakeProductReaderService{})
+	}
+	server := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil)), limiter)
+	uniwishv1.RegisterScrapeRequestServiceServer(server, scrapes)
+	uniwishv1.RegisterProductServiceServer(server, products)
+	return dial(t, server)
+}
+
+func TestNewServer_Reflection(t *testing.T) {
+	conn := newTestServer(t, nil, nil, nil)
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("reflection failed: %v", err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("reflection request failed: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("reflection response failed: %v", err)
+	}
+
+	var names []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		names = append(names, service.GetName())
+	}
+	sort.Strings(names)
+	expected := "grpc.reflection.v1.ServerReflection,grpc.reflection.v1alpha.ServerReflection,uniwish.v1.ProductService,uniwish.v1.ScrapeRequestService"
+	if strings.Join(names, ",") != expected {
+		t.Fatalf("expected services %s, received %s", expected, strings.Join(names, ","))
+	}
+}
+
+func TestUnaryInterceptor_RequestID(t *testing.T) {
+	client := uniwishv1.NewProductServiceClient(newTestServer(t, nil, nil, nil))
+
+	tests := []struct {
+		name      string
+		requestID string
+		echoed    bool
+	}{
+		{name: "propagated", requestID: "abc-123", echoed: true},
+		{name: "generated"},
+		{name: "invalid", requestID: "has spaces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, RequestIDKey, tt.requestID)
+			}
+			var header metadata.MD
+			if _, err := client.ListProducts(ctx, &uniwishv1.ListProductsRequest{}, grpc.Header(&header)); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			ids := header.Get(RequestIDKey)
+			if len(ids) != 1 || ids[0] == "" {
+				t.Fatalf("expected a request id, received %v", ids)
+			}
+			if (ids[0] == tt.requestID) != tt.echoed {
+				t.Fatalf("expected echoed %v, received %q", tt.echoed, ids[0])
+			}
+		})
+	}
+}
+
+func TestUnaryInterceptor_RecoversPanics(t *testing.T) {
+	conn := newTestServer(t, nil, nil, NewProductServer(&FakeProductReaderService{panics: true}))
+	_, err := uniwishv1.NewProductServiceClient(conn).ListProducts(context.Background(), &uniwishv1.ListProductsRequest{})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, received %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	conn := newTestServer(t, middleware.NewRateLimiter(1, 1), nil, nil)
+	scrapes := uniwishv1.NewScrapeRequestServiceClient(conn)
+	products := uniwishv1.NewProductServiceClient(conn)
+	req := &uniwishv1.CreateScrapeRequestRequest{Url: "https://www.zara.com/item"}
+
+	if _, err := scrapes.CreateScrapeRequest(context.Background(), req); err != nil {
+		t.Fatalf("expected the first request allowed, received %v", err)
+	}
+	var header metadata.MD
+	_, err := scrapes.CreateScrapeRequest(context.Background(), req, grpc.Header(&header))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, received %v", err)
+	}
+	if retryAfter := header.Get("retry-after"); len(retryAfter) != 1 || retryAfter[0] == "0" {
+		t.Fatalf("expected a retry-after header, received %v", retryAfter)
+	}
+	// only scrape requests are limited
+	if _, err := products.ListProducts(context.Background(), &uniwishv1.ListProductsRequest{}); err != nil {
+		t.Fatalf("expected products not limited, received %v", err)
+	}
+}
@@ -0,0 +1,1156 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: uniwish.proto
+
+package uniwishv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CreateScrapeRequestRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Url   string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// priority is either "interactive" or "bulk", defaults to "interactive"
+	Priority      string `protobuf:"bytes,2,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateScrapeRequestRequest) Reset() {
+	*x = CreateScrapeRequestRequest{}
+	mi := &file_uniwish_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateScrapeRequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateScrapeRequestRequest) ProtoMessage() {}
+
+func (x *CreateScrapeRequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateScrapeRequestRequest.ProtoReflect.Descriptor instead.
+func (*CreateScrapeRequestRequest) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateScrapeRequestRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CreateScrapeRequestRequest) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+type CreateScrapeRequestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateScrapeRequestResponse) Reset() {
+	*x = CreateScrapeRequestResponse{}
+	mi := &file_uniwish_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateScrapeRequestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateScrapeRequestResponse) ProtoMessage() {}
+
+func (x *CreateScrapeRequestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateScrapeRequestResponse.ProtoReflect.Descriptor instead.
+func (*CreateScrapeRequestResponse) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateScrapeRequestResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CreateScrapeRequestResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type WatchScrapeRequestRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// display_currency is an ISO 4217 code the product of a done request is also priced in
+	DisplayCurrency string `protobuf:"bytes,2,opt,name=display_currency,json=displayCurrency,proto3" json:"display_currency,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WatchScrapeRequestRequest) Reset() {
+	*x = WatchScrapeRequestRequest{}
+	mi := &file_uniwish_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchScrapeRequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchScrapeRequestRequest) ProtoMessage() {}
+
+func (x *WatchScrapeRequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchScrapeRequestRequest.ProtoReflect.Descriptor instead.
+func (*WatchScrapeRequestRequest) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *WatchScrapeRequestRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *WatchScrapeRequestRequest) GetDisplayCurrency() string {
+	if x != nil {
+		return x.DisplayCurrency
+	}
+	return ""
+}
+
+type ScrapeStatus struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ScrapeRequestId string                 `protobuf:"bytes,1,opt,name=scrape_request_id,json=scrapeRequestId,proto3" json:"scrape_request_id,omitempty"`
+	// status is one of pending, processing, done, failed or cancelled
+	Status    string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	ErrorKind string `protobuf:"bytes,3,opt,name=error_kind,json=errorKind,proto3" json:"error_kind,omitempty"`
+	ProductId string `protobuf:"bytes,4,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	// product is the product a done request scraped, unset when it cannot be read
+	Product       *ProductDetail         `protobuf:"bytes,5,opt,name=product,proto3" json:"product,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScrapeStatus) Reset() {
+	*x = ScrapeStatus{}
+	mi := &file_uniwish_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrapeStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrapeStatus) ProtoMessage() {}
+
+func (x *ScrapeStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrapeStatus.ProtoReflect.Descriptor instead.
+func (*ScrapeStatus) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ScrapeStatus) GetScrapeRequestId() string {
+	if x != nil {
+		return x.ScrapeRequestId
+	}
+	return ""
+}
+
+func (x *ScrapeStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ScrapeStatus) GetErrorKind() string {
+	if x != nil {
+		return x.ErrorKind
+	}
+	return ""
+}
+
+func (x *ScrapeStatus) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *ScrapeStatus) GetProduct() *ProductDetail {
+	if x != nil {
+		return x.Product
+	}
+	return nil
+}
+
+func (x *ScrapeStatus) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+type GetProductRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// display_currency is an ISO 4217 code prices are also converted to, empty for none
+	DisplayCurrency string `protobuf:"bytes,2,opt,name=display_currency,json=displayCurrency,proto3" json:"display_currency,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetProductRequest) Reset() {
+	*x = GetProductRequest{}
+	mi := &file_uniwish_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProductRequest) ProtoMessage() {}
+
+func (x *GetProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProductRequest.ProtoReflect.Descriptor instead.
+func (*GetProductRequest) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetProductRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetProductRequest) GetDisplayCurrency() string {
+	if x != nil {
+		return x.DisplayCurrency
+	}
+	return ""
+}
+
+type ListProductsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// display_currency is an ISO 4217 code prices are also converted to, empty for none
+	DisplayCurrency string `protobuf:"bytes,1,opt,name=display_currency,json=displayCurrency,proto3" json:"display_currency,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ListProductsRequest) Reset() {
+	*x = ListProductsRequest{}
+	mi := &file_uniwish_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProductsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProductsRequest) ProtoMessage() {}
+
+func (x *ListProductsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProductsRequest.ProtoReflect.Descriptor instead.
+func (*ListProductsRequest) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListProductsRequest) GetDisplayCurrency() string {
+	if x != nil {
+		return x.DisplayCurrency
+	}
+	return ""
+}
+
+type ListProductsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Products      []*ProductListItem     `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProductsResponse) Reset() {
+	*x = ListProductsResponse{}
+	mi := &file_uniwish_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProductsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProductsResponse) ProtoMessage() {}
+
+func (x *ListProductsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProductsResponse.ProtoReflect.Descriptor instead.
+func (*ListProductsResponse) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListProductsResponse) GetProducts() []*ProductListItem {
+	if x != nil {
+		return x.Products
+	}
+	return nil
+}
+
+type ProductListItem struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Name             string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Store            string                 `protobuf:"bytes,2,opt,name=store,proto3" json:"store,omitempty"`
+	ImageUrl         string                 `protobuf:"bytes,3,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"`
+	LastPrice        float64                `protobuf:"fixed64,4,opt,name=last_price,json=lastPrice,proto3" json:"last_price,omitempty"`
+	Currency         string                 `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	MirroredImageUrl string                 `protobuf:"bytes,6,opt,name=mirrored_image_url,json=mirroredImageUrl,proto3" json:"mirrored_image_url,omitempty"`
+	ThumbnailUrl     string                 `protobuf:"bytes,7,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"`
+	// display fields are set when a display currency was asked for and the price's currency has a rate
+	DisplayPrice    *float64 `protobuf:"fixed64,8,opt,name=display_price,json=displayPrice,proto3,oneof" json:"display_price,omitempty"`
+	DisplayCurrency string   `protobuf:"bytes,9,opt,name=display_currency,json=displayCurrency,proto3" json:"display_currency,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ProductListItem) Reset() {
+	*x = ProductListItem{}
+	mi := &file_uniwish_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProductListItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProductListItem) ProtoMessage() {}
+
+func (x *ProductListItem) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProductListItem.ProtoReflect.Descriptor instead.
+func (*ProductListItem) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ProductListItem) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProductListItem) GetStore() string {
+	if x != nil {
+		return x.Store
+	}
+	return ""
+}
+
+func (x *ProductListItem) GetImageUrl() string {
+	if x != nil {
+		return x.ImageUrl
+	}
+	return ""
+}
+
+func (x *ProductListItem) GetLastPrice() float64 {
+	if x != nil {
+		return x.LastPrice
+	}
+	return 0
+}
+
+func (x *ProductListItem) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *ProductListItem) GetMirroredImageUrl() string {
+	if x != nil {
+		return x.MirroredImageUrl
+	}
+	return ""
+}
+
+func (x *ProductListItem) GetThumbnailUrl() string {
+	if x != nil {
+		return x.ThumbnailUrl
+	}
+	return ""
+}
+
+func (x *ProductListItem) GetDisplayPrice() float64 {
+	if x != nil && x.DisplayPrice != nil {
+		return *x.DisplayPrice
+	}
+	return 0
+}
+
+func (x *ProductListItem) GetDisplayCurrency() string {
+	if x != nil {
+		return x.DisplayCurrency
+	}
+	return ""
+}
+
+type Product struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Name             string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Store            string                 `protobuf:"bytes,2,opt,name=store,proto3" json:"store,omitempty"`
+	ImageUrl         string                 `protobuf:"bytes,3,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"`
+	MirroredImageUrl string                 `protobuf:"bytes,4,opt,name=mirrored_image_url,json=mirroredImageUrl,proto3" json:"mirrored_image_url,omitempty"`
+	ThumbnailUrl     string                 `protobuf:"bytes,5,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"`
+	// discontinued_at is set once the store removed the product
+	DiscontinuedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=discontinued_at,json=discontinuedAt,proto3" json:"discontinued_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Product) Reset() {
+	*x = Product{}
+	mi := &file_uniwish_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Product) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Product) ProtoMessage() {}
+
+func (x *Product) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Product.ProtoReflect.Descriptor instead.
+func (*Product) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Product) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Product) GetStore() string {
+	if x != nil {
+		return x.Store
+	}
+	return ""
+}
+
+func (x *Product) GetImageUrl() string {
+	if x != nil {
+		return x.ImageUrl
+	}
+	return ""
+}
+
+func (x *Product) GetMirroredImageUrl() string {
+	if x != nil {
+		return x.MirroredImageUrl
+	}
+	return ""
+}
+
+func (x *Product) GetThumbnailUrl() string {
+	if x != nil {
+		return x.ThumbnailUrl
+	}
+	return ""
+}
+
+func (x *Product) GetDiscontinuedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DiscontinuedAt
+	}
+	return nil
+}
+
+type Offer struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Price           float64                `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	Currency        string                 `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	DisplayPrice    *float64               `protobuf:"fixed64,3,opt,name=display_price,json=displayPrice,proto3,oneof" json:"display_price,omitempty"`
+	DisplayCurrency string                 `protobuf:"bytes,4,opt,name=display_currency,json=displayCurrency,proto3" json:"display_currency,omitempty"`
+	Region          string                 `protobuf:"bytes,5,opt,name=region,proto3" json:"region,omitempty"`
+	Availability    string                 `protobuf:"bytes,6,opt,name=availability,proto3" json:"availability,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Offer) Reset() {
+	*x = Offer{}
+	mi := &file_uniwish_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Offer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Offer) ProtoMessage() {}
+
+func (x *Offer) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Offer.ProtoReflect.Descriptor instead.
+func (*Offer) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Offer) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Offer) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Offer) GetDisplayPrice() float64 {
+	if x != nil && x.DisplayPrice != nil {
+		return *x.DisplayPrice
+	}
+	return 0
+}
+
+func (x *Offer) GetDisplayCurrency() string {
+	if x != nil {
+		return x.DisplayCurrency
+	}
+	return ""
+}
+
+func (x *Offer) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *Offer) GetAvailability() string {
+	if x != nil {
+		return x.Availability
+	}
+	return ""
+}
+
+func (x *Offer) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// RegionPrice is the latest price of the product in one market, display fields make regions comparable
+type RegionPrice struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Region          string                 `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`
+	Url             string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Price           float64                `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
+	Currency        string                 `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	DisplayPrice    *float64               `protobuf:"fixed64,5,opt,name=display_price,json=displayPrice,proto3,oneof" json:"display_price,omitempty"`
+	DisplayCurrency string                 `protobuf:"bytes,6,opt,name=display_currency,json=displayCurrency,proto3" json:"display_currency,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RegionPrice) Reset() {
+	*x = RegionPrice{}
+	mi := &file_uniwish_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegionPrice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegionPrice) ProtoMessage() {}
+
+func (x *RegionPrice) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegionPrice.ProtoReflect.Descriptor instead.
+func (*RegionPrice) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RegionPrice) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *RegionPrice) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *RegionPrice) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *RegionPrice) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *RegionPrice) GetDisplayPrice() float64 {
+	if x != nil && x.DisplayPrice != nil {
+		return *x.DisplayPrice
+	}
+	return 0
+}
+
+func (x *RegionPrice) GetDisplayCurrency() string {
+	if x != nil {
+		return x.DisplayCurrency
+	}
+	return ""
+}
+
+func (x *RegionPrice) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// Match is the same item sold by another store
+type Match struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ProductId       string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Store           string                 `protobuf:"bytes,2,opt,name=store,proto3" json:"store,omitempty"`
+	Name            string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Url             string                 `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	Price           float64                `protobuf:"fixed64,5,opt,name=price,proto3" json:"price,omitempty"`
+	Currency        string                 `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency,omitempty"`
+	DisplayPrice    *float64               `protobuf:"fixed64,7,opt,name=display_price,json=displayPrice,proto3,oneof" json:"display_price,omitempty"`
+	DisplayCurrency string                 `protobuf:"bytes,8,opt,name=display_currency,json=displayCurrency,proto3" json:"display_currency,omitempty"`
+	Confidence      float64                `protobuf:"fixed64,9,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Method          string                 `protobuf:"bytes,10,opt,name=method,proto3" json:"method,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Match) Reset() {
+	*x = Match{}
+	mi := &file_uniwish_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Match) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Match) ProtoMessage() {}
+
+func (x *Match) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Match.ProtoReflect.Descriptor instead.
+func (*Match) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Match) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *Match) GetStore() string {
+	if x != nil {
+		return x.Store
+	}
+	return ""
+}
+
+func (x *Match) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Match) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Match) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Match) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Match) GetDisplayPrice() float64 {
+	if x != nil && x.DisplayPrice != nil {
+		return *x.DisplayPrice
+	}
+	return 0
+}
+
+func (x *Match) GetDisplayCurrency() string {
+	if x != nil {
+		return x.DisplayCurrency
+	}
+	return ""
+}
+
+func (x *Match) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Match) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+type ProductDetail struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Product         *Product               `protobuf:"bytes,1,opt,name=product,proto3" json:"product,omitempty"`
+	Offers          []*Offer               `protobuf:"bytes,2,rep,name=offers,proto3" json:"offers,omitempty"`
+	Regions         []*RegionPrice         `protobuf:"bytes,3,rep,name=regions,proto3" json:"regions,omitempty"`
+	AlsoAvailableAt []*Match               `protobuf:"bytes,4,rep,name=also_available_at,json=alsoAvailableAt,proto3" json:"also_available_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ProductDetail) Reset() {
+	*x = ProductDetail{}
+	mi := &file_uniwish_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProductDetail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProductDetail) ProtoMessage() {}
+
+func (x *ProductDetail) ProtoReflect() protoreflect.Message {
+	mi := &file_uniwish_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProductDetail.ProtoReflect.Descriptor instead.
+func (*ProductDetail) Descriptor() ([]byte, []int) {
+	return file_uniwish_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ProductDetail) GetProduct() *Product {
+	if x != nil {
+		return x.Product
+	}
+	return nil
+}
+
+func (x *ProductDetail) GetOffers() []*Offer {
+	if x != nil {
+		return x.Offers
+	}
+	return nil
+}
+
+func (x *ProductDetail) GetRegions() []*RegionPrice {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+func (x *ProductDetail) GetAlsoAvailableAt() []*Match {
+	if x != nil {
+		return x.AlsoAvailableAt
+	}
+	return nil
+}
+
+var File_uniwish_proto protoreflect.FileDescriptor
+
+const file_uniwish_proto_rawDesc = "" +
+	"\n" +
+	"\runiwish.proto\x12\n" +
+	"uniwish.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"J\n" +
+	"\x1aCreateScrapeRequestRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x1a\n" +
+	"\bpriority\x18\x02 \x01(\tR\bpriority\"E\n" +
+	"\x1bCreateScrapeRequestResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\"V\n" +
+	"\x19WatchScrapeRequestRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12)\n" +
+	"\x10display_currency\x18\x02 \x01(\tR\x0fdisplayCurrency\"\x82\x02\n" +
+	"\fScrapeStatus\x12*\n" +
+	"\x11scrape_request_id\x18\x01 \x01(\tR\x0fscrapeRequestId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"error_kind\x18\x03 \x01(\tR\terrorKind\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x04 \x01(\tR\tproductId\x123\n" +
+	"\aproduct\x18\x05 \x01(\v2\x19.uniwish.v1.ProductDetailR\aproduct\x12;\n" +
+	"\voccurred_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\"N\n" +
+	"\x11GetProductRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12)\n" +
+	"\x10display_currency\x18\x02 \x01(\tR\x0fdisplayCurrency\"@\n" +
+	"\x13ListProductsRequest\x12)\n" +
+	"\x10display_currency\x18\x01 \x01(\tR\x0fdisplayCurrency\"O\n" +
+	"\x14ListProductsResponse\x127\n" +
+	"\bproducts\x18\x01 \x03(\v2\x1b.uniwish.v1.ProductListItemR\bproducts\"\xcd\x02\n" +
+	"\x0fProductListItem\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05store\x18\x02 \x01(\tR\x05store\x12\x1b\n" +
+	"\timage_url\x18\x03 \x01(\tR\bimageUrl\x12\x1d\n" +
+	"\n" +
+	"last_price\x18\x04 \x01(\x01R\tlastPrice\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\x12,\n" +
+	"\x12mirrored_image_url\x18\x06 \x01(\tR\x10mirroredImageUrl\x12#\n" +
+	"\rthumbnail_url\x18\a \x01(\tR\fthumbnailUrl\x12(\n" +
+	"\rdisplay_price\x18\b \x01(\x01H\x00R\fdisplayPrice\x88\x01\x01\x12)\n" +
+	"\x10display_currency\x18\t \x01(\tR\x0fdisplayCurrencyB\x10\n" +
+	"\x0e_display_price\"\xe8\x01\n" +
+	"\aProduct\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05store\x18\x02 \x01(\tR\x05store\x12\x1b\n" +
+	"\timage_url\x18\x03 \x01(\tR\bimageUrl\x12,\n" +
+	"\x12mirrored_image_url\x18\x04 \x01(\tR\x10mirroredImageUrl\x12#\n" +
+	"\rthumbnail_url\x18\x05 \x01(\tR\fthumbnailUrl\x12C\n" +
+	"\x0fdiscontinued_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x0ediscontinuedAt\"\x97\x02\n" +
+	"\x05Offer\x12\x14\n" +
+	"\x05price\x18\x01 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x12(\n" +
+	"\rdisplay_price\x18\x03 \x01(\x01H\x00R\fdisplayPrice\x88\x01\x01\x12)\n" +
+	"\x10display_currency\x18\x04 \x01(\tR\x0fdisplayCurrency\x12\x16\n" +
+	"\x06region\x18\x05 \x01(\tR\x06region\x12\"\n" +
+	"\favailability\x18\x06 \x01(\tR\favailability\x129\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\x10\n" +
+	"\x0e_display_price\"\x8b\x02\n" +
+	"\vRegionPrice\x12\x16\n" +
+	"\x06region\x18\x01 \x01(\tR\x06region\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x14\n" +
+	"\x05price\x18\x03 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bcurrency\x18\x04 \x01(\tR\bcurrency\x12(\n" +
+	"\rdisplay_price\x18\x05 \x01(\x01H\x00R\fdisplayPrice\x88\x01\x01\x12)\n" +
+	"\x10display_currency\x18\x06 \x01(\tR\x0fdisplayCurrency\x129\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\x10\n" +
+	"\x0e_display_price\"\xb3\x02\n" +
+	"\x05Match\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x01 \x01(\tR\tproductId\x12\x14\n" +
+	"\x05store\x18\x02 \x01(\tR\x05store\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\x12\x14\n" +
+	"\x05price\x18\x05 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bcurrency\x18\x06 \x01(\tR\bcurrency\x12(\n" +
+	"\rdisplay_price\x18\a \x01(\x01H\x00R\fdisplayPrice\x88\x01\x01\x12)\n" +
+	"\x10display_currency\x18\b \x01(\tR\x0fdisplayCurrency\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\t \x01(\x01R\n" +
+	"confidence\x12\x16\n" +
+	"\x06method\x18\n" +
+	" \x01(\tR\x06methodB\x10\n" +
+	"\x0e_display_price\"\xdb\x01\n" +
+	"\rProductDetail\x12-\n" +
+	"\aproduct\x18\x01 \x01(\v2\x13.uniwish.v1.ProductR\aproduct\x12)\n" +
+	"\x06offers\x18\x02 \x03(\v2\x11.uniwish.v1.OfferR\x06offers\x121\n" +
+	"\aregions\x18\x03 \x03(\v2\x17.uniwish.v1.RegionPriceR\aregions\x12=\n" +
+	"\x11also_available_at\x18\x04 \x03(\v2\x11.uniwish.v1.MatchR\x0falsoAvailableAt2\xd7\x01\n" +
+	"\x14ScrapeRequestService\x12f\n" +
+	"\x13CreateScrapeRequest\x12&.uniwish.v1.CreateScrapeRequestRequest\x1a'.uniwish.v1.CreateScrapeRequestResponse\x12W\n" +
+	"\x12WatchScrapeRequest\x12%.uniwish.v1.WatchScrapeRequestRequest\x1a\x18.uniwish.v1.ScrapeStatus0\x012\xab\x01\n" +
+	"\x0eProductService\x12F\n" +
+	"\n" +
+	"GetProduct\x12\x1d.uniwish.v1.GetProductRequest\x1a\x19.uniwish.v1.ProductDetail\x12Q\n" +
+	"\fListProducts\x12\x1f.uniwish.v1.ListProductsRequest\x1a .uniwish.v1.ListProductsResponseB(Z&uniwish.com/internal/api/rpc/uniwishv1b\x06proto3"
+
+var (
+	file_uniwish_proto_rawDescOnce sync.Once
+	file_uniwish_proto_rawDescData []byte
+)
+
+func file_uniwish_proto_rawDescGZIP() []byte {
+	file_uniwish_proto_rawDescOnce.Do(func() {
+		file_uniwish_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_uniwish_proto_rawDesc), len(file_uniwish_proto_rawDesc)))
+	})
+	return file_uniwish_proto_rawDescData
+}
+
+var file_uniwish_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_uniwish_proto_goTypes = []any{
+	(*CreateScrapeRequestRequest)(nil),  // 0: uniwish.v1.CreateScrapeRequestRequest
+	(*CreateScrapeRequestResponse)(nil), // 1: uniwish.v1.CreateScrapeRequestResponse
+	(*WatchScrapeRequestRequest)(nil),   // 2: uniwish.v1.WatchScrapeRequestRequest
+	(*ScrapeStatus)(nil),                // 3: uniwish.v1.ScrapeStatus
+	(*GetProductRequest)(nil),           // 4: uniwish.v1.GetProductRequest
+	(*ListProductsRequest)(nil),         // 5: uniwish.v1.ListProductsRequest
+	(*ListProductsResponse)(nil),        // 6: uniwish.v1.ListProductsResponse
+	(*ProductListItem)(nil),             // 7: uniwish.v1.ProductListItem
+	(*Product)(nil),                     // 8: uniwish.v1.Product
+	(*Offer)(nil),                       // 9: uniwish.v1.Offer
+	(*RegionPrice)(nil),                 // 10: uniwish.v1.RegionPrice
+	(*Match)(nil),                       // 11: uniwish.v1.Match
+	(*ProductDetail)(nil),               // 12: uniwish.v1.ProductDetail
+	(*timestamppb.Timestamp)(nil),       // 13: google.protobuf.Timestamp
+}
+var file_uniwish_proto_depIdxs = []int32{
+	12, // 0: uniwish.v1.ScrapeStatus.product:type_name -> uniwish.v1.ProductDetail
+	13, // 1: uniwish.v1.ScrapeStatus.occurred_at:type_name -> google.protobuf.Timestamp
+	7,  // 2: uniwish.v1.ListProductsResponse.products:type_name -> uniwish.v1.ProductListItem
+	13, // 3: uniwish.v1.Product.discontinued_at:type_name -> google.protobuf.Timestamp
+	13, // 4: uniwish.v1.Offer.updated_at:type_name -> google.protobuf.Timestamp
+	13, // 5: uniwish.v1.RegionPrice.updated_at:type_name -> google.protobuf.Timestamp
+	8,  // 6: uniwish.v1.ProductDetail.product:type_name -> uniwish.v1.Product
+	9,  // 7: uniwish.v1.ProductDetail.offers:type_name -> uniwish.v1.Offer
+	10, // 8: uniwish.v1.ProductDetail.regions:type_name -> uniwish.v1.RegionPrice
+	11, // 9: uniwish.v1.ProductDetail.also_available_at:type_name -> uniwish.v1.Match
+	0,  // 10: uniwish.v1.ScrapeRequestService.CreateScrapeRequest:input_type -> uniwish.v1.CreateScrapeRequestRequest
+	2,  // 11: uniwish.v1.ScrapeRequestService.WatchScrapeRequest:input_type -> uniwish.v1.WatchScrapeRequestRequest
+	4,  // 12: uniwish.v1.ProductService.GetProduct:input_type -> uniwish.v1.GetProductRequest
+	5,  // 13: uniwish.v1.ProductService.ListProducts:input_type -> uniwish.v1.ListProductsRequest
+	1,  // 14: uniwish.v1.ScrapeRequestService.CreateScrapeRequest:output_type -> uniwish.v1.CreateScrapeRequestResponse
+	3,  // 15: uniwish.v1.ScrapeRequestService.WatchScrapeRequest:output_type -> uniwish.v1.ScrapeStatus
+	12, // 16: uniwish.v1.ProductService.GetProduct:output_type -> uniwish.v1.ProductDetail
+	6,  // 17: uniwish.v1.ProductService.ListProducts:output_type -> uniwish.v1.ListProductsResponse
+	14, // [14:18] is the sub-list for method output_type
+	10, // [10:14] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_uniwish_proto_init() }
+func file_uniwish_proto_init() {
+	if File_uniwish_proto != nil {
+		return
+	}
+	file_uniwish_proto_msgTypes[7].OneofWrappers = []any{}
+	file_uniwish_proto_msgTypes[9].OneofWrappers = []any{}
+	file_uniwish_proto_msgTypes[10].OneofWrappers = []any{}
+	file_uniwish_proto_msgTypes[11].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_uniwish_proto_rawDesc), len(file_uniwish_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_uniwish_proto_goTypes,
+		DependencyIndexes: file_uniwish_proto_depIdxs,
+		MessageInfos:      file_uniwish_proto_msgTypes,
+	}.Build()
+	File_uniwish_proto = out.File
+	file_uniwish_proto_goTypes = nil
+	file_uniwish_proto_depIdxs = nil
+}
@@ -0,0 +1,317 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: uniwish.proto
+
+package uniwishv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ScrapeRequestService_CreateScrapeRequest_FullMethodName = "/uniwish.v1.ScrapeRequestService/CreateScrapeRequest"
+	ScrapeRequestService_WatchScrapeRequest_FullMethodName  = "/uniwish.v1.ScrapeRequestService/WatchScrapeRequest"
+)
+
+// ScrapeRequestServiceClient is the client API for ScrapeRequestService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ScrapeRequestService submits product pages to scrape and follows their progress
+type ScrapeRequestServiceClient interface {
+	// CreateScrapeRequest enqueues a url to scrape, its request starts pending
+	CreateScrapeRequest(ctx context.Context, in *CreateScrapeRequestRequest, opts ...grpc.CallOption) (*CreateScrapeRequestResponse, error)
+	// WatchScrapeRequest streams the request's current status then its transitions, ending once it is final.
+	// A stream ending early, like when the api drains, is resumed by watching again
+	WatchScrapeRequest(ctx context.Context, in *WatchScrapeRequestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScrapeStatus], error)
+}
+
+type scrapeRequestServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewScrapeRequestServiceClient(cc grpc.ClientConnInterface) ScrapeRequestServiceClient {
+	return &scrapeRequestServiceClient{cc}
+}
+
+func (c *scrapeRequestServiceClient) CreateScrapeRequest(ctx context.Context, in *CreateScrapeRequestRequest, opts ...grpc.CallOption) (*CreateScrapeRequestResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateScrapeRequestResponse)
+	err := c.cc.Invoke(ctx, ScrapeRequestService_CreateScrapeRequest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scrapeRequestServiceClient) WatchScrapeRequest(ctx context.Context, in *WatchScrapeRequestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScrapeStatus], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ScrapeRequestService_ServiceDesc.Streams[0], ScrapeRequestService_WatchScrapeRequest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchScrapeRequestRequest, ScrapeStatus]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ScrapeRequestService_WatchScrapeRequestClient = grpc.ServerStreamingClient[ScrapeStatus]
+
+// ScrapeRequestServiceServer is the server API for ScrapeRequestService service.
+// All implementations must embed UnimplementedScrapeRequestServiceServer
+// for forward compatibility.
+//
+// ScrapeRequestService submits product pages to scrape and follows their progress
+type ScrapeRequestServiceServer interface {
+	// CreateScrapeRequest enqueues a url to scrape, its request starts pending
+	CreateScrapeRequest(context.Context, *CreateScrapeRequestRequest) (*CreateScrapeRequestResponse, error)
+	// WatchScrapeRequest streams the request's current status then its transitions, ending once it is final.
+	// A stream ending early, like when the api drains, is resumed by watching again
+	WatchScrapeRequest(*WatchScrapeRequestRequest, grpc.ServerStreamingServer[ScrapeStatus]) error
+	mustEmbedUnimplementedScrapeRequestServiceServer()
+}
+
+// UnimplementedScrapeRequestServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedScrapeRequestServiceServer struct{}
+
+func (UnimplementedScrapeRequestServiceServer) CreateScrapeRequest(context.Context, *CreateScrapeRequestRequest) (*CreateScrapeRequestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateScrapeRequest not implemented")
+}
+func (UnimplementedScrapeRequestServiceServer) WatchScrapeRequest(*WatchScrapeRequestRequest, grpc.ServerStreamingServer[ScrapeStatus]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchScrapeRequest not implemented")
+}
+func (UnimplementedScrapeRequestServiceServer) mustEmbedUnimplementedScrapeRequestServiceServer() {}
+func (UnimplementedScrapeRequestServiceServer) testEmbeddedByValue()                              {}
+
+// UnsafeScrapeRequestServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ScrapeRequestServiceServer will
+// result in compilation errors.
+type UnsafeScrapeRequestServiceServer interface {
+	mustEmbedUnimplementedScrapeRequestServiceServer()
+}
+
+func RegisterScrapeRequestServiceServer(s grpc.ServiceRegistrar, srv ScrapeRequestServiceServer) {
+	// If the following call pancis, it indicates UnimplementedScrapeRequestServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ScrapeRequestService_ServiceDesc, srv)
+}
+
+func _ScrapeRequestService_CreateScrapeRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateScrapeRequestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScrapeRequestServiceServer).CreateScrapeRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ScrapeRequestService_CreateScrapeRequest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScrapeRequestServiceServer).CreateScrapeRequest(ctx, req.(*CreateScrapeRequestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ScrapeRequestService_WatchScrapeRequest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchScrapeRequestRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScrapeRequestServiceServer).WatchScrapeRequest(m, &grpc.GenericServerStream[WatchScrapeRequestRequest, ScrapeStatus]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ScrapeRequestService_WatchScrapeRequestServer = grpc.ServerStreamingServer[ScrapeStatus]
+
+// ScrapeRequestService_ServiceDesc is the grpc.ServiceDesc for ScrapeRequestService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ScrapeRequestService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "uniwish.v1.ScrapeRequestService",
+	HandlerType: (*ScrapeRequestServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateScrapeRequest",
+			Handler:    _ScrapeRequestService_CreateScrapeRequest_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchScrapeRequest",
+			Handler:       _ScrapeRequestService_WatchScrapeRequest_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "uniwish.proto",
+}
+
+const (
+	ProductService_GetProduct_FullMethodName   = "/uniwish.v1.ProductService/GetProduct"
+	ProductService_ListProducts_FullMethodName = "/uniwish.v1.ProductService/ListProducts"
+)
+
+// ProductServiceClient is the client API for ProductService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ProductService reads the scraped products
+type ProductServiceClient interface {
+	GetProduct(ctx context.Context, in *GetProductRequest, opts ...grpc.CallOption) (*ProductDetail, error)
+	ListProducts(ctx context.Context, in *ListProductsRequest, opts ...grpc.CallOption) (*ListProductsResponse, error)
+}
+
+type productServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProductServiceClient(cc grpc.ClientConnInterface) ProductServiceClient {
+	return &productServiceClient{cc}
+}
+
+func (c *productServiceClient) GetProduct(ctx context.Context, in *GetProductRequest, opts ...grpc.CallOption) (*ProductDetail, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProductDetail)
+	err := c.cc.Invoke(ctx, ProductService_GetProduct_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *productServiceClient) ListProducts(ctx context.Context, in *ListProductsRequest, opts ...grpc.CallOption) (*ListProductsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListProductsResponse)
+	err := c.cc.Invoke(ctx, ProductService_ListProducts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProductServiceServer is the server API for ProductService service.
+// All implementations must embed UnimplementedProductServiceServer
+// for forward compatibility.
+//
+// ProductService reads the scraped products
+type ProductServiceServer interface {
+	GetProduct(context.Context, *GetProductRequest) (*ProductDetail, error)
+	ListProducts(context.Context, *ListProductsRequest) (*ListProductsResponse, error)
+	mustEmbedUnimplementedProductServiceServer()
+}
+
+// UnimplementedProductServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedProductServiceServer struct{}
+
+func (UnimplementedProductServiceServer) GetProduct(context.Context, *GetProductRequest) (*ProductDetail, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProduct not implemented")
+}
+func (UnimplementedProductServiceServer) ListProducts(context.Context, *ListProductsRequest) (*ListProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProducts not implemented")
+}
+func (UnimplementedProductServiceServer) mustEmbedUnimplementedProductServiceServer() {}
+func (UnimplementedProductServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeProductServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProductServiceServer will
+// result in compilation errors.
+type UnsafeProductServiceServer interface {
+	mustEmbedUnimplementedProductServiceServer()
+}
+
+func RegisterProductServiceServer(s grpc.ServiceRegistrar, srv ProductServiceServer) {
+	// If the following call pancis, it indicates UnimplementedProductServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ProductService_ServiceDesc, srv)
+}
+
+func _ProductService_GetProduct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProductRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductServiceServer).GetProduct(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProductService_GetProduct_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductServiceServer).GetProduct(ctx, req.(*GetProductRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProductService_ListProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductServiceServer).ListProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProductService_ListProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductServiceServer).ListProducts(ctx, req.(*ListProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ProductService_ServiceDesc is the grpc.ServiceDesc for ProductService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProductService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "uniwish.v1.ProductService",
+	HandlerType: (*ProductServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetProduct",
+			Handler:    _ProductService_GetProduct_Handler,
+		},
+		{
+			MethodName: "ListProducts",
+			Handler:    _ProductService_ListProducts_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "uniwish.proto",
+}
@@ -1,50 +1,162 @@
 /*
 uniwish.com/internal/api/server
 
-Contains our wrapper of http.Server adding logging capacity, routes and middleware
+Contains our wrapper of http.Server adding logging capacity, routes and middleware,
+along with the gRPC server when it is enabled
 */
 package api
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"uniwish.com/internal/api/config"
 	"uniwish.com/internal/api/middleware"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/api/rpc"
+	"uniwish.com/internal/api/services"
+	"uniwish.com/internal/blob"
+	"uniwish.com/internal/metrics"
 	"uniwish.com/internal/scrapers"
 )
 
 type Server struct {
 	httpServer *http.Server
 	logger     *slog.Logger
+	drain      *services.Drain
+	// certs is nil when serving plain http
+	certs *CertReloader
+	// grpcServer is nil when the gRPC api is disabled
+	grpcServer *grpc.Server
+	grpcAddr   string
 }
 
 func (s *Server) ListenAndServe() error {
+	if s.certs != nil {
+		s.logger.Info("https server started", "addr", s.httpServer.Addr)
+		// the certificate comes from TLSConfig.GetCertificate, not from files named here
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
 	s.logger.Info("http server started", "addr", s.httpServer.Addr)
 	return s.httpServer.ListenAndServe()
 }
 
+// ServeGRPC serves the gRPC api until Shutdown, returning at once when it is disabled
+func (s *Server) ServeGRPC() error {
+	if s.grpcServer == nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.grpcAddr)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("grpc server started", "addr", s.grpcAddr, "tls", s.certs != nil)
+	return s.grpcServer.Serve(listener)
+}
+
+// Drain fails readiness then waits delay, so load balancers stop routing here before the listener closes
+func (s *Server) Drain(ctx context.Context, delay time.Duration) {
+	s.drain.Start()
+	if delay <= 0 {
+		return
+	}
+	s.logger.Info("http server draining", "delay", delay)
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+}
+
+// Shutdown waits for in-flight requests until shutdownCtx ends, then closes what is left
 func (s *Server) Shutdown(shutdownCtx context.Context) error {
 	s.logger.Info("http server shutting down")
-	return s.httpServer.Shutdown(shutdownCtx)
+	err := s.httpServer.Shutdown(shutdownCtx)
+	if err != nil {
+		s.httpServer.Close()
+	}
+	if s.grpcServer != nil {
+		s.logger.Info("grpc server shutting down")
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			s.grpcServer.Stop()
+		}
+	}
+	return err
 }
-func NewServer(cfg *config.Config, logger *slog.Logger, db *sql.DB, registry scrapers.Registry) *Server {
+
+func NewServer(cfg *config.Config, logger *slog.Logger, db *sql.DB, queue repository.Queue, registry scrapers.Registry, images blob.Store) (*Server, error) {
 	mux := http.NewServeMux()
+	drain := &services.Drain{}
 
-	RegisterRoutes(mux, db, registry)
+	RegisterRoutes(mux, cfg, db, queue, registry, images, drain)
 
-	handler := middleware.Logging(logger)(mux)
+	metricsRegistry := metrics.NewRegistry(db)
+	mux.Handle("GET /metrics", metrics.Handler(metricsRegistry))
+
+	httpMetrics := middleware.NewHTTPMetrics(metricsRegistry)
+	handler := middleware.Chain(mux,
+		middleware.Route(mux),
+		middleware.ClientAddress(cfg.TrustedProxies),
+		middleware.RequestID(),
+		middleware.Tracing(),
+		middleware.Logging(logger),
+		middleware.Recovery(),
+		middleware.Metrics(httpMetrics),
+		middleware.CORS(cfg.CORSAllowedOrigins),
+	)
 
 	srv := &http.Server{
-		Addr:    ":" + strconv.Itoa(cfg.Port),
-		Handler: handler,
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
 	}
 
-	return &Server{
+	server := &Server{
 		httpServer: srv,
 		logger:     logger,
+		drain:      drain,
+	}
+	if cfg.TLSCertFile != "" {
+		certs, err := NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: certs.GetCertificate}
+		server.certs = certs
+	}
+
+	if cfg.GRPCPort != 0 {
+		var opts []grpc.ServerOption
+		if server.certs != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: server.certs.GetCertificate})))
+		}
+		var limiter *middleware.RateLimiter
+		if cfg.RateLimitPerMinute > 0 {
+			// buckets are not shared with the REST api, a client of both gets both
+			limiter = middleware.NewRateLimiter(cfg.RateLimitPerMinute, cfg.RateLimitBurst)
+		}
+		server.grpcServer = rpc.NewServer(logger, limiter, opts...)
+		server.grpcAddr = ":" + strconv.Itoa(cfg.GRPCPort)
+		RegisterGRPCServices(server.grpcServer, cfg, db, queue, registry, drain)
 	}
+	return server, nil
 }
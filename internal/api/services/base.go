@@ -0,0 +1,10 @@
+/*
+uniwish.com/internal/api/services/base
+
+centralizes what services share
+*/
+package services
+
+import "go.opentelemetry.io/otel"
+
+var tracer = otel.Tracer("uniwish.com/internal/api/services")
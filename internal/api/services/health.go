@@ -2,18 +2,155 @@
 uniwish.com/internal/api/services/health
 
 contains logic of application's self health service
+
+liveness only says the process is up, readiness runs the registered checks
 */
 package services
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"uniwish.com/internal/api/repository"
+)
+
+const (
+	CheckStatusOK   = "ok"
+	CheckStatusFail = "fail"
+)
+
+type HealthCheckFunc func(context.Context) error
+
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type ReadinessReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+func (r *ReadinessReport) Ready() bool {
+	return r.Status == CheckStatusOK
+}
 
-type HealthService struct{}
+type HealthService struct {
+	checks  map[string]HealthCheckFunc
+	timeout time.Duration
+}
 
-func NewHealthService() *HealthService {
-	return &HealthService{}
+func NewHealthService(checks map[string]HealthCheckFunc) *HealthService {
+	return &HealthService{checks: checks, timeout: 2 * time.Second}
 }
 
 func (s *HealthService) Check(ctx context.Context) error {
-	// stub function used to make sure our system is at least up
+	// liveness, reaching this means the process serves requests
+	return nil
+}
+
+func (s *HealthService) Ready(ctx context.Context) *ReadinessReport {
+	// runs every check concurrently under a shared timeout
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	report := &ReadinessReport{Status: CheckStatusOK, Checks: make(map[string]CheckResult, len(s.checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for name, check := range s.checks {
+		wg.Go(func() {
+			start := time.Now()
+			err := check(ctx)
+			result := CheckResult{Status: CheckStatusOK, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = CheckStatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = CheckStatusFail
+			}
+		})
+	}
+	wg.Wait()
+
+	return report
+}
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+func DatabaseCheck(db Pinger) HealthCheckFunc {
+	return db.PingContext
+}
+
+func SchemaCheck(reader repository.SchemaReader, required int64) HealthCheckFunc {
+	// fails on a dirty schema or one behind what this build expects,
+	// a newer schema is tolerated so rolling deploys can migrate before replacing old binaries
+	return func(ctx context.Context) error {
+		state, err := reader.SchemaState(ctx)
+		if err != nil {
+			return err
+		}
+		if state.Dirty {
+			return fmt.Errorf("schema version %d is dirty", state.Version)
+		}
+		if state.Version < required {
+			return fmt.Errorf("schema version %d behind required %d", state.Version, required)
+		}
+		return nil
+	}
+}
+
+func RecencyCheck(last func() time.Time, maxAge time.Duration) HealthCheckFunc {
+	// fails when last reports nothing more recent than maxAge ago
+	return func(_ context.Context) error {
+		age := time.Since(last())
+		if age > maxAge {
+			return fmt.Errorf("last success %s ago exceeds %s", age.Round(time.Second), maxAge)
+		}
+		return nil
+	}
+}
+
+var ErrDraining = errors.New("draining for shutdown")
+
+// Drain fails readiness once shutdown begins, so load balancers route new requests elsewhere
+// while the ones in flight finish
+type Drain struct {
+	draining atomic.Bool
+	init     sync.Once
+	stop     sync.Once
+	done     chan struct{}
+}
+
+func (d *Drain) Start() {
+	d.draining.Store(true)
+	d.Done()
+	d.stop.Do(func() { close(d.done) })
+}
+
+// Done is closed once draining starts, so long lived responses like event streams end and clients reconnect elsewhere
+func (d *Drain) Done() <-chan struct{} {
+	d.init.Do(func() { d.done = make(chan struct{}) })
+	return d.done
+}
+
+func (d *Drain) Check(_ context.Context) error {
+	if d.draining.Load() {
+		return ErrDraining
+	}
 	return nil
 }
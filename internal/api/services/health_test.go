@@ -7,11 +7,15 @@ package services
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
+
+	"uniwish.com/internal/api/repository"
 )
 
 func TestHealthService_Check_Ok(t *testing.T) {
-	srv := NewHealthService()
+	srv := NewHealthService(nil)
 
 	err := srv.Check(context.Background())
 
@@ -19,3 +23,127 @@ func TestHealthService_Check_Ok(t *testing.T) {
 		t.Fatalf("expected nil got, %v", err)
 	}
 }
+
+func TestHealthService_Ready(t *testing.T) {
+	tests := []struct {
+		name           string
+		checks         map[string]HealthCheckFunc
+		expectedStatus string
+		failing        []string
+	}{
+		{
+			name:           "no_checks",
+			expectedStatus: CheckStatusOK,
+		},
+		{
+			name: "all_pass",
+			checks: map[string]HealthCheckFunc{
+				"a": func(context.Context) error { return nil },
+				"b": func(context.Context) error { return nil },
+			},
+			expectedStatus: CheckStatusOK,
+		},
+		{
+			name: "one_fails",
+			checks: map[string]HealthCheckFunc{
+				"a": func(context.Context) error { return nil },
+				"b": func(context.Context) error { return errors.New("down") },
+			},
+			expectedStatus: CheckStatusFail,
+			failing:        []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewHealthService(tt.checks).Ready(context.Background())
+
+			if report.Status != tt.expectedStatus {
+				t.Fatalf("expected status %s, received %s", tt.expectedStatus, report.Status)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("expected %d check results, received %d", len(tt.checks), len(report.Checks))
+			}
+			for _, name := range tt.failing {
+				if report.Checks[name].Status != CheckStatusFail || report.Checks[name].Error == "" {
+					t.Fatalf("expected check %s to fail with an error, received %+v", name, report.Checks[name])
+				}
+			}
+		})
+	}
+}
+
+type FakeSchemaReader struct {
+	state *repository.SchemaState
+	err   error
+}
+
+func (r *FakeSchemaReader) SchemaState(_ context.Context) (*repository.SchemaState, error) {
+	return r.state, r.err
+}
+
+func TestSchemaCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		reader    *FakeSchemaReader
+		expectErr bool
+	}{
+		{name: "current", reader: &FakeSchemaReader{state: &repository.SchemaState{Version: 5}}},
+		{name: "newer", reader: &FakeSchemaReader{state: &repository.SchemaState{Version: 6}}},
+		{name: "behind", reader: &FakeSchemaReader{state: &repository.SchemaState{Version: 4}}, expectErr: true},
+		{name: "dirty", reader: &FakeSchemaReader{state: &repository.SchemaState{Version: 5, Dirty: true}}, expectErr: true},
+		{name: "unreadable", reader: &FakeSchemaReader{err: errors.New("no table")}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := SchemaCheck(tt.reader, 5)(context.Background())
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %v, received %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRecencyCheck(t *testing.T) {
+	recent := func() time.Time { return time.Now() }
+	stale := func() time.Time { return time.Now().Add(-time.Hour) }
+
+	if err := RecencyCheck(recent, time.Minute)(context.Background()); err != nil {
+		t.Fatalf("expected recent success to pass, received %v", err)
+	}
+	if err := RecencyCheck(stale, time.Minute)(context.Background()); err == nil {
+		t.Fatal("expected stale success to fail")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	drain := &Drain{}
+	service := NewHealthService(map[string]HealthCheckFunc{"drain": drain.Check})
+
+	if report := service.Ready(context.Background()); !report.Ready() {
+		t.Fatalf("expected ready before draining, received %+v", report)
+	}
+	select {
+	case <-drain.Done():
+		t.Fatal("expected done to stay open before draining")
+	default:
+	}
+
+	drain.Start()
+	drain.Start()
+	select {
+	case <-drain.Done():
+	default:
+		t.Fatal("expected done closed once draining")
+	}
+	report := service.Ready(context.Background())
+	if report.Ready() || report.Checks["drain"].Error != ErrDraining.Error() {
+		t.Fatalf("expected draining to fail readiness, received %+v", report)
+	}
+	if err := service.Check(context.Background()); err != nil {
+		t.Fatalf("expected liveness to hold while draining, received %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+uniwish.com/internal/api/services/notifications
+
+contains logic of the notifications users receive about their wishlist, like a product being discontinued
+*/
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+)
+
+const (
+	DefaultNotificationLimit = 50
+	MaxNotificationLimit     = 200
+)
+
+type ListNotificationsInput struct {
+	// OwnerID is the user whose notifications are listed, required
+	OwnerID string
+	// Limit and Offset are read from the query string, empty takes the defaults
+	Limit  string
+	Offset string
+}
+
+type NotificationProductResponse struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Store string    `json:"store"`
+}
+
+type NotificationResponse struct {
+	ID        uuid.UUID                   `json:"id"`
+	Kind      string                      `json:"kind"`
+	Product   NotificationProductResponse `json:"product"`
+	CreatedAt time.Time                   `json:"created_at"`
+}
+
+type NotificationListResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+}
+
+type NotificationReaderService interface {
+	List(ctx context.Context, input ListNotificationsInput) (*NotificationListResponse, error)
+}
+
+type DefaultNotificationReaderService struct {
+	repo repository.NotificationReader
+}
+
+func NewDefaultNotificationReaderService(repo repository.NotificationReader) NotificationReaderService {
+	return &DefaultNotificationReaderService{repo: repo}
+}
+
+func (s *DefaultNotificationReaderService) List(ctx context.Context, input ListNotificationsInput) (*NotificationListResponse, error) {
+	ctx, span := tracer.Start(ctx, "DefaultNotificationReaderService.List")
+	defer span.End()
+
+	// notifications are per user, anonymous callers have none to read
+	if input.OwnerID == "" {
+		return nil, apiErrors.ErrUnauthorized
+	}
+
+	var fields []apiErrors.FieldError
+	limit, err := parseBound(input.Limit, DefaultNotificationLimit)
+	if err != nil || limit < 1 || limit > MaxNotificationLimit {
+		fields = append(fields, apiErrors.FieldError{
+			Field:   "limit",
+			Code:    "invalid",
+			Message: fmt.Sprintf("limit must be between 1 and %d", MaxNotificationLimit),
+		})
+	}
+	offset, err := parseBound(input.Offset, 0)
+	if err != nil || offset < 0 {
+		fields = append(fields, apiErrors.FieldError{Field: "offset", Code: "invalid", Message: "offset must be a non negative integer"})
+	}
+	if len(fields) > 0 {
+		return nil, apiErrors.Invalid(fields...)
+	}
+
+	items, err := s.repo.ListNotifications(ctx, input.OwnerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &NotificationListResponse{Notifications: make([]NotificationResponse, len(items))}
+	for i, item := range items {
+		resp.Notifications[i] = NotificationResponse{
+			ID:   item.ID,
+			Kind: item.Kind,
+			Product: NotificationProductResponse{
+				ID:    item.ProductID,
+				Name:  item.ProductName,
+				Store: item.ProductStore,
+			},
+			CreatedAt: item.CreatedAt,
+		}
+	}
+	return resp, nil
+}
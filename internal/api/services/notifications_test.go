@@ -0,0 +1,65 @@
+/*
+uniwish.com/interal/api/services/notifications_test
+
+tests for the notification service
+*/
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+)
+
+type FakeNotificationReader struct {
+	ownerID string
+	limit   int
+}
+
+func (r *FakeNotificationReader) ListNotifications(_ context.Context, ownerID string, limit int, _ int) ([]repository.NotificationListItem, error) {
+	r.ownerID, r.limit = ownerID, limit
+	return []repository.NotificationListItem{
+		{ID: uuid.New(), Kind: "product_discontinued", ProductID: uuid.New(), ProductName: "jacket", ProductStore: "zara", CreatedAt: time.Now()},
+	}, nil
+}
+
+func TestNotificationReaderService_List(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         ListNotificationsInput
+		expectedError error
+		expectedLimit int
+	}{
+		{name: "defaults", input: ListNotificationsInput{OwnerID: "user-1"}, expectedLimit: DefaultNotificationLimit},
+		{name: "limit", input: ListNotificationsInput{OwnerID: "user-1", Limit: "5"}, expectedLimit: 5},
+		{name: "anonymous", input: ListNotificationsInput{}, expectedError: apiErrors.ErrUnauthorized},
+		{name: "limit_too_large", input: ListNotificationsInput{OwnerID: "user-1", Limit: "1000"}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "negative_offset", input: ListNotificationsInput{OwnerID: "user-1", Offset: "-1"}, expectedError: apiErrors.ErrInputInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeNotificationReader{}
+			srv := NewDefaultNotificationReaderService(repo)
+
+			resp, err := srv.List(context.Background(), tt.input)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if tt.expectedError != nil {
+				return
+			}
+			if repo.ownerID != tt.input.OwnerID || repo.limit != tt.expectedLimit {
+				t.Fatalf("unexpected query %+v", repo)
+			}
+			if len(resp.Notifications) != 1 || resp.Notifications[0].Product.Name != "jacket" {
+				t.Fatalf("unexpected notifications %+v", resp.Notifications)
+			}
+		})
+	}
+}
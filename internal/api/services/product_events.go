@@ -0,0 +1,120 @@
+/*
+uniwish.com/internal/api/services/product_events
+
+contains logic of the product audit trail, the changes scrapes found on a product
+*/
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/domain"
+)
+
+const (
+	DefaultProductEventLimit = 50
+	MaxProductEventLimit     = 200
+)
+
+var productEventKinds = []string{
+	string(domain.ProductNameChanged),
+	string(domain.ProductImageChanged),
+	string(domain.ProductURLChanged),
+	string(domain.ProductPriceChanged),
+	string(domain.ProductAvailabilityChanged),
+	string(domain.ProductDiscontinued),
+	string(domain.ProductRelisted),
+}
+
+type ListProductEventsInput struct {
+	// Kinds keeps only events of these kinds, empty keeps all
+	Kinds []string
+	// Limit and Offset are read from the query string, empty takes the defaults
+	Limit  string
+	Offset string
+}
+
+type ProductEventResponse struct {
+	ID     uuid.UUID `json:"id"`
+	Kind   string    `json:"kind"`
+	Region string    `json:"region,omitempty"`
+	Before string    `json:"before"`
+	After  string    `json:"after"`
+	// ScrapeRequestID is the scrape request whose scrape found the change
+	ScrapeRequestID *uuid.UUID `json:"scrape_request_id,omitempty"`
+	OccurredAt      time.Time  `json:"occurred_at"`
+}
+
+type ProductEventListResponse struct {
+	Events []ProductEventResponse `json:"events"`
+}
+
+type ProductEventReaderService interface {
+	List(ctx context.Context, productID uuid.UUID, input ListProductEventsInput) (*ProductEventListResponse, error)
+}
+
+type DefaultProductEventReaderService struct {
+	repo repository.ProductEventReader
+}
+
+func NewDefaultProductEventReaderService(repo repository.ProductEventReader) ProductEventReaderService {
+	return &DefaultProductEventReaderService{repo: repo}
+}
+
+func (s *DefaultProductEventReaderService) List(ctx context.Context, productID uuid.UUID, input ListProductEventsInput) (*ProductEventListResponse, error) {
+	ctx, span := tracer.Start(ctx, "DefaultProductEventReaderService.List")
+	defer span.End()
+
+	var fields []apiErrors.FieldError
+	for _, kind := range input.Kinds {
+		if !slices.Contains(productEventKinds, kind) {
+			fields = append(fields, apiErrors.FieldError{Field: "kind", Code: "invalid", Message: kind + " is not a product event kind"})
+			break
+		}
+	}
+	limit, err := parseBound(input.Limit, DefaultProductEventLimit)
+	if err != nil || limit < 1 || limit > MaxProductEventLimit {
+		fields = append(fields, apiErrors.FieldError{
+			Field:   "limit",
+			Code:    "invalid",
+			Message: fmt.Sprintf("limit must be between 1 and %d", MaxProductEventLimit),
+		})
+	}
+	offset, err := parseBound(input.Offset, 0)
+	if err != nil || offset < 0 {
+		fields = append(fields, apiErrors.FieldError{Field: "offset", Code: "invalid", Message: "offset must be a non negative integer"})
+	}
+	if len(fields) > 0 {
+		return nil, apiErrors.Invalid(fields...)
+	}
+
+	items, err := s.repo.ListProductEvents(ctx, productID, repository.ProductEventFilter{Kinds: input.Kinds}, limit, offset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apiErrors.ErrNoProductFound
+		}
+		return nil, err
+	}
+
+	resp := &ProductEventListResponse{Events: make([]ProductEventResponse, len(items))}
+	for i, item := range items {
+		resp.Events[i] = ProductEventResponse{
+			ID:              item.ID,
+			Kind:            item.Kind,
+			Region:          item.Region,
+			Before:          item.Before,
+			After:           item.After,
+			ScrapeRequestID: item.ScrapeRequestID,
+			OccurredAt:      item.OccurredAt,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,84 @@
+/*
+uniwish.com/interal/api/services/product_events_test
+
+tests for the product event service
+*/
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+)
+
+type FakeProductEventReader struct {
+	err    error
+	filter repository.ProductEventFilter
+	limit  int
+	offset int
+}
+
+func (r *FakeProductEventReader) ListProductEvents(_ context.Context, _ uuid.UUID, filter repository.ProductEventFilter, limit int, offset int) ([]repository.ProductEventListItem, error) {
+	r.filter, r.limit, r.offset = filter, limit, offset
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []repository.ProductEventListItem{
+		{ID: uuid.New(), Kind: "price_changed", Region: "es", Before: "29.95 EUR", After: "24.95 EUR", OccurredAt: time.Now()},
+	}, nil
+}
+
+func TestProductEventReaderService_List(t *testing.T) {
+	tests := []struct {
+		name           string
+		repo           *FakeProductEventReader
+		input          ListProductEventsInput
+		expectedErr    error
+		expectedLimit  int
+		expectedOffset int
+	}{
+		{name: "defaults", repo: &FakeProductEventReader{}, expectedLimit: DefaultProductEventLimit},
+		{
+			name:          "filtered",
+			repo:          &FakeProductEventReader{},
+			input:         ListProductEventsInput{Kinds: []string{"price_changed"}, Limit: "10", Offset: "20"},
+			expectedLimit: 10, expectedOffset: 20,
+		},
+		{
+			name:          "lifecycle_kinds",
+			repo:          &FakeProductEventReader{},
+			input:         ListProductEventsInput{Kinds: []string{"discontinued", "relisted"}},
+			expectedLimit: DefaultProductEventLimit,
+		},
+		{name: "unknown_kind", repo: &FakeProductEventReader{}, input: ListProductEventsInput{Kinds: []string{"renamed"}}, expectedErr: apiErrors.ErrInputInvalid},
+		{name: "limit_too_large", repo: &FakeProductEventReader{}, input: ListProductEventsInput{Limit: "201"}, expectedErr: apiErrors.ErrInputInvalid},
+		{name: "negative_offset", repo: &FakeProductEventReader{}, input: ListProductEventsInput{Offset: "-1"}, expectedErr: apiErrors.ErrInputInvalid},
+		{name: "unknown_product", repo: &FakeProductEventReader{err: sql.ErrNoRows}, expectedErr: apiErrors.ErrNoProductFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewDefaultProductEventReaderService(tt.repo)
+
+			resp, err := srv.List(context.Background(), uuid.New(), tt.input)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected err %v, received %v", tt.expectedErr, err)
+			}
+			if tt.expectedErr != nil {
+				return
+			}
+			if len(resp.Events) != 1 || resp.Events[0].Kind != "price_changed" {
+				t.Fatalf("unexpected events %+v", resp.Events)
+			}
+			if tt.repo.limit != tt.expectedLimit || tt.repo.offset != tt.expectedOffset || len(tt.repo.filter.Kinds) != len(tt.input.Kinds) {
+				t.Fatalf("unexpected query %+v", tt.repo)
+			}
+		})
+	}
+}
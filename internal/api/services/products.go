@@ -9,11 +9,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 	apiErrors "uniwish.com/internal/api/errors"
 	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/currency"
 )
 
 type ProductListItemResponse struct {
@@ -22,6 +24,12 @@ type ProductListItemResponse struct {
 	ImageURL  string  `json:"image_url"`
 	LastPrice float64 `json:"last_price"`
 	Currency  string  `json:"currency"`
+	// mirrored image urls are set once the worker copied the store's image
+	MirroredImageURL string `json:"mirrored_image_url,omitempty"`
+	ThumbnailURL     string `json:"thumbnail_url,omitempty"`
+	// display fields are set when a display currency was asked for and the price's currency has a rate
+	DisplayPrice    *float64 `json:"display_price,omitempty"`
+	DisplayCurrency string   `json:"display_currency,omitempty"`
 }
 
 type ProductListResponse struct {
@@ -29,31 +37,140 @@ type ProductListResponse struct {
 }
 
 type ProductItemResponse struct {
-	Name     string `json:"name"`
-	Store    string `json:"store"`
-	ImageURL string `json:"image_url"`
+	Name             string `json:"name"`
+	Store            string `json:"store"`
+	ImageURL         string `json:"image_url"`
+	MirroredImageURL string `json:"mirrored_image_url,omitempty"`
+	ThumbnailURL     string `json:"thumbnail_url,omitempty"`
+	// DiscontinuedAt is set once the store removed the product
+	DiscontinuedAt *time.Time `json:"discontinued_at,omitempty"`
+}
+
+// ImagesPath is where the api serves mirrored images, see handlers.ImageHandler
+const ImagesPath = "/images/"
+
+func imageURL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return ImagesPath + key
 }
 
 type OfferListResponse struct {
-	Price        float64   `json:"price"`
-	Currency     string    `json:"currency"`
-	Availability string    `json:"availability"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	Price           float64   `json:"price"`
+	Currency        string    `json:"currency"`
+	DisplayPrice    *float64  `json:"display_price,omitempty"`
+	DisplayCurrency string    `json:"display_currency,omitempty"`
+	Region          string    `json:"region,omitempty"`
+	Availability    string    `json:"availability"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// RegionPriceResponse is the latest price of the product in one market, display fields make regions comparable
+type RegionPriceResponse struct {
+	Region          string    `json:"region"`
+	URL             string    `json:"url"`
+	Price           float64   `json:"price"`
+	Currency        string    `json:"currency"`
+	DisplayPrice    *float64  `json:"display_price,omitempty"`
+	DisplayCurrency string    `json:"display_currency,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
+
+// MatchResponse is the same item sold by another store
+type MatchResponse struct {
+	ProductID       uuid.UUID `json:"product_id"`
+	Store           string    `json:"store"`
+	Name            string    `json:"name"`
+	URL             string    `json:"url"`
+	Price           float64   `json:"price"`
+	Currency        string    `json:"currency"`
+	DisplayPrice    *float64  `json:"display_price,omitempty"`
+	DisplayCurrency string    `json:"display_currency,omitempty"`
+	Confidence      float64   `json:"confidence"`
+	Method          string    `json:"method"`
+}
+
 type ProductDetailResponse struct {
-	Product ProductItemResponse `json:"product"`
-	Offers  []OfferListResponse `json:"offers"`
+	Product         ProductItemResponse   `json:"product"`
+	Offers          []OfferListResponse   `json:"offers"`
+	Regions         []RegionPriceResponse `json:"regions"`
+	AlsoAvailableAt []MatchResponse       `json:"also_available_at"`
 }
 
 type ProductReaderService interface {
-	Get(context.Context, uuid.UUID) (*ProductDetailResponse, error)
-	List(context.Context) (*ProductListResponse, error)
+	// displayCurrency is an ISO 4217 code prices are also converted to, empty for none
+	Get(ctx context.Context, id uuid.UUID, displayCurrency string) (*ProductDetailResponse, error)
+	List(ctx context.Context, displayCurrency string) (*ProductListResponse, error)
 }
 type DefaultProductReaderService struct {
-	repo repository.ProductReader
+	repo  repository.ProductReader
+	rates repository.FXRateRepository
+}
+
+// displayConverter converts prices to a display currency, the zero value leaves prices alone
+type displayConverter struct {
+	rates *currency.Rates
+	to    string
+}
+
+func (s *DefaultProductReaderService) converterFor(ctx context.Context, displayCurrency string) (displayConverter, error) {
+	if displayCurrency == "" {
+		return displayConverter{}, nil
+	}
+
+	code, err := currency.Normalize(displayCurrency)
+	if err != nil {
+		return displayConverter{}, apiErrors.Invalid(apiErrors.FieldError{
+			Field:   "currency",
+			Code:    "invalid",
+			Message: "currency must be an ISO 4217 code",
+		})
+	}
+
+	var rates *currency.Rates
+	if s.rates != nil {
+		if rates, err = s.rates.LatestRates(ctx); err != nil {
+			return displayConverter{}, err
+		}
+	}
+
+	if rates == nil || !rates.Supports(code) {
+		return displayConverter{}, apiErrors.Invalid(apiErrors.FieldError{
+			Field:   "currency",
+			Code:    "unsupported",
+			Message: "no exchange rate for " + code,
+		})
+	}
+	return displayConverter{rates: rates, to: code}, nil
 }
 
-func (s *DefaultProductReaderService) List(ctx context.Context) (*ProductListResponse, error) {
+func (c displayConverter) convert(amount float64, from string) (*float64, string) {
+	// prices in a currency without a rate keep only their original amount
+	if c.rates == nil {
+		return nil, ""
+	}
+
+	converted, err := c.rates.Convert(amount, from, c.to)
+	if err != nil {
+		return nil, ""
+	}
+
+	units, _ := currency.MinorUnits(c.to)
+	scale := math.Pow10(units)
+	converted = math.Round(converted*scale) / scale
+	return &converted, c.to
+}
+
+func (s *DefaultProductReaderService) List(ctx context.Context, displayCurrency string) (*ProductListResponse, error) {
+	ctx, span := tracer.Start(ctx, "DefaultProductReaderService.List")
+	defer span.End()
+
+	converter, err := s.converterFor(ctx, displayCurrency)
+	if err != nil {
+		return nil, err
+	}
+
 	list, err := s.repo.ListProducts(ctx)
 	if err != nil {
 		return nil, err
@@ -70,14 +187,26 @@ func (s *DefaultProductReaderService) List(ctx context.Context) (*ProductListRes
 			ImageURL:  product.ImageURL,
 			LastPrice: product.LastPrice,
 			Currency:  product.Currency,
+
+			MirroredImageURL: imageURL(product.ImageKey),
+			ThumbnailURL:     imageURL(product.ThumbnailKey),
 		}
+		pli.DisplayPrice, pli.DisplayCurrency = converter.convert(product.LastPrice, product.Currency)
 		plr.Products = append(plr.Products, pli)
 	}
 
 	return plr, nil
 }
 
-func (s *DefaultProductReaderService) Get(ctx context.Context, uuid uuid.UUID) (*ProductDetailResponse, error) {
+func (s *DefaultProductReaderService) Get(ctx context.Context, uuid uuid.UUID, displayCurrency string) (*ProductDetailResponse, error) {
+	ctx, span := tracer.Start(ctx, "DefaultProductReaderService.Get")
+	defer span.End()
+
+	converter, err := s.converterFor(ctx, displayCurrency)
+	if err != nil {
+		return nil, err
+	}
+
 	product, err := s.repo.GetProduct(ctx, uuid)
 
 	if err != nil {
@@ -92,9 +221,12 @@ func (s *DefaultProductReaderService) Get(ctx context.Context, uuid uuid.UUID) (
 	}
 
 	pir := ProductItemResponse{
-		Name:     product.Name,
-		Store:    product.Store,
-		ImageURL: product.ImageURL,
+		Name:             product.Name,
+		Store:            product.Store,
+		ImageURL:         product.ImageURL,
+		MirroredImageURL: imageURL(product.ImageKey),
+		ThumbnailURL:     imageURL(product.ThumbnailKey),
+		DiscontinuedAt:   product.DiscontinuedAt,
 	}
 
 	offers := make([]OfferListResponse, 0, len(product.Offers))
@@ -103,18 +235,52 @@ func (s *DefaultProductReaderService) Get(ctx context.Context, uuid uuid.UUID) (
 		olr := OfferListResponse{
 			Price:        o.Price,
 			Currency:     o.Currency,
+			Region:       o.Region,
 			Availability: o.Availability,
 			UpdatedAt:    o.UpdatedAt,
 		}
+		olr.DisplayPrice, olr.DisplayCurrency = converter.convert(o.Price, o.Currency)
 		offers = append(offers, olr)
 	}
+
+	regions := make([]RegionPriceResponse, 0, len(product.Regions))
+	for _, r := range product.Regions {
+		rpr := RegionPriceResponse{
+			Region:    r.Region,
+			URL:       r.URL,
+			Price:     r.LastPrice,
+			Currency:  r.Currency,
+			UpdatedAt: r.UpdatedAt,
+		}
+		rpr.DisplayPrice, rpr.DisplayCurrency = converter.convert(r.LastPrice, r.Currency)
+		regions = append(regions, rpr)
+	}
+
+	matches := make([]MatchResponse, 0, len(product.AlsoAvailableAt))
+	for _, m := range product.AlsoAvailableAt {
+		mr := MatchResponse{
+			ProductID:  m.ProductID,
+			Store:      m.Store,
+			Name:       m.Name,
+			URL:        m.URL,
+			Price:      m.LastPrice,
+			Currency:   m.Currency,
+			Confidence: m.Confidence,
+			Method:     m.Method,
+		}
+		mr.DisplayPrice, mr.DisplayCurrency = converter.convert(m.LastPrice, m.Currency)
+		matches = append(matches, mr)
+	}
+
 	pdr := &ProductDetailResponse{
-		Product: pir,
-		Offers:  offers,
+		Product:         pir,
+		Offers:          offers,
+		Regions:         regions,
+		AlsoAvailableAt: matches,
 	}
 	return pdr, nil
 }
 
-func NewDefaultProductReaderService(repo repository.ProductReader) ProductReaderService {
-	return &DefaultProductReaderService{repo: repo}
+func NewDefaultProductReaderService(repo repository.ProductReader, rates repository.FXRateRepository) ProductReaderService {
+	return &DefaultProductReaderService{repo: repo, rates: rates}
 }
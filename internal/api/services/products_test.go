@@ -0,0 +1,150 @@
+/*
+uniwish.com/interal/api/services/products_test
+
+tests for product service
+*/
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/currency"
+)
+
+type FakeProductReader struct{}
+
+func (r *FakeProductReader) ListProducts(_ context.Context) ([]repository.ProductListItem, error) {
+	return []repository.ProductListItem{
+		{Name: "jacket", LastPrice: 100, Currency: "USD", ImageKey: "originals/ab.jpeg", ThumbnailKey: "thumbnails/ab.jpeg"},
+		{Name: "shoes", LastPrice: 50, Currency: "MXN"},
+	}, nil
+}
+
+func (r *FakeProductReader) GetProduct(_ context.Context, _ uuid.UUID) (*repository.ProductDetail, error) {
+	return &repository.ProductDetail{
+		Name:   "jacket",
+		Offers: []repository.OfferListItem{{Price: 80, Currency: "EUR", Region: "es", UpdatedAt: time.Now()}},
+		Regions: []repository.RegionListItem{
+			{Region: "es", URL: "https://store.com/es/jacket", LastPrice: 80, Currency: "EUR", UpdatedAt: time.Now()},
+			{Region: "us", URL: "https://store.com/us/jacket", LastPrice: 110, Currency: "USD", UpdatedAt: time.Now()},
+		},
+		AlsoAvailableAt: []repository.MatchListItem{
+			{ProductID: uuid.New(), Store: "reseller", Name: "jacket", LastPrice: 125, Currency: "USD", Confidence: 0.99, Method: "gtin"},
+		},
+	}, nil
+}
+
+type FakeRates struct {
+	rates *currency.Rates
+}
+
+func (r *FakeRates) SaveRates(_ context.Context, _ *currency.Rates) error {
+	return nil
+}
+
+func (r *FakeRates) LatestRates(_ context.Context) (*currency.Rates, error) {
+	return r.rates, nil
+}
+
+var fakeRates = &FakeRates{rates: &currency.Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.25, "JPY": 160}}}
+
+func TestProductReaderService_DisplayCurrency(t *testing.T) {
+	srv := NewDefaultProductReaderService(&FakeProductReader{}, fakeRates)
+
+	list, err := srv.List(context.Background(), "eur")
+	if err != nil {
+		t.Fatalf("expected err to be nil, received %v", err)
+	}
+
+	converted := list.Products[0]
+	if converted.DisplayPrice == nil || *converted.DisplayPrice != 80 || converted.DisplayCurrency != "EUR" {
+		t.Fatalf("expected 80 EUR, received %+v", converted)
+	}
+	if converted.LastPrice != 100 || converted.Currency != "USD" {
+		t.Fatalf("expected original price to be kept, received %+v", converted)
+	}
+
+	// no rate for MXN, the price is left unconverted rather than failing the list
+	if unconverted := list.Products[1]; unconverted.DisplayPrice != nil || unconverted.DisplayCurrency != "" {
+		t.Fatalf("expected no display price, received %+v", unconverted)
+	}
+
+	detail, err := srv.Get(context.Background(), uuid.New(), "JPY")
+	if err != nil {
+		t.Fatalf("expected err to be nil, received %v", err)
+	}
+	// rounded to JPY's zero minor units
+	if offer := detail.Offers[0]; offer.DisplayPrice == nil || *offer.DisplayPrice != 12800 {
+		t.Fatalf("expected 12800 JPY, received %+v", offer)
+	}
+}
+
+func TestProductReaderService_Regions(t *testing.T) {
+	srv := NewDefaultProductReaderService(&FakeProductReader{}, fakeRates)
+
+	detail, err := srv.Get(context.Background(), uuid.New(), "EUR")
+	if err != nil {
+		t.Fatalf("expected err to be nil, received %v", err)
+	}
+
+	if len(detail.Regions) != 2 {
+		t.Fatalf("expected 2 regions, received %d", len(detail.Regions))
+	}
+	// both regions are comparable in the display currency
+	us := detail.Regions[1]
+	if us.Region != "us" || us.Price != 110 || us.DisplayPrice == nil || *us.DisplayPrice != 88 {
+		t.Fatalf("expected us at 110 USD shown as 88 EUR, received %+v", us)
+	}
+	if match := detail.AlsoAvailableAt[0]; match.Store != "reseller" || match.DisplayPrice == nil || *match.DisplayPrice != 100 {
+		t.Fatalf("expected reseller at 125 USD shown as 100 EUR, received %+v", match)
+	}
+	if detail.Offers[0].Region != "es" {
+		t.Fatalf("expected offer region es, received %q", detail.Offers[0].Region)
+	}
+}
+
+func TestProductReaderService_DisplayCurrencyRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		rates    *FakeRates
+	}{
+		{name: "invalid_code", currency: "EURO", rates: fakeRates},
+		{name: "no_rate", currency: "MXN", rates: fakeRates},
+		{name: "no_rates_loaded", currency: "USD", rates: &FakeRates{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewDefaultProductReaderService(&FakeProductReader{}, tt.rates)
+
+			if _, err := srv.List(context.Background(), tt.currency); !errors.Is(err, apiErrors.ErrInputInvalid) {
+				t.Fatalf("expected %v, received %v", apiErrors.ErrInputInvalid, err)
+			}
+		})
+	}
+}
+
+func TestProductReaderService_MirroredImages(t *testing.T) {
+	srv := NewDefaultProductReaderService(&FakeProductReader{}, nil)
+
+	list, err := srv.List(context.Background(), "")
+	if err != nil {
+		t.Fatalf("expected err to be nil, received %v", err)
+	}
+
+	mirrored := list.Products[0]
+	if mirrored.MirroredImageURL != "/images/originals/ab.jpeg" || mirrored.ThumbnailURL != "/images/thumbnails/ab.jpeg" {
+		t.Fatalf("expected mirrored image urls, received %+v", mirrored)
+	}
+	// not mirrored yet, only the store's own url is known
+	if unmirrored := list.Products[1]; unmirrored.MirroredImageURL != "" || unmirrored.ThumbnailURL != "" {
+		t.Fatalf("expected no mirrored image urls, received %+v", unmirrored)
+	}
+}
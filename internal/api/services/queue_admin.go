@@ -0,0 +1,202 @@
+/*
+uniwish.com/internal/api/services/queue_admin
+
+contains the operator facing queue actions, used to recover from a broken store by retrying or cancelling jobs in bulk
+*/
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/logging"
+)
+
+const (
+	DefaultAdminListLimit = 50
+	MaxAdminListLimit     = 500
+	// DefaultPurgeAge is how long done requests are kept when a purge does not say
+	DefaultPurgeAge = 30 * 24 * time.Hour
+)
+
+var scrapeRequestStatuses = []string{
+	repository.StatusPending,
+	repository.StatusProcessing,
+	repository.StatusDone,
+	repository.StatusFailed,
+	repository.StatusCancelled,
+}
+
+type ListScrapeRequestsInput struct {
+	Status    string
+	Store     string
+	ErrorKind string
+	// Limit and Offset are read from the query string, empty takes the defaults
+	Limit  string
+	Offset string
+}
+
+// ScrapeRequestSelection picks the requests a bulk action applies to, by id and/or filter
+type ScrapeRequestSelection struct {
+	IDs       []string
+	Store     string
+	ErrorKind string
+	// All must be set to act on every request, guarding against an accidentally empty selection
+	All bool
+}
+
+type PurgeInput struct {
+	// OlderThanDays defaults to DefaultPurgeAge when zero
+	OlderThanDays int
+}
+
+type QueueAdministrator interface {
+	List(ctx context.Context, input ListScrapeRequestsInput) ([]repository.ScrapeRequestListItem, error)
+	Retry(ctx context.Context, selection ScrapeRequestSelection) (int64, error)
+	Cancel(ctx context.Context, selection ScrapeRequestSelection) (int64, error)
+	Purge(ctx context.Context, input PurgeInput) (int64, error)
+}
+
+// DisabledQueueAdmin refuses every operation, standing in for the service while queue.admin is off
+type DisabledQueueAdmin struct{}
+
+func (DisabledQueueAdmin) List(context.Context, ListScrapeRequestsInput) ([]repository.ScrapeRequestListItem, error) {
+	return nil, errors.ErrQueueAdminUnsupported
+}
+
+func (DisabledQueueAdmin) Retry(context.Context, ScrapeRequestSelection) (int64, error) {
+	return 0, errors.ErrQueueAdminUnsupported
+}
+
+func (DisabledQueueAdmin) Cancel(context.Context, ScrapeRequestSelection) (int64, error) {
+	return 0, errors.ErrQueueAdminUnsupported
+}
+
+func (DisabledQueueAdmin) Purge(context.Context, PurgeInput) (int64, error) {
+	return 0, errors.ErrQueueAdminUnsupported
+}
+
+type QueueAdminService struct {
+	repo repository.QueueAdmin
+	now  func() time.Time
+}
+
+func NewQueueAdminService(repo repository.QueueAdmin) *QueueAdminService {
+	return &QueueAdminService{repo: repo, now: time.Now}
+}
+
+func (s *QueueAdminService) List(ctx context.Context, input ListScrapeRequestsInput) ([]repository.ScrapeRequestListItem, error) {
+	ctx, span := tracer.Start(ctx, "QueueAdminService.List")
+	defer span.End()
+
+	var fields []errors.FieldError
+	if input.Status != "" && !slices.Contains(scrapeRequestStatuses, input.Status) {
+		fields = append(fields, errors.FieldError{Field: "status", Code: "invalid", Message: "status is not a scrape request status"})
+	}
+	limit, err := parseBound(input.Limit, DefaultAdminListLimit)
+	if err != nil || limit < 1 || limit > MaxAdminListLimit {
+		fields = append(fields, errors.FieldError{
+			Field:   "limit",
+			Code:    "invalid",
+			Message: fmt.Sprintf("limit must be between 1 and %d", MaxAdminListLimit),
+		})
+	}
+	offset, err := parseBound(input.Offset, 0)
+	if err != nil || offset < 0 {
+		fields = append(fields, errors.FieldError{Field: "offset", Code: "invalid", Message: "offset must be a non negative integer"})
+	}
+	if len(fields) > 0 {
+		return nil, errors.Invalid(fields...)
+	}
+
+	return s.repo.List(ctx, repository.ScrapeRequestFilter{
+		Status:    input.Status,
+		Store:     input.Store,
+		ErrorKind: input.ErrorKind,
+	}, limit, offset)
+}
+
+func (s *QueueAdminService) Retry(ctx context.Context, selection ScrapeRequestSelection) (int64, error) {
+	ctx, span := tracer.Start(ctx, "QueueAdminService.Retry")
+	defer span.End()
+	filter, err := selection.filter()
+	if err != nil {
+		return 0, err
+	}
+
+	retried, err := s.repo.Retry(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	logging.FromContext(ctx).Info("scrape requests retried", "count", retried, "store", filter.Store, "error_kind", filter.ErrorKind, "ids", len(filter.IDs))
+	return retried, nil
+}
+
+func (s *QueueAdminService) Cancel(ctx context.Context, selection ScrapeRequestSelection) (int64, error) {
+	ctx, span := tracer.Start(ctx, "QueueAdminService.Cancel")
+	defer span.End()
+	filter, err := selection.filter()
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled, err := s.repo.Cancel(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	logging.FromContext(ctx).Info("scrape requests cancelled", "count", cancelled, "store", filter.Store, "error_kind", filter.ErrorKind, "ids", len(filter.IDs))
+	return cancelled, nil
+}
+
+func (s *QueueAdminService) Purge(ctx context.Context, input PurgeInput) (int64, error) {
+	ctx, span := tracer.Start(ctx, "QueueAdminService.Purge")
+	defer span.End()
+	if input.OlderThanDays < 0 {
+		return 0, errors.Invalid(errors.FieldError{Field: "older_than_days", Code: "invalid", Message: "older_than_days must be positive"})
+	}
+
+	age := DefaultPurgeAge
+	if input.OlderThanDays > 0 {
+		age = time.Duration(input.OlderThanDays) * 24 * time.Hour
+	}
+
+	purged, err := s.repo.Purge(ctx, s.now().Add(-age))
+	if err != nil {
+		return 0, err
+	}
+	logging.FromContext(ctx).Info("scrape requests purged", "count", purged, "older_than", age)
+	return purged, nil
+}
+
+func (sel ScrapeRequestSelection) filter() (repository.ScrapeRequestFilter, error) {
+	if len(sel.IDs) == 0 && sel.Store == "" && sel.ErrorKind == "" && !sel.All {
+		return repository.ScrapeRequestFilter{}, errors.Invalid(errors.FieldError{
+			Field:   "ids",
+			Code:    "required",
+			Message: "ids, store or error_kind is required, or all to select every request",
+		})
+	}
+
+	filter := repository.ScrapeRequestFilter{Store: sel.Store, ErrorKind: sel.ErrorKind}
+	for _, raw := range sel.IDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return repository.ScrapeRequestFilter{}, errors.Invalid(errors.FieldError{Field: "ids", Code: "invalid", Message: "ids must be uuids"})
+		}
+		filter.IDs = append(filter.IDs, id)
+	}
+	return filter, nil
+}
+
+func parseBound(raw string, def int) (int, error) {
+	if raw == "" {
+		return def, nil
+	}
+	return strconv.Atoi(raw)
+}
@@ -0,0 +1,156 @@
+/*
+uniwish.com/internal/api/services/queue_admin_test
+
+tests for the queue admin service
+*/
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+)
+
+type FakeQueueAdmin struct {
+	filter repository.ScrapeRequestFilter
+	limit  int
+	offset int
+	cutoff time.Time
+}
+
+func (r *FakeQueueAdmin) List(_ context.Context, filter repository.ScrapeRequestFilter, limit int, offset int) ([]repository.ScrapeRequestListItem, error) {
+	r.filter, r.limit, r.offset = filter, limit, offset
+	return []repository.ScrapeRequestListItem{{ID: uuid.New(), Status: filter.Status}}, nil
+}
+
+func (r *FakeQueueAdmin) Retry(_ context.Context, filter repository.ScrapeRequestFilter) (int64, error) {
+	r.filter = filter
+	return 3, nil
+}
+
+func (r *FakeQueueAdmin) Cancel(_ context.Context, filter repository.ScrapeRequestFilter) (int64, error) {
+	r.filter = filter
+	return 2, nil
+}
+
+func (r *FakeQueueAdmin) Purge(_ context.Context, cutoff time.Time) (int64, error) {
+	r.cutoff = cutoff
+	return 1, nil
+}
+
+func TestQueueAdminService_List(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          ListScrapeRequestsInput
+		expectedError  error
+		expectedLimit  int
+		expectedOffset int
+	}{
+		{name: "defaults", input: ListScrapeRequestsInput{}, expectedLimit: DefaultAdminListLimit},
+		{name: "paged", input: ListScrapeRequestsInput{Status: "failed", Limit: "10", Offset: "20"}, expectedLimit: 10, expectedOffset: 20},
+		{name: "unknown_status", input: ListScrapeRequestsInput{Status: "broken"}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "limit_too_large", input: ListScrapeRequestsInput{Limit: "10000"}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "limit_not_a_number", input: ListScrapeRequestsInput{Limit: "many"}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "negative_offset", input: ListScrapeRequestsInput{Offset: "-1"}, expectedError: apiErrors.ErrInputInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeQueueAdmin{}
+			service := NewQueueAdminService(repo)
+
+			_, err := service.List(context.Background(), tt.input)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if err == nil && (repo.limit != tt.expectedLimit || repo.offset != tt.expectedOffset) {
+				t.Fatalf("expected limit %d offset %d, received %d %d", tt.expectedLimit, tt.expectedOffset, repo.limit, repo.offset)
+			}
+		})
+	}
+}
+
+func TestQueueAdminService_Selection(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name          string
+		selection     ScrapeRequestSelection
+		expectedError error
+		expected      repository.ScrapeRequestFilter
+	}{
+		{name: "ids", selection: ScrapeRequestSelection{IDs: []string{id.String()}}, expected: repository.ScrapeRequestFilter{IDs: []uuid.UUID{id}}},
+		{name: "filter", selection: ScrapeRequestSelection{Store: "zara", ErrorKind: "scrape_failed"}, expected: repository.ScrapeRequestFilter{Store: "zara", ErrorKind: "scrape_failed"}},
+		{name: "all", selection: ScrapeRequestSelection{All: true}, expected: repository.ScrapeRequestFilter{}},
+		{name: "empty", selection: ScrapeRequestSelection{}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "invalid_id", selection: ScrapeRequestSelection{IDs: []string{"nope"}}, expectedError: apiErrors.ErrInputInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for action, run := range map[string]func(*QueueAdminService) (int64, error){
+				"retry":  func(s *QueueAdminService) (int64, error) { return s.Retry(context.Background(), tt.selection) },
+				"cancel": func(s *QueueAdminService) (int64, error) { return s.Cancel(context.Background(), tt.selection) },
+			} {
+				repo := &FakeQueueAdmin{}
+				_, err := run(NewQueueAdminService(repo))
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("%s: expected error %v, received %v", action, tt.expectedError, err)
+				}
+				if err != nil {
+					continue
+				}
+				if repo.filter.Store != tt.expected.Store || repo.filter.ErrorKind != tt.expected.ErrorKind || len(repo.filter.IDs) != len(tt.expected.IDs) {
+					t.Fatalf("%s: expected filter %+v, received %+v", action, tt.expected, repo.filter)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueAdminService_Purge(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		input          PurgeInput
+		expectedError  error
+		expectedCutoff time.Time
+	}{
+		{name: "default_age", input: PurgeInput{}, expectedCutoff: now.Add(-DefaultPurgeAge)},
+		{name: "days", input: PurgeInput{OlderThanDays: 7}, expectedCutoff: now.AddDate(0, 0, -7)},
+		{name: "negative", input: PurgeInput{OlderThanDays: -1}, expectedError: apiErrors.ErrInputInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeQueueAdmin{}
+			service := NewQueueAdminService(repo)
+			service.now = func() time.Time { return now }
+
+			_, err := service.Purge(context.Background(), tt.input)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if err == nil && !repo.cutoff.Equal(tt.expectedCutoff) {
+				t.Fatalf("expected cutoff %v, received %v", tt.expectedCutoff, repo.cutoff)
+			}
+		})
+	}
+}
+
+func TestDisabledQueueAdmin(t *testing.T) {
+	var service QueueAdministrator = DisabledQueueAdmin{}
+
+	if _, err := service.List(context.Background(), ListScrapeRequestsInput{}); !errors.Is(err, apiErrors.ErrQueueAdminUnsupported) {
+		t.Fatalf("expected %v, received %v", apiErrors.ErrQueueAdminUnsupported, err)
+	}
+	if _, err := service.Retry(context.Background(), ScrapeRequestSelection{All: true}); !errors.Is(err, apiErrors.ErrQueueAdminUnsupported) {
+		t.Fatalf("expected %v, received %v", apiErrors.ErrQueueAdminUnsupported, err)
+	}
+}
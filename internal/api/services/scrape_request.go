@@ -8,39 +8,299 @@ package services
 import (
 	"context"
 	goErrors "errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"uniwish.com/internal/api/errors"
 	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/logging"
 	"uniwish.com/internal/scrapers"
+	"uniwish.com/internal/tracing"
 )
 
+const (
+	PriorityInteractive = "interactive"
+	PriorityBulk        = "bulk"
+)
+
+type ScrapeRequestInput struct {
+	URL string
+	// OwnerID identifies the user the request is scheduled fairly against
+	OwnerID string
+	// ClientIP is where the request came from, every request's quota is counted against it
+	ClientIP string
+	// Priority is either "interactive" or "bulk", defaults to "interactive"
+	Priority string
+}
+
 type ScrapeRequester interface {
-	Request(ctx context.Context, rawUrl string) (uuid.UUID, error)
+	Request(ctx context.Context, input ScrapeRequestInput) (uuid.UUID, error)
 }
 type ScrapeRequestService struct {
-	repo     repository.ScrapeRequestRepository
+	queue    repository.Queue
 	registry scrapers.Registry
+	// Quota enforces DailyQuota requests per client address and per owner when set
+	Quota      repository.QuotaRepository
+	DailyQuota int
+	// MaxBatchSize caps the urls accepted by RequestBatch, 0 or anything above repository.MaxEnqueueBatch
+	// means repository.MaxEnqueueBatch
+	MaxBatchSize int
+	// Discontinued refuses urls of products discontinued for longer than DiscontinuedGrace when set,
+	// within the grace a scrape may still find a page that was only briefly gone
+	Discontinued      repository.DiscontinuedProductReader
+	DiscontinuedGrace time.Duration
 }
 
-func NewScrapeRequestService(sr repository.ScrapeRequestRepository, registry scrapers.Registry) *ScrapeRequestService {
-	return &ScrapeRequestService{repo: sr, registry: registry}
+func NewScrapeRequestService(queue repository.Queue, registry scrapers.Registry) *ScrapeRequestService {
+	return &ScrapeRequestService{queue: queue, registry: registry, MaxBatchSize: DefaultMaxBatchSize}
 }
 
-func (s *ScrapeRequestService) Request(ctx context.Context, rawUrl string) (uuid.UUID, error) {
-	// validates the rawUrl and if ok, inserts a scrape request to the db
-	if rawUrl == "" {
-		return uuid.Nil, errors.ErrInputInvalid
+func (s *ScrapeRequestService) Request(ctx context.Context, input ScrapeRequestInput) (uuid.UUID, error) {
+	// validates the input url and priority and if ok, enqueues a scrape request
+	ctx, span := tracer.Start(ctx, "ScrapeRequestService.Request")
+	defer span.End()
+
+	if input.URL == "" {
+		return uuid.Nil, errors.Invalid(errors.FieldError{Field: "url", Code: "required", Message: "url is required"})
 	}
 
-	err := s.registry.ValidateUrl(rawUrl)
+	priority, err := parsePriority(input.Priority)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	store, err := s.registry.StoreFor(input.URL)
 
 	switch {
 	case goErrors.Is(err, scrapers.ErrInvalidURL):
-		return uuid.Nil, errors.ErrInputInvalid
+		return uuid.Nil, errors.Invalid(errors.FieldError{Field: "url", Code: "invalid", Message: "url is not a valid product url"})
 	case goErrors.Is(err, scrapers.ErrNoScraper):
 		return uuid.Nil, errors.ErrStoreUnsupported
+	}
+
+	discontinued, err := s.discontinued(ctx, input.URL)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if discontinued {
+		return uuid.Nil, errors.ErrProductDiscontinued
+	}
+
+	if err := s.consumeQuota(ctx, input.OwnerID, input.ClientIP, 1); err != nil {
+		return uuid.Nil, err
+	}
+
+	id, err := s.queue.Enqueue(ctx, repository.ScrapeRequest{
+		URL:      input.URL,
+		Priority: priority,
+		OwnerID:  input.OwnerID,
+		Store:    store,
+		// lets the worker's span link back to this request
+		TraceContext: tracing.Inject(ctx),
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	logging.FromContext(ctx).Info("scrape request enqueued", "scrape_request_id", id, "store", store, "priority", input.Priority)
+	return id, nil
+}
+
+func (s *ScrapeRequestService) consumeQuota(ctx context.Context, ownerID string, clientIP string, n int) error {
+	// consumed before enqueueing, a failed enqueue costs the caller one request rather than letting quotas be overshot.
+	// owner ids are whatever the caller claims, so the client address is always charged and a fresh id does not
+	// buy a fresh quota, the owner is charged as well so a user spread over several addresses is still held to theirs
+	if s.Quota == nil || s.DailyQuota <= 0 {
+		return nil
+	}
+	var keys []string
+	if clientIP != "" {
+		keys = append(keys, "ip:"+clientIP)
+	}
+	if ownerID != "" {
+		keys = append(keys, ownerID)
+	}
+
+	for _, key := range keys {
+		// an owner refused after its address keeps the address charged, erring on the side of the limit
+		ok, err := s.Quota.Consume(ctx, key, time.Now(), n, s.DailyQuota)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.ErrQuotaExceeded
+		}
+	}
+	return nil
+}
+
+func (s *ScrapeRequestService) discontinued(ctx context.Context, url string) (bool, error) {
+	if s.Discontinued == nil {
+		return false, nil
+	}
+
+	since, err := s.Discontinued.DiscontinuedSince(ctx, url)
+	if err != nil {
+		return false, err
+	}
+	return since != nil && time.Since(*since) > s.DiscontinuedGrace, nil
+}
+
+func parsePriority(raw string) (repository.Priority, error) {
+	switch raw {
+	case "", PriorityInteractive:
+		return repository.PriorityInteractive, nil
+	case PriorityBulk:
+		return repository.PriorityBulk, nil
+	default:
+		return 0, errors.Invalid(errors.FieldError{
+			Field:   "priority",
+			Code:    "invalid",
+			Message: "priority must be " + PriorityInteractive + " or " + PriorityBulk,
+		})
+	}
+}
+
+// PriorityName is parsePriority's inverse, reporting stored priorities the way requests spell them
+func PriorityName(p repository.Priority) string {
+	if p >= repository.PriorityInteractive {
+		return PriorityInteractive
+	}
+	return PriorityBulk
+}
+
+const (
+	BatchItemAccepted         = "accepted"
+	BatchItemInvalid          = "invalid"
+	BatchItemUnsupportedStore = "unsupported_store"
+	BatchItemDuplicate        = "duplicate"
+	BatchItemDiscontinued     = "discontinued"
+)
+
+// DefaultMaxBatchSize caps how many urls a single batch may carry
+const DefaultMaxBatchSize = 500
+
+type BatchScrapeRequestInput struct {
+	URLs     []string
+	OwnerID  string
+	ClientIP string
+	// Priority is either "interactive" or "bulk", defaults to "bulk"
+	Priority string
+}
+
+type BatchItemResult struct {
+	URL    string
+	Status string
+	// ID is set for accepted items
+	ID uuid.UUID
+}
+
+type BatchScrapeRequester interface {
+	RequestBatch(ctx context.Context, input BatchScrapeRequestInput) ([]BatchItemResult, error)
+}
+
+func (s *ScrapeRequestService) RequestBatch(ctx context.Context, input BatchScrapeRequestInput) ([]BatchItemResult, error) {
+	// validates every url on its own, then enqueues the valid ones atomically
+	// invalid and unsupported urls are reported per item rather than failing the batch
+	ctx, span := tracer.Start(ctx, "ScrapeRequestService.RequestBatch")
+	defer span.End()
+
+	if len(input.URLs) == 0 {
+		return nil, errors.Invalid(errors.FieldError{Field: "urls", Code: "required", Message: "at least one url is required"})
+	}
+	maxBatchSize := s.MaxBatchSize
+	if maxBatchSize <= 0 || maxBatchSize > repository.MaxEnqueueBatch {
+		maxBatchSize = repository.MaxEnqueueBatch
+	}
+	if len(input.URLs) > maxBatchSize {
+		return nil, errors.Invalid(errors.FieldError{
+			Field:   "urls",
+			Code:    "too_many",
+			Message: fmt.Sprintf("at most %d urls are accepted per batch", maxBatchSize),
+		})
+	}
+
+	if input.Priority == "" {
+		input.Priority = PriorityBulk
+	}
+	priority, err := parsePriority(input.Priority)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchItemResult, len(input.URLs))
+	accepted := make([]int, 0, len(input.URLs))
+	requests := make([]repository.ScrapeRequest, 0, len(input.URLs))
+	seen := make(map[string]bool, len(input.URLs))
+	traceContext := tracing.Inject(ctx)
+
+	for i, rawURL := range input.URLs {
+		results[i] = BatchItemResult{URL: rawURL, Status: s.validateBatchItem(rawURL)}
+		if results[i].Status != BatchItemAccepted {
+			continue
+		}
+
+		if seen[rawURL] {
+			results[i].Status = BatchItemDuplicate
+			continue
+		}
+		seen[rawURL] = true
+
+		discontinued, err := s.discontinued(ctx, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if discontinued {
+			results[i].Status = BatchItemDiscontinued
+			continue
+		}
+
+		store, _ := s.registry.StoreFor(rawURL)
+		accepted = append(accepted, i)
+		requests = append(requests, repository.ScrapeRequest{
+			URL:          rawURL,
+			Priority:     priority,
+			OwnerID:      input.OwnerID,
+			Store:        store,
+			TraceContext: traceContext,
+		})
+	}
+
+	if len(requests) == 0 {
+		return results, nil
+	}
+
+	if err := s.consumeQuota(ctx, input.OwnerID, input.ClientIP, len(requests)); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.queue.EnqueueBatch(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range accepted {
+		results[i].ID = ids[j]
+	}
+
+	logging.FromContext(ctx).Info(
+		"scrape request batch enqueued",
+		"submitted", len(input.URLs),
+		"accepted", len(requests),
+		"priority", input.Priority,
+	)
+	return results, nil
+}
+
+func (s *ScrapeRequestService) validateBatchItem(rawURL string) string {
+	err := s.registry.ValidateUrl(rawURL)
+	switch {
+	case err == nil:
+		return BatchItemAccepted
+	case goErrors.Is(err, scrapers.ErrNoScraper):
+		return BatchItemUnsupportedStore
 	default:
-		return s.repo.Insert(ctx, rawUrl)
+		return BatchItemInvalid
 	}
 }
@@ -8,32 +8,52 @@ package services
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 	apiErrors "uniwish.com/internal/api/errors"
 	"uniwish.com/internal/api/repository"
 	"uniwish.com/internal/scrapers"
+	"uniwish.com/internal/testutil"
 )
 
 var fakeId uuid.UUID = uuid.New()
 
 type FakeRepo struct {
-	id uuid.UUID
+	id       uuid.UUID
+	inserted repository.ScrapeRequest
+	batch    []repository.ScrapeRequest
 }
 
-func (r *FakeRepo) Insert(_ context.Context, _ string) (uuid.UUID, error) {
+func (r *FakeRepo) Enqueue(_ context.Context, req repository.ScrapeRequest) (uuid.UUID, error) {
+	r.inserted = req
 	return fakeId, nil
 }
-func (r *FakeRepo) Dequeue(_ context.Context) (*repository.ScrapeRequest, error) {
+func (r *FakeRepo) EnqueueBatch(_ context.Context, reqs []repository.ScrapeRequest) ([]uuid.UUID, error) {
+	r.batch = reqs
+	ids := make([]uuid.UUID, len(reqs))
+	for i := range reqs {
+		ids[i] = uuid.New()
+	}
+	return ids, nil
+}
+func (r *FakeRepo) Claim(_ context.Context) (*repository.ScrapeRequest, error) {
 	return nil, nil
 }
 
-func (r *FakeRepo) MarkDone(_ context.Context, _ uuid.UUID) error {
+func (r *FakeRepo) Ack(_ context.Context, _ repository.Lease) error {
+	return nil
+}
+
+func (r *FakeRepo) Nack(_ context.Context, _ repository.Lease, _ repository.Failure) error {
 	return nil
 }
 
-func (r *FakeRepo) MarkFailed(_ context.Context, _ uuid.UUID) error {
+func (r *FakeRepo) ExtendLease(_ context.Context, _ repository.Lease) error {
 	return nil
 }
 
@@ -93,7 +113,7 @@ func TestScrapeRequestService(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			srv := NewScrapeRequestService(&FakeRepo{}, FakeRegistry)
-			r, e := srv.Request(context.Background(), tt.url)
+			r, e := srv.Request(context.Background(), ScrapeRequestInput{URL: tt.url})
 
 			if !errors.Is(e, tt.expectedError) {
 				t.Fatalf("expected error %v, received %v", tt.expectedError, e)
@@ -105,3 +125,276 @@ func TestScrapeRequestService(t *testing.T) {
 		})
 	}
 }
+
+func TestScrapeRequestService_Scheduling(t *testing.T) {
+	tests := []struct {
+		name             string
+		priority         string
+		expectedError    error
+		expectedPriority repository.Priority
+	}{
+		{
+			name:             "default_interactive",
+			priority:         "",
+			expectedPriority: repository.PriorityInteractive,
+		},
+		{
+			name:             "interactive",
+			priority:         PriorityInteractive,
+			expectedPriority: repository.PriorityInteractive,
+		},
+		{
+			name:             "bulk",
+			priority:         PriorityBulk,
+			expectedPriority: repository.PriorityBulk,
+		},
+		{
+			name:          "unknown_priority",
+			priority:      "urgent",
+			expectedError: apiErrors.ErrInputInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeRepo{}
+			srv := NewScrapeRequestService(repo, FakeRegistry)
+			_, e := srv.Request(context.Background(), ScrapeRequestInput{
+				URL:      "http://www.store.com/item",
+				OwnerID:  "user-1",
+				Priority: tt.priority,
+			})
+
+			if !errors.Is(e, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, e)
+			}
+			if e != nil {
+				return
+			}
+
+			if repo.inserted.Priority != tt.expectedPriority {
+				t.Fatalf("expected priority %d, received %d", tt.expectedPriority, repo.inserted.Priority)
+			}
+			if repo.inserted.OwnerID != "user-1" {
+				t.Fatalf("expected owner user-1, received %s", repo.inserted.OwnerID)
+			}
+			if repo.inserted.Store != "store.com" {
+				t.Fatalf("expected store store.com, received %s", repo.inserted.Store)
+			}
+		})
+	}
+}
+
+func TestScrapeRequestService_PropagatesTraceContext(t *testing.T) {
+	testutil.RecordSpans(t)
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "POST /scrape-requests")
+	defer span.End()
+
+	repo := &FakeRepo{}
+	srv := NewScrapeRequestService(repo, FakeRegistry)
+	if _, err := srv.Request(ctx, ScrapeRequestInput{URL: "http://store.com"}); err != nil {
+		t.Fatalf("expected nil error, received %v", err)
+	}
+
+	traceparent := repo.inserted.TraceContext["traceparent"]
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Fatalf("expected traceparent of trace %s, received %q", span.SpanContext().TraceID(), traceparent)
+	}
+}
+
+type FakeQuota struct {
+	allowed bool
+	// exhausted are refused even when allowed
+	exhausted []string
+	consumed  []string
+}
+
+func (q *FakeQuota) Consume(_ context.Context, ownerID string, _ time.Time, _ int, _ int) (bool, error) {
+	q.consumed = append(q.consumed, ownerID)
+	return q.allowed && !slices.Contains(q.exhausted, ownerID), nil
+}
+
+func TestScrapeRequestService_DailyQuota(t *testing.T) {
+	tests := []struct {
+		name             string
+		ownerID          string
+		clientIP         string
+		allowed          bool
+		exhausted        []string
+		expectedError    error
+		expectedEnqueued bool
+		expectedConsumed []string
+	}{
+		{name: "within_quota", ownerID: "user-1", clientIP: "203.0.113.7", allowed: true, expectedEnqueued: true, expectedConsumed: []string{"ip:203.0.113.7", "user-1"}},
+		{name: "exceeded", ownerID: "user-1", clientIP: "203.0.113.7", allowed: false, expectedError: apiErrors.ErrQuotaExceeded, expectedConsumed: []string{"ip:203.0.113.7"}},
+		{
+			// a fresh owner id does not buy a fresh quota from the same address
+			name: "new_owner_same_address", ownerID: "user-2", clientIP: "203.0.113.7", allowed: true, exhausted: []string{"ip:203.0.113.7"},
+			expectedError: apiErrors.ErrQuotaExceeded, expectedConsumed: []string{"ip:203.0.113.7"},
+		},
+		{
+			name: "owner_exceeded_elsewhere", ownerID: "user-1", clientIP: "198.51.100.4", allowed: true, exhausted: []string{"user-1"},
+			expectedError: apiErrors.ErrQuotaExceeded, expectedConsumed: []string{"ip:198.51.100.4", "user-1"},
+		},
+		{name: "no_address", ownerID: "user-1", allowed: true, expectedEnqueued: true, expectedConsumed: []string{"user-1"}},
+		{name: "anonymous", clientIP: "203.0.113.7", allowed: false, expectedError: apiErrors.ErrQuotaExceeded, expectedConsumed: []string{"ip:203.0.113.7"}},
+		{name: "unidentified", allowed: false, expectedEnqueued: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeRepo{}
+			quota := &FakeQuota{allowed: tt.allowed, exhausted: tt.exhausted}
+			srv := NewScrapeRequestService(repo, FakeRegistry)
+			srv.Quota = quota
+			srv.DailyQuota = 10
+
+			_, e := srv.Request(context.Background(), ScrapeRequestInput{URL: "http://www.store.com/item", OwnerID: tt.ownerID, ClientIP: tt.clientIP})
+
+			if !errors.Is(e, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, e)
+			}
+			if enqueued := repo.inserted.URL != ""; enqueued != tt.expectedEnqueued {
+				t.Fatalf("expected enqueued %v, received %v", tt.expectedEnqueued, enqueued)
+			}
+			if !slices.Equal(quota.consumed, tt.expectedConsumed) {
+				t.Fatalf("expected quota consumed for %q, received %q", tt.expectedConsumed, quota.consumed)
+			}
+		})
+	}
+}
+
+// FakeDiscontinued holds when the products at each url were discontinued
+type FakeDiscontinued map[string]time.Time
+
+func (d FakeDiscontinued) DiscontinuedSince(_ context.Context, url string) (*time.Time, error) {
+	if since, ok := d[url]; ok {
+		return &since, nil
+	}
+	return nil, nil
+}
+
+func TestScrapeRequestService_Discontinued(t *testing.T) {
+	discontinued := FakeDiscontinued{
+		"http://www.store.com/gone":     time.Now().Add(-30 * 24 * time.Hour),
+		"http://www.store.com/just-now": time.Now().Add(-time.Hour),
+	}
+	tests := []struct {
+		name          string
+		url           string
+		expectedError error
+	}{
+		{name: "listed", url: "http://www.store.com/item"},
+		{name: "within_grace", url: "http://www.store.com/just-now"},
+		{name: "past_grace", url: "http://www.store.com/gone", expectedError: apiErrors.ErrProductDiscontinued},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeRepo{}
+			srv := NewScrapeRequestService(repo, FakeRegistry)
+			srv.Discontinued = discontinued
+			srv.DiscontinuedGrace = 7 * 24 * time.Hour
+
+			_, err := srv.Request(context.Background(), ScrapeRequestInput{URL: tt.url})
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if enqueued := repo.inserted.URL != ""; enqueued != (tt.expectedError == nil) {
+				t.Fatalf("expected enqueued %v, received %v", tt.expectedError == nil, enqueued)
+			}
+		})
+	}
+
+	repo := &FakeRepo{}
+	srv := NewScrapeRequestService(repo, FakeRegistry)
+	srv.Discontinued = discontinued
+	srv.DiscontinuedGrace = 7 * 24 * time.Hour
+
+	results, err := srv.RequestBatch(context.Background(), BatchScrapeRequestInput{
+		URLs: []string{"http://www.store.com/gone", "http://www.store.com/just-now"},
+	})
+	if err != nil {
+		t.Fatalf("expected err to be nil, received %v", err)
+	}
+	if results[0].Status != BatchItemDiscontinued || results[1].Status != BatchItemAccepted || len(repo.batch) != 1 {
+		t.Fatalf("expected only the url within grace enqueued, received %+v", results)
+	}
+}
+
+func TestScrapeRequestService_RequestBatch(t *testing.T) {
+	repo := &FakeRepo{}
+	srv := NewScrapeRequestService(repo, FakeRegistry)
+
+	results, err := srv.RequestBatch(context.Background(), BatchScrapeRequestInput{
+		URLs: []string{
+			"http://www.store.com/a",
+			"not a url",
+			"http://other.com/b",
+			"http://www.store.com/a",
+			"http://www.store.com/c",
+		},
+		OwnerID: "user-1",
+	})
+	if err != nil {
+		t.Fatalf("expected err to be nil, received %v", err)
+	}
+
+	expected := []string{BatchItemAccepted, BatchItemInvalid, BatchItemUnsupportedStore, BatchItemDuplicate, BatchItemAccepted}
+	for i, result := range results {
+		if result.Status != expected[i] {
+			t.Fatalf("expected item %d to be %s, received %s", i, expected[i], result.Status)
+		}
+		if (result.ID != uuid.Nil) != (result.Status == BatchItemAccepted) {
+			t.Fatalf("expected only accepted items to carry an id, item %d: %+v", i, result)
+		}
+	}
+
+	if len(repo.batch) != 2 {
+		t.Fatalf("expected 2 enqueued requests, received %d", len(repo.batch))
+	}
+	for _, req := range repo.batch {
+		if req.Priority != repository.PriorityBulk || req.OwnerID != "user-1" || req.Store != "store.com" {
+			t.Fatalf("unexpected enqueued request %+v", req)
+		}
+	}
+}
+
+func TestScrapeRequestService_RequestBatchRejected(t *testing.T) {
+	tests := []struct {
+		name          string
+		urls          []string
+		maxBatchSize  int
+		quota         *FakeQuota
+		expectedError error
+	}{
+		{name: "empty", urls: nil, expectedError: apiErrors.ErrInputInvalid},
+		{name: "too_many", urls: make([]string, DefaultMaxBatchSize+1), maxBatchSize: DefaultMaxBatchSize, expectedError: apiErrors.ErrInputInvalid},
+		{name: "beyond_one_insert", urls: make([]string, repository.MaxEnqueueBatch+1), expectedError: apiErrors.ErrInputInvalid},
+		{name: "quota_exceeded", urls: []string{"http://www.store.com/a"}, quota: &FakeQuota{allowed: false}, expectedError: apiErrors.ErrQuotaExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeRepo{}
+			srv := NewScrapeRequestService(repo, FakeRegistry)
+			srv.MaxBatchSize = tt.maxBatchSize
+			if tt.quota != nil {
+				srv.Quota = tt.quota
+				srv.DailyQuota = 10
+			}
+
+			_, err := srv.RequestBatch(context.Background(), BatchScrapeRequestInput{URLs: tt.urls, OwnerID: "user-1"})
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if repo.batch != nil {
+				t.Fatalf("expected nothing enqueued, received %d requests", len(repo.batch))
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+/*
+uniwish.com/internal/api/services/scrape_status
+
+contains the live status of scrape requests, fanning the transitions workers publish out to the clients watching them
+*/
+package services
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/logging"
+)
+
+const (
+	// scrapeStatusBuffer is how many statuses a watcher may fall behind by before it is dropped
+	scrapeStatusBuffer = 16
+	// listenRetryDelay spaces attempts to listen again after the listener failed
+	listenRetryDelay = 5 * time.Second
+)
+
+type ScrapeStatusResponse struct {
+	ScrapeRequestID uuid.UUID  `json:"scrape_request_id"`
+	Status          string     `json:"status"`
+	ErrorKind       string     `json:"error_kind,omitempty"`
+	ProductID       *uuid.UUID `json:"product_id,omitempty"`
+	// Product is the product a done request scraped, left out when it cannot be read
+	Product    *ProductDetailResponse `json:"product,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Final reports whether the request will not change status again
+func (r ScrapeStatusResponse) Final() bool {
+	switch r.Status {
+	case repository.StatusDone, repository.StatusFailed, repository.StatusCancelled:
+		return true
+	}
+	return false
+}
+
+type ScrapeStatusStreamer interface {
+	// WatchScrapeRequest returns the request's current status, nil when it is not known, and its transitions from now on
+	// until cancel is called. Requests owned by someone other than ownerID are not found
+	WatchScrapeRequest(ctx context.Context, id uuid.UUID, ownerID string) (*repository.ScrapeStatus, <-chan repository.ScrapeStatus, func(), error)
+	// WatchOwner returns the transitions of every request ownerID enqueued until cancel is called
+	WatchOwner(ctx context.Context, ownerID string) (<-chan repository.ScrapeStatus, func(), error)
+	// Describe renders status for clients, attaching the product of done requests priced in displayCurrency
+	Describe(ctx context.Context, status repository.ScrapeStatus, displayCurrency string) ScrapeStatusResponse
+}
+
+// ScrapeStatusBroker shares one listener between every watcher, it starts listening on the first watch.
+// A watcher's channel is closed when it falls behind or statuses may have been missed, it should watch again
+type ScrapeStatusBroker struct {
+	listener repository.ScrapeStatusListener
+	start    sync.Once
+
+	mu       sync.Mutex
+	watchers map[*statusWatcher]struct{}
+}
+
+type statusWatcher struct {
+	// scrapeRequestID is nil for watchers of every request of ownerID
+	scrapeRequestID uuid.UUID
+	ownerID         string
+	statuses        chan repository.ScrapeStatus
+}
+
+func (w *statusWatcher) matches(status *repository.ScrapeStatus) bool {
+	if w.scrapeRequestID == uuid.Nil {
+		return status.OwnerID == w.ownerID
+	}
+	return status.ScrapeRequestID == w.scrapeRequestID && (status.OwnerID == "" || status.OwnerID == w.ownerID)
+}
+
+func NewScrapeStatusBroker(listener repository.ScrapeStatusListener) *ScrapeStatusBroker {
+	return &ScrapeStatusBroker{listener: listener, watchers: map[*statusWatcher]struct{}{}}
+}
+
+func (b *ScrapeStatusBroker) watch(scrapeRequestID uuid.UUID, ownerID string) (<-chan repository.ScrapeStatus, func()) {
+	// the listener lives as long as the process, watchers come and go
+	b.start.Do(func() { go b.run(context.Background()) })
+
+	w := &statusWatcher{scrapeRequestID: scrapeRequestID, ownerID: ownerID, statuses: make(chan repository.ScrapeStatus, scrapeStatusBuffer)}
+	b.mu.Lock()
+	b.watchers[w] = struct{}{}
+	b.mu.Unlock()
+	return w.statuses, func() { b.drop(w) }
+}
+
+func (b *ScrapeStatusBroker) drop(w *statusWatcher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.watchers[w]; ok {
+		delete(b.watchers, w)
+		close(w.statuses)
+	}
+}
+
+func (b *ScrapeStatusBroker) run(ctx context.Context) {
+	for {
+		err := b.listener.Listen(ctx, b.publish)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.FromContext(ctx).Warn("scrape status listener failed", "err", err)
+		// whatever was published while not listening is lost
+		b.publish(nil)
+		select {
+		case <-time.After(listenRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publish hands status to its watchers, a nil status drops every watcher as they may have missed some
+func (b *ScrapeStatusBroker) publish(status *repository.ScrapeStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for w := range b.watchers {
+		if status == nil {
+			delete(b.watchers, w)
+			close(w.statuses)
+			continue
+		}
+		if !w.matches(status) {
+			continue
+		}
+		select {
+		case w.statuses <- *status:
+		default:
+			// a slow watcher is not waited on, it is dropped and watches again
+			delete(b.watchers, w)
+			close(w.statuses)
+		}
+	}
+}
+
+type ScrapeStatusService struct {
+	broker   *ScrapeStatusBroker
+	statuses repository.ScrapeStatusReader
+	products ProductReaderService
+}
+
+func NewScrapeStatusService(broker *ScrapeStatusBroker, statuses repository.ScrapeStatusReader, products ProductReaderService) *ScrapeStatusService {
+	return &ScrapeStatusService{broker: broker, statuses: statuses, products: products}
+}
+
+func (s *ScrapeStatusService) WatchScrapeRequest(ctx context.Context, id uuid.UUID, ownerID string) (*repository.ScrapeStatus, <-chan repository.ScrapeStatus, func(), error) {
+	ctx, span := tracer.Start(ctx, "ScrapeStatusService.WatchScrapeRequest")
+	defer span.End()
+
+	// watching before reading the current status leaves no gap for a transition to slip through
+	statuses, cancel := s.broker.watch(id, ownerID)
+
+	status, err := s.statuses.GetScrapeStatus(ctx, id)
+	if goErrors.Is(err, sql.ErrNoRows) || (err == nil && status.OwnerID != "" && status.OwnerID != ownerID) {
+		cancel()
+		return nil, nil, nil, errors.ErrScrapeRequestNotFound
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	return status, statuses, cancel, nil
+}
+
+func (s *ScrapeStatusService) WatchOwner(ctx context.Context, ownerID string) (<-chan repository.ScrapeStatus, func(), error) {
+	// anonymous requests have no owner to stream them to
+	if ownerID == "" {
+		return nil, nil, errors.ErrUnauthorized
+	}
+	statuses, cancel := s.broker.watch(uuid.Nil, ownerID)
+	return statuses, cancel, nil
+}
+
+func (s *ScrapeStatusService) Describe(ctx context.Context, status repository.ScrapeStatus, displayCurrency string) ScrapeStatusResponse {
+	resp := ScrapeStatusResponse{
+		ScrapeRequestID: status.ScrapeRequestID,
+		Status:          status.Status,
+		ErrorKind:       status.ErrorKind,
+		ProductID:       status.ProductID,
+		OccurredAt:      status.OccurredAt,
+	}
+	if status.ProductID == nil || s.products == nil {
+		return resp
+	}
+	product, err := s.products.Get(ctx, *status.ProductID, displayCurrency)
+	if err != nil {
+		// clients still get the product id to read it themselves
+		logging.FromContext(ctx).Warn("scrape status product not read", "product_id", *status.ProductID, "err", err)
+		return resp
+	}
+	resp.Product = product
+	return resp
+}
@@ -0,0 +1,194 @@
+/*
+uniwish.com/internal/api/services/scrape_status_test
+
+tests for watching scrape request statuses
+*/
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+)
+
+// FakeScrapeStatusListener hands the broker's notify func to the test instead of listening
+type FakeScrapeStatusListener struct {
+	notify chan func(*repository.ScrapeStatus)
+}
+
+func NewFakeScrapeStatusListener() *FakeScrapeStatusListener {
+	return &FakeScrapeStatusListener{notify: make(chan func(*repository.ScrapeStatus), 1)}
+}
+
+func (l *FakeScrapeStatusListener) Listen(ctx context.Context, notify func(*repository.ScrapeStatus)) error {
+	l.notify <- notify
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// Notify waits for the broker to listen
+func (l *FakeScrapeStatusListener) Notify(t *testing.T) func(*repository.ScrapeStatus) {
+	t.Helper()
+	select {
+	case notify := <-l.notify:
+		return notify
+	case <-time.After(time.Second):
+		t.Fatal("broker never listened")
+		return nil
+	}
+}
+
+type FakeScrapeStatusReader struct {
+	status *repository.ScrapeStatus
+	err    error
+}
+
+func (r *FakeScrapeStatusReader) GetScrapeStatus(context.Context, uuid.UUID) (*repository.ScrapeStatus, error) {
+	return r.status, r.err
+}
+
+// received drains what statuses holds without waiting, reporting whether it was closed
+func received(statuses <-chan repository.ScrapeStatus) ([]repository.ScrapeStatus, bool) {
+	var got []repository.ScrapeStatus
+	for {
+		select {
+		case status, ok := <-statuses:
+			if !ok {
+				return got, true
+			}
+			got = append(got, status)
+		default:
+			return got, false
+		}
+	}
+}
+
+func TestScrapeStatusBroker_FansOut(t *testing.T) {
+	listener := NewFakeScrapeStatusListener()
+	broker := NewScrapeStatusBroker(listener)
+	requestID := uuid.New()
+
+	request, cancelRequest := broker.watch(requestID, "user-1")
+	defer cancelRequest()
+	owner, cancelOwner := broker.watch(uuid.Nil, "user-1")
+	defer cancelOwner()
+	other, cancelOther := broker.watch(uuid.Nil, "user-2")
+	defer cancelOther()
+	notify := listener.Notify(t)
+
+	notify(&repository.ScrapeStatus{ScrapeRequestID: requestID, OwnerID: "user-1", Status: repository.StatusProcessing})
+	notify(&repository.ScrapeStatus{ScrapeRequestID: uuid.New(), OwnerID: "user-1", Status: repository.StatusDone})
+
+	if got, _ := received(request); len(got) != 1 || got[0].ScrapeRequestID != requestID {
+		t.Fatalf("expected only the watched request's status, received %+v", got)
+	}
+	if got, _ := received(owner); len(got) != 2 {
+		t.Fatalf("expected every status of the owner, received %+v", got)
+	}
+	if got, _ := received(other); len(got) != 0 {
+		t.Fatalf("expected nothing for another owner, received %+v", got)
+	}
+}
+
+func TestScrapeStatusBroker_DropsWatchers(t *testing.T) {
+	t.Run("slow", func(t *testing.T) {
+		listener := NewFakeScrapeStatusListener()
+		broker := NewScrapeStatusBroker(listener)
+		statuses, cancel := broker.watch(uuid.Nil, "user-1")
+		defer cancel()
+		notify := listener.Notify(t)
+
+		for range scrapeStatusBuffer + 1 {
+			notify(&repository.ScrapeStatus{ScrapeRequestID: uuid.New(), OwnerID: "user-1", Status: repository.StatusDone})
+		}
+		if got, closed := received(statuses); !closed || len(got) != scrapeStatusBuffer {
+			t.Fatalf("expected the buffered statuses then a close, received %d closed %v", len(got), closed)
+		}
+	})
+
+	t.Run("missed", func(t *testing.T) {
+		listener := NewFakeScrapeStatusListener()
+		broker := NewScrapeStatusBroker(listener)
+		statuses, cancel := broker.watch(uuid.New(), "user-1")
+		defer cancel()
+
+		listener.Notify(t)(nil)
+		if _, closed := received(statuses); !closed {
+			t.Fatal("expected watchers dropped once statuses may have been missed")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		broker := NewScrapeStatusBroker(NewFakeScrapeStatusListener())
+		statuses, cancel := broker.watch(uuid.New(), "user-1")
+		cancel()
+		cancel()
+		if _, closed := received(statuses); !closed {
+			t.Fatal("expected cancel to close the watcher")
+		}
+	})
+}
+
+func TestScrapeStatusService_WatchScrapeRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		reader          repository.ScrapeStatusReader
+		expectedError   error
+		expectedCurrent bool
+	}{
+		{name: "current", reader: &FakeScrapeStatusReader{status: &repository.ScrapeStatus{OwnerID: "user-1", Status: repository.StatusPending}}, expectedCurrent: true},
+		{name: "anonymous_request", reader: &FakeScrapeStatusReader{status: &repository.ScrapeStatus{Status: repository.StatusPending}}, expectedCurrent: true},
+		{name: "other_owner", reader: &FakeScrapeStatusReader{status: &repository.ScrapeStatus{OwnerID: "user-2"}}, expectedError: apiErrors.ErrScrapeRequestNotFound},
+		{name: "unknown", reader: &FakeScrapeStatusReader{err: sql.ErrNoRows}, expectedError: apiErrors.ErrScrapeRequestNotFound},
+		{name: "database_error", reader: &FakeScrapeStatusReader{err: sql.ErrConnDone}, expectedError: sql.ErrConnDone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := NewScrapeStatusBroker(NewFakeScrapeStatusListener())
+			service := NewScrapeStatusService(broker, tt.reader, nil)
+			current, statuses, cancel, err := service.WatchScrapeRequest(context.Background(), uuid.New(), "user-1")
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if err != nil {
+				if len(broker.watchers) != 0 {
+					t.Fatal("expected the watcher dropped on error")
+				}
+				return
+			}
+			defer cancel()
+			if (current != nil) != tt.expectedCurrent || statuses == nil {
+				t.Fatalf("expected current %v, received %+v", tt.expectedCurrent, current)
+			}
+		})
+	}
+}
+
+func TestScrapeStatusService_WatchOwnerRequiresUser(t *testing.T) {
+	service := NewScrapeStatusService(NewScrapeStatusBroker(NewFakeScrapeStatusListener()), nil, nil)
+	if _, _, err := service.WatchOwner(context.Background(), ""); !errors.Is(err, apiErrors.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, received %v", err)
+	}
+}
+
+func TestScrapeStatusService_Describe(t *testing.T) {
+	products := NewDefaultProductReaderService(&FakeProductReader{}, &FakeRates{})
+	service := NewScrapeStatusService(NewScrapeStatusBroker(NewFakeScrapeStatusListener()), nil, products)
+	productID := uuid.New()
+
+	done := service.Describe(context.Background(), repository.ScrapeStatus{Status: repository.StatusDone, ProductID: &productID}, "")
+	if !done.Final() || done.Product == nil || done.Product.Product.Name != "jacket" {
+		t.Fatalf("expected a final status with its product, received %+v", done)
+	}
+	processing := service.Describe(context.Background(), repository.ScrapeStatus{Status: repository.StatusProcessing}, "")
+	if processing.Final() || processing.Product != nil {
+		t.Fatalf("expected a status still in flight, received %+v", processing)
+	}
+}
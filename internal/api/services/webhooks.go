@@ -0,0 +1,211 @@
+/*
+uniwish.com/internal/api/services/webhooks
+
+contains the operator facing management of webhook subscriptions, their delivery log and redeliveries
+*/
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	goErrors "errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/domain"
+	"uniwish.com/internal/logging"
+)
+
+// MinWebhookSecretLength keeps caller chosen secrets from being guessable
+const MinWebhookSecretLength = 16
+
+var webhookDeliveryStatuses = []string{
+	repository.WebhookDeliveryPending,
+	repository.WebhookDeliveryDelivered,
+	repository.WebhookDeliveryFailed,
+}
+
+type CreateWebhookInput struct {
+	URL        string
+	EventTypes []string
+	// Secret keys the deliveries' signatures, one is generated when empty
+	Secret string
+}
+
+type ListWebhookDeliveriesInput struct {
+	Status string
+	// Limit and Offset are read from the query string, empty takes the defaults
+	Limit  string
+	Offset string
+}
+
+type WebhookAdministrator interface {
+	// Create returns the subscription with its secret, which is not shown again
+	Create(ctx context.Context, input CreateWebhookInput) (*repository.WebhookSubscription, error)
+	List(ctx context.Context) ([]repository.WebhookSubscription, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, input ListWebhookDeliveriesInput) ([]repository.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) (*repository.WebhookDeliveryDetail, error)
+	Redeliver(ctx context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) error
+}
+
+type WebhookAdminService struct {
+	repo repository.WebhookAdmin
+}
+
+func NewWebhookAdminService(repo repository.WebhookAdmin) *WebhookAdminService {
+	return &WebhookAdminService{repo: repo}
+}
+
+func (s *WebhookAdminService) Create(ctx context.Context, input CreateWebhookInput) (*repository.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookAdminService.Create")
+	defer span.End()
+
+	var fields []errors.FieldError
+	if input.URL == "" {
+		fields = append(fields, errors.FieldError{Field: "url", Code: "required", Message: "url is required"})
+	} else if parsed, err := url.Parse(input.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		fields = append(fields, errors.FieldError{Field: "url", Code: "invalid", Message: "url must be an absolute http or https url"})
+	}
+
+	var eventTypes []string
+	if len(input.EventTypes) == 0 {
+		fields = append(fields, errors.FieldError{Field: "event_types", Code: "required", Message: "at least one event type is required"})
+	}
+	for _, eventType := range input.EventTypes {
+		if !slices.Contains(domain.WebhookEventTypes, domain.WebhookEventType(eventType)) {
+			fields = append(fields, errors.FieldError{Field: "event_types", Code: "invalid", Message: eventType + " is not a webhook event type"})
+			break
+		}
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	if input.Secret != "" && len(input.Secret) < MinWebhookSecretLength {
+		fields = append(fields, errors.FieldError{
+			Field:   "secret",
+			Code:    "invalid",
+			Message: fmt.Sprintf("secret must be at least %d characters", MinWebhookSecretLength),
+		})
+	}
+	if len(fields) > 0 {
+		return nil, errors.Invalid(fields...)
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		generated, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	subscription := repository.WebhookSubscription{
+		ID:         uuid.New(),
+		URL:        input.URL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := s.repo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("webhook subscription created", "webhook_id", subscription.ID, "event_types", eventTypes)
+	return &subscription, nil
+}
+
+func (s *WebhookAdminService) List(ctx context.Context) ([]repository.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookAdminService.List")
+	defer span.End()
+
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *WebhookAdminService) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "WebhookAdminService.Delete")
+	defer span.End()
+
+	err := s.repo.DeleteSubscription(ctx, id)
+	if goErrors.Is(err, sql.ErrNoRows) {
+		return errors.ErrWebhookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("webhook subscription deleted", "webhook_id", id)
+	return nil
+}
+
+func (s *WebhookAdminService) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, input ListWebhookDeliveriesInput) ([]repository.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookAdminService.ListDeliveries")
+	defer span.End()
+
+	var fields []errors.FieldError
+	if input.Status != "" && !slices.Contains(webhookDeliveryStatuses, input.Status) {
+		fields = append(fields, errors.FieldError{Field: "status", Code: "invalid", Message: "status is not a webhook delivery status"})
+	}
+	limit, err := parseBound(input.Limit, DefaultAdminListLimit)
+	if err != nil || limit < 1 || limit > MaxAdminListLimit {
+		fields = append(fields, errors.FieldError{
+			Field:   "limit",
+			Code:    "invalid",
+			Message: fmt.Sprintf("limit must be between 1 and %d", MaxAdminListLimit),
+		})
+	}
+	offset, err := parseBound(input.Offset, 0)
+	if err != nil || offset < 0 {
+		fields = append(fields, errors.FieldError{Field: "offset", Code: "invalid", Message: "offset must be a non negative integer"})
+	}
+	if len(fields) > 0 {
+		return nil, errors.Invalid(fields...)
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, subscriptionID, input.Status, limit, offset)
+	if goErrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.ErrWebhookNotFound
+	}
+	return deliveries, err
+}
+
+func (s *WebhookAdminService) GetDelivery(ctx context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) (*repository.WebhookDeliveryDetail, error) {
+	ctx, span := tracer.Start(ctx, "WebhookAdminService.GetDelivery")
+	defer span.End()
+
+	delivery, err := s.repo.GetDelivery(ctx, subscriptionID, deliveryID)
+	if goErrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.ErrWebhookDeliveryNotFound
+	}
+	return delivery, err
+}
+
+func (s *WebhookAdminService) Redeliver(ctx context.Context, subscriptionID uuid.UUID, deliveryID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "WebhookAdminService.Redeliver")
+	defer span.End()
+
+	err := s.repo.Redeliver(ctx, subscriptionID, deliveryID)
+	if goErrors.Is(err, sql.ErrNoRows) {
+		return errors.ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("webhook delivery queued for redelivery", "webhook_id", subscriptionID, "delivery_id", deliveryID)
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,172 @@
+/*
+uniwish.com/internal/api/services/webhooks_test
+
+tests for the webhook admin service
+*/
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/api/repository"
+)
+
+type FakeWebhookAdmin struct {
+	created repository.WebhookSubscription
+	status  string
+	limit   int
+	offset  int
+	// err is returned by every lookup of a subscription or delivery
+	err error
+}
+
+func (r *FakeWebhookAdmin) CreateSubscription(_ context.Context, subscription repository.WebhookSubscription) error {
+	r.created = subscription
+	return nil
+}
+
+func (r *FakeWebhookAdmin) ListSubscriptions(context.Context) ([]repository.WebhookSubscription, error) {
+	return []repository.WebhookSubscription{r.created}, nil
+}
+
+func (r *FakeWebhookAdmin) DeleteSubscription(context.Context, uuid.UUID) error {
+	return r.err
+}
+
+func (r *FakeWebhookAdmin) ListDeliveries(_ context.Context, _ uuid.UUID, status string, limit int, offset int) ([]repository.WebhookDelivery, error) {
+	r.status, r.limit, r.offset = status, limit, offset
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []repository.WebhookDelivery{{ID: uuid.New(), Status: repository.WebhookDeliveryPending}}, nil
+}
+
+func (r *FakeWebhookAdmin) GetDelivery(_ context.Context, _ uuid.UUID, deliveryID uuid.UUID) (*repository.WebhookDeliveryDetail, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &repository.WebhookDeliveryDetail{WebhookDelivery: repository.WebhookDelivery{ID: deliveryID}}, nil
+}
+
+func (r *FakeWebhookAdmin) Redeliver(context.Context, uuid.UUID, uuid.UUID) error {
+	return r.err
+}
+
+func TestWebhookAdminService_Create(t *testing.T) {
+	tests := []struct {
+		name               string
+		input              CreateWebhookInput
+		expectedError      error
+		expectedEventTypes []string
+	}{
+		{
+			name:               "created",
+			input:              CreateWebhookInput{URL: "https://hooks.example.com/uniwish", EventTypes: []string{"price.changed", "scrape.failed", "price.changed"}},
+			expectedEventTypes: []string{"price.changed", "scrape.failed"},
+		},
+		{
+			name:               "own_secret",
+			input:              CreateWebhookInput{URL: "http://hooks.example.com", EventTypes: []string{"product.created"}, Secret: "0123456789abcdef"},
+			expectedEventTypes: []string{"product.created"},
+		},
+		{name: "missing_url", input: CreateWebhookInput{EventTypes: []string{"price.changed"}}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "relative_url", input: CreateWebhookInput{URL: "/hooks", EventTypes: []string{"price.changed"}}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "not_http", input: CreateWebhookInput{URL: "ftp://hooks.example.com", EventTypes: []string{"price.changed"}}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "no_event_types", input: CreateWebhookInput{URL: "https://hooks.example.com"}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "unknown_event_type", input: CreateWebhookInput{URL: "https://hooks.example.com", EventTypes: []string{"price.dropped"}}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "short_secret", input: CreateWebhookInput{URL: "https://hooks.example.com", EventTypes: []string{"price.changed"}, Secret: "short"}, expectedError: apiErrors.ErrInputInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeWebhookAdmin{}
+			subscription, err := NewWebhookAdminService(repo).Create(context.Background(), tt.input)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if tt.expectedError != nil {
+				return
+			}
+
+			if !slices.Equal(repo.created.EventTypes, tt.expectedEventTypes) {
+				t.Fatalf("expected event types %q, received %q", tt.expectedEventTypes, repo.created.EventTypes)
+			}
+			if subscription.Secret != repo.created.Secret || len(subscription.Secret) < MinWebhookSecretLength {
+				t.Fatalf("expected the stored secret returned, received %q", subscription.Secret)
+			}
+			if tt.input.Secret != "" && subscription.Secret != tt.input.Secret {
+				t.Fatalf("expected the caller's secret kept, received %q", subscription.Secret)
+			}
+		})
+	}
+}
+
+func TestWebhookAdminService_GeneratesDistinctSecrets(t *testing.T) {
+	service := NewWebhookAdminService(&FakeWebhookAdmin{})
+	input := CreateWebhookInput{URL: "https://hooks.example.com", EventTypes: []string{"price.changed"}}
+
+	first, err := service.Create(context.Background(), input)
+	if err != nil {
+		t.Fatalf("error not nil: %v", err)
+	}
+	second, err := service.Create(context.Background(), input)
+	if err != nil {
+		t.Fatalf("error not nil: %v", err)
+	}
+	if first.Secret == second.Secret {
+		t.Fatal("expected every subscription to get its own secret")
+	}
+}
+
+func TestWebhookAdminService_ListDeliveries(t *testing.T) {
+	tests := []struct {
+		name           string
+		repo           *FakeWebhookAdmin
+		input          ListWebhookDeliveriesInput
+		expectedError  error
+		expectedLimit  int
+		expectedOffset int
+	}{
+		{name: "defaults", repo: &FakeWebhookAdmin{}, expectedLimit: DefaultAdminListLimit},
+		{name: "paged", repo: &FakeWebhookAdmin{}, input: ListWebhookDeliveriesInput{Status: "failed", Limit: "10", Offset: "20"}, expectedLimit: 10, expectedOffset: 20},
+		{name: "unknown_status", repo: &FakeWebhookAdmin{}, input: ListWebhookDeliveriesInput{Status: "lost"}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "limit_too_large", repo: &FakeWebhookAdmin{}, input: ListWebhookDeliveriesInput{Limit: "10000"}, expectedError: apiErrors.ErrInputInvalid},
+		{name: "unknown_webhook", repo: &FakeWebhookAdmin{err: sql.ErrNoRows}, expectedError: apiErrors.ErrWebhookNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWebhookAdminService(tt.repo).ListDeliveries(context.Background(), uuid.New(), tt.input)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if tt.expectedError != nil {
+				return
+			}
+			if tt.repo.status != tt.input.Status || tt.repo.limit != tt.expectedLimit || tt.repo.offset != tt.expectedOffset {
+				t.Fatalf("expected %q %d %d, received %q %d %d", tt.input.Status, tt.expectedLimit, tt.expectedOffset, tt.repo.status, tt.repo.limit, tt.repo.offset)
+			}
+		})
+	}
+}
+
+func TestWebhookAdminService_MissingRows(t *testing.T) {
+	service := NewWebhookAdminService(&FakeWebhookAdmin{err: sql.ErrNoRows})
+	ctx := context.Background()
+
+	if err := service.Delete(ctx, uuid.New()); !errors.Is(err, apiErrors.ErrWebhookNotFound) {
+		t.Fatalf("expected ErrWebhookNotFound, received %v", err)
+	}
+	if _, err := service.GetDelivery(ctx, uuid.New(), uuid.New()); !errors.Is(err, apiErrors.ErrWebhookDeliveryNotFound) {
+		t.Fatalf("expected ErrWebhookDeliveryNotFound, received %v", err)
+	}
+	if err := service.Redeliver(ctx, uuid.New(), uuid.New()); !errors.Is(err, apiErrors.ErrWebhookDeliveryNotFound) {
+		t.Fatalf("expected ErrWebhookDeliveryNotFound, received %v", err)
+	}
+}
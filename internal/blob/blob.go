@@ -0,0 +1,85 @@
+/*
+uniwish.com/internal/blob/blob
+
+pluggable object storage for mirrored product images
+*/
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"uniwish.com/internal/api/config"
+)
+
+var (
+	ErrNotFound       = errors.New("blob not found")
+	ErrInvalidKey     = errors.New("invalid blob key")
+	ErrUnknownBackend = errors.New("unknown blob backend")
+)
+
+const (
+	BackendFilesystem = "filesystem"
+	BackendS3         = "s3"
+)
+
+type Info struct {
+	ContentType string
+	Size        int64
+}
+
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get fails with ErrNotFound for missing keys, the caller closes the returned body
+	Get(ctx context.Context, key string) (io.ReadCloser, *Info, error)
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+type Options struct {
+	// Dir is the root of the filesystem backend
+	Dir string
+	// S3 configures the s3 backend, any S3 compatible endpoint such as MinIO works
+	S3 S3Options
+}
+
+// Open builds the configured backend
+func Open(backend string, opts Options) (Store, error) {
+	switch backend {
+	case BackendFilesystem:
+		return NewFilesystemStore(opts.Dir)
+	case BackendS3:
+		return NewS3Store(opts.S3)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, backend)
+	}
+}
+
+// OpenImageStore builds the image store cfg describes, nil when mirroring is disabled
+func OpenImageStore(cfg *config.Config) (Store, error) {
+	if cfg.ImageStore == "" {
+		return nil, nil
+	}
+	return Open(cfg.ImageStore, Options{
+		Dir: cfg.ImageStoreDir,
+		S3: S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			UseSSL:    cfg.S3UseSSL,
+		},
+	})
+}
+
+// ValidateKey rejects keys that could escape the store, keys are slash separated relative paths
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+uniwish.com/internal/blob/filesystem
+
+blob store on the local filesystem
+*/
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+type FilesystemStore struct {
+	root string
+}
+
+func NewFilesystemStore(root string) (*FilesystemStore, error) {
+	if root == "" {
+		return nil, errors.New("filesystem blob store needs a directory")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("blob directory error: %w", err)
+	}
+	return &FilesystemStore{root: root}, nil
+}
+
+func (s *FilesystemStore) path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *FilesystemStore) Put(_ context.Context, key string, body io.Reader, _ int64, _ string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("blob directory error: %w", err)
+	}
+
+	// written aside and renamed so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("blob write error: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("blob write error: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("blob write error: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("blob write error: %w", err)
+	}
+	return nil
+}
+
+func (s *FilesystemStore) Get(_ context.Context, key string) (io.ReadCloser, *Info, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("blob read error: %w", err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("blob read error: %w", err)
+	}
+	// the filesystem keeps no metadata, keys carry the extension instead
+	return f, &Info{ContentType: mime.TypeByExtension(filepath.Ext(target)), Size: stat.Size()}, nil
+}
+
+func (s *FilesystemStore) Exists(_ context.Context, key string) (bool, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
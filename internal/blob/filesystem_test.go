@@ -0,0 +1,75 @@
+/*
+uniwish.com/internal/blob/filesystem_test
+
+tests the filesystem blob store
+*/
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFilesystemStore(t *testing.T) {
+	store, err := NewFilesystemStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "thumbnails/ab.jpg", strings.NewReader("jpeg"), 4, "image/jpeg"); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+
+	exists, err := store.Exists(ctx, "thumbnails/ab.jpg")
+	if err != nil || !exists {
+		t.Fatalf("expected blob to exist, received %v %v", exists, err)
+	}
+
+	body, info, err := store.Get(ctx, "thumbnails/ab.jpg")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	if string(data) != "jpeg" || info.ContentType != "image/jpeg" || info.Size != 4 {
+		t.Fatalf("unexpected blob %q %+v", data, info)
+	}
+
+	if _, _, err := store.Get(ctx, "thumbnails/missing.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, received %v", err)
+	}
+	if exists, err := store.Exists(ctx, "thumbnails/missing.jpg"); err != nil || exists {
+		t.Fatalf("expected missing blob, received %v %v", exists, err)
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{key: "products/ab.jpg", valid: true},
+		{key: "ab.jpg", valid: true},
+		{key: ""},
+		{key: "/etc/passwd"},
+		{key: "../secret"},
+		{key: "products/../../secret"},
+		{key: "products//ab.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			err := ValidateKey(tt.key)
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("expected valid %v, received %v", tt.valid, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidKey) {
+				t.Fatalf("expected ErrInvalidKey, received %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+uniwish.com/internal/blob/s3
+
+blob store on S3 or any S3 compatible service such as MinIO
+*/
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type S3Options struct {
+	// Endpoint is host[:port] without a scheme, like s3.amazonaws.com or minio:9000
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("s3 blob store needs an endpoint and a bucket")
+	}
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: opts.UseSSL,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 client error: %w", err)
+	}
+	return &S3Store{client: client, bucket: opts.Bucket}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, key, body, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("s3 put error: %w", err)
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, *Info, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+	// GetObject is lazy, stat first so a missing key surfaces as ErrNotFound before any body is served
+	stat, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, nil, s.translate(key, err)
+	}
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, s.translate(key, err)
+	}
+	return object, &Info{ContentType: stat.ContentType, Size: stat.Size}, nil
+}
+
+func (s *S3Store) Exists(ctx context.Context, key string) (bool, error) {
+	if err := ValidateKey(key); err != nil {
+		return false, err
+	}
+	_, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		return true, nil
+	}
+	if err = s.translate(key, err); errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *S3Store) translate(key string, err error) error {
+	if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return fmt.Errorf("s3 error: %w", err)
+}
@@ -0,0 +1,85 @@
+/*
+uniwish.com/internal/currency/amount
+
+exact monetary amounts held as integer minor units
+*/
+package currency
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidDecimal = errors.New("invalid decimal")
+
+// Amount is Minor units of Currency, 1299.95 EUR is {129995, "EUR"}
+type Amount struct {
+	Minor    int64
+	Currency string
+}
+
+func (a Amount) units() int {
+	units, err := MinorUnits(a.Currency)
+	if err != nil {
+		// unknown codes are treated as cents rather than failing formatting
+		return 2
+	}
+	return units
+}
+
+// Decimal formats the amount exactly, with as many decimals as the currency is quoted in
+func (a Amount) Decimal() string {
+	units := a.units()
+	minor := a.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	digits := strconv.FormatInt(minor, 10)
+	if units == 0 {
+		return sign + digits
+	}
+	if len(digits) <= units {
+		digits = strings.Repeat("0", units-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-units] + "." + digits[len(digits)-units:]
+}
+
+// Float is the amount in major units, only meant for display and conversion
+func (a Amount) Float() float64 {
+	return float64(a.Minor) / math.Pow10(a.units())
+}
+
+// ParseDecimal reads a plain decimal such as a NUMERIC column's text, 35.9000 EUR is {3590, "EUR"}
+// digits beyond the currency's minor units must be zeros as rounding them would lose money silently
+func ParseDecimal(decimal string, code string) (Amount, error) {
+	amount := Amount{Currency: code}
+	units := amount.units()
+
+	sign := int64(1)
+	if rest, ok := strings.CutPrefix(decimal, "-"); ok {
+		sign, decimal = -1, rest
+	}
+	whole, fraction, _ := strings.Cut(decimal, ".")
+	if whole == "" {
+		return Amount{}, ErrInvalidDecimal
+	}
+	if len(fraction) > units {
+		if strings.Trim(fraction[units:], "0") != "" {
+			return Amount{}, ErrInvalidDecimal
+		}
+		fraction = fraction[:units]
+	}
+	fraction += strings.Repeat("0", units-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || minor < 0 {
+		return Amount{}, ErrInvalidDecimal
+	}
+	amount.Minor = sign * minor
+	return amount, nil
+}
@@ -0,0 +1,70 @@
+/*
+uniwish.com/internal/currency/ecb
+
+parses the European Central Bank reference rates feed (eurofxref-daily.xml), quoting rates against EUR
+
+	<gesmes:Envelope>
+	  <Cube>
+	    <Cube time="2026-10-16">
+	      <Cube currency="USD" rate="1.0812"/>
+*/
+package currency
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+var ErrMalformedFeed = errors.New("malformed rates feed")
+
+type ecbEnvelope struct {
+	Cube struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// ParseECB reads the most recent day of an ECB feed, historical feeds list days newest first
+func ParseECB(r io.Reader) (*Rates, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedFeed, err)
+	}
+	if len(envelope.Cube.Days) == 0 {
+		return nil, fmt.Errorf("%w: no rates", ErrMalformedFeed)
+	}
+
+	day := envelope.Cube.Days[0]
+	date, err := time.Parse(time.DateOnly, day.Time)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedFeed, err)
+	}
+
+	rates := &Rates{Base: "EUR", Date: date, Rates: make(map[string]float64, len(day.Rates))}
+	for _, quote := range day.Rates {
+		code, err := Normalize(quote.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedFeed, err)
+		}
+
+		rate, err := strconv.ParseFloat(quote.Rate, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("%w: invalid rate %q for %s", ErrMalformedFeed, quote.Rate, code)
+		}
+		rates.Rates[code] = rate
+	}
+
+	if len(rates.Rates) == 0 {
+		return nil, fmt.Errorf("%w: no rates", ErrMalformedFeed)
+	}
+	return rates, nil
+}
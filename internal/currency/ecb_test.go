@@ -0,0 +1,58 @@
+/*
+uniwish.com/internal/currency/ecb_test
+
+tests ECB rates feed parsing
+*/
+package currency
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseECB(t *testing.T) {
+	feed, err := os.Open("testdata/eurofxref-daily.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+
+	rates, err := ParseECB(feed)
+	if err != nil {
+		t.Fatalf("expected err to be nil, received %v", err)
+	}
+
+	if rates.Base != "EUR" {
+		t.Fatalf("expected EUR base, received %s", rates.Base)
+	}
+	if !rates.Date.Equal(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected 2026-10-16, received %s", rates.Date)
+	}
+	if len(rates.Rates) != 4 || rates.Rates["USD"] != 1.0812 {
+		t.Fatalf("unexpected rates %v", rates.Rates)
+	}
+}
+
+func TestParseECB_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		feed string
+	}{
+		{name: "not_xml", feed: "rates"},
+		{name: "no_days", feed: `<Envelope><Cube></Cube></Envelope>`},
+		{name: "bad_date", feed: `<Envelope><Cube><Cube time="yesterday"><Cube currency="USD" rate="1.1"/></Cube></Cube></Envelope>`},
+		{name: "unknown_currency", feed: `<Envelope><Cube><Cube time="2026-10-16"><Cube currency="XXQ" rate="1.1"/></Cube></Cube></Envelope>`},
+		{name: "bad_rate", feed: `<Envelope><Cube><Cube time="2026-10-16"><Cube currency="USD" rate="-1"/></Cube></Cube></Envelope>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseECB(strings.NewReader(tt.feed)); !errors.Is(err, ErrMalformedFeed) {
+				t.Fatalf("expected %v, received %v", ErrMalformedFeed, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+uniwish.com/internal/currency/rates
+
+exchange rates against a base currency and conversion between any two quoted currencies
+*/
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNoRate = errors.New("no exchange rate")
+
+// Rates quotes how many units of each currency one unit of Base buys on Date
+type Rates struct {
+	Base  string
+	Date  time.Time
+	Rates map[string]float64
+}
+
+func (r *Rates) rate(code string) (float64, bool) {
+	if code == r.Base {
+		return 1, true
+	}
+	rate, ok := r.Rates[code]
+	return rate, ok && rate > 0
+}
+
+// Convert crosses amount from one currency to another through the base currency
+func (r *Rates) Convert(amount float64, from string, to string) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+
+	fromRate, ok := r.rate(from)
+	if !ok {
+		return 0, fmt.Errorf("%w for %s", ErrNoRate, from)
+	}
+	toRate, ok := r.rate(to)
+	if !ok {
+		return 0, fmt.Errorf("%w for %s", ErrNoRate, to)
+	}
+
+	return amount / fromRate * toRate, nil
+}
+
+// Supports reports whether amounts can be converted to or from code
+func (r *Rates) Supports(code string) bool {
+	_, ok := r.rate(code)
+	return ok
+}
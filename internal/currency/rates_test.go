@@ -0,0 +1,119 @@
+/*
+uniwish.com/internal/currency/rates_test
+
+tests currency codes and conversion
+*/
+package currency
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code          string
+		expected      string
+		expectedError error
+	}{
+		{code: "EUR", expected: "EUR"},
+		{code: " usd ", expected: "USD"},
+		{code: "EURO", expectedError: ErrUnknownCurrency},
+		{code: "", expectedError: ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			code, err := Normalize(tt.code)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if code != tt.expected {
+				t.Fatalf("expected %q, received %q", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestRates_Convert(t *testing.T) {
+	rates := &Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.25, "GBP": 0.8}}
+
+	tests := []struct {
+		name          string
+		amount        float64
+		from          string
+		to            string
+		expected      float64
+		expectedError error
+	}{
+		{name: "same", amount: 10, from: "USD", to: "USD", expected: 10},
+		{name: "from_base", amount: 10, from: "EUR", to: "USD", expected: 12.5},
+		{name: "to_base", amount: 12.5, from: "USD", to: "EUR", expected: 10},
+		{name: "cross", amount: 12.5, from: "USD", to: "GBP", expected: 8},
+		{name: "no_rate", amount: 10, from: "JPY", to: "EUR", expectedError: ErrNoRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := rates.Convert(tt.amount, tt.from, tt.to)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if math.Abs(converted-tt.expected) > 1e-9 {
+				t.Fatalf("expected %f, received %f", tt.expected, converted)
+			}
+		})
+	}
+}
+
+func TestAmount_Decimal(t *testing.T) {
+	tests := []struct {
+		amount   Amount
+		expected string
+	}{
+		{amount: Amount{Minor: 129995, Currency: "EUR"}, expected: "1299.95"},
+		{amount: Amount{Minor: 5, Currency: "EUR"}, expected: "0.05"},
+		{amount: Amount{Minor: 1299, Currency: "JPY"}, expected: "1299"},
+		{amount: Amount{Minor: 1299, Currency: "KWD"}, expected: "1.299"},
+		{amount: Amount{Minor: -150, Currency: "USD"}, expected: "-1.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if decimal := tt.amount.Decimal(); decimal != tt.expected {
+				t.Fatalf("expected %q, received %q", tt.expected, decimal)
+			}
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		decimal       string
+		code          string
+		expected      Amount
+		expectedError error
+	}{
+		{decimal: "35.9000", code: "EUR", expected: Amount{Minor: 3590, Currency: "EUR"}},
+		{decimal: "35.9", code: "EUR", expected: Amount{Minor: 3590, Currency: "EUR"}},
+		{decimal: "1299", code: "JPY", expected: Amount{Minor: 1299, Currency: "JPY"}},
+		{decimal: "1.299", code: "KWD", expected: Amount{Minor: 1299, Currency: "KWD"}},
+		{decimal: "-1.50", code: "USD", expected: Amount{Minor: -150, Currency: "USD"}},
+		{decimal: "1.005", code: "EUR", expectedError: ErrInvalidDecimal},
+		{decimal: ".5", code: "EUR", expectedError: ErrInvalidDecimal},
+		{decimal: "1,50", code: "EUR", expectedError: ErrInvalidDecimal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code+"_"+tt.decimal, func(t *testing.T) {
+			amount, err := ParseDecimal(tt.decimal, tt.code)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if amount != tt.expected {
+				t.Fatalf("expected %+v, received %+v", tt.expected, amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+/*
+uniwish.com/internal/currency/source
+
+opens a rates feed from a local file or an http(s) url
+*/
+package currency
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var feedClient = &http.Client{Timeout: 30 * time.Second}
+
+func OpenSource(ctx context.Context, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := feedClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("rates feed responded %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
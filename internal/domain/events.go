@@ -0,0 +1,33 @@
+package domain
+
+import "github.com/google/uuid"
+
+type ProductEventKind string
+
+const (
+	ProductNameChanged         ProductEventKind = "name_changed"
+	ProductImageChanged        ProductEventKind = "image_changed"
+	ProductURLChanged          ProductEventKind = "url_changed"
+	ProductPriceChanged        ProductEventKind = "price_changed"
+	ProductAvailabilityChanged ProductEventKind = "availability_changed"
+	// ProductDiscontinued and ProductRelisted read listed or discontinued before and after
+	ProductDiscontinued ProductEventKind = "discontinued"
+	ProductRelisted     ProductEventKind = "relisted"
+)
+
+type NotificationKind string
+
+// NotificationProductDiscontinued tells a user a product on their wishlist is no longer sold
+const NotificationProductDiscontinued NotificationKind = "product_discontinued"
+
+// ProductEvent is a change a scrape found on a stored product, Before and After as shown to users
+type ProductEvent struct {
+	ProductID uuid.UUID
+	// ScrapeRequestID is the job whose scrape found the change
+	ScrapeRequestID uuid.UUID
+	Kind            ProductEventKind
+	// Region is set for changes of a single market, its url, price and availability
+	Region string
+	Before string
+	After  string
+}
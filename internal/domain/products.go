@@ -1,15 +1,19 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"uniwish.com/internal/currency"
+)
 
 type Offer struct {
 	ID           uuid.UUID
 	ProductID    uuid.UUID
-	Price        float64
-	Currency     string
+	Price        currency.Amount
 	Size         string
 	Color        string
 	Availability string
+	// Region is the market the offer was scraped in, see ProductSnapshot.Region
+	Region string
 }
 
 type ProductSnapshot struct {
@@ -19,6 +23,24 @@ type ProductSnapshot struct {
 	Store    string
 	SKU      string
 	ImageURL string
+	// Region is the market a store serves the url for, like "es" in zara.com/es/en/..., empty when it has none
+	Region string
+	// Brand, GTIN and MPN identify the item across stores when the page publishes them
+	Brand string
+	GTIN  string
+	MPN   string
+}
+
+// ProductImage is a product's image mirrored into the blob store
+type ProductImage struct {
+	SourceURL    string
+	Key          string
+	ThumbnailKey string
+	ContentType  string
+	Width        int
+	Height       int
+	// PHash is the perceptual difference hash, near duplicates are a few bits apart
+	PHash uint64
 }
 
 type ProductRecord struct {
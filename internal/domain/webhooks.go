@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookEventType string
+
+const (
+	WebhookProductCreated      WebhookEventType = "product.created"
+	WebhookProductUpdated      WebhookEventType = "product.updated"
+	WebhookPriceChanged        WebhookEventType = "price.changed"
+	WebhookAvailabilityChanged WebhookEventType = "availability.changed"
+	WebhookProductDiscontinued WebhookEventType = "product.discontinued"
+	WebhookProductRelisted     WebhookEventType = "product.relisted"
+	WebhookScrapeFailed        WebhookEventType = "scrape.failed"
+)
+
+// WebhookEventTypes lists every event type subscriptions may ask for
+var WebhookEventTypes = []WebhookEventType{
+	WebhookProductCreated,
+	WebhookProductUpdated,
+	WebhookPriceChanged,
+	WebhookAvailabilityChanged,
+	WebhookProductDiscontinued,
+	WebhookProductRelisted,
+	WebhookScrapeFailed,
+}
+
+// WebhookEvent is sent to every subscription of its type, Data is marshalled as the body's data
+type WebhookEvent struct {
+	// ID is shared by the event's deliveries so receivers can tell redeliveries apart
+	ID         uuid.UUID
+	Type       WebhookEventType
+	OccurredAt time.Time
+	Data       any
+}
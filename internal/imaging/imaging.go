@@ -0,0 +1,88 @@
+/*
+uniwish.com/internal/imaging/imaging
+
+decodes product images, renders thumbnails and computes perceptual hashes
+*/
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math/bits"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var ErrUnsupportedImage = errors.New("unsupported image")
+
+// DefaultThumbnailSize bounds the longest side of thumbnails in pixels
+const DefaultThumbnailSize = 320
+
+// Decode reads a jpeg, png, gif or webp image and reports its format
+func Decode(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+	return img, format, nil
+}
+
+// Thumbnail scales img down so its longest side is at most size, smaller images are returned as is
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, bounds, draw.Src, nil)
+	return thumbnail
+}
+
+// EncodeJPEG is how thumbnails are stored, whatever the original format
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DHash is the 64 bit difference hash of img, resized and re-encoded copies of one image land a few bits apart
+func DHash(img image.Image) uint64 {
+	// 9x8 so every row yields 8 left to right comparisons
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.BiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := range 8 {
+		for x := range 8 {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// Distance is the number of differing bits between two hashes, 0 for identical images
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
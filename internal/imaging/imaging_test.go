@@ -0,0 +1,85 @@
+/*
+uniwish.com/internal/imaging/imaging_test
+
+tests thumbnails and perceptual hashes
+*/
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// gradient draws a diagonal gradient, with invert the mirrored one
+func gradient(width int, height int, invert bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			v := uint8((x*255/width + y*255/height) / 2)
+			if invert {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name           string
+		width, height  int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{name: "landscape", width: 1200, height: 800, expectedWidth: 320, expectedHeight: 213},
+		{name: "portrait", width: 800, height: 1600, expectedWidth: 160, expectedHeight: 320},
+		{name: "small", width: 100, height: 50, expectedWidth: 100, expectedHeight: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounds := Thumbnail(gradient(tt.width, tt.height, false), DefaultThumbnailSize).Bounds()
+			if bounds.Dx() != tt.expectedWidth || bounds.Dy() != tt.expectedHeight {
+				t.Fatalf("expected %dx%d, received %dx%d", tt.expectedWidth, tt.expectedHeight, bounds.Dx(), bounds.Dy())
+			}
+		})
+	}
+}
+
+func TestDHash(t *testing.T) {
+	original := gradient(600, 400, false)
+
+	// a re-encoded thumbnail is the same picture to a perceptual hash
+	encoded, err := EncodeJPEG(Thumbnail(original, 120))
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	copied, format, err := Decode(encoded)
+	if err != nil || format != "jpeg" {
+		t.Fatalf("decode failed: %v %s", err, format)
+	}
+
+	if distance := Distance(DHash(original), DHash(copied)); distance > 5 {
+		t.Fatalf("expected resized copy within 5 bits, received %d", distance)
+	}
+	if distance := Distance(DHash(original), DHash(gradient(600, 400, true))); distance < 20 {
+		t.Fatalf("expected a different image over 20 bits away, received %d", distance)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, gradient(10, 10, false))
+	if _, format, err := Decode(buf.Bytes()); err != nil || format != "png" {
+		t.Fatalf("expected png, received %s %v", format, err)
+	}
+
+	if _, _, err := Decode([]byte("<html>")); !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("expected ErrUnsupportedImage, received %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+/*
+uniwish.com/internal/logging/context
+
+carries a request scoped slog.Logger through context.Context
+*/
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+func FromContext(ctx context.Context) *slog.Logger {
+	// falls back to the default logger outside of a request
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
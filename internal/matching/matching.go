@@ -0,0 +1,230 @@
+/*
+uniwish.com/internal/matching/matching
+
+scores whether two products from different stores are the same item
+*/
+package matching
+
+import (
+	"math/bits"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	MethodGTIN  = "gtin"
+	MethodMPN   = "mpn"
+	MethodFuzzy = "fuzzy"
+)
+
+const (
+	// gtinConfidence is below 1 only because stores do reuse barcodes across colours
+	gtinConfidence = 0.99
+	mpnConfidence  = 0.9
+	// DefaultThreshold is the fuzzy score a pair needs before it is considered a match
+	DefaultThreshold = 0.75
+)
+
+// Candidate is what matching knows about a product, identifiers are optional
+type Candidate struct {
+	ID       string
+	Store    string
+	Brand    string
+	Name     string
+	ImageURL string
+	// ImageHash is the perceptual hash of the mirrored image, 0 when it was not mirrored
+	ImageHash uint64
+	GTIN      string
+	MPN       string
+}
+
+type Match struct {
+	ID         string
+	Confidence float64
+	Method     string
+}
+
+// Score compares two products, identifiers win over names so a shared GTIN matches even under different titles
+func Score(a Candidate, b Candidate) Match {
+	if a.Store == b.Store {
+		// the same store never lists one item twice under different ids
+		return Match{ID: b.ID}
+	}
+
+	if gtinA, ok := NormalizeGTIN(a.GTIN); ok {
+		if gtinB, ok := NormalizeGTIN(b.GTIN); ok {
+			if gtinA == gtinB {
+				return Match{ID: b.ID, Confidence: gtinConfidence, Method: MethodGTIN}
+			}
+			// two valid, different barcodes are different items whatever the names say
+			return Match{ID: b.ID}
+		}
+	}
+
+	if sameBrand(a.Brand, b.Brand) && a.MPN != "" && normalizeMPN(a.MPN) == normalizeMPN(b.MPN) {
+		return Match{ID: b.ID, Confidence: mpnConfidence, Method: MethodMPN}
+	}
+
+	confidence := NameSimilarity(a.Name, b.Name)
+	// a missing image is unknown rather than different, so it only weighs in when both sides have one
+	switch {
+	case a.ImageHash != 0 && b.ImageHash != 0:
+		confidence = 0.7*confidence + 0.3*HashSimilarity(a.ImageHash, b.ImageHash)
+	case a.ImageURL != "" && b.ImageURL != "":
+		confidence = 0.8*confidence + 0.2*ImageSimilarity(a.ImageURL, b.ImageURL)
+	}
+	if !sameBrand(a.Brand, b.Brand) {
+		confidence *= 0.5
+	}
+	return Match{ID: b.ID, Confidence: confidence, Method: MethodFuzzy}
+}
+
+// Best returns the highest scoring candidate at or above threshold
+func Best(product Candidate, candidates []Candidate, threshold float64) (Match, bool) {
+	var best Match
+	for _, candidate := range candidates {
+		if candidate.ID == product.ID {
+			continue
+		}
+		match := Score(product, candidate)
+		if match.Confidence > best.Confidence {
+			best = match
+		}
+	}
+	return best, best.Method != "" && best.Confidence >= threshold
+}
+
+// NormalizeGTIN accepts GTIN-8/12/13/14 (EAN and UPC included) with a valid check digit, zero padded to 14 digits
+func NormalizeGTIN(raw string) (string, bool) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, raw)
+
+	switch len(digits) {
+	case 8, 12, 13, 14:
+	default:
+		return "", false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+
+	digits = strings.Repeat("0", 14-len(digits)) + digits
+	sum := 0
+	for i, r := range digits[:13] {
+		weight := 1
+		if i%2 == 0 {
+			weight = 3
+		}
+		sum += int(r-'0') * weight
+	}
+	if (10-sum%10)%10 != int(digits[13]-'0') {
+		return "", false
+	}
+	return digits, true
+}
+
+func normalizeMPN(mpn string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, mpn))
+}
+
+func sameBrand(a string, b string) bool {
+	// an unknown brand does not count against a match
+	return a == "" || b == "" || strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// NameSimilarity is the Dice coefficient of the names' character trigrams, 1 for identical names
+func NameSimilarity(a string, b string) float64 {
+	gramsA, gramsB := trigrams(a), trigrams(b)
+	if len(gramsA) == 0 || len(gramsB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for gram := range gramsA {
+		if gramsB[gram] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(gramsA)+len(gramsB))
+}
+
+func trigrams(name string) map[string]bool {
+	grams := make(map[string]bool)
+	for _, token := range Tokens(name) {
+		padded := []rune("  " + token + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			grams[string(padded[i:i+3])] = true
+		}
+	}
+	return grams
+}
+
+// Tokens lower-cases name and splits it into words, order kept and duplicates dropped
+func Tokens(name string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !slices.Contains(tokens, field) {
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+// ImageSimilarity is 1 for the same image file, resellers often hotlink or re-upload the brand's image under its name
+func ImageSimilarity(a string, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	baseA, baseB := imageBase(a), imageBase(b)
+	if baseA != "" && baseA == baseB {
+		return 1
+	}
+	return 0
+}
+
+const (
+	// hashes this close are the same picture resized or re-encoded
+	sameImageDistance = 6
+	// hashes this far apart share nothing more than random images do
+	differentImageDistance = 20
+)
+
+// HashSimilarity maps the distance between two perceptual hashes to 1 for the same picture down to 0 for unrelated ones
+func HashSimilarity(a uint64, b uint64) float64 {
+	distance := bits.OnesCount64(a ^ b)
+	switch {
+	case distance <= sameImageDistance:
+		return 1
+	case distance >= differentImageDistance:
+		return 0
+	}
+	return float64(differentImageDistance-distance) / float64(differentImageDistance-sameImageDistance)
+}
+
+func imageBase(rawURL string) string {
+	withoutQuery, _, _ := strings.Cut(rawURL, "?")
+	base := path.Base(withoutQuery)
+	if base == "." || base == "/" {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(base, path.Ext(base)))
+}
@@ -0,0 +1,145 @@
+/*
+uniwish.com/internal/matching/matching_test
+
+tests product matching scores
+*/
+package matching
+
+import (
+	"testing"
+)
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+		ok       bool
+	}{
+		{raw: "4006381333931", expected: "04006381333931", ok: true},
+		{raw: "036000291452", expected: "00036000291452", ok: true},
+		{raw: "9638-5074", expected: "00000096385074", ok: true},
+		{raw: "4006381333932"},
+		{raw: "40063813339"},
+		{raw: "40063813339a1"},
+		{raw: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			gtin, ok := NormalizeGTIN(tt.raw)
+			if ok != tt.ok || gtin != tt.expected {
+				t.Fatalf("expected %q %v, received %q %v", tt.expected, tt.ok, gtin, ok)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	brand := Candidate{ID: "a", Store: "zara", Brand: "ZARA", Name: "Wide leg high rise jeans", ImageURL: "https://static.zara.net/photos/04365021800-p.jpg"}
+
+	tests := []struct {
+		name           string
+		other          Candidate
+		expectedMethod string
+		matches        bool
+	}{
+		{
+			name:           "gtin",
+			other:          Candidate{ID: "b", Store: "reseller", Name: "Jeans", GTIN: "4006381333931"},
+			expectedMethod: MethodGTIN,
+			matches:        true,
+		},
+		{
+			name:           "mpn",
+			other:          Candidate{ID: "b", Store: "reseller", Brand: "zara", Name: "Denim", MPN: "4365/021-800"},
+			expectedMethod: MethodMPN,
+			matches:        true,
+		},
+		{
+			name:           "fuzzy",
+			other:          Candidate{ID: "b", Store: "reseller", Name: "ZARA wide-leg high rise jeans", ImageURL: "https://cdn.reseller.com/img/04365021800-p.jpg?w=400"},
+			expectedMethod: MethodFuzzy,
+			matches:        true,
+		},
+		{
+			name:           "different_item",
+			other:          Candidate{ID: "b", Store: "reseller", Name: "Leather ankle boots"},
+			expectedMethod: MethodFuzzy,
+		},
+		{
+			name:           "different_brand",
+			other:          Candidate{ID: "b", Store: "reseller", Brand: "Levi's", Name: "Wide leg high rise jeans"},
+			expectedMethod: MethodFuzzy,
+		},
+		{
+			name:  "different_gtin",
+			other: Candidate{ID: "b", Store: "reseller", Name: "Wide leg high rise jeans", GTIN: "036000291452"},
+		},
+		{
+			name:  "same_store",
+			other: Candidate{ID: "b", Store: "zara", Name: "Wide leg high rise jeans"},
+		},
+	}
+
+	product := brand
+	product.GTIN = "4006381333931"
+	product.MPN = "4365021800"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := Score(product, tt.other)
+			if match.Method != tt.expectedMethod {
+				t.Fatalf("expected method %q, received %+v", tt.expectedMethod, match)
+			}
+			if matches := match.Confidence >= DefaultThreshold; matches != tt.matches {
+				t.Fatalf("expected match %v, received %+v", tt.matches, match)
+			}
+		})
+	}
+}
+
+func TestBest(t *testing.T) {
+	product := Candidate{ID: "a", Store: "zara", Name: "Wide leg high rise jeans"}
+	candidates := []Candidate{
+		{ID: "a", Store: "zara", Name: "Wide leg high rise jeans"},
+		{ID: "b", Store: "reseller", Name: "Wide leg jeans"},
+		{ID: "c", Store: "outlet", Name: "Wide leg high rise jeans"},
+	}
+
+	match, ok := Best(product, candidates, DefaultThreshold)
+	if !ok || match.ID != "c" {
+		t.Fatalf("expected c to match, received %+v %v", match, ok)
+	}
+
+	if _, ok := Best(product, candidates[:1], DefaultThreshold); ok {
+		t.Fatal("expected a product not to match itself")
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	if similarity := NameSimilarity("Wide leg jeans", "wide-leg JEANS"); similarity != 1 {
+		t.Fatalf("expected identical names to score 1, received %f", similarity)
+	}
+	if similarity := NameSimilarity("ZARA wide leg jeans", "Wide leg jeans"); similarity < DefaultThreshold {
+		t.Fatalf("expected a brand prefixed name to match, received %f", similarity)
+	}
+	if similarity := NameSimilarity("Wide leg jeans", "Leather ankle boots"); similarity > 0.2 {
+		t.Fatalf("expected unrelated names to score low, received %f", similarity)
+	}
+}
+
+func TestScore_ImageHash(t *testing.T) {
+	product := Candidate{ID: "a", Store: "zara", Name: "Wide leg jeans", ImageHash: 0xF0F0F0F0F0F0F0F0}
+
+	// the same picture under a looser title
+	same := Candidate{ID: "b", Store: "reseller", Name: "Wide jeans", ImageHash: 0xF0F0F0F0F0F0F0F1}
+	// a different picture under the same title
+	different := Candidate{ID: "c", Store: "outlet", Name: "Wide leg jeans", ImageHash: 0x0F0F0F0F0F0F0F0F}
+
+	if match := Score(product, same); match.Confidence < DefaultThreshold {
+		t.Fatalf("expected the same image to match, received %+v", match)
+	}
+	if match := Score(product, different); match.Confidence >= DefaultThreshold {
+		t.Fatalf("expected a different image not to match, received %+v", match)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+uniwish.com/internal/metrics
+
+centralizes prometheus registry setup and collectors shared by the api and worker
+*/
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"uniwish.com/internal/api/repository"
+)
+
+const Namespace = "uniwish"
+
+func NewRegistry(db *sql.DB) *prometheus.Registry {
+	// process, runtime and DB pool metrics every binary exposes
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, Namespace),
+	)
+	return reg
+}
+
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
+}
+
+type QueueCollector struct {
+	inspector        repository.QueueInspector
+	timeout          time.Duration
+	depth            *prometheus.Desc
+	oldestPendingAge *prometheus.Desc
+}
+
+func NewQueueCollector(inspector repository.QueueInspector) *QueueCollector {
+	// queue stats are read on every scrape rather than tracked, so they stay right across processes
+	return &QueueCollector{
+		inspector: inspector,
+		timeout:   5 * time.Second,
+		depth: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "queue", "depth"),
+			"Scrape requests in the queue by status.",
+			[]string{"status"}, nil,
+		),
+		oldestPendingAge: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "queue", "oldest_pending_age_seconds"),
+			"Age of the oldest pending scrape request, zero when none are pending.",
+			nil, nil,
+		),
+	}
+}
+
+func (c *QueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depth
+	ch <- c.oldestPendingAge
+}
+
+func (c *QueueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stats, err := c.inspector.Stats(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.depth, err)
+		return
+	}
+
+	for status, count := range stats.Depth {
+		ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(count), status)
+	}
+	ch <- prometheus.MustNewConstMetric(c.oldestPendingAge, prometheus.GaugeValue, stats.OldestPendingAge.Seconds())
+}
@@ -0,0 +1,56 @@
+/*
+uniwish.com/internal/metrics/metrics_test
+
+tests for shared collectors
+*/
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"uniwish.com/internal/api/repository"
+)
+
+type FakeInspector struct {
+	stats *repository.QueueStats
+	err   error
+}
+
+func (i *FakeInspector) Stats(_ context.Context) (*repository.QueueStats, error) {
+	return i.stats, i.err
+}
+
+func TestQueueCollector(t *testing.T) {
+	collector := NewQueueCollector(&FakeInspector{
+		stats: &repository.QueueStats{
+			Depth:            map[string]int64{"pending": 3, "failed": 1},
+			OldestPendingAge: 90 * time.Second,
+		},
+	})
+
+	expected := `
+# HELP uniwish_queue_depth Scrape requests in the queue by status.
+# TYPE uniwish_queue_depth gauge
+uniwish_queue_depth{status="failed"} 1
+uniwish_queue_depth{status="pending"} 3
+# HELP uniwish_queue_oldest_pending_age_seconds Age of the oldest pending scrape request, zero when none are pending.
+# TYPE uniwish_queue_oldest_pending_age_seconds gauge
+uniwish_queue_oldest_pending_age_seconds 90
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueCollector_InspectorError(t *testing.T) {
+	collector := NewQueueCollector(&FakeInspector{err: errors.New("db down")})
+
+	if _, err := testutil.CollectAndLint(collector); err == nil {
+		t.Fatal("expected collection error")
+	}
+}
@@ -0,0 +1,175 @@
+/*
+uniwish.com/internal/migrator
+
+applies the embedded migrations with golang-migrate and guards startup against schemas this build cannot run on
+*/
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/migrations"
+)
+
+var ErrSchemaDirty = errors.New("schema is dirty")
+var ErrSchemaTooNew = errors.New("schema is newer than this build")
+
+var upMigration = regexp.MustCompile(`^(\d+)_(.+)\.up\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Applied bool
+}
+
+type Runner struct {
+	m    *migrate.Migrate
+	conn *sql.Conn
+}
+
+// New binds a runner to one of db's connections, closing the runner releases it and leaves db open
+func New(ctx context.Context, db *sql.DB) (*Runner, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Runner{m: m, conn: conn}, nil
+}
+
+func (r *Runner) Close() error {
+	sourceErr, dbErr := r.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// Stop asks a running migration to halt once its current file is applied
+func (r *Runner) Stop() {
+	select {
+	case r.m.GracefulStop <- true:
+	default:
+	}
+}
+
+// Up applies every pending migration, an up to date schema is not an error
+func (r *Runner) Up() error {
+	return ignoreNoChange(r.m.Up())
+}
+
+// Down rolls back every applied migration
+func (r *Runner) Down() error {
+	return ignoreNoChange(r.m.Down())
+}
+
+// Steps applies n migrations, rolling back when n is negative
+func (r *Runner) Steps(n int) error {
+	return ignoreNoChange(r.m.Steps(n))
+}
+
+// Force records version as applied and clean without running anything, to recover from a failed migration
+func (r *Runner) Force(version int) error {
+	return r.m.Force(version)
+}
+
+func (r *Runner) Version() (*repository.SchemaState, error) {
+	version, dirty, err := r.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return &repository.SchemaState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &repository.SchemaState{Version: int64(version), Dirty: dirty}, nil
+}
+
+// Status lists the embedded migrations, marking those at or below the applied version
+func (r *Runner) Status() ([]Migration, error) {
+	state, err := r.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := Available()
+	if err != nil {
+		return nil, err
+	}
+	for i := range available {
+		available[i].Applied = available[i].Version <= state.Version
+	}
+	return available, nil
+}
+
+// Available lists the embedded migrations in order
+func Available() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var available []Migration
+	for _, entry := range entries {
+		match := upMigration.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		available = append(available, Migration{Version: version, Name: match[2]})
+	}
+
+	slices.SortFunc(available, func(a, b Migration) int {
+		return int(a.Version - b.Version)
+	})
+	return available, nil
+}
+
+// Check refuses a dirty schema or one newer than expected, an older one is left to the readiness check
+// so an instance can still come up and report it
+func Check(ctx context.Context, reader repository.SchemaReader, expected int64) error {
+	state, err := reader.SchemaState(ctx)
+	if err != nil {
+		return err
+	}
+	if state.Dirty {
+		return fmt.Errorf("%w: version %d failed to apply, fix it and force the version", ErrSchemaDirty, state.Version)
+	}
+	if state.Version > expected {
+		return fmt.Errorf("%w: version %d, this build knows up to %d", ErrSchemaTooNew, state.Version, expected)
+	}
+	return nil
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+/*
+uniwish.com/internal/migrator/migrator_test
+
+tests the embedded migrations and the startup schema check
+*/
+package migrator
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/testutil"
+	"uniwish.com/migrations"
+)
+
+func TestAvailable_MatchesSchemaVersion(t *testing.T) {
+	available, err := Available()
+	if err != nil {
+		t.Fatalf("reading migrations failed: %v", err)
+	}
+
+	for i, migration := range available {
+		if migration.Version != int64(i+1) {
+			t.Fatalf("expected migration %d to be version %d, received %d", i, i+1, migration.Version)
+		}
+	}
+	if latest := available[len(available)-1].Version; latest != repository.SchemaVersion {
+		t.Fatalf("expected the latest migration %d to be repository.SchemaVersion %d", latest, repository.SchemaVersion)
+	}
+}
+
+func TestAvailable_HaveDownMigrations(t *testing.T) {
+	ups, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+	downs, err := fs.Glob(migrations.FS, "*.down.sql")
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+	if len(ups) != len(downs) {
+		t.Fatalf("expected every up migration to have a down migration, %d up and %d down", len(ups), len(downs))
+	}
+}
+
+type FakeSchemaReader struct {
+	state *repository.SchemaState
+	err   error
+}
+
+func (r *FakeSchemaReader) SchemaState(_ context.Context) (*repository.SchemaState, error) {
+	return r.state, r.err
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		reader        *FakeSchemaReader
+		expectedError error
+	}{
+		{name: "current", reader: &FakeSchemaReader{state: &repository.SchemaState{Version: 12}}},
+		{name: "behind", reader: &FakeSchemaReader{state: &repository.SchemaState{Version: 3}}},
+		{name: "dirty", reader: &FakeSchemaReader{state: &repository.SchemaState{Version: 12, Dirty: true}}, expectedError: ErrSchemaDirty},
+		{name: "newer", reader: &FakeSchemaReader{state: &repository.SchemaState{Version: 13}}, expectedError: ErrSchemaTooNew},
+		{name: "unreadable", reader: &FakeSchemaReader{err: context.DeadlineExceeded}, expectedError: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(context.Background(), tt.reader, 12)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRunner_StatusOnMigratedDatabase(t *testing.T) {
+	testutil.RequireIntegration(t)
+	db := testutil.OpenDB()
+	defer db.Close()
+	ctx := context.Background()
+
+	runner, err := New(ctx, db)
+	if err != nil {
+		t.Fatalf("runner setup failed: %v", err)
+	}
+
+	// the test database is migrated before the suite runs, so up has nothing left to do
+	if err := runner.Up(); err != nil {
+		t.Fatalf("up failed: %v", err)
+	}
+
+	status, err := runner.Status()
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	for _, migration := range status {
+		if !migration.Applied {
+			t.Fatalf("expected migration %d_%s to be applied", migration.Version, migration.Name)
+		}
+	}
+
+	if err := runner.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatalf("expected the database to stay open, received %v", err)
+	}
+}
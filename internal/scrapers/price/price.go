@@ -0,0 +1,292 @@
+/*
+uniwish.com/internal/scrapers/price
+
+parses storefront price strings such as "1.299,95 €" or "CHF 1'299.–" into exact amounts
+*/
+package price
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"uniwish.com/internal/currency"
+)
+
+var (
+	ErrInvalidPrice   = errors.New("invalid price")
+	ErrNoCurrency     = errors.New("price has no currency")
+	ErrCurrencyClash  = errors.New("price currency does not match expected currency")
+	errAmbiguousGroup = errors.New("ambiguous separator")
+)
+
+// maxDigits keeps every parsed amount within int64 minor units
+const maxDigits = 18
+
+type Options struct {
+	// Currency is the ISO 4217 code the store quotes in, it resolves ambiguous symbols like "$" or "kr"
+	// and is used when the string carries no currency at all
+	Currency string
+	// Locale is a BCP 47 tag like "de-DE", it decides whether a lone separator before three digits is decimal
+	Locale string
+	// Machine marks raw as structured data such as a schema.org price, where '.' is the decimal separator
+	// whatever the locale and zeros may pad the decimals past the currency's, "9.990" is 9.99
+	Machine bool
+}
+
+// symbols maps currency markers to the codes they may stand for, the first being the default
+var symbols = map[string][]string{
+	"€":   {"EUR"},
+	"£":   {"GBP"},
+	"$":   {"USD", "CAD", "AUD", "NZD", "MXN", "SGD", "HKD", "ARS", "CLP", "COP"},
+	"US$": {"USD"},
+	"C$":  {"CAD"},
+	"CA$": {"CAD"},
+	"A$":  {"AUD"},
+	"AU$": {"AUD"},
+	"NZ$": {"NZD"},
+	"HK$": {"HKD"},
+	"S$":  {"SGD"},
+	"MX$": {"MXN"},
+	"R$":  {"BRL"},
+	"¥":   {"JPY", "CNY"},
+	"JP¥": {"JPY"},
+	"CN¥": {"CNY"},
+	"元":   {"CNY"},
+	"円":   {"JPY"},
+	"₩":   {"KRW"},
+	"₹":   {"INR"},
+	"₺":   {"TRY"},
+	"₽":   {"RUB"},
+	"₴":   {"UAH"},
+	"₪":   {"ILS"},
+	"₫":   {"VND"},
+	"฿":   {"THB"},
+	"₱":   {"PHP"},
+	"zł":  {"PLN"},
+	"Kč":  {"CZK"},
+	"Ft":  {"HUF"},
+	"lei": {"RON"},
+	"лв":  {"BGN"},
+	"kr":  {"SEK", "NOK", "DKK", "ISK"},
+	"Fr":  {"CHF"},
+	"Fr.": {"CHF"},
+	"R":   {"ZAR"},
+	"RM":  {"MYR"},
+	"Rp":  {"IDR"},
+}
+
+// commaDecimal lists the languages writing "1.299,95", every other language is assumed to write "1,299.95"
+var commaDecimal = []string{
+	"bg", "ca", "cs", "da", "de", "el", "es", "et", "fi", "fr", "hr", "hu", "id", "is", "it", "lt",
+	"lv", "nb", "nl", "nn", "no", "pl", "pt", "ro", "ru", "sk", "sl", "sr", "sv", "tr", "uk", "vi",
+}
+
+// Parse reads raw into an exact amount, negative prices and more decimals than the currency allows are rejected
+func Parse(raw string, opts Options) (currency.Amount, error) {
+	number, marker := split(raw)
+	if number == "" {
+		return currency.Amount{}, fmt.Errorf("%w: %q has no digits", ErrInvalidPrice, raw)
+	}
+
+	code, err := resolveCurrency(marker, opts.Currency)
+	if err != nil {
+		return currency.Amount{}, fmt.Errorf("%w in %q", err, raw)
+	}
+	units, _ := currency.MinorUnits(code)
+
+	decimal := decimalSeparator(opts.Locale)
+	if opts.Machine {
+		decimal = '.'
+	}
+	minor, err := parseNumber(number, units, decimal, opts.Machine)
+	if err != nil {
+		return currency.Amount{}, fmt.Errorf("%w: %q: %w", ErrInvalidPrice, raw, err)
+	}
+	return currency.Amount{Minor: minor, Currency: code}, nil
+}
+
+// split separates the numeric part of raw from the currency marker around it
+func split(raw string) (string, string) {
+	var number, marker strings.Builder
+	for _, r := range strings.TrimSpace(raw) {
+		switch {
+		case (r == '.' || r == ',') && number.Len() == 0 && marker.Len() > 0:
+			// the dot of a marker such as "Fr."
+			marker.WriteRune(r)
+		case unicode.IsDigit(r), r == '.', r == ',', r == '-', r == '–', r == '+':
+			number.WriteRune(r)
+		case r == '\'', r == '’', unicode.IsSpace(r):
+			// apostrophes and (narrow) no-break spaces group thousands, spaces before the digits only separate the marker
+			if number.Len() > 0 {
+				number.WriteRune(' ')
+			}
+		default:
+			marker.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(number.String()), strings.TrimSpace(marker.String())
+}
+
+func resolveCurrency(marker string, expected string) (string, error) {
+	if expected != "" {
+		normalized, err := currency.Normalize(expected)
+		if err != nil {
+			return "", err
+		}
+		expected = normalized
+	}
+
+	if marker == "" {
+		if expected == "" {
+			return "", ErrNoCurrency
+		}
+		return expected, nil
+	}
+
+	candidates, ok := symbols[marker]
+	if !ok {
+		code, err := currency.Normalize(marker)
+		if err != nil {
+			return "", err
+		}
+		candidates = []string{code}
+	}
+
+	switch {
+	case expected == "":
+		return candidates[0], nil
+	case slices.Contains(candidates, expected):
+		return expected, nil
+	default:
+		return "", fmt.Errorf("%w: %s is not %s", ErrCurrencyClash, marker, expected)
+	}
+}
+
+func decimalSeparator(locale string) rune {
+	if locale == "" {
+		return 0
+	}
+	language, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	language, _, _ = strings.Cut(language, "_")
+	if slices.Contains(commaDecimal, language) {
+		return ','
+	}
+	return '.'
+}
+
+// parseNumber turns a number with grouping and decimal separators into minor units
+// decimal is the locale's separator, 0 when unknown, padded drops zeros past the currency's decimals
+func parseNumber(number string, units int, decimal rune, padded bool) (int64, error) {
+	if strings.HasPrefix(number, "-") || strings.HasPrefix(number, "–") {
+		return 0, errors.New("negative amount")
+	}
+	number = strings.TrimPrefix(number, "+")
+
+	// "29,–" and "29.-" are whole amounts
+	for _, suffix := range []string{",-", ".-", ",–", ".–", ",--", ".--"} {
+		number = strings.TrimSuffix(number, suffix)
+	}
+	if strings.ContainsAny(number, "-–+") {
+		return 0, errors.New("unexpected sign")
+	}
+
+	whole, fraction, err := splitDecimal(number, units, decimal)
+	if err != nil {
+		return 0, err
+	}
+
+	whole, err = ungroup(whole)
+	if err != nil {
+		return 0, err
+	}
+	for padded && len(fraction) > units && strings.HasSuffix(fraction, "0") {
+		fraction = fraction[:len(fraction)-1]
+	}
+	if len(fraction) > units {
+		return 0, fmt.Errorf("%d decimals, currency allows %d", len(fraction), units)
+	}
+	digits := strings.TrimLeft(whole, "0") + fraction + strings.Repeat("0", units-len(fraction))
+	if len(digits) > maxDigits {
+		return 0, errors.New("amount too large")
+	}
+
+	var minor int64
+	for _, d := range digits {
+		minor = minor*10 + int64(d-'0')
+	}
+	return minor, nil
+}
+
+// splitDecimal finds the decimal separator, the last of '.' and ',' when both are used
+func splitDecimal(number string, units int, decimal rune) (string, string, error) {
+	last := strings.LastIndexAny(number, ".,")
+	if last == -1 {
+		return number, "", nil
+	}
+	separator := rune(number[last])
+	whole, fraction := number[:last], number[last+1:]
+
+	if strings.ContainsAny(fraction, " ") {
+		return "", "", errors.New("grouped decimals")
+	}
+
+	other := strings.ContainsRune(whole, otherSeparator(separator))
+	repeated := strings.ContainsRune(whole, separator)
+	switch {
+	case other && repeated:
+		return "", "", errors.New("mixed separators")
+	case other:
+		// "1.299,95": the last separator is the decimal one
+		return whole, fraction, nil
+	case repeated:
+		// "1.299.000": only grouping
+		return number, "", nil
+	case len(fraction) != 3:
+		return whole, fraction, nil
+	}
+
+	// a lone separator before three digits, "1.299" or "1,299"
+	switch {
+	case decimal != 0:
+		if separator == decimal {
+			return whole, fraction, nil
+		}
+		return number, "", nil
+	case units < 3:
+		return number, "", nil
+	case units == 3:
+		// the currency allows three decimals, so only the locale could tell
+		return "", "", errAmbiguousGroup
+	}
+	return whole, fraction, nil
+}
+
+func otherSeparator(separator rune) rune {
+	if separator == '.' {
+		return ','
+	}
+	return '.'
+}
+
+// ungroup strips grouping separators, every group but the first must be three digits
+func ungroup(whole string) (string, error) {
+	groups := strings.FieldsFunc(whole, func(r rune) bool {
+		return r == '.' || r == ',' || r == ' '
+	})
+	if len(groups) == 0 {
+		return "0", nil
+	}
+	if strings.IndexAny(whole, ".,") == 0 || strings.LastIndexAny(whole, ".,") == len(whole)-1 {
+		return "", errors.New("misplaced separator")
+	}
+
+	for i, group := range groups {
+		if i > 0 && len(group) != 3 {
+			return "", fmt.Errorf("group %q is not three digits", group)
+		}
+	}
+	return strings.Join(groups, ""), nil
+}
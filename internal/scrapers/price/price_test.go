@@ -0,0 +1,69 @@
+/*
+uniwish.com/internal/scrapers/price/price_test
+
+tests price parsing across locales and currencies
+*/
+package price
+
+import (
+	"errors"
+	"testing"
+
+	"uniwish.com/internal/currency"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		opts          Options
+		expected      currency.Amount
+		expectedError error
+	}{
+		{name: "plain", raw: "29.95", opts: Options{Currency: "EUR"}, expected: currency.Amount{Minor: 2995, Currency: "EUR"}},
+		{name: "comma_decimal_symbol", raw: "29,95 €", expected: currency.Amount{Minor: 2995, Currency: "EUR"}},
+		{name: "european_grouping", raw: "1.299,95", opts: Options{Currency: "EUR"}, expected: currency.Amount{Minor: 129995, Currency: "EUR"}},
+		{name: "english_grouping", raw: "$1,299.95", expected: currency.Amount{Minor: 129995, Currency: "USD"}},
+		{name: "nbsp_grouping", raw: "1 299,95 kr", opts: Options{Currency: "SEK"}, expected: currency.Amount{Minor: 129995, Currency: "SEK"}},
+		{name: "narrow_nbsp_grouping", raw: "1 299,95 €", expected: currency.Amount{Minor: 129995, Currency: "EUR"}},
+		{name: "swiss_apostrophe", raw: "CHF 1'299.–", expected: currency.Amount{Minor: 129900, Currency: "CHF"}},
+		{name: "marker_with_dot", raw: "Fr. 12.50", expected: currency.Amount{Minor: 1250, Currency: "CHF"}},
+		{name: "iso_prefix", raw: "EUR 1.299,95", expected: currency.Amount{Minor: 129995, Currency: "EUR"}},
+		{name: "pound", raw: "£12", expected: currency.Amount{Minor: 1200, Currency: "GBP"}},
+		{name: "single_decimal", raw: "12,5 €", expected: currency.Amount{Minor: 1250, Currency: "EUR"}},
+		{name: "lone_group_separator", raw: "1.299 €", expected: currency.Amount{Minor: 129900, Currency: "EUR"}},
+		{name: "repeated_group_separator", raw: "1.299.000", opts: Options{Currency: "EUR"}, expected: currency.Amount{Minor: 129900000, Currency: "EUR"}},
+		{name: "zero_decimal_currency", raw: "¥1,299", expected: currency.Amount{Minor: 1299, Currency: "JPY"}},
+		{name: "ambiguous_symbol_resolved", raw: "$1,299", opts: Options{Currency: "cad"}, expected: currency.Amount{Minor: 129900, Currency: "CAD"}},
+		{name: "three_decimal_locale", raw: "1.299", opts: Options{Currency: "KWD", Locale: "en-KW"}, expected: currency.Amount{Minor: 1299, Currency: "KWD"}},
+		{name: "three_decimal_group_locale", raw: "1.299", opts: Options{Currency: "KWD", Locale: "de"}, expected: currency.Amount{Minor: 1299000, Currency: "KWD"}},
+		{name: "three_decimal_ambiguous", raw: "1.299", opts: Options{Currency: "KWD"}, expectedError: ErrInvalidPrice},
+		{name: "too_many_decimals", raw: "29.955", opts: Options{Currency: "EUR", Locale: "en"}, expectedError: ErrInvalidPrice},
+		{name: "negative", raw: "-29.95", opts: Options{Currency: "EUR"}, expectedError: ErrInvalidPrice},
+		{name: "bad_grouping", raw: "1.29.95", opts: Options{Currency: "EUR"}, expectedError: ErrInvalidPrice},
+		{name: "no_digits", raw: "€", expectedError: ErrInvalidPrice},
+		{name: "no_currency", raw: "29.95", expectedError: ErrNoCurrency},
+		{name: "unknown_currency", raw: "29.95 XYZ", expectedError: currency.ErrUnknownCurrency},
+		{name: "currency_clash", raw: "£29.95", opts: Options{Currency: "EUR"}, expectedError: ErrCurrencyClash},
+		{name: "too_large", raw: "99999999999999999999", opts: Options{Currency: "EUR"}, expectedError: ErrInvalidPrice},
+		{name: "machine_padded_decimals", raw: "9.990", opts: Options{Currency: "EUR", Machine: true}, expected: currency.Amount{Minor: 999, Currency: "EUR"}},
+		{name: "machine_ignores_locale", raw: "1.290", opts: Options{Currency: "EUR", Locale: "de", Machine: true}, expected: currency.Amount{Minor: 129, Currency: "EUR"}},
+		{name: "machine_three_decimals", raw: "1.299", opts: Options{Currency: "KWD", Machine: true}, expected: currency.Amount{Minor: 1299, Currency: "KWD"}},
+		{name: "machine_too_many_decimals", raw: "9.995", opts: Options{Currency: "EUR", Machine: true}, expectedError: ErrInvalidPrice},
+		{name: "machine_grouped", raw: "1,299.00", opts: Options{Currency: "EUR", Machine: true}, expected: currency.Amount{Minor: 129900, Currency: "EUR"}},
+		{name: "unpadded_without_machine", raw: "9.990", opts: Options{Currency: "EUR", Locale: "en"}, expectedError: ErrInvalidPrice},
+		{name: "machine_negative", raw: "-9.99", opts: Options{Currency: "EUR", Machine: true}, expectedError: ErrInvalidPrice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := Parse(tt.raw, tt.opts)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if amount != tt.expected {
+				t.Fatalf("expected %+v, received %+v", tt.expected, amount)
+			}
+		})
+	}
+}
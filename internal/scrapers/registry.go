@@ -8,18 +8,23 @@ package scrapers
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
+	"uniwish.com/internal/api/config"
 	"uniwish.com/internal/scrapers/zara"
 )
 
 var ErrNoScraper = errors.New("no scraper available")
 var ErrInvalidURL = errors.New("invalid url")
+var ErrUnknownStore = errors.New("unknown store")
 
 type Registry interface {
 	ValidateUrl(string) error
+	StoreFor(string) (string, error)
 	NewScraperFor(string) (Scraper, error)
 }
 
@@ -52,6 +57,11 @@ func (sr *ScraperRegistry) ValidateUrl(rawURL string) error {
 	return nil
 }
 
+func (sr *ScraperRegistry) StoreFor(rawURL string) (string, error) {
+	// the registered host key doubles as the store identifier for scheduling
+	return sr.getHost(rawURL)
+}
+
 func (sr *ScraperRegistry) NewScraperFor(rawURL string) (Scraper, error) {
 	host, err := sr.getHost(rawURL)
 
@@ -71,9 +81,47 @@ func NewScraperRegistry(byHostMap map[string]ScraperFactory) *ScraperRegistry {
 	return &ScraperRegistry{byHost: byHostMap}
 }
 
-var DefaultScraperRegistry = NewScraperRegistry(map[string]ScraperFactory{
-	"zara.com": func() Scraper {
-		return zara.NewZaraScraper(10 * time.Second)
+// stores lists every supported store by host, building its scraper with the configured timeout
+var stores = map[string]func(timeout time.Duration) Scraper{
+	"zara.com": func(timeout time.Duration) Scraper {
+		return zara.NewZaraScraper(timeout)
 	},
-},
-)
+}
+
+// DefaultScraperRegistry registers every store with a 10s timeout, for callers without a config
+var DefaultScraperRegistry = func() *ScraperRegistry {
+	byHost := make(map[string]ScraperFactory, len(stores))
+	for host, newScraper := range stores {
+		byHost[host] = func() Scraper { return newScraper(10 * time.Second) }
+	}
+	return NewScraperRegistry(byHost)
+}()
+
+// NewConfiguredRegistry registers the stores cfg leaves enabled, each with its own timeout
+// overrides naming a store we do not scrape are refused as they are most likely typos
+func NewConfiguredRegistry(cfg *config.Config) (*ScraperRegistry, error) {
+	known := make(map[string]bool, len(stores))
+	for host := range stores {
+		known[config.StoreKey(host)] = true
+	}
+	var unknown []string
+	for store := range cfg.Scrapers {
+		if !known[store] {
+			unknown = append(unknown, store)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStore, strings.Join(unknown, ", "))
+	}
+
+	byHost := make(map[string]ScraperFactory, len(stores))
+	for host, newScraper := range stores {
+		settings := cfg.Scraper(host)
+		if !settings.Enabled {
+			continue
+		}
+		byHost[host] = func() Scraper { return newScraper(settings.Timeout) }
+	}
+	return NewScraperRegistry(byHost), nil
+}
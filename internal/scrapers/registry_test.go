@@ -8,6 +8,8 @@ package scrapers
 import (
 	"errors"
 	"testing"
+
+	"uniwish.com/internal/api/config"
 )
 
 func TestRegistry_ValidateUrl(t *testing.T) {
@@ -33,3 +35,36 @@ func TestRegistry_ValidateUrl(t *testing.T) {
 		}
 	}
 }
+
+func TestNewConfiguredRegistry(t *testing.T) {
+	tests := []struct {
+		name          string
+		environ       []string
+		expectedErr   error
+		expectedStore error
+	}{
+		{name: "defaults", environ: []string{}, expectedStore: nil},
+		{name: "disabled_store", environ: []string{"SCRAPER_ZARA_COM_ENABLED=false"}, expectedStore: ErrNoScraper},
+		{name: "unknown_store", environ: []string{"SCRAPER_ZARAH_COM_TIMEOUT=5s"}, expectedErr: ErrUnknownStore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.Parse(config.Options{Environ: tt.environ})
+			if err != nil {
+				t.Fatalf("config parse: %v", err)
+			}
+
+			registry, err := NewConfiguredRegistry(cfg)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected %v, received %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if err := registry.ValidateUrl("https://www.zara.com/item"); !errors.Is(err, tt.expectedStore) {
+				t.Fatalf("expected %v, received %v", tt.expectedStore, err)
+			}
+		})
+	}
+}
@@ -7,21 +7,33 @@ contains logic related to http interaction with zara pages
 package zara
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/html"
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/currency"
 	"uniwish.com/internal/domain"
+	"uniwish.com/internal/scrapers/price"
 )
 
+var tracer = otel.Tracer("uniwish.com/internal/scrapers/zara")
+
 type ZaraProductJSON struct {
 	Type     string `json:"@type"`
 	Name     string `json:"name"`
@@ -29,6 +41,10 @@ type ZaraProductJSON struct {
 	ImageURL string `json:"image"`
 	Size     string `json:"size"`
 	Color    string `json:"color"`
+	Brand    brand  `json:"brand"`
+	MPN      string `json:"mpn"`
+	GTIN13   string `json:"gtin13"`
+	GTIN     string `json:"gtin"`
 	Offer    struct {
 		Price        string `json:"price"`
 		Currency     string `json:"priceCurrency"`
@@ -36,6 +52,26 @@ type ZaraProductJSON struct {
 	} `json:"offers"`
 }
 
+// brand is published either as a name or as a schema.org Brand object
+type brand string
+
+func (b *brand) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*b = brand(name)
+		return nil
+	}
+	var object struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		// a malformed brand should not cost the whole product
+		return nil
+	}
+	*b = brand(object.Name)
+	return nil
+}
+
 type ZaraScraper struct {
 	client *http.Client
 }
@@ -49,6 +85,9 @@ func NewZaraScraper(timeout time.Duration) *ZaraScraper {
 }
 
 func (s *ZaraScraper) Fetch(ctx context.Context, URL string) (io.ReadCloser, error) {
+	ctx, span := tracer.Start(ctx, "ZaraScraper.Fetch", trace.WithAttributes(attribute.String("url.full", URL)))
+	defer span.End()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("request error: %w", err)
@@ -59,13 +98,30 @@ func (s *ZaraScraper) Fetch(ctx context.Context, URL string) (io.ReadCloser, err
 		return nil, fmt.Errorf("request error: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s answered %d: %w", URL, resp.StatusCode, apiErrors.ErrProductRemoved)
+	}
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		return nil, fmt.Errorf("Unexpected http error")
 	}
+	// zara redirects the pages of removed products to their category rather than answering 404
+	if final := resp.Request.URL; final.String() != URL && !IsProductPath(final.Path) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s redirected to %s: %w", URL, final, apiErrors.ErrProductRemoved)
+	}
 
 	return resp.Body, nil
 }
 
+var productPath = regexp.MustCompile(`-p\d+\.html$`)
+
+// IsProductPath tells product pages, /es/en/jeans-p04365052.html, from categories, /es/en/woman-jeans-l1119.html
+func IsProductPath(path string) bool {
+	return productPath.MatchString(path)
+}
+
 func (s *ZaraScraper) Scrape(ctx context.Context, URL string) (*domain.ProductRecord, error) {
 	pageBody, err := s.Fetch(ctx, URL)
 	if err != nil {
@@ -73,11 +129,43 @@ func (s *ZaraScraper) Scrape(ctx context.Context, URL string) (*domain.ProductRe
 	}
 	defer pageBody.Close()
 
+	// ParseProduct only sees the page, so its span is opened here
+	_, span := tracer.Start(ctx, "ZaraScraper.ParseProduct")
 	product, offers, err := s.ParseProduct(pageBody)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+	span.End()
+
 	product.URL = URL
+	product.Region = Region(URL)
+	for i := range *offers {
+		(*offers)[i].Region = product.Region
+	}
 	return &domain.ProductRecord{Product: product, Offers: offers}, nil
 }
 
+// Region reads the market from the first path segment, zara.com/es/en/... is "es"
+func Region(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	segment, _, _ := strings.Cut(strings.TrimPrefix(parsed.Path, "/"), "/")
+	if len(segment) != 2 {
+		return ""
+	}
+	for _, r := range segment {
+		if !unicode.IsLetter(r) {
+			return ""
+		}
+	}
+	return strings.ToLower(segment)
+}
+
 func (s *ZaraScraper) ParseProduct(page io.Reader) (*domain.ProductSnapshot, *[]domain.Offer, error) {
 	products, err := s.extractProductsFromPage(page)
 	if err != nil {
@@ -90,11 +178,19 @@ func (s *ZaraScraper) ParseProduct(page io.Reader) (*domain.ProductSnapshot, *[]
 		SKU:      products[0].SKU,
 		Store:    "zara",
 		ImageURL: products[0].ImageURL,
+		Brand:    string(products[0].Brand),
+		GTIN:     cmp.Or(products[0].GTIN13, products[0].GTIN),
+		MPN:      products[0].MPN,
 	}
 
 	var offers []domain.Offer
 	for _, productOffering := range products {
-		price, err := strconv.ParseFloat(productOffering.Offer.Price, 64)
+		// stored prices must carry a currency they can be converted from
+		currencyCode, err := currency.Normalize(productOffering.Offer.Currency)
+		if err != nil {
+			return nil, nil, err
+		}
+		amount, err := price.Parse(productOffering.Offer.Price, price.Options{Currency: currencyCode, Machine: true})
 		if err != nil {
 			return nil, nil, err
 		}
@@ -103,8 +199,7 @@ func (s *ZaraScraper) ParseProduct(page io.Reader) (*domain.ProductSnapshot, *[]
 			domain.Offer{
 				ID:           uuid.New(),
 				ProductID:    productId,
-				Price:        price,
-				Currency:     productOffering.Offer.Currency,
+				Price:        amount,
 				Size:         productOffering.Size,
 				Color:        productOffering.Color,
 				Availability: availability,
@@ -8,10 +8,16 @@ package zara
 import (
 	"bytes"
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+
+	apiErrors "uniwish.com/internal/api/errors"
+	"uniwish.com/internal/currency"
+	"uniwish.com/internal/scrapers/price"
 )
 
 type FakeZaraScraper struct {
@@ -60,6 +66,9 @@ func TestZaraScraper_Scrape(t *testing.T) {
 	if productRecord.Product.SKU != expectedSku {
 		t.Fatalf("name expected to be %s, received %s", expectedSku, productRecord.Product.SKU)
 	}
+	if productRecord.Product.Brand != "ZARA" || productRecord.Product.MPN == "" {
+		t.Fatalf("expected brand and mpn to be read, received %q %q", productRecord.Product.Brand, productRecord.Product.MPN)
+	}
 	if len(*productRecord.Offers) != 24 {
 		t.Fatalf("expected json length 24, receieved %d", len(*productRecord.Offers))
 	}
@@ -68,7 +77,7 @@ func TestZaraScraper_Scrape(t *testing.T) {
 		if o.ProductID != productRecord.Product.ID {
 			t.Fatalf("product offer's parent %v doesn't patch parent %v", o.ProductID, productRecord.Product.ID)
 		}
-		if o.Price == 0 {
+		if o.Price.Minor == 0 {
 			t.Fatalf("price nil")
 		}
 	}
@@ -91,3 +100,127 @@ func TestZaraScraper_ExtractJLJSON_Success(t *testing.T) {
 		t.Fatalf("expected json length 24, receieved %d", len(ldjson))
 	}
 }
+
+func TestZaraScraper_ParseProduct_Currency(t *testing.T) {
+	tests := []struct {
+		name             string
+		currency         string
+		expectedCurrency string
+		expectedError    error
+	}{
+		{name: "valid", currency: "EUR", expectedCurrency: "EUR"},
+		{name: "normalized", currency: " usd ", expectedCurrency: "USD"},
+		{name: "unknown", currency: "EURO", expectedError: currency.ErrUnknownCurrency},
+		{name: "missing", currency: "", expectedError: currency.ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scraper := &FakeZaraScraper{}
+			page := `<html><script type="application/ld+json">[{"@type": "Product", "name": "jacket", "sku": "1",
+				"offers": {"price": "29.95", "priceCurrency": "` + tt.currency + `"}}]</script></html>`
+
+			_, offers, err := scraper.ParseProduct(strings.NewReader(page))
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if err == nil && (*offers)[0].Price.Currency != tt.expectedCurrency {
+				t.Fatalf("expected currency %s, received %s", tt.expectedCurrency, (*offers)[0].Price.Currency)
+			}
+		})
+	}
+}
+
+func TestZaraScraper_ParseProduct_Price(t *testing.T) {
+	tests := []struct {
+		name          string
+		price         string
+		expected      currency.Amount
+		expectedError error
+	}{
+		{name: "decimal", price: "29.95", expected: currency.Amount{Minor: 2995, Currency: "EUR"}},
+		{name: "european", price: "1.299,95", expected: currency.Amount{Minor: 129995, Currency: "EUR"}},
+		{name: "symbol", price: "29,95 €", expected: currency.Amount{Minor: 2995, Currency: "EUR"}},
+		{name: "padded_decimals", price: "9.990", expected: currency.Amount{Minor: 999, Currency: "EUR"}},
+		{name: "invalid", price: "free", expectedError: price.ErrInvalidPrice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scraper := &FakeZaraScraper{}
+			page := `<html><script type="application/ld+json">[{"@type": "Product", "name": "jacket", "sku": "1",
+				"offers": {"price": "` + tt.price + `", "priceCurrency": "EUR"}}]</script></html>`
+
+			_, offers, err := scraper.ParseProduct(strings.NewReader(page))
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if err == nil && (*offers)[0].Price != tt.expected {
+				t.Fatalf("expected price %+v, received %+v", tt.expected, (*offers)[0].Price)
+			}
+		})
+	}
+}
+
+func TestRegion(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://www.zara.com/es/en/jeans-p04365021.html", expected: "es"},
+		{url: "https://www.zara.com/US/en/jeans-p04365021.html", expected: "us"},
+		{url: "https://www.zara.com/jeans-p04365021.html", expected: ""},
+		{url: "https://www.zara.com/", expected: ""},
+		{url: "https://www.zara.com/e1/en/", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if region := Region(tt.url); region != tt.expected {
+				t.Fatalf("expected region %q, received %q", tt.expected, region)
+			}
+		})
+	}
+}
+
+func TestZaraScraper_ScrapeRemoved(t *testing.T) {
+	page, _ := os.ReadFile("testdata/zara_product.html")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/es/en/gone-p1.html", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/es/en/moved-p2.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/es/en/woman-jeans-l1119.html", http.StatusFound)
+	})
+	mux.HandleFunc("/es/en/renamed-p3.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/es/en/jeans-p3.html", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(page)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		path        string
+		expectedErr error
+	}{
+		{path: "/es/en/gone-p1.html", expectedErr: apiErrors.ErrProductRemoved},
+		{path: "/es/en/moved-p2.html", expectedErr: apiErrors.ErrProductRemoved},
+		{path: "/es/en/renamed-p3.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			scraper := &FakeZaraScraper{}
+			scraper.client = ts.Client()
+
+			_, err := scraper.Scrape(context.Background(), ts.URL+tt.path)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected %v, received %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
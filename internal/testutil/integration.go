@@ -1,10 +1,13 @@
 package testutil
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
 	"testing"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func RequireIntegration(t *testing.T) {
@@ -13,6 +16,26 @@ func RequireIntegration(t *testing.T) {
 	}
 }
 
+func RequireRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	rawURL := os.Getenv("REDIS_URL")
+	if rawURL == "" {
+		t.Skip("REDIS_URL not set, redis tests disabled")
+	}
+
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		t.Fatalf("invalid REDIS_URL: %v", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("redis ping failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
 func OpenDB() *sql.DB {
 	if os.Getenv("INTEGRATION_TESTS") == "" {
 		log.Println("INTEGRATION_TESTS not set, skipping")
@@ -48,8 +71,20 @@ func TruncateTables(t *testing.T, db *sql.DB) {
 	_, err := db.Exec(`
 		TRUNCATE TABLE
 			prices,
+			product_regions,
+			product_images,
+			product_matches,
+			product_match_groups,
+			product_events,
+			notifications,
+			wishlist_items,
 			products,
-			scrape_requests
+			scrape_requests,
+			scrape_request_quotas,
+			fx_rates,
+			webhook_delivery_attempts,
+			webhook_deliveries,
+			webhook_subscriptions
 		RESTART IDENTITY
 		CASCADE
 	`)
@@ -12,16 +12,21 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 	"uniwish.com/internal/api/repository"
 )
 
+var tracer = otel.Tracer("uniwish.com/internal/worker")
+
 var ErrNoWork = errors.New("no job available")
+var ErrRetriesExhausted = errors.New("retries exhausted")
 
 type WorkerRepo interface {
 	BeginSession(context.Context) (WorkerSession, error)
 }
 type DefaultWorkerRepo struct {
-	db repository.TransactionCreator
+	db       repository.TransactionCreator
+	queueFor repository.QueueFactory
 }
 
 func (wr *DefaultWorkerRepo) BeginSession(ctx context.Context) (WorkerSession, error) {
@@ -30,28 +35,105 @@ func (wr *DefaultWorkerRepo) BeginSession(ctx context.Context) (WorkerSession, e
 		return nil, err
 	}
 
+	queue := wr.queueFor(tx)
+	if external, ok := queue.(repository.ExternalQueue); ok {
+		queue = &deferredQueue{Queue: external}
+	}
+
 	return &DefaultWorkerSession{
-		repository.NewPostgresScrapeRequestRepository(wr.db),
-		NewProductWriter(wr.db),
+		queue,
+		NewProductWriter(tx),
+		NewProductMatcher(tx),
+		NewWishlistWriter(tx),
+		NewWebhookWriter(tx),
+		NewStatusPublisher(tx),
 		tx,
 	}, nil
 
 }
 
+// deferredQueue holds back acks and nacks to a queue outside the session's transaction until the transaction commits,
+// so a request is never finished while the writes it produced roll back.
+// should sending them fail after the commit, the request is reclaimed once its lease lapses and scraped again
+type deferredQueue struct {
+	repository.Queue
+	pending []func() error
+}
+
+func (q *deferredQueue) Ack(ctx context.Context, lease repository.Lease) error {
+	q.pending = append(q.pending, func() error { return q.Queue.Ack(ctx, lease) })
+	return nil
+}
+
+func (q *deferredQueue) Nack(ctx context.Context, lease repository.Lease, failure repository.Failure) error {
+	q.pending = append(q.pending, func() error { return q.Queue.Nack(ctx, lease, failure) })
+	return nil
+}
+
+func (q *deferredQueue) flush() error {
+	pending := q.pending
+	q.pending = nil
+	for _, send := range pending {
+		if err := send(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Attempter runs steps a job does not hinge on, a failing step is undone without aborting the rest of the session
+type Attempter interface {
+	Attempt(ctx context.Context, name string, step func() error) error
+}
+
 type WorkerSession interface {
-	repository.ScrapeRequestRepository
+	repository.Queue
 	ProductWriter
+	ProductMatcher
+	WishlistWriter
+	WebhookWriter
+	StatusPublisher
+	Attempter
 	repository.Transaction
 }
 
 type DefaultWorkerSession struct {
-	repository.ScrapeRequestRepository
+	repository.Queue
 	ProductWriter
+	ProductMatcher
+	WishlistWriter
+	WebhookWriter
+	StatusPublisher
 	*sql.Tx
 }
 
-func NewWorkerRepo(db repository.TransactionCreator) *DefaultWorkerRepo {
-	return &DefaultWorkerRepo{db}
+func (s *DefaultWorkerSession) Commit() error {
+	if err := s.Tx.Commit(); err != nil {
+		return err
+	}
+	if deferred, ok := s.Queue.(*deferredQueue); ok {
+		return deferred.flush()
+	}
+	return nil
+}
+
+func (s *DefaultWorkerSession) Attempt(ctx context.Context, name string, step func() error) error {
+	// postgres aborts the whole transaction on a failed statement, a savepoint confines the failure to the step
+	if _, err := s.Tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := step(); err != nil {
+		if _, rollbackErr := s.Tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	_, err := s.Tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func NewWorkerRepo(db repository.TransactionCreator, queueFor repository.QueueFactory) *DefaultWorkerRepo {
+	return &DefaultWorkerRepo{db, queueFor}
 }
 
 type JobErrorKind string
@@ -59,6 +141,10 @@ type JobErrorKind string
 const (
 	JobUnsupportedStore JobErrorKind = "unsupported_store"
 	JobScrapeFailed     JobErrorKind = "scrape_failed"
+	// JobProductRemoved is a removed page no stored product has, there is nothing to mark discontinued
+	JobProductRemoved JobErrorKind = "product_removed"
+	// JobRetriesExhausted is a request whose lease lapsed on every earlier claim, its scrape keeps crashing or hanging the worker
+	JobRetriesExhausted JobErrorKind = "retries_exhausted"
 )
 
 type JobError struct {
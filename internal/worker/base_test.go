@@ -0,0 +1,41 @@
+/*
+uniwish.com/interal/worker/base_test
+
+tests for the worker session
+*/
+package worker
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/repository"
+)
+
+func TestDeferredQueue_FinishesOnFlush(t *testing.T) {
+	queue := &DefaultFakeWorkerSession{}
+	deferred := &deferredQueue{Queue: queue}
+	ctx := context.Background()
+
+	if err := deferred.Ack(ctx, repository.Lease{ID: uuid.New(), Token: uuid.New()}); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	if err := deferred.Nack(ctx, repository.Lease{ID: uuid.New(), Token: uuid.New()}, repository.Failure{Kind: "scrape_failed"}); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	if len(queue.Calls()) != 0 {
+		t.Fatalf("expected nothing sent before the commit, received %q", queue.Calls())
+	}
+
+	if err := deferred.flush(); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	if expected := []string{"Ack", "Nack"}; !slices.Equal(queue.Calls(), expected) {
+		t.Fatalf("Expected %q, received %q", expected, queue.Calls())
+	}
+	if err := deferred.flush(); err != nil || len(queue.Calls()) != 2 {
+		t.Fatalf("expected a second flush to send nothing, received %q %v", queue.Calls(), err)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"uniwish.com/internal/api/errors"
 	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/currency"
 	"uniwish.com/internal/domain"
 	"uniwish.com/internal/scrapers"
 )
@@ -38,14 +39,12 @@ func NewFakeProduct() *domain.ProductRecord {
 		{
 			ID:        uuid.New(),
 			ProductID: productID,
-			Price:     45.32,
-			Currency:  "EUR",
+			Price:     currency.Amount{Minor: 4532, Currency: "EUR"},
 		},
 		{
 			ID:        uuid.New(),
 			ProductID: productID,
-			Price:     25.32,
-			Currency:  "EUR",
+			Price:     currency.Amount{Minor: 2532, Currency: "EUR"},
 		},
 	}
 	return &domain.ProductRecord{Product: product, Offers: offers}
@@ -85,40 +84,111 @@ func (wr *DefaultFakeRepo) BeginSession(ctx context.Context) (WorkerSession, err
 }
 
 type FakeWorkerSession interface {
-	repository.ScrapeRequestRepository
+	repository.Queue
 	ProductWriter
+	ProductMatcher
+	WishlistWriter
+	WebhookWriter
+	StatusPublisher
+	Attempter
 	repository.Transaction
 	Calls() []string
+	Webhooks() []domain.WebhookEvent
+	Statuses() []repository.ScrapeStatus
 }
 type DefaultFakeWorkerSession struct {
 	CallRecorder
+	webhooks []domain.WebhookEvent
+	statuses []repository.ScrapeStatus
 }
 
-func (r *DefaultFakeWorkerSession) Insert(ctx context.Context, url string) (uuid.UUID, error) {
-	r.record("Insert")
+func (r *DefaultFakeWorkerSession) Enqueue(ctx context.Context, req repository.ScrapeRequest) (uuid.UUID, error) {
+	r.record("Enqueue")
 	return uuid.New(), nil
 }
-func (r *DefaultFakeWorkerSession) Dequeue(ctx context.Context) (*repository.ScrapeRequest, error) {
-	r.record("Dequeue")
+func (r *DefaultFakeWorkerSession) EnqueueBatch(ctx context.Context, reqs []repository.ScrapeRequest) ([]uuid.UUID, error) {
+	r.record("EnqueueBatch")
+	ids := make([]uuid.UUID, len(reqs))
+	for i := range reqs {
+		ids[i] = uuid.New()
+	}
+	return ids, nil
+}
+func (r *DefaultFakeWorkerSession) Claim(ctx context.Context) (*repository.ScrapeRequest, error) {
+	r.record("Claim")
 	return NewFakeJob(), nil
 }
-func (r *DefaultFakeWorkerSession) MarkDone(ctx context.Context, id uuid.UUID) error {
-	r.record("MarkDone")
+func (r *DefaultFakeWorkerSession) Ack(ctx context.Context, lease repository.Lease) error {
+	r.record("Ack")
+	return nil
+}
+func (r *DefaultFakeWorkerSession) Nack(ctx context.Context, lease repository.Lease, failure repository.Failure) error {
+	r.record("Nack")
 	return nil
 }
-func (r *DefaultFakeWorkerSession) MarkFailed(ctx context.Context, id uuid.UUID) error {
-	r.record("MarkFailed")
+func (r *DefaultFakeWorkerSession) ExtendLease(ctx context.Context, lease repository.Lease) error {
+	r.record("ExtendLease")
 	return nil
 }
 
-func (r *DefaultFakeWorkerSession) UpsertProduct(context.Context, domain.ProductSnapshot) (uuid.UUID, error) {
+func (r *DefaultFakeWorkerSession) UpsertProduct(_ context.Context, product domain.ProductSnapshot) (uuid.UUID, []domain.ProductEvent, error) {
 	r.record("UpsertProduct")
-	return uuid.Nil, nil
+	return product.ID, nil, nil
 }
-func (r *DefaultFakeWorkerSession) InsertPrice(context.Context, []domain.Offer) error {
+func (r *DefaultFakeWorkerSession) InsertPrice(context.Context, []domain.Offer) ([]domain.ProductEvent, error) {
 	r.record("InsertPrice")
+	return nil, nil
+}
+func (r *DefaultFakeWorkerSession) SaveImage(context.Context, uuid.UUID, domain.ProductImage) error {
+	r.record("SaveImage")
+	return nil
+}
+func (r *DefaultFakeWorkerSession) RecordEvents(context.Context, []domain.ProductEvent) error {
+	r.record("RecordEvents")
 	return nil
 }
+func (r *DefaultFakeWorkerSession) DiscontinueProduct(context.Context, string) (bool, []domain.ProductEvent, error) {
+	r.record("DiscontinueProduct")
+	return false, nil, nil
+}
+func (r *DefaultFakeWorkerSession) AddToWishlist(context.Context, string, uuid.UUID) error {
+	r.record("AddToWishlist")
+	return nil
+}
+func (r *DefaultFakeWorkerSession) NotifyWishlisters(context.Context, uuid.UUID, domain.NotificationKind) (int64, error) {
+	r.record("NotifyWishlisters")
+	return 1, nil
+}
+func (r *DefaultFakeWorkerSession) Webhooks() []domain.WebhookEvent {
+	return r.webhooks
+}
+
+func (r *DefaultFakeWorkerSession) EnqueueWebhooks(_ context.Context, events []domain.WebhookEvent) (int64, error) {
+	r.record("EnqueueWebhooks")
+	r.webhooks = append(r.webhooks, events...)
+	return int64(len(events)), nil
+}
+func (r *DefaultFakeWorkerSession) Statuses() []repository.ScrapeStatus {
+	return r.statuses
+}
+
+func (r *DefaultFakeWorkerSession) PublishStatus(_ context.Context, status repository.ScrapeStatus) error {
+	r.record("PublishStatus")
+	r.statuses = append(r.statuses, status)
+	return nil
+}
+func (r *DefaultFakeWorkerSession) MatchProduct(context.Context, uuid.UUID, domain.ProductSnapshot) error {
+	r.record("MatchProduct")
+	return nil
+}
+
+func (r *DefaultFakeWorkerSession) Attempt(_ context.Context, _ string, step func() error) error {
+	err := step()
+	if err != nil {
+		r.record("RollbackToSavepoint")
+	}
+	return err
+}
 
 func (t *DefaultFakeWorkerSession) Rollback() error {
 	t.record("Rollback")
@@ -144,8 +214,8 @@ type FakeNoJobsRepoSession struct {
 	DefaultFakeWorkerSession
 }
 
-func (f *FakeNoJobsRepoSession) Dequeue(ctx context.Context) (*repository.ScrapeRequest, error) {
-	f.DefaultFakeWorkerSession.Dequeue(ctx)
+func (f *FakeNoJobsRepoSession) Claim(ctx context.Context) (*repository.ScrapeRequest, error) {
+	f.DefaultFakeWorkerSession.Claim(ctx)
 	return nil, nil
 }
 
@@ -164,11 +234,158 @@ type FakeDBErrorRepoSession struct {
 	DefaultFakeWorkerSession
 }
 
-func (f *FakeDBErrorRepoSession) Dequeue(ctx context.Context) (*repository.ScrapeRequest, error) {
-	f.DefaultFakeWorkerSession.Dequeue(ctx)
+func (f *FakeDBErrorRepoSession) Claim(ctx context.Context) (*repository.ScrapeRequest, error) {
+	f.DefaultFakeWorkerSession.Claim(ctx)
 	return nil, sql.ErrConnDone
 }
 
+type ChangedProductRepo struct {
+	DefaultFakeRepo
+}
+
+func (wr *ChangedProductRepo) BeginSession(ctx context.Context) (WorkerSession, error) {
+	if wr.session == nil {
+		wr.session = &ChangedProductRepoSession{}
+	}
+	return wr.session, nil
+}
+
+// ChangedProductRepoSession finds the scraped product stored before, renamed and cheaper than it was
+type ChangedProductRepoSession struct {
+	DefaultFakeWorkerSession
+	events []domain.ProductEvent
+}
+
+func (f *ChangedProductRepoSession) UpsertProduct(ctx context.Context, product domain.ProductSnapshot) (uuid.UUID, []domain.ProductEvent, error) {
+	f.DefaultFakeWorkerSession.UpsertProduct(ctx, product)
+	// a stored product keeps the id it was first scraped with
+	id := uuid.New()
+	return id, []domain.ProductEvent{
+		{ProductID: id, Kind: domain.ProductNameChanged, Before: "jacket", After: product.Name},
+	}, nil
+}
+
+func (f *ChangedProductRepoSession) InsertPrice(ctx context.Context, offers []domain.Offer) ([]domain.ProductEvent, error) {
+	f.DefaultFakeWorkerSession.InsertPrice(ctx, offers)
+	return []domain.ProductEvent{
+		{ProductID: offers[0].ProductID, Kind: domain.ProductPriceChanged, Before: "45.32 EUR", After: "25.32 EUR"},
+	}, nil
+}
+
+func (f *ChangedProductRepoSession) RecordEvents(ctx context.Context, events []domain.ProductEvent) error {
+	f.DefaultFakeWorkerSession.RecordEvents(ctx, events)
+	f.events = append(f.events, events...)
+	return nil
+}
+
+type DiscontinuedProductRepo struct {
+	DefaultFakeRepo
+	// known is whether a stored product has the removed page's url
+	known bool
+}
+
+func (wr *DiscontinuedProductRepo) BeginSession(ctx context.Context) (WorkerSession, error) {
+	if wr.session == nil {
+		wr.session = &DiscontinuedProductRepoSession{known: wr.known}
+	}
+	return wr.session, nil
+}
+
+type DiscontinuedProductRepoSession struct {
+	DefaultFakeWorkerSession
+	known bool
+}
+
+func (f *DiscontinuedProductRepoSession) DiscontinueProduct(ctx context.Context, url string) (bool, []domain.ProductEvent, error) {
+	f.DefaultFakeWorkerSession.DiscontinueProduct(ctx, url)
+	if !f.known {
+		return false, nil, nil
+	}
+	return true, []domain.ProductEvent{
+		{ProductID: uuid.New(), Kind: domain.ProductDiscontinued, Before: "listed", After: "discontinued"},
+	}, nil
+}
+
+type FaultyWebhookRepo struct {
+	DefaultFakeRepo
+}
+
+func (wr *FaultyWebhookRepo) BeginSession(ctx context.Context) (WorkerSession, error) {
+	if wr.session == nil {
+		wr.session = &FaultyWebhookRepoSession{}
+	}
+	return wr.session, nil
+}
+
+type FaultyWebhookRepoSession struct {
+	DefaultFakeWorkerSession
+}
+
+func (f *FaultyWebhookRepoSession) EnqueueWebhooks(ctx context.Context, events []domain.WebhookEvent) (int64, error) {
+	f.DefaultFakeWorkerSession.EnqueueWebhooks(ctx, events)
+	return 0, sql.ErrConnDone
+}
+
+type FaultyStatusRepo struct {
+	DefaultFakeRepo
+}
+
+func (wr *FaultyStatusRepo) BeginSession(ctx context.Context) (WorkerSession, error) {
+	if wr.session == nil {
+		wr.session = &FaultyStatusRepoSession{}
+	}
+	return wr.session, nil
+}
+
+type FaultyStatusRepoSession struct {
+	DefaultFakeWorkerSession
+}
+
+func (f *FaultyStatusRepoSession) PublishStatus(ctx context.Context, status repository.ScrapeStatus) error {
+	f.DefaultFakeWorkerSession.PublishStatus(ctx, status)
+	return sql.ErrConnDone
+}
+
+type FaultyNackRepo struct {
+	DefaultFakeRepo
+}
+
+func (wr *FaultyNackRepo) BeginSession(ctx context.Context) (WorkerSession, error) {
+	if wr.session == nil {
+		wr.session = &FaultyNackRepoSession{}
+	}
+	return wr.session, nil
+}
+
+type FaultyNackRepoSession struct {
+	DefaultFakeWorkerSession
+}
+
+func (f *FaultyNackRepoSession) Nack(ctx context.Context, lease repository.Lease, failure repository.Failure) error {
+	f.DefaultFakeWorkerSession.Nack(ctx, lease, failure)
+	return sql.ErrConnDone
+}
+
+type FaultyMatchRepo struct {
+	DefaultFakeRepo
+}
+
+func (wr *FaultyMatchRepo) BeginSession(ctx context.Context) (WorkerSession, error) {
+	if wr.session == nil {
+		wr.session = &FaultyMatchRepoSession{}
+	}
+	return wr.session, nil
+}
+
+type FaultyMatchRepoSession struct {
+	DefaultFakeWorkerSession
+}
+
+func (f *FaultyMatchRepoSession) MatchProduct(ctx context.Context, id uuid.UUID, product domain.ProductSnapshot) error {
+	f.DefaultFakeWorkerSession.MatchProduct(ctx, id, product)
+	return sql.ErrConnDone
+}
+
 type FaultyTransactionRepo struct {
 	DefaultFakeRepo
 }
@@ -213,6 +430,15 @@ func (s *DefaultFakeScraper) Scrape(ctx context.Context, url string) (*domain.Pr
 	return NewFakeProduct(), nil
 }
 
+type FakeRemovedScraper struct {
+	DefaultFakeScraper
+}
+
+func (s *FakeRemovedScraper) Scrape(ctx context.Context, url string) (*domain.ProductRecord, error) {
+	s.DefaultFakeScraper.Scrape(ctx, url)
+	return nil, errors.ErrProductRemoved
+}
+
 type FakeFaultyScraper struct {
 	DefaultFakeScraper
 }
@@ -0,0 +1,72 @@
+/*
+uniwish.com/interal/worker/fx
+
+keeps the exchange rates table up to date from an ECB format feed
+*/
+package worker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/currency"
+)
+
+type FXRefresher struct {
+	// Source is a file path or an http(s) url serving an ECB format feed
+	Source   string
+	Interval time.Duration
+	Repo     repository.FXRateRepository
+	Logger   *slog.Logger
+	// Open defaults to currency.OpenSource
+	Open func(context.Context, string) (io.ReadCloser, error)
+}
+
+func (f *FXRefresher) Refresh(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "FXRefresher.Refresh")
+	defer span.End()
+
+	open := f.Open
+	if open == nil {
+		open = currency.OpenSource
+	}
+
+	feed, err := open(ctx, f.Source)
+	if err != nil {
+		return err
+	}
+	defer feed.Close()
+
+	rates, err := currency.ParseECB(feed)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Repo.SaveRates(ctx, rates); err != nil {
+		return err
+	}
+
+	f.Logger.Info("exchange rates refreshed", "date", rates.Date.Format(time.DateOnly), "currencies", len(rates.Rates))
+	return nil
+}
+
+func (f *FXRefresher) Run(ctx context.Context) {
+	// refreshes right away, then every Interval, a failed refresh keeps serving the previous rates
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Refresh(ctx); err != nil {
+			f.Logger.Error("exchange rates refresh failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+uniwish.com/interal/worker/fx_test
+
+tests for the exchange rates refresher
+*/
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"uniwish.com/internal/currency"
+)
+
+type FakeFXRateRepo struct {
+	saved *currency.Rates
+}
+
+func (r *FakeFXRateRepo) SaveRates(_ context.Context, rates *currency.Rates) error {
+	r.saved = rates
+	return nil
+}
+
+func (r *FakeFXRateRepo) LatestRates(_ context.Context) (*currency.Rates, error) {
+	return r.saved, nil
+}
+
+func TestFXRefresher_Refresh(t *testing.T) {
+	tests := []struct {
+		name          string
+		feed          string
+		openErr       error
+		expectedError error
+		expectedSaved bool
+	}{
+		{
+			name:          "loaded",
+			feed:          `<Envelope><Cube><Cube time="2026-10-16"><Cube currency="USD" rate="1.08"/></Cube></Cube></Envelope>`,
+			expectedSaved: true,
+		},
+		{
+			name:          "malformed",
+			feed:          `<Envelope></Envelope>`,
+			expectedError: currency.ErrMalformedFeed,
+		},
+		{
+			name:          "unreachable",
+			openErr:       io.ErrUnexpectedEOF,
+			expectedError: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &FakeFXRateRepo{}
+			refresher := &FXRefresher{
+				Source: "rates.xml",
+				Repo:   repo,
+				Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				Open: func(context.Context, string) (io.ReadCloser, error) {
+					if tt.openErr != nil {
+						return nil, tt.openErr
+					}
+					return io.NopCloser(strings.NewReader(tt.feed)), nil
+				},
+			}
+
+			err := refresher.Refresh(context.Background())
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+			if (repo.saved != nil) != tt.expectedSaved {
+				t.Fatalf("expected saved %v, received %+v", tt.expectedSaved, repo.saved)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+/*
+uniwish.com/interal/worker/image
+
+mirrors product images into the blob store with a thumbnail and perceptual hash
+*/
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+
+	"uniwish.com/internal/blob"
+	"uniwish.com/internal/domain"
+	"uniwish.com/internal/imaging"
+)
+
+// DefaultMaxImageBytes caps downloads, product images are rarely above a few megabytes
+const DefaultMaxImageBytes = 10 << 20
+
+var ErrImageTooLarge = errors.New("image too large")
+
+type ImageMirror struct {
+	Store         blob.Store
+	Client        *http.Client
+	MaxBytes      int64
+	ThumbnailSize int
+}
+
+func NewImageMirror(store blob.Store, timeout time.Duration) *ImageMirror {
+	return &ImageMirror{
+		Store:         store,
+		Client:        &http.Client{Timeout: timeout},
+		MaxBytes:      DefaultMaxImageBytes,
+		ThumbnailSize: imaging.DefaultThumbnailSize,
+	}
+}
+
+// Mirror downloads imageURL and stores it under a key derived from its content, so unchanged images are stored once
+func (m *ImageMirror) Mirror(ctx context.Context, imageURL string) (*domain.ProductImage, error) {
+	ctx, span := tracer.Start(ctx, "ImageMirror.Mirror")
+	defer span.End()
+
+	data, err := m.download(ctx, imageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	img, format, err := imaging.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:])
+	contentType := "image/" + format
+	result := &domain.ProductImage{
+		SourceURL:    imageURL,
+		Key:          "originals/" + name + "." + format,
+		ThumbnailKey: "thumbnails/" + name + ".jpeg",
+		ContentType:  contentType,
+		Width:        img.Bounds().Dx(),
+		Height:       img.Bounds().Dy(),
+		PHash:        imaging.DHash(img),
+	}
+
+	if err := m.put(ctx, result.Key, data, contentType); err != nil {
+		return nil, err
+	}
+	if err := m.putThumbnail(ctx, result.ThumbnailKey, img); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (m *ImageMirror) download(ctx context.Context, imageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("image request error: %w", err)
+	}
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("image request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image responded %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, m.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("image read error: %w", err)
+	}
+	if int64(len(data)) > m.MaxBytes {
+		return nil, fmt.Errorf("%w: over %d bytes", ErrImageTooLarge, m.MaxBytes)
+	}
+	return data, nil
+}
+
+func (m *ImageMirror) put(ctx context.Context, key string, data []byte, contentType string) error {
+	exists, err := m.Store.Exists(ctx, key)
+	if err != nil || exists {
+		return err
+	}
+	return m.Store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+func (m *ImageMirror) putThumbnail(ctx context.Context, key string, img image.Image) error {
+	exists, err := m.Store.Exists(ctx, key)
+	if err != nil || exists {
+		return err
+	}
+	data, err := imaging.EncodeJPEG(imaging.Thumbnail(img, m.ThumbnailSize))
+	if err != nil {
+		return fmt.Errorf("thumbnail encode error: %w", err)
+	}
+	return m.Store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg")
+}
@@ -0,0 +1,170 @@
+/*
+uniwish.com/interal/worker/image_test
+
+tests for mirroring product images
+*/
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"uniwish.com/internal/blob"
+	"uniwish.com/internal/imaging"
+)
+
+func fakePNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png encode failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// imageTransport answers every request with body, standing in for a store CDN
+type imageTransport struct {
+	status int
+	body   []byte
+}
+
+func (tr *imageTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(tr.status)
+	rec.Write(tr.body)
+	return rec.Result(), nil
+}
+
+func newTestImageMirror(t *testing.T, status int, body []byte) (*ImageMirror, blob.Store) {
+	t.Helper()
+	store, err := blob.NewFilesystemStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	mirror := NewImageMirror(store, time.Second)
+	mirror.Client.Transport = &imageTransport{status: status, body: body}
+	return mirror, store
+}
+
+func TestImageMirror_Mirror(t *testing.T) {
+	data := fakePNG(t, 800, 400)
+	mirror, store := newTestImageMirror(t, http.StatusOK, data)
+	ctx := context.Background()
+
+	result, err := mirror.Mirror(ctx, "http://store.com/img.png")
+	if err != nil {
+		t.Fatalf("mirror failed: %v", err)
+	}
+
+	if result.Width != 800 || result.Height != 400 || result.ContentType != "image/png" || result.PHash == 0 {
+		t.Fatalf("unexpected image %+v", result)
+	}
+
+	original, _, err := store.Get(ctx, result.Key)
+	if err != nil {
+		t.Fatalf("original not stored: %v", err)
+	}
+	defer original.Close()
+	if stored, _ := io.ReadAll(original); !bytes.Equal(stored, data) {
+		t.Fatal("expected the original bytes to be stored")
+	}
+
+	thumbnail, _, err := store.Get(ctx, result.ThumbnailKey)
+	if err != nil {
+		t.Fatalf("thumbnail not stored: %v", err)
+	}
+	defer thumbnail.Close()
+	thumbnailData, _ := io.ReadAll(thumbnail)
+	img, _, err := imaging.Decode(thumbnailData)
+	if err != nil || img.Bounds().Dx() != imaging.DefaultThumbnailSize {
+		t.Fatalf("expected a %dpx thumbnail, received %v %v", imaging.DefaultThumbnailSize, img.Bounds(), err)
+	}
+
+	// content addressed, the same image mirrors to the same keys
+	again, err := mirror.Mirror(ctx, "http://store.com/other-name.png")
+	if err != nil || again.Key != result.Key {
+		t.Fatalf("expected the same key, received %+v %v", again, err)
+	}
+}
+
+func TestImageMirror_MirrorFails(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          []byte
+		maxBytes      int64
+		expectedError error
+	}{
+		{name: "not_an_image", status: http.StatusOK, body: []byte("<html>"), expectedError: imaging.ErrUnsupportedImage},
+		{name: "too_large", status: http.StatusOK, body: make([]byte, 64), maxBytes: 32, expectedError: ErrImageTooLarge},
+		{name: "not_found", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mirror, _ := newTestImageMirror(t, tt.status, tt.body)
+			if tt.maxBytes > 0 {
+				mirror.MaxBytes = tt.maxBytes
+			}
+
+			_, err := mirror.Mirror(context.Background(), "http://store.com/img.png")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.expectedError != nil && !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, received %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestProcessJob_MirrorsImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		expectedCalls []string
+	}{
+		{
+			name:          "mirrored",
+			status:        http.StatusOK,
+			expectedCalls: []string{"UpsertProduct", "SaveImage", "InsertPrice", "MatchProduct", "EnqueueWebhooks", "Ack", "PublishStatus", "Commit"},
+		},
+		{
+			// a failed mirror keeps the scrape
+			name:          "mirror_failed",
+			status:        http.StatusForbidden,
+			expectedCalls: []string{"UpsertProduct", "InsertPrice", "MatchProduct", "EnqueueWebhooks", "Ack", "PublishStatus", "Commit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &DefaultFakeRepo{}
+			worker := NewWorker(repo, NewFakeScraperRegistry(&DefaultFakeScraper{}))
+			worker.Images, _ = newTestImageMirror(t, tt.status, fakePNG(t, 10, 10))
+
+			if err := worker.ProcessJob(context.Background(), NewFakeJob()); err != nil {
+				t.Fatalf("process failed: %v", err)
+			}
+			if !slices.Equal(repo.Session().Calls(), tt.expectedCalls) {
+				t.Fatalf("Expected %q, received %q", tt.expectedCalls, repo.Session().Calls())
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+/*
+uniwish.com/interal/worker/match
+
+links freshly scraped products to the same item in other stores
+*/
+package worker
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/domain"
+	"uniwish.com/internal/matching"
+)
+
+const (
+	// maxMatchCandidates bounds how many products of other stores are scored per scrape
+	maxMatchCandidates = 200
+	// nameTokens is how many of the longest name words are used to look up fuzzy candidates
+	nameTokens = 3
+)
+
+type ProductMatcher interface {
+	// MatchProduct puts the product into the match group of its best match in another store, if any
+	MatchProduct(ctx context.Context, id uuid.UUID, product domain.ProductSnapshot) error
+}
+
+type DefaultProductMatcher struct {
+	db        repository.DB
+	Threshold float64
+}
+
+func NewProductMatcher(db repository.DB) *DefaultProductMatcher {
+	return &DefaultProductMatcher{db: db, Threshold: matching.DefaultThreshold}
+}
+
+type matchCandidate struct {
+	matching.Candidate
+	groupID uuid.NullUUID
+}
+
+func (m *DefaultProductMatcher) MatchProduct(ctx context.Context, id uuid.UUID, product domain.ProductSnapshot) error {
+	ctx, span := tracer.Start(ctx, "DefaultProductMatcher.MatchProduct")
+	defer span.End()
+
+	// a product keeps the group it was first matched into, regrouping is left to operators
+	var (
+		matched   bool
+		imageHash int64
+	)
+	err := m.db.QueryRowContext(ctx,
+		`
+	SELECT
+		EXISTS (SELECT 1 FROM product_matches WHERE product_id = $1),
+		COALESCE((SELECT phash FROM product_images WHERE product_id = $1), 0)
+	`, id,
+	).Scan(&matched, &imageHash)
+	if err != nil {
+		return fmt.Errorf("product match lookup error: %w", err)
+	}
+	if matched {
+		return nil
+	}
+
+	self := matching.Candidate{
+		ID:        id.String(),
+		Store:     product.Store,
+		Brand:     product.Brand,
+		Name:      product.Name,
+		ImageURL:  product.ImageURL,
+		ImageHash: uint64(imageHash),
+		GTIN:      product.GTIN,
+		MPN:       product.MPN,
+	}
+	candidates, err := m.candidates(ctx, self)
+	if err != nil {
+		return err
+	}
+
+	plain := make([]matching.Candidate, len(candidates))
+	for i, c := range candidates {
+		plain[i] = c.Candidate
+	}
+	best, ok := matching.Best(self, plain, m.Threshold)
+	if !ok {
+		return nil
+	}
+
+	matchedIdx := slices.IndexFunc(candidates, func(c matchCandidate) bool { return c.ID == best.ID })
+	groupID := candidates[matchedIdx].groupID.UUID
+	if !candidates[matchedIdx].groupID.Valid {
+		groupID = uuid.New()
+		if _, err := m.db.ExecContext(ctx, `INSERT INTO product_match_groups (id) VALUES ($1)`, groupID); err != nil {
+			return fmt.Errorf("match group insert error: %w", err)
+		}
+		// the matched product joins with the same confidence, it is as sure a match from its side
+		if err := m.join(ctx, uuid.MustParse(best.ID), groupID, best); err != nil {
+			return err
+		}
+	}
+	return m.join(ctx, id, groupID, best)
+}
+
+func (m *DefaultProductMatcher) join(ctx context.Context, id uuid.UUID, groupID uuid.UUID, match matching.Match) error {
+	_, err := m.db.ExecContext(ctx,
+		`
+	INSERT INTO product_matches (product_id, group_id, confidence, method)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (product_id) DO NOTHING
+	`, id, groupID, match.Confidence, match.Method)
+	if err != nil {
+		return fmt.Errorf("product match insert error: %w", err)
+	}
+	return nil
+}
+
+func (m *DefaultProductMatcher) candidates(ctx context.Context, self matching.Candidate) ([]matchCandidate, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`
+	SELECT p.id, p.store, p.brand, p.name, p.image_url, COALESCE(pi.phash, 0), p.gtin, p.mpn, pm.group_id
+	FROM products p
+	LEFT JOIN product_matches pm ON pm.product_id = p.id
+	LEFT JOIN product_images pi ON pi.product_id = p.id
+	WHERE p.store <> $1
+	AND (
+		($2 <> '' AND p.gtin = $2)
+		OR ($3 <> '' AND p.mpn = $3)
+		OR lower(p.name) LIKE ANY($4)
+	)
+	LIMIT $5
+	`, self.Store, self.GTIN, self.MPN, pq.Array(namePatterns(self.Name)), maxMatchCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("match candidates error: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []matchCandidate
+	for rows.Next() {
+		var (
+			c         matchCandidate
+			imageHash int64
+		)
+		if err := rows.Scan(
+			&c.ID, &c.Store, &c.Brand, &c.Name, &c.ImageURL, &imageHash,
+			&c.GTIN, &c.MPN, &c.groupID); err != nil {
+			return nil, err
+		}
+		c.ImageHash = uint64(imageHash)
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// namePatterns turns the longest words of name into LIKE patterns, short words match too much to narrow anything
+// and are too short for the products_name_trgm_idx trigram index to serve
+func namePatterns(name string) []string {
+	tokens := slices.DeleteFunc(matching.Tokens(name), func(token string) bool {
+		return len([]rune(token)) < 3
+	})
+	slices.SortStableFunc(tokens, func(a string, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+
+	patterns := make([]string, 0, nameTokens)
+	for _, token := range tokens[:min(len(tokens), nameTokens)] {
+		patterns = append(patterns, "%"+token+"%")
+	}
+	return patterns
+}
@@ -0,0 +1,71 @@
+/*
+uniwish.com/interal/worker/match_test
+
+testing for cross store product matching
+*/
+package worker
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/domain"
+	"uniwish.com/internal/testutil"
+)
+
+func TestNamePatterns(t *testing.T) {
+	patterns := namePatterns("Wide leg high-rise jeans TRF")
+	expected := []string{"%jeans%", "%wide%", "%high%"}
+	if !slices.Equal(patterns, expected) {
+		t.Fatalf("expected %q, received %q", expected, patterns)
+	}
+}
+
+func TestProductMatcher_MatchProduct(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	writer := NewProductWriter(tx)
+	matcher := NewProductMatcher(tx)
+	ctx := context.Background()
+
+	products := []domain.ProductSnapshot{
+		{ID: uuid.New(), Store: "zara", SKU: "1", Name: "Wide leg jeans", Brand: "ZARA", GTIN: "4006381333931"},
+		{ID: uuid.New(), Store: "reseller", SKU: "a", Name: "Jeans", GTIN: "4006381333931"},
+		{ID: uuid.New(), Store: "outlet", SKU: "x", Name: "ZARA wide leg jeans"},
+		{ID: uuid.New(), Store: "boots", SKU: "b", Name: "Leather ankle boots"},
+	}
+
+	ids := make([]uuid.UUID, len(products))
+	for i, product := range products {
+		if ids[i], _, err = writer.UpsertProduct(ctx, product); err != nil {
+			t.Fatalf("upsert failed: %v", err)
+		}
+		if err := matcher.MatchProduct(ctx, ids[i], product); err != nil {
+			t.Fatalf("match failed: %v", err)
+		}
+	}
+
+	groups := make([]uuid.NullUUID, len(ids))
+	for i, id := range ids {
+		err := tx.QueryRow(`
+		SELECT group_id FROM products p LEFT JOIN product_matches pm ON pm.product_id = p.id WHERE p.id = $1
+		`, id).Scan(&groups[i])
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+	}
+
+	if !groups[0].Valid || groups[0] != groups[1] || groups[0] != groups[2] {
+		t.Fatalf("expected the jeans to share a group, received %v", groups[:3])
+	}
+	if groups[3].Valid {
+		t.Fatalf("expected the boots to stay unmatched, received %v", groups[3])
+	}
+}
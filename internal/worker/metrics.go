@@ -0,0 +1,67 @@
+/*
+uniwish.com/interal/worker/metrics
+
+prometheus instrumentation of job processing, a nil *Metrics records nothing
+*/
+package worker
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	OutcomeDone   = "done"
+	OutcomeFailed = "failed"
+	OutcomeError  = "error"
+)
+
+type Metrics struct {
+	jobs           *prometheus.CounterVec
+	scrapeDuration *prometheus.HistogramVec
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		jobs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "uniwish",
+			Subsystem: "worker",
+			Name:      "jobs_processed_total",
+			Help:      "Scrape jobs processed by outcome and, for failed jobs, error kind.",
+		}, []string{"outcome", "error_kind"}),
+		scrapeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "uniwish",
+			Subsystem: "worker",
+			Name:      "scrape_duration_seconds",
+			Help:      "Time spent fetching and parsing a product page by store.",
+			Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"store"}),
+	}
+	reg.MustRegister(m.jobs, m.scrapeDuration)
+	return m
+}
+
+func (m *Metrics) jobProcessed(err error) {
+	if m == nil {
+		return
+	}
+
+	var je JobError
+	switch {
+	case err == nil:
+		m.jobs.WithLabelValues(OutcomeDone, "").Inc()
+	case errors.As(err, &je):
+		m.jobs.WithLabelValues(OutcomeFailed, string(je.Kind)).Inc()
+	default:
+		m.jobs.WithLabelValues(OutcomeError, "").Inc()
+	}
+}
+
+func (m *Metrics) observeScrape(store string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.scrapeDuration.WithLabelValues(store).Observe(time.Since(start).Seconds())
+}
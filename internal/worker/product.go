@@ -7,17 +7,27 @@ package worker
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"sort"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/currency"
 	"uniwish.com/internal/domain"
+	"uniwish.com/internal/matching"
 )
 
 type ProductWriter interface {
-	UpsertProduct(context.Context, domain.ProductSnapshot) (uuid.UUID, error)
-	InsertPrice(context.Context, []domain.Offer) error
+	// UpsertProduct stores the snapshot, reporting how it changed the product if it was stored before
+	UpsertProduct(context.Context, domain.ProductSnapshot) (uuid.UUID, []domain.ProductEvent, error)
+	// InsertPrice stores the offers, reporting how they changed on the last scrape of their regions
+	InsertPrice(context.Context, []domain.Offer) ([]domain.ProductEvent, error)
+	SaveImage(context.Context, uuid.UUID, domain.ProductImage) error
+	RecordEvents(context.Context, []domain.ProductEvent) error
+	// DiscontinueProduct marks the product stored for url discontinued, found is false when no product has that url
+	DiscontinueProduct(ctx context.Context, url string) (found bool, events []domain.ProductEvent, err error)
 }
 
 type DefaultProductWriter struct {
@@ -28,50 +38,379 @@ func NewProductWriter(db repository.DB) ProductWriter {
 	return &DefaultProductWriter{db: db}
 }
 
-func (pr *DefaultProductWriter) UpsertProduct(ctx context.Context, product domain.ProductSnapshot) (uuid.UUID, error) {
-	_, err := pr.db.ExecContext(ctx,
+func (pr *DefaultProductWriter) UpsertProduct(ctx context.Context, product domain.ProductSnapshot) (uuid.UUID, []domain.ProductEvent, error) {
+	ctx, span := tracer.Start(ctx, "DefaultProductWriter.UpsertProduct")
+	defer span.End()
+
+	// barcodes are stored zero padded so EAN-13 and UPC-A spellings of one item compare equal
+	gtin, _ := matching.NormalizeGTIN(product.GTIN)
+
+	// the same sku scraped in another region resolves to the existing product, whose id is returned
+	// previous reads the row as it was before the upsert, all null for a new product
+	// a product scraped again is listed again, whenever it was discontinued
+	var (
+		id                   uuid.UUID
+		previousName         sql.NullString
+		previousImage        sql.NullString
+		previousDiscontinued sql.NullTime
+	)
+	err := pr.db.QueryRowContext(ctx,
 		`
+	WITH previous AS (
+		SELECT name, image_url, discontinued_at FROM products WHERE store = $2 AND store_product_id = $3
+	)
 	INSERT INTO products
-	(id, store, store_product_id, name, image_url, url)
-	VALUES ($1, $2, $3, $4, $5, $6)
+	(id, store, store_product_id, name, image_url, url, brand, gtin, mpn)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	ON CONFLICT (store, store_product_id)
 	DO UPDATE SET
 		name = EXCLUDED.name,
+		image_url = COALESCE(NULLIF(EXCLUDED.image_url, ''), products.image_url),
+		brand = COALESCE(NULLIF(EXCLUDED.brand, ''), products.brand),
+		gtin = COALESCE(NULLIF(EXCLUDED.gtin, ''), products.gtin),
+		mpn = COALESCE(NULLIF(EXCLUDED.mpn, ''), products.mpn),
+		discontinued_at = NULL,
 		updated_at = now()
-	RETURNING id
-	`, product.ID, product.Store, product.SKU, product.Name, product.ImageURL, product.URL)
+	RETURNING id, (SELECT name FROM previous), (SELECT image_url FROM previous), (SELECT discontinued_at FROM previous)
+	`, product.ID, product.Store, product.SKU, product.Name, product.ImageURL, product.URL,
+		product.Brand, gtin, product.MPN).Scan(&id, &previousName, &previousImage, &previousDiscontinued)
+
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("product upsert error: %w", err)
+	}
 
+	var previousURL sql.NullString
+	err = pr.db.QueryRowContext(ctx,
+		`
+	WITH previous AS (
+		SELECT url FROM product_regions WHERE product_id = $1 AND region = $2
+	)
+	INSERT INTO product_regions (product_id, region, url)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (product_id, region)
+	DO UPDATE SET
+		url = EXCLUDED.url,
+		updated_at = now()
+	RETURNING (SELECT url FROM previous)
+	`, id, product.Region, product.URL).Scan(&previousURL)
+
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("product region upsert error: %w", err)
+	}
+
+	var events []domain.ProductEvent
+	if previousName.Valid && previousName.String != product.Name {
+		events = append(events, domain.ProductEvent{
+			ProductID: id, Kind: domain.ProductNameChanged, Before: previousName.String, After: product.Name,
+		})
+	}
+	// pages dropping their image keep the stored one, so only a new image counts as a change
+	if previousImage.Valid && product.ImageURL != "" && previousImage.String != product.ImageURL {
+		events = append(events, domain.ProductEvent{
+			ProductID: id, Kind: domain.ProductImageChanged, Before: previousImage.String, After: product.ImageURL,
+		})
+	}
+	if previousURL.Valid && previousURL.String != product.URL {
+		events = append(events, domain.ProductEvent{
+			ProductID: id, Kind: domain.ProductURLChanged, Region: product.Region, Before: previousURL.String, After: product.URL,
+		})
+	}
+	if previousDiscontinued.Valid {
+		events = append(events, domain.ProductEvent{
+			ProductID: id, Kind: domain.ProductRelisted, Before: "discontinued", After: "listed",
+		})
+	}
+	return id, events, nil
+}
+
+func (pr *DefaultProductWriter) DiscontinueProduct(ctx context.Context, url string) (bool, []domain.ProductEvent, error) {
+	ctx, span := tracer.Start(ctx, "DefaultProductWriter.DiscontinueProduct")
+	defer span.End()
+
+	// a product keeps the time it was first found gone, later scrapes of the dead page only confirm it
+	rows, err := pr.db.QueryContext(ctx,
+		`
+	WITH matched AS (
+		SELECT product_id AS id FROM product_regions WHERE url = $1
+		UNION
+		SELECT id FROM products WHERE url = $1
+	), discontinued AS (
+		UPDATE products SET discontinued_at = now(), updated_at = now()
+		WHERE id IN (SELECT id FROM matched) AND discontinued_at IS NULL
+		RETURNING id
+	)
+	SELECT matched.id, discontinued.id IS NOT NULL
+	FROM matched LEFT JOIN discontinued ON discontinued.id = matched.id
+	`, url)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("product upsert error: %ws", err)
+		return false, nil, fmt.Errorf("product discontinue error: %w", err)
 	}
-	return product.ID, nil
+	defer rows.Close()
+
+	found := false
+	var events []domain.ProductEvent
+	for rows.Next() {
+		var (
+			id     uuid.UUID
+			marked bool
+		)
+		if err := rows.Scan(&id, &marked); err != nil {
+			return false, nil, fmt.Errorf("product discontinue scan error: %w", err)
+		}
+		found = true
+		if marked {
+			events = append(events, domain.ProductEvent{
+				ProductID: id, Kind: domain.ProductDiscontinued, Before: "listed", After: "discontinued",
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, nil, fmt.Errorf("product discontinue scan error: %w", err)
+	}
+	return found, events, nil
 }
-func (pr *DefaultProductWriter) InsertPrice(ctx context.Context, offers []domain.Offer) error {
+
+func (pr *DefaultProductWriter) InsertPrice(ctx context.Context, offers []domain.Offer) ([]domain.ProductEvent, error) {
+	ctx, span := tracer.Start(ctx, "DefaultProductWriter.InsertPrice")
+	defer span.End()
+
 	offersCount := len(offers)
 	if offersCount == 0 {
-		return nil
+		return nil, nil
 	}
 
 	ids := make([]string, 0, offersCount)
 	productIds := make([]string, 0, offersCount)
-	prices := make([]float64, 0, offersCount)
+	prices := make([]string, 0, offersCount)
 	currencies := make([]string, 0, offersCount)
+	regions := make([]string, 0, offersCount)
+	availabilities := make([]string, 0, offersCount)
 
 	for _, o := range offers {
 		ids = append(ids, o.ID.String())
 		productIds = append(productIds, o.ProductID.String())
-		prices = append(prices, o.Price)
-		currencies = append(currencies, o.Currency)
+		// passed as exact decimals so no float rounding reaches the numeric column
+		prices = append(prices, o.Price.Decimal())
+		currencies = append(currencies, o.Price.Currency)
+		regions = append(regions, o.Region)
+		availabilities = append(availabilities, o.Availability)
 	}
+
+	previous, err := pr.lastScrape(ctx, productIds)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = pr.db.ExecContext(ctx,
+		`
+	INSERT INTO prices (id, product_id, price, currency, region, availability)
+	SELECT * FROM UNNEST($1::uuid[], $2::uuid[], $3::numeric[], $4::text[], $5::text[], $6::text[])
+	`, pq.Array(ids), pq.Array(productIds), pq.Array(prices), pq.Array(currencies), pq.Array(regions),
+		pq.Array(availabilities))
+
+	if err != nil {
+		return nil, fmt.Errorf("price insert error: %w", err)
+	}
+
+	return offerEvents(previous, offers), nil
+}
+
+// lastScrape reads the offers of the latest scrape of every region the products were scraped in
+func (pr *DefaultProductWriter) lastScrape(ctx context.Context, productIds []string) ([]domain.Offer, error) {
+	// a scrape's offers are inserted in one transaction and so share their scraped_at
+	rows, err := pr.db.QueryContext(ctx,
+		`
+	SELECT product_id, region, price::text, currency, availability
+	FROM (
+		SELECT product_id, region, price, currency, availability,
+			rank() OVER (PARTITION BY product_id, region ORDER BY scraped_at DESC) AS scrape
+		FROM prices
+		WHERE product_id = ANY($1::uuid[])
+	) latest
+	WHERE scrape = 1
+	`, pq.Array(productIds))
+	if err != nil {
+		return nil, fmt.Errorf("last scrape query error: %w", err)
+	}
+	defer rows.Close()
+
+	var offers []domain.Offer
+	for rows.Next() {
+		var (
+			o     domain.Offer
+			price string
+		)
+		if err := rows.Scan(&o.ProductID, &o.Region, &price, &o.Price.Currency, &o.Availability); err != nil {
+			return nil, fmt.Errorf("last scrape scan error: %w", err)
+		}
+		if o.Price, err = currency.ParseDecimal(price, o.Price.Currency); err != nil {
+			return nil, fmt.Errorf("last scrape price %q: %w", price, err)
+		}
+		offers = append(offers, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("last scrape scan error: %w", err)
+	}
+	return offers, nil
+}
+
+type offerKey struct {
+	productID uuid.UUID
+	region    string
+}
+
+// offerSummary is what users see of a region's offers, its cheapest price and its best availability
+type offerSummary struct {
+	price        currency.Amount
+	availability string
+}
+
+// availabilityRank orders schema.org availabilities from most to least buyable, others rank last
+var availabilityRank = map[string]int{
+	"InStock":             0,
+	"LimitedAvailability": 1,
+	"PreOrder":            2,
+	"BackOrder":           3,
+}
+
+func rankAvailability(availability string) int {
+	if rank, ok := availabilityRank[availability]; ok {
+		return rank
+	}
+	return len(availabilityRank)
+}
+
+func summarizeOffers(offers []domain.Offer) map[offerKey]offerSummary {
+	summaries := make(map[offerKey]offerSummary, len(offers))
+	for _, o := range offers {
+		key := offerKey{productID: o.ProductID, region: o.Region}
+		s, ok := summaries[key]
+		if !ok {
+			summaries[key] = offerSummary{price: o.Price, availability: o.Availability}
+			continue
+		}
+		if o.Price.Currency == s.price.Currency && o.Price.Minor < s.price.Minor {
+			s.price = o.Price
+		}
+		if rankAvailability(o.Availability) < rankAvailability(s.availability) {
+			s.availability = o.Availability
+		}
+		summaries[key] = s
+	}
+	return summaries
+}
+
+// offerEvents compares the offers with the previous scrape of their regions, first scrapes have nothing to compare to
+func offerEvents(previous, current []domain.Offer) []domain.ProductEvent {
+	before := summarizeOffers(previous)
+	after := summarizeOffers(current)
+
+	keys := make([]offerKey, 0, len(after))
+	for key := range after {
+		keys = append(keys, key)
+	}
+	// map order is random, keep events in a stable order
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].productID != keys[j].productID {
+			return keys[i].productID.String() < keys[j].productID.String()
+		}
+		return keys[i].region < keys[j].region
+	})
+
+	var events []domain.ProductEvent
+	for _, key := range keys {
+		b, ok := before[key]
+		if !ok {
+			continue
+		}
+		a := after[key]
+		if b.price != a.price {
+			events = append(events, domain.ProductEvent{
+				ProductID: key.productID, Kind: domain.ProductPriceChanged, Region: key.region,
+				Before: formatAmount(b.price), After: formatAmount(a.price),
+			})
+		}
+		// prices stored before availability was kept read empty, which is unknown rather than a change
+		if b.availability != "" && b.availability != a.availability {
+			events = append(events, domain.ProductEvent{
+				ProductID: key.productID, Kind: domain.ProductAvailabilityChanged, Region: key.region,
+				Before: b.availability, After: a.availability,
+			})
+		}
+	}
+	return events
+}
+
+func formatAmount(a currency.Amount) string {
+	return a.Decimal() + " " + a.Currency
+}
+
+func (pr *DefaultProductWriter) RecordEvents(ctx context.Context, events []domain.ProductEvent) error {
+	ctx, span := tracer.Start(ctx, "DefaultProductWriter.RecordEvents")
+	defer span.End()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(events))
+	productIds := make([]string, 0, len(events))
+	scrapeRequestIds := make([]sql.NullString, 0, len(events))
+	kinds := make([]string, 0, len(events))
+	regions := make([]string, 0, len(events))
+	befores := make([]string, 0, len(events))
+	afters := make([]string, 0, len(events))
+
+	for _, e := range events {
+		ids = append(ids, uuid.New().String())
+		productIds = append(productIds, e.ProductID.String())
+		scrapeRequestIds = append(scrapeRequestIds, sql.NullString{
+			String: e.ScrapeRequestID.String(), Valid: e.ScrapeRequestID != uuid.Nil,
+		})
+		kinds = append(kinds, string(e.Kind))
+		regions = append(regions, e.Region)
+		befores = append(befores, e.Before)
+		afters = append(afters, e.After)
+	}
+
 	_, err := pr.db.ExecContext(ctx,
 		`
-	INSERT INTO prices (id, product_id, price, currency)
-	SELECT * FROM UNNEST($1::uuid[], $2::uuid[], $3::float[], $4::text[])
-	`, pq.Array(ids), pq.Array(productIds), pq.Array(prices), pq.Array(currencies))
+	INSERT INTO product_events (id, product_id, scrape_request_id, kind, region, before, after)
+	SELECT * FROM UNNEST($1::uuid[], $2::uuid[], $3::uuid[], $4::text[], $5::text[], $6::text[], $7::text[])
+	`, pq.Array(ids), pq.Array(productIds), pq.Array(scrapeRequestIds), pq.Array(kinds), pq.Array(regions),
+		pq.Array(befores), pq.Array(afters))
 
 	if err != nil {
-		return fmt.Errorf("price insert error: %w", err)
+		return fmt.Errorf("product event insert error: %w", err)
 	}
+	return nil
+}
+
+func (pr *DefaultProductWriter) SaveImage(ctx context.Context, productID uuid.UUID, image domain.ProductImage) error {
+	ctx, span := tracer.Start(ctx, "DefaultProductWriter.SaveImage")
+	defer span.End()
+
+	// phash is stored bit for bit in a signed BIGINT
+	_, err := pr.db.ExecContext(ctx,
+		`
+	INSERT INTO product_images
+	(product_id, source_url, image_key, thumbnail_key, content_type, width, height, phash)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (product_id)
+	DO UPDATE SET
+		source_url = EXCLUDED.source_url,
+		image_key = EXCLUDED.image_key,
+		thumbnail_key = EXCLUDED.thumbnail_key,
+		content_type = EXCLUDED.content_type,
+		width = EXCLUDED.width,
+		height = EXCLUDED.height,
+		phash = EXCLUDED.phash,
+		mirrored_at = now()
+	`, productID, image.SourceURL, image.Key, image.ThumbnailKey, image.ContentType,
+		image.Width, image.Height, int64(image.PHash))
 
+	if err != nil {
+		return fmt.Errorf("product image upsert error: %w", err)
+	}
 	return nil
 }
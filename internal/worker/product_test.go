@@ -0,0 +1,280 @@
+/*
+uniwish.com/interal/worker/product_test
+
+testing for the product writer
+*/
+package worker
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/currency"
+	"uniwish.com/internal/domain"
+	"uniwish.com/internal/testutil"
+)
+
+func TestProductWriter_UpsertProductAcrossRegions(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	writer := NewProductWriter(tx)
+	ctx := context.Background()
+
+	regions := []struct {
+		region string
+		url    string
+		price  currency.Amount
+	}{
+		{region: "es", url: "https://www.zara.com/es/en/jacket-p1.html", price: currency.Amount{Minor: 2995, Currency: "EUR"}},
+		{region: "us", url: "https://www.zara.com/us/en/jacket-p1.html", price: currency.Amount{Minor: 3590, Currency: "USD"}},
+	}
+
+	var ids []uuid.UUID
+	for _, r := range regions {
+		id, _, err := writer.UpsertProduct(ctx, domain.ProductSnapshot{
+			ID: uuid.New(), Store: "zara", SKU: "1", Name: "jacket", URL: r.url, Region: r.region,
+		})
+		if err != nil {
+			t.Fatalf("upsert failed: %v", err)
+		}
+		_, err = writer.InsertPrice(ctx, []domain.Offer{{ID: uuid.New(), ProductID: id, Price: r.price, Region: r.region}})
+		if err != nil {
+			t.Fatalf("insert price failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if ids[0] != ids[1] {
+		t.Fatalf("expected both regions to share product %v, received %v", ids[0], ids[1])
+	}
+
+	var count int
+	err = tx.QueryRow(`SELECT count(*) FROM product_regions WHERE product_id = $1`, ids[0]).Scan(&count)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if count != len(regions) {
+		t.Fatalf("expected %d regions, received %d", len(regions), count)
+	}
+
+	var price string
+	err = tx.QueryRow(`SELECT price::text FROM prices WHERE product_id = $1 AND region = 'us'`, ids[0]).Scan(&price)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if price != "35.9000" {
+		t.Fatalf("expected exact price 35.9000, received %s", price)
+	}
+}
+
+func TestProductWriter_ReportsChanges(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	writer := NewProductWriter(tx)
+	ctx := context.Background()
+
+	snapshot := domain.ProductSnapshot{
+		ID: uuid.New(), Store: "zara", SKU: "1", Name: "jacket", Region: "es",
+		URL: "https://www.zara.com/es/en/jacket-p1.html", ImageURL: "https://static.zara.net/jacket.jpg",
+	}
+	id, events, err := writer.UpsertProduct(ctx, snapshot)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected a new product without events, received %+v, %v", events, err)
+	}
+	offers := []domain.Offer{
+		{ID: uuid.New(), ProductID: id, Price: currency.Amount{Minor: 2995, Currency: "EUR"}, Region: "es", Availability: "OutOfStock"},
+		{ID: uuid.New(), ProductID: id, Price: currency.Amount{Minor: 3995, Currency: "EUR"}, Region: "es", Availability: "OutOfStock"},
+	}
+	if events, err = writer.InsertPrice(ctx, offers); err != nil || len(events) != 0 {
+		t.Fatalf("expected a first scrape without events, received %+v, %v", events, err)
+	}
+	// a transaction's prices share now(), so the first scrape is moved back to tell the two apart
+	if _, err = tx.Exec(`UPDATE prices SET scraped_at = now() - interval '1 day' WHERE product_id = $1`, id); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	snapshot.Name = "cropped jacket"
+	snapshot.ImageURL = ""
+	snapshot.URL = "https://www.zara.com/es/en/cropped-jacket-p1.html"
+	_, productEvents, err := writer.UpsertProduct(ctx, snapshot)
+	if err != nil {
+		t.Fatalf("upsert failed: %v", err)
+	}
+	offers = []domain.Offer{
+		{ID: uuid.New(), ProductID: id, Price: currency.Amount{Minor: 2495, Currency: "EUR"}, Region: "es", Availability: "InStock"},
+		{ID: uuid.New(), ProductID: id, Price: currency.Amount{Minor: 3995, Currency: "EUR"}, Region: "es", Availability: "OutOfStock"},
+	}
+	offerEvents, err := writer.InsertPrice(ctx, offers)
+	if err != nil {
+		t.Fatalf("insert price failed: %v", err)
+	}
+
+	expected := []domain.ProductEvent{
+		{ProductID: id, Kind: domain.ProductNameChanged, Before: "jacket", After: "cropped jacket"},
+		{ProductID: id, Kind: domain.ProductURLChanged, Region: "es",
+			Before: "https://www.zara.com/es/en/jacket-p1.html", After: "https://www.zara.com/es/en/cropped-jacket-p1.html"},
+		{ProductID: id, Kind: domain.ProductPriceChanged, Region: "es", Before: "29.95 EUR", After: "24.95 EUR"},
+		{ProductID: id, Kind: domain.ProductAvailabilityChanged, Region: "es", Before: "OutOfStock", After: "InStock"},
+	}
+	received := append(productEvents, offerEvents...)
+	if !slices.Equal(received, expected) {
+		t.Fatalf("expected %+v, received %+v", expected, received)
+	}
+
+	var imageURL string
+	if err = tx.QueryRow(`SELECT image_url FROM products WHERE id = $1`, id).Scan(&imageURL); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if imageURL != "https://static.zara.net/jacket.jpg" {
+		t.Fatalf("expected the image to be kept, received %q", imageURL)
+	}
+
+	jobID := uuid.New()
+	for i := range received {
+		received[i].ScrapeRequestID = jobID
+	}
+	if err = writer.RecordEvents(ctx, received); err != nil {
+		t.Fatalf("record events failed: %v", err)
+	}
+	var count int
+	err = tx.QueryRow(`SELECT count(*) FROM product_events WHERE product_id = $1 AND scrape_request_id = $2`, id, jobID).Scan(&count)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if count != len(expected) {
+		t.Fatalf("expected %d events, received %d", len(expected), count)
+	}
+}
+
+func TestOfferEvents(t *testing.T) {
+	productID := uuid.New()
+	eur := func(minor int64) currency.Amount { return currency.Amount{Minor: minor, Currency: "EUR"} }
+
+	tests := []struct {
+		name     string
+		previous []domain.Offer
+		current  []domain.Offer
+		expected []domain.ProductEvent
+	}{
+		{
+			name:    "first_scrape",
+			current: []domain.Offer{{ProductID: productID, Price: eur(100), Availability: "InStock"}},
+		},
+		{
+			name:     "unchanged",
+			previous: []domain.Offer{{ProductID: productID, Price: eur(100), Availability: "InStock"}},
+			current:  []domain.Offer{{ProductID: productID, Price: eur(100), Availability: "InStock"}},
+		},
+		{
+			name: "cheapest_and_best_offer",
+			previous: []domain.Offer{
+				{ProductID: productID, Price: eur(200), Availability: "OutOfStock"},
+				{ProductID: productID, Price: eur(100), Availability: "PreOrder"},
+			},
+			current: []domain.Offer{
+				{ProductID: productID, Price: eur(150), Availability: "InStock"},
+				{ProductID: productID, Price: eur(300), Availability: "OutOfStock"},
+			},
+			expected: []domain.ProductEvent{
+				{ProductID: productID, Kind: domain.ProductPriceChanged, Before: "1.00 EUR", After: "1.50 EUR"},
+				{ProductID: productID, Kind: domain.ProductAvailabilityChanged, Before: "PreOrder", After: "InStock"},
+			},
+		},
+		{
+			name:     "unknown_availability",
+			previous: []domain.Offer{{ProductID: productID, Price: eur(100)}},
+			current:  []domain.Offer{{ProductID: productID, Price: eur(100), Availability: "InStock"}},
+		},
+		{
+			name:     "other_region",
+			previous: []domain.Offer{{ProductID: productID, Price: eur(100), Region: "es"}},
+			current:  []domain.Offer{{ProductID: productID, Price: eur(200), Region: "fr"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := offerEvents(tt.previous, tt.current)
+			if !slices.Equal(events, tt.expected) {
+				t.Fatalf("expected %+v, received %+v", tt.expected, events)
+			}
+		})
+	}
+}
+
+func TestProductWriter_DiscontinueProduct(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+
+	writer := NewProductWriter(tx)
+	wishlist := NewWishlistWriter(tx)
+	ctx := context.Background()
+
+	snapshot := domain.ProductSnapshot{
+		ID: uuid.New(), Store: "zara", SKU: "1", Name: "jacket", Region: "es",
+		URL: "https://www.zara.com/es/en/jacket-p1.html",
+	}
+	id, _, err := writer.UpsertProduct(ctx, snapshot)
+	if err != nil {
+		t.Fatalf("upsert failed: %v", err)
+	}
+	for _, owner := range []string{"user-1", "user-2", "user-1"} {
+		if err = wishlist.AddToWishlist(ctx, owner, id); err != nil {
+			t.Fatalf("wishlist failed: %v", err)
+		}
+	}
+
+	found, events, err := writer.DiscontinueProduct(ctx, "https://www.zara.com/es/en/unknown-p2.html")
+	if err != nil || found || len(events) != 0 {
+		t.Fatalf("expected an unknown url to match nothing, received %v %+v %v", found, events, err)
+	}
+
+	found, events, err = writer.DiscontinueProduct(ctx, snapshot.URL)
+	if err != nil || !found {
+		t.Fatalf("expected the product to be found, received %v %v", found, err)
+	}
+	if len(events) != 1 || events[0].ProductID != id || events[0].Kind != domain.ProductDiscontinued {
+		t.Fatalf("expected a discontinued event, received %+v", events)
+	}
+	notified, err := wishlist.NotifyWishlisters(ctx, id, domain.NotificationProductDiscontinued)
+	if err != nil || notified != 2 {
+		t.Fatalf("expected both wishlisters notified, received %d %v", notified, err)
+	}
+
+	// the dead page scraped again keeps the first timestamp and reports nothing new
+	found, events, err = writer.DiscontinueProduct(ctx, snapshot.URL)
+	if err != nil || !found || len(events) != 0 {
+		t.Fatalf("expected no new events, received %v %+v %v", found, events, err)
+	}
+
+	_, events, err = writer.UpsertProduct(ctx, snapshot)
+	if err != nil {
+		t.Fatalf("upsert failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != domain.ProductRelisted {
+		t.Fatalf("expected the product relisted, received %+v", events)
+	}
+	var discontinued bool
+	if err = tx.QueryRow(`SELECT discontinued_at IS NOT NULL FROM products WHERE id = $1`, id).Scan(&discontinued); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if discontinued {
+		t.Fatal("expected the relisted product to no longer be discontinued")
+	}
+}
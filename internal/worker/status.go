@@ -0,0 +1,72 @@
+/*
+uniwish.com/interal/worker/status
+
+publishes a job's status transitions through postgres NOTIFY for the api to stream to its owner
+
+NOTIFY is delivered on commit, so listeners never hear of a transition that was rolled back.
+publishing is best-effort, a listener missing an update reads the request's status again
+*/
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/logging"
+)
+
+type StatusPublisher interface {
+	PublishStatus(ctx context.Context, status repository.ScrapeStatus) error
+}
+
+type DefaultStatusPublisher struct {
+	db repository.DB
+}
+
+func NewStatusPublisher(db repository.DB) StatusPublisher {
+	return &DefaultStatusPublisher{db: db}
+}
+
+func (sp *DefaultStatusPublisher) PublishStatus(ctx context.Context, status repository.ScrapeStatus) error {
+	ctx, span := tracer.Start(ctx, "DefaultStatusPublisher.PublishStatus")
+	defer span.End()
+
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("status payload error: %w", err)
+	}
+	if _, err = sp.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, repository.ScrapeStatusChannel, string(payload)); err != nil {
+		return fmt.Errorf("status notify error: %w", err)
+	}
+	return nil
+}
+
+// publishStatus notifies status within session, a failed notify is rolled back to its savepoint and logged
+// so it never decides the job's outcome
+func publishStatus(ctx context.Context, session WorkerSession, status repository.ScrapeStatus) {
+	err := session.Attempt(ctx, "publish_status", func() error {
+		return session.PublishStatus(ctx, status)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Warn("status not published", "scrape_request_id", status.ScrapeRequestID, "status", status.Status, "err", err)
+	}
+}
+
+// jobStatus is job's transition to status, productID is the product a done job scraped when known
+func jobStatus(job *repository.ScrapeRequest, status string, errorKind JobErrorKind, productID uuid.UUID) repository.ScrapeStatus {
+	s := repository.ScrapeStatus{
+		ScrapeRequestID: job.ID,
+		OwnerID:         job.OwnerID,
+		Status:          status,
+		ErrorKind:       string(errorKind),
+		OccurredAt:      time.Now().UTC(),
+	}
+	if productID != uuid.Nil {
+		s.ProductID = &productID
+	}
+	return s
+}
@@ -0,0 +1,75 @@
+/*
+uniwish.com/interal/worker/status_test
+
+tests for publishing job status transitions
+*/
+package worker
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/testutil"
+)
+
+func TestStatusPublisher_DeliveredOnCommit(t *testing.T) {
+	testutil.RequireIntegration(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	received := make(chan *repository.ScrapeStatus, 1)
+	listener := repository.NewPostgresScrapeStatusListener(os.Getenv("DATABASE_URL"))
+	go listener.Listen(ctx, func(status *repository.ScrapeStatus) {
+		if status == nil {
+			return
+		}
+		select {
+		case received <- status:
+		default:
+		}
+	})
+
+	job := NewFakeJob()
+	job.OwnerID = "user-1"
+	publish := func(commit bool) {
+		tx, err := testDB.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("transaction failed %v", err)
+		}
+		status := jobStatus(job, repository.StatusFailed, JobScrapeFailed, uuid.Nil)
+		if commit {
+			status.Status = repository.StatusDone
+		}
+		if err := NewStatusPublisher(tx).PublishStatus(ctx, status); err != nil {
+			tx.Rollback()
+			t.Fatalf("publish failed: %v", err)
+		}
+		if commit {
+			tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+	}
+
+	// the listener may not be up for the first ones, so keep publishing until one arrives
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case status := <-received:
+			if status.ScrapeRequestID != job.ID || status.Status != repository.StatusDone || status.OwnerID != "user-1" {
+				t.Fatalf("expected only the committed status, received %+v", status)
+			}
+			return
+		case <-ticker.C:
+			publish(false)
+			publish(true)
+		case <-ctx.Done():
+			t.Fatal("status never received")
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -20,14 +21,60 @@ type WorkerSupervisor struct {
 	Worker           JobWorker
 	PollInterval     time.Duration
 	FailureTolerance int
-	Sleep            func(time.Duration)
-	OnFatal          func()
-	Logger           *slog.Logger
+	// Concurrency is how many loops run the worker at once, below 1 runs a single loop
+	Concurrency int
+	Sleep       func(time.Duration)
+	OnFatal     func()
+	Logger      *slog.Logger
+	// lastSuccess holds a timestamp per loop, swapped in whole by Run
+	lastSuccess atomic.Pointer[[]atomic.Int64]
+}
+
+func (ws *WorkerSupervisor) LastSuccess() time.Time {
+	// when the stalest loop last completed an iteration without a worker error, zero before Run
+	// one stuck loop is enough to hold on to a job past its lease
+	loops := ws.lastSuccess.Load()
+	if loops == nil {
+		return time.Unix(0, 0)
+	}
+	oldest := int64(0)
+	for i := range *loops {
+		if last := (*loops)[i].Load(); i == 0 || last < oldest {
+			oldest = last
+		}
+	}
+	return time.Unix(0, oldest)
 }
 
 func (ws *WorkerSupervisor) Run(ctx context.Context) {
+	// the loops share the failure counter, the database they fail on is shared too
 	var failures atomic.Int32
+	var fatal sync.Once
+
+	loops := make([]atomic.Int64, max(ws.Concurrency, 1))
+	for i := range loops {
+		loops[i].Store(time.Now().UnixNano())
+	}
+	ws.lastSuccess.Store(&loops)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range loops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ws.loop(ctx, &failures, &loops[i], func() {
+				fatal.Do(ws.OnFatal)
+				cancel()
+			})
+		}()
+	}
+	wg.Wait()
+}
 
+func (ws *WorkerSupervisor) loop(ctx context.Context, failures *atomic.Int32, lastSuccess *atomic.Int64, onFatal func()) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -38,20 +85,24 @@ func (ws *WorkerSupervisor) Run(ctx context.Context) {
 			switch {
 			case err == nil:
 				failures.Store(0)
+				lastSuccess.Store(time.Now().UnixNano())
 			case errors.Is(err, ErrNoWork):
 				// No work is not a failure nor a success
 				// so lets not reset the failure counter, but also not increment it
+				// the loop itself is healthy though
+				lastSuccess.Store(time.Now().UnixNano())
 			case errors.As(err, &je):
 				// handling dead letter is proper health
 				// resetting the failure counter because processing problems could be input issues
 				ws.Logger.Error("job error", "error", err)
 				failures.Store(0)
+				lastSuccess.Store(time.Now().UnixNano())
 			default:
 				ws.Logger.Error("worker error", "error", err)
 
 				if failures.Add(1) >= int32(ws.FailureTolerance) {
 					ws.Logger.Error("worker tolerance exceeded", "failures", failures.Load())
-					ws.OnFatal()
+					onFatal()
 					return
 				}
 			}
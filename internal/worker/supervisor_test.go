@@ -10,6 +10,7 @@ import (
 	"database/sql"
 	"io"
 	"log/slog"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -135,3 +136,72 @@ func TestWorkerSupervisor_StopsOnCancel(t *testing.T) {
 		t.Fatal("supervisor did not end on context cancel")
 	}
 }
+
+func TestWorkerSupervisor_TracksLastSuccess(t *testing.T) {
+	ws := WorkerSupervisor{
+		Worker:           &FakeWorker{Results: []error{ErrNoWork, sql.ErrConnDone}},
+		PollInterval:     0,
+		FailureTolerance: 1,
+		Sleep:            func(time.Duration) {},
+		OnFatal:          func() {},
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	before := time.Now()
+	ws.Run(context.Background())
+
+	if ws.LastSuccess().Before(before) {
+		t.Fatalf("expected last success after %v, received %v", before, ws.LastSuccess())
+	}
+}
+
+type CountingWorker struct {
+	calls   atomic.Int32
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (w *CountingWorker) RunOnce(ctx context.Context) error {
+	running := w.running.Add(1)
+	defer w.running.Add(-1)
+	for {
+		peak := w.peak.Load()
+		if running <= peak || w.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+
+	// hold the job long enough for the other loops to claim theirs
+	time.Sleep(5 * time.Millisecond)
+	if w.calls.Add(1) > 12 {
+		return sql.ErrConnDone
+	}
+	return nil
+}
+
+func TestWorkerSupervisor_Concurrency(t *testing.T) {
+	var fatals atomic.Int32
+	w := &CountingWorker{}
+	ws := WorkerSupervisor{
+		Worker:           w,
+		PollInterval:     0,
+		FailureTolerance: 2,
+		Concurrency:      3,
+		Sleep:            func(time.Duration) {},
+		OnFatal:          func() { fatals.Add(1) },
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	before := time.Now()
+	ws.Run(context.Background())
+
+	if peak := w.peak.Load(); peak != 3 {
+		t.Fatalf("expected 3 jobs at once, received %d", peak)
+	}
+	if fatals.Load() != 1 {
+		t.Fatalf("expected a single fatal call, received %d", fatals.Load())
+	}
+	if ws.LastSuccess().Before(before) {
+		t.Fatalf("expected every loop to have succeeded after %v, received %v", before, ws.LastSuccess())
+	}
+}
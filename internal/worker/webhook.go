@@ -0,0 +1,168 @@
+/*
+uniwish.com/interal/worker/webhook
+
+queues the webhook deliveries a job's outcome triggers, in the job's transaction so none is sent for a rolled back job
+
+every event is sent as {"id", "type", "occurred_at", "data"}, see WebhookDispatcher for how
+*/
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/domain"
+)
+
+type WebhookWriter interface {
+	// EnqueueWebhooks queues a delivery of every event to each subscription of its type, returning how many were
+	EnqueueWebhooks(ctx context.Context, events []domain.WebhookEvent) (int64, error)
+}
+
+type DefaultWebhookWriter struct {
+	db repository.DB
+}
+
+func NewWebhookWriter(db repository.DB) WebhookWriter {
+	return &DefaultWebhookWriter{db: db}
+}
+
+type webhookBody struct {
+	ID         uuid.UUID               `json:"id"`
+	Type       domain.WebhookEventType `json:"type"`
+	OccurredAt time.Time               `json:"occurred_at"`
+	Data       any                     `json:"data"`
+}
+
+func (ww *DefaultWebhookWriter) EnqueueWebhooks(ctx context.Context, events []domain.WebhookEvent) (int64, error) {
+	ctx, span := tracer.Start(ctx, "DefaultWebhookWriter.EnqueueWebhooks")
+	defer span.End()
+
+	ids := make([]string, len(events))
+	types := make([]string, len(events))
+	payloads := make([]string, len(events))
+	for i, e := range events {
+		body, err := json.Marshal(webhookBody{ID: e.ID, Type: e.Type, OccurredAt: e.OccurredAt, Data: e.Data})
+		if err != nil {
+			return 0, fmt.Errorf("webhook payload error: %w", err)
+		}
+		ids[i] = e.ID.String()
+		types[i] = string(e.Type)
+		payloads[i] = string(body)
+	}
+
+	result, err := ww.db.ExecContext(ctx,
+		`
+	INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload)
+	SELECT gen_random_uuid(), s.id, e.id, e.type, e.payload::jsonb
+	FROM unnest($1::uuid[], $2::text[], $3::text[]) AS e(id, type, payload)
+	JOIN webhook_subscriptions s ON e.type = ANY(s.event_types)
+	`, pq.Array(ids), pq.Array(types), pq.Array(payloads))
+
+	if err != nil {
+		return 0, fmt.Errorf("webhook delivery insert error: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+type webhookOffer struct {
+	Price        float64 `json:"price"`
+	Currency     string  `json:"currency"`
+	Region       string  `json:"region,omitempty"`
+	Availability string  `json:"availability,omitempty"`
+}
+
+// webhookProductData is the data of product.created, carrying the first prices scraped
+type webhookProductData struct {
+	ProductID       uuid.UUID      `json:"product_id"`
+	ScrapeRequestID uuid.UUID      `json:"scrape_request_id"`
+	Store           string         `json:"store"`
+	Name            string         `json:"name"`
+	URL             string         `json:"url"`
+	Region          string         `json:"region,omitempty"`
+	Offers          []webhookOffer `json:"offers"`
+}
+
+// webhookChangeData is the data of events mirroring a product event, which Change names
+type webhookChangeData struct {
+	ProductID       uuid.UUID               `json:"product_id"`
+	ScrapeRequestID uuid.UUID               `json:"scrape_request_id"`
+	Change          domain.ProductEventKind `json:"change"`
+	Region          string                  `json:"region,omitempty"`
+	Before          string                  `json:"before"`
+	After           string                  `json:"after"`
+}
+
+type webhookScrapeFailedData struct {
+	ScrapeRequestID uuid.UUID    `json:"scrape_request_id"`
+	URL             string       `json:"url"`
+	Store           string       `json:"store"`
+	ErrorKind       JobErrorKind `json:"error_kind"`
+	Error           string       `json:"error"`
+}
+
+// webhookTypes maps the product events integrators are told about to their webhook event type
+var webhookTypes = map[domain.ProductEventKind]domain.WebhookEventType{
+	domain.ProductNameChanged:         domain.WebhookProductUpdated,
+	domain.ProductImageChanged:        domain.WebhookProductUpdated,
+	domain.ProductURLChanged:          domain.WebhookProductUpdated,
+	domain.ProductPriceChanged:        domain.WebhookPriceChanged,
+	domain.ProductAvailabilityChanged: domain.WebhookAvailabilityChanged,
+	domain.ProductDiscontinued:        domain.WebhookProductDiscontinued,
+	domain.ProductRelisted:            domain.WebhookProductRelisted,
+}
+
+func newWebhookEvent(eventType domain.WebhookEventType, data any) domain.WebhookEvent {
+	return domain.WebhookEvent{ID: uuid.New(), Type: eventType, OccurredAt: time.Now().UTC(), Data: data}
+}
+
+func productCreatedWebhook(productID uuid.UUID, scrapeRequestID uuid.UUID, record *domain.ProductRecord) domain.WebhookEvent {
+	product := record.Product
+	offers := make([]webhookOffer, len(*record.Offers))
+	for i, o := range *record.Offers {
+		offers[i] = webhookOffer{Price: o.Price.Float(), Currency: o.Price.Currency, Region: o.Region, Availability: o.Availability}
+	}
+	return newWebhookEvent(domain.WebhookProductCreated, webhookProductData{
+		ProductID:       productID,
+		ScrapeRequestID: scrapeRequestID,
+		Store:           product.Store,
+		Name:            product.Name,
+		URL:             product.URL,
+		Region:          product.Region,
+		Offers:          offers,
+	})
+}
+
+func productEventWebhooks(events []domain.ProductEvent) []domain.WebhookEvent {
+	var webhooks []domain.WebhookEvent
+	for _, e := range events {
+		eventType, ok := webhookTypes[e.Kind]
+		if !ok {
+			continue
+		}
+		webhooks = append(webhooks, newWebhookEvent(eventType, webhookChangeData{
+			ProductID:       e.ProductID,
+			ScrapeRequestID: e.ScrapeRequestID,
+			Change:          e.Kind,
+			Region:          e.Region,
+			Before:          e.Before,
+			After:           e.After,
+		}))
+	}
+	return webhooks
+}
+
+func scrapeFailedWebhook(job *repository.ScrapeRequest, kind JobErrorKind, err error) domain.WebhookEvent {
+	return newWebhookEvent(domain.WebhookScrapeFailed, webhookScrapeFailedData{
+		ScrapeRequestID: job.ID,
+		URL:             job.URL,
+		Store:           job.Store,
+		ErrorKind:       kind,
+		Error:           err.Error(),
+	})
+}
@@ -0,0 +1,187 @@
+/*
+uniwish.com/interal/worker/webhook_dispatcher
+
+sends the queued webhook deliveries, retrying failing ones with exponential backoff until their attempts run out
+
+deliveries are POSTed as JSON with the headers
+
+	X-Uniwish-Event-ID     shared by every delivery and redelivery of the event, for receivers to deduplicate
+	X-Uniwish-Event-Type
+	X-Uniwish-Delivery-ID
+	X-Uniwish-Timestamp    unix seconds of the attempt
+	X-Uniwish-Signature    "sha256=" and the hex HMAC-SHA256 of timestamp "." body, keyed by the subscription's secret
+
+any 2xx response delivers, redirects are not followed
+*/
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"uniwish.com/internal/api/repository"
+)
+
+const (
+	DefaultWebhookBatchSize = 20
+	// MaxWebhookBackoff caps the pause between two attempts
+	MaxWebhookBackoff = 6 * time.Hour
+)
+
+type WebhookDispatcher struct {
+	Outbox repository.WebhookOutbox
+	// Client's timeout bounds every attempt
+	Client   *http.Client
+	Interval time.Duration
+	// BatchSize is how many deliveries are claimed at once, zero takes DefaultWebhookBatchSize
+	BatchSize int
+	// MaxAttempts is how many attempts a delivery gets before it fails for good
+	MaxAttempts int
+	// RetryBackoff is the pause before the first retry, doubling with every further one up to MaxWebhookBackoff
+	RetryBackoff time.Duration
+	Logger       *slog.Logger
+}
+
+func NewWebhookDispatcher(outbox repository.WebhookOutbox, timeout time.Duration, logger *slog.Logger) *WebhookDispatcher {
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &WebhookDispatcher{Outbox: outbox, Client: client, Logger: logger, MaxAttempts: 1}
+}
+
+// SignWebhook is the X-Uniwish-Signature of body sent at timestamp, receivers recompute it to authenticate deliveries
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch attempts the due deliveries of one batch, returning how many were attempted
+func (d *WebhookDispatcher) Dispatch(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "WebhookDispatcher.Dispatch")
+	defer span.End()
+
+	// deliveries are attempted one after the other, the lease outlasts the whole batch timing out
+	batch := d.batchSize()
+	lease := d.Client.Timeout*time.Duration(batch) + time.Minute
+	deliveries, err := d.Outbox.ClaimDeliveries(ctx, batch, lease)
+	if err != nil {
+		return 0, fmt.Errorf("webhook claim error: %w", err)
+	}
+
+	for i, delivery := range deliveries {
+		if err := d.Outbox.RecordAttempt(ctx, d.attempt(ctx, delivery)); err != nil {
+			return i, fmt.Errorf("webhook attempt record error: %w", err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (d *WebhookDispatcher) attempt(ctx context.Context, delivery repository.PendingWebhookDelivery) repository.WebhookAttemptResult {
+	start := time.Now()
+	statusCode, err := d.send(ctx, delivery)
+	result := repository.WebhookAttemptResult{
+		DeliveryID:    delivery.ID,
+		StatusCode:    statusCode,
+		Duration:      time.Since(start),
+		Status:        repository.WebhookDeliveryDelivered,
+		NextAttemptAt: time.Now(),
+	}
+	if err == nil {
+		return result
+	}
+
+	result.Error = err.Error()
+	attempts := delivery.Attempts + 1
+	if attempts >= d.MaxAttempts {
+		result.Status = repository.WebhookDeliveryFailed
+		d.Logger.Warn("webhook delivery failed", "delivery_id", delivery.ID, "event_type", delivery.EventType, "attempts", attempts, "error", err)
+		return result
+	}
+	result.Status = repository.WebhookDeliveryPending
+	result.NextAttemptAt = result.NextAttemptAt.Add(d.backoff(attempts))
+	return result
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, delivery repository.PendingWebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "uniwish-webhooks/1.0")
+	req.Header.Set("X-Uniwish-Event-ID", delivery.EventID.String())
+	req.Header.Set("X-Uniwish-Event-Type", delivery.EventType)
+	req.Header.Set("X-Uniwish-Delivery-ID", delivery.ID.String())
+	req.Header.Set("X-Uniwish-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Uniwish-Signature", SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drained so the connection is reused, receivers have nothing to tell us past the status
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the pause after the given number of failed attempts
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	backoff := d.RetryBackoff
+	for i := 1; i < attempts && backoff < MaxWebhookBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, MaxWebhookBackoff)
+}
+
+func (d *WebhookDispatcher) batchSize() int {
+	if d.BatchSize > 0 {
+		return d.BatchSize
+	}
+	return DefaultWebhookBatchSize
+}
+
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	// drains the due deliveries, then polls again every Interval
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			attempted, err := d.Dispatch(ctx)
+			if err != nil {
+				d.Logger.Error("webhook dispatch failed", "error", err)
+				break
+			}
+			if attempted < d.batchSize() {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
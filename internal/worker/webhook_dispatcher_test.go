@@ -0,0 +1,137 @@
+/*
+uniwish.com/interal/worker/webhook_dispatcher_test
+
+tests for sending webhook deliveries and retrying them
+*/
+package worker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/repository"
+)
+
+type FakeWebhookOutbox struct {
+	due      []repository.PendingWebhookDelivery
+	recorded []repository.WebhookAttemptResult
+}
+
+func (o *FakeWebhookOutbox) ClaimDeliveries(_ context.Context, limit int, _ time.Duration) ([]repository.PendingWebhookDelivery, error) {
+	claimed := o.due[:min(limit, len(o.due))]
+	o.due = o.due[len(claimed):]
+	return claimed, nil
+}
+
+func (o *FakeWebhookOutbox) RecordAttempt(_ context.Context, result repository.WebhookAttemptResult) error {
+	o.recorded = append(o.recorded, result)
+	return nil
+}
+
+func TestWebhookDispatcher_Dispatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		attempts       int
+		expectedStatus string
+		expectedCode   int
+		expectedRetry  time.Duration
+	}{
+		{name: "delivered", status: http.StatusNoContent, expectedStatus: repository.WebhookDeliveryDelivered, expectedCode: 204},
+		{name: "retried", status: http.StatusInternalServerError, attempts: 1, expectedStatus: repository.WebhookDeliveryPending, expectedCode: 500, expectedRetry: 2 * time.Minute},
+		{name: "redirect_not_followed", status: http.StatusFound, expectedStatus: repository.WebhookDeliveryPending, expectedCode: 302, expectedRetry: time.Minute},
+		{name: "attempts_exhausted", status: http.StatusBadGateway, attempts: 2, expectedStatus: repository.WebhookDeliveryFailed, expectedCode: 502},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := []byte(`{"type":"price.changed"}`)
+			delivery := repository.PendingWebhookDelivery{
+				ID: uuid.New(), EventID: uuid.New(), EventType: "price.changed", Payload: payload, Attempts: tt.attempts, Secret: "s3cret",
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				timestamp, _ := strconv.ParseInt(r.Header.Get("X-Uniwish-Timestamp"), 10, 64)
+				if r.Header.Get("X-Uniwish-Signature") != SignWebhook("s3cret", timestamp, body) {
+					t.Errorf("expected the body signed with the subscription's secret")
+				}
+				if r.Header.Get("X-Uniwish-Event-ID") != delivery.EventID.String() || r.Header.Get("X-Uniwish-Event-Type") != "price.changed" {
+					t.Errorf("expected the event identified, received %v", r.Header)
+				}
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			delivery.URL = server.URL
+
+			outbox := &FakeWebhookOutbox{due: []repository.PendingWebhookDelivery{delivery}}
+			dispatcher := NewWebhookDispatcher(outbox, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			dispatcher.MaxAttempts = 3
+			dispatcher.RetryBackoff = time.Minute
+
+			start := time.Now()
+			attempted, err := dispatcher.Dispatch(context.Background())
+			if err != nil || attempted != 1 {
+				t.Fatalf("expected one attempt, received %d, %v", attempted, err)
+			}
+
+			result := outbox.recorded[0]
+			if result.DeliveryID != delivery.ID || result.Status != tt.expectedStatus || result.StatusCode != tt.expectedCode {
+				t.Fatalf("expected %s with %d, received %+v", tt.expectedStatus, tt.expectedCode, result)
+			}
+			if tt.expectedStatus != repository.WebhookDeliveryDelivered && result.Error == "" {
+				t.Fatalf("expected the failure recorded, received %+v", result)
+			}
+			if tt.expectedRetry > 0 {
+				retryIn := result.NextAttemptAt.Sub(start)
+				if retryIn < tt.expectedRetry || retryIn > tt.expectedRetry+time.Second {
+					t.Fatalf("expected a retry in %v, received %v", tt.expectedRetry, retryIn)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookDispatcher_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	outbox := &FakeWebhookOutbox{due: []repository.PendingWebhookDelivery{{ID: uuid.New(), URL: server.URL, Payload: []byte(`{}`)}}}
+	dispatcher := NewWebhookDispatcher(outbox, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	dispatcher.MaxAttempts = 3
+
+	if _, err := dispatcher.Dispatch(context.Background()); err != nil {
+		t.Fatalf("expected an unreachable receiver to be retried rather than fail dispatching, received %v", err)
+	}
+	if result := outbox.recorded[0]; result.Status != repository.WebhookDeliveryPending || result.StatusCode != 0 || result.Error == "" {
+		t.Fatalf("expected a pending delivery without status code, received %+v", result)
+	}
+}
+
+func TestWebhookDispatcher_Backoff(t *testing.T) {
+	dispatcher := &WebhookDispatcher{RetryBackoff: 30 * time.Second}
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 30 * time.Second},
+		{attempts: 2, expected: time.Minute},
+		{attempts: 4, expected: 4 * time.Minute},
+		{attempts: 30, expected: MaxWebhookBackoff},
+	}
+	for _, tt := range tests {
+		if received := dispatcher.backoff(tt.attempts); received != tt.expected {
+			t.Fatalf("expected %v after %d attempts, received %v", tt.expected, tt.attempts, received)
+		}
+	}
+}
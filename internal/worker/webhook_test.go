@@ -0,0 +1,63 @@
+/*
+uniwish.com/interal/worker/webhook_test
+
+tests for queueing webhook deliveries
+*/
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/domain"
+	"uniwish.com/internal/testutil"
+)
+
+func TestWebhookWriter_EnqueueWebhooks(t *testing.T) {
+	testutil.RequireIntegration(t)
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("transaction failed %v", err)
+	}
+	defer tx.Rollback()
+	ctx := context.Background()
+
+	subscriptionID := uuid.New()
+	_, err = tx.Exec(
+		`INSERT INTO webhook_subscriptions (id, url, secret, event_types) VALUES ($1, 'https://hooks.example.com', 's3cret', '{price.changed}')`,
+		subscriptionID,
+	)
+	if err != nil {
+		t.Fatalf("subscription insert failed: %v", err)
+	}
+
+	priceChanged := newWebhookEvent(domain.WebhookPriceChanged, webhookChangeData{ProductID: uuid.New(), Change: domain.ProductPriceChanged, Before: "29.95 EUR", After: "24.95 EUR"})
+	created := newWebhookEvent(domain.WebhookProductCreated, webhookProductData{ProductID: uuid.New()})
+
+	queued, err := NewWebhookWriter(tx).EnqueueWebhooks(ctx, []domain.WebhookEvent{priceChanged, created})
+	if err != nil || queued != 1 {
+		t.Fatalf("expected only the subscribed event queued, received %d, %v", queued, err)
+	}
+
+	var (
+		eventID uuid.UUID
+		payload []byte
+	)
+	err = tx.QueryRow(`SELECT event_id, payload FROM webhook_deliveries WHERE subscription_id = $1`, subscriptionID).Scan(&eventID, &payload)
+	if err != nil {
+		t.Fatalf("delivery read failed: %v", err)
+	}
+	var body struct {
+		ID   uuid.UUID         `json:"id"`
+		Type string            `json:"type"`
+		Data webhookChangeData `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if eventID != priceChanged.ID || body.ID != priceChanged.ID || body.Type != "price.changed" || body.Data.After != "24.95 EUR" {
+		t.Fatalf("expected the price change's envelope, received %s", payload)
+	}
+}
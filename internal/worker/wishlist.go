@@ -0,0 +1,66 @@
+/*
+uniwish.com/interal/worker/wishlist
+
+db logic for wishlists and the notifications their owners receive
+
+the extension submits a page's url when its user saves it, so owning a scrape request of a product wishlists it
+*/
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/domain"
+)
+
+type WishlistWriter interface {
+	AddToWishlist(ctx context.Context, ownerID string, productID uuid.UUID) error
+	// NotifyWishlisters notifies every owner who wishlisted the product, returning how many were
+	NotifyWishlisters(ctx context.Context, productID uuid.UUID, kind domain.NotificationKind) (int64, error)
+}
+
+type DefaultWishlistWriter struct {
+	db repository.DB
+}
+
+func NewWishlistWriter(db repository.DB) WishlistWriter {
+	return &DefaultWishlistWriter{db: db}
+}
+
+func (ww *DefaultWishlistWriter) AddToWishlist(ctx context.Context, ownerID string, productID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "DefaultWishlistWriter.AddToWishlist")
+	defer span.End()
+
+	_, err := ww.db.ExecContext(ctx,
+		`
+	INSERT INTO wishlist_items (owner_id, product_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`, ownerID, productID)
+
+	if err != nil {
+		return fmt.Errorf("wishlist insert error: %w", err)
+	}
+	return nil
+}
+
+func (ww *DefaultWishlistWriter) NotifyWishlisters(ctx context.Context, productID uuid.UUID, kind domain.NotificationKind) (int64, error) {
+	ctx, span := tracer.Start(ctx, "DefaultWishlistWriter.NotifyWishlisters")
+	defer span.End()
+
+	result, err := ww.db.ExecContext(ctx,
+		`
+	INSERT INTO notifications (id, owner_id, product_id, kind)
+	SELECT gen_random_uuid(), owner_id, product_id, $2
+	FROM wishlist_items
+	WHERE product_id = $1
+	`, productID, string(kind))
+
+	if err != nil {
+		return 0, fmt.Errorf("notification insert error: %w", err)
+	}
+	return result.RowsAffected()
+}
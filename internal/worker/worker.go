@@ -7,15 +7,31 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	apiErrors "uniwish.com/internal/api/errors"
 	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/domain"
+	"uniwish.com/internal/logging"
 	"uniwish.com/internal/scrapers"
+	"uniwish.com/internal/tracing"
 )
 
 type Worker struct {
 	repo     WorkerRepo
 	registry scrapers.Registry
+	// LeaseHeartbeat is how often a claimed job's lease is renewed while processing, zero disables renewal
+	LeaseHeartbeat time.Duration
+	// Metrics records job outcomes and scrape timings, nil disables recording
+	Metrics *Metrics
+	// Images mirrors product images into the blob store, nil disables mirroring
+	Images *ImageMirror
 }
 
 func NewWorker(
@@ -32,6 +48,7 @@ func (w *Worker) RunOnce(ctx context.Context) error {
 	}
 
 	err = w.ProcessJob(ctx, job)
+	w.Metrics.jobProcessed(err)
 	if err != nil {
 		return fmt.Errorf("process job error: %w", err)
 	}
@@ -47,68 +64,270 @@ func (w *Worker) ClaimJob(ctx context.Context) (*repository.ScrapeRequest, error
 	}
 
 	// grab a job
-	job, err := session.Dequeue(ctx)
+	job, err := session.Claim(ctx)
 
 	if err != nil {
 		session.Rollback()
-		return nil, fmt.Errorf("deqeue error: %w", err)
+		return nil, fmt.Errorf("claim error: %w", err)
 	}
 
 	if job == nil {
 		// though technically no need for rollback as nothing was updated
-		// rollbacking just in case Dequeue changes before this does
+		// rollbacking just in case Claim changes before this does
 		session.Rollback()
 		return nil, ErrNoWork
 	}
 
+	publishStatus(ctx, session, jobStatus(job, repository.StatusProcessing, "", uuid.Nil))
 	if err = session.Commit(); err != nil {
 		session.Rollback()
-		return nil, fmt.Errorf("dequeue commit error: %w", err)
+		return nil, fmt.Errorf("claim commit error: %w", err)
 	}
 	return job, nil
 }
 
 func (w *Worker) ProcessJob(ctx context.Context, job *repository.ScrapeRequest) error {
+	// the job is processed in its own trace, linked to the api request that enqueued it
+	ctx, span := tracer.Start(ctx, "Worker.ProcessJob",
+		trace.WithLinks(tracing.Link(job.TraceContext)),
+		trace.WithAttributes(
+			attribute.String("scrape_request.id", job.ID.String()),
+			attribute.String("scrape_request.store", job.Store),
+		),
+	)
+	defer span.End()
+
+	err := w.processJob(ctx, job)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (w *Worker) processJob(ctx context.Context, job *repository.ScrapeRequest) error {
 	session, err := w.repo.BeginSession(ctx)
 
 	if err != nil {
 		return err
 	}
+	if job.RetriesExhausted() {
+		// dead letter rather than scrape again what took every earlier worker down with it
+		return w.fail(ctx, session, job, JobRetriesExhausted,
+			fmt.Errorf("%w: claimed %d times, at most %d", ErrRetriesExhausted, job.Attempts, job.MaxAttempts))
+	}
 	scraper, err := w.registry.NewScraperFor(job.URL)
 	if err != nil {
 		// dead letter and surpress unsupported urls
-		session.MarkFailed(ctx, job.ID)
-		session.Commit()
-		return JobError{JobID: job.ID, Err: err, Kind: JobUnsupportedStore}
+		return w.fail(ctx, session, job, JobUnsupportedStore, err)
 	}
+	stopHeartbeat := w.keepLease(ctx, job.Lease())
+	defer stopHeartbeat()
+
+	scrapeStart := time.Now()
 	productRecord, err := scraper.Scrape(ctx, job.URL)
+	w.Metrics.observeScrape(job.Store, scrapeStart)
+	if errors.Is(err, apiErrors.ErrProductRemoved) {
+		return w.discontinue(ctx, session, job, err)
+	}
 	if err != nil {
 		// dead letter failing scrapes but escalate for logging
-		// TODO consider different error codes to easily diagnose different fail cases
-		session.MarkFailed(ctx, job.ID)
-		session.Commit()
-		return JobError{JobID: job.ID, Err: err, Kind: JobScrapeFailed}
+		return w.fail(ctx, session, job, JobScrapeFailed, err)
 	}
 
-	// TODO add processing lease metadata on jobs for reaper processes
-	// otherwise failure (transcient or not) on these tasks can leave zombie (eternally processing) jobs
-	_, err = session.UpsertProduct(ctx, *productRecord.Product)
+	image := w.mirrorImage(ctx, productRecord.Product.ImageURL)
+
+	// should anything below fail without acking, the job's lease lapses and another worker reclaims it
+	productID, events, err := session.UpsertProduct(ctx, *productRecord.Product)
 	if err != nil {
 		session.Rollback()
 		return fmt.Errorf("process job error: %w", err)
 	}
+	if image != nil {
+		if err = session.SaveImage(ctx, productID, *image); err != nil {
+			session.Rollback()
+			return fmt.Errorf("process job error: %w", err)
+		}
+	}
+	// offers hang off the stored product, which differs from the scraped id once the sku was seen in another region
+	for i := range *productRecord.Offers {
+		(*productRecord.Offers)[i].ProductID = productID
+	}
+
+	offerEvents, err := session.InsertPrice(ctx, *productRecord.Offers)
 
-	err = session.InsertPrice(ctx, *productRecord.Offers)
+	if err != nil {
+		session.Rollback()
+		return fmt.Errorf("process job error: %w", err)
+	}
+
+	events = append(events, offerEvents...)
+	if len(events) > 0 {
+		for i := range events {
+			events[i].ScrapeRequestID = job.ID
+		}
+		if err = session.RecordEvents(ctx, events); err != nil {
+			session.Rollback()
+			return fmt.Errorf("process job error: %w", err)
+		}
+	}
+
+	// matching only links the product to other stores, a failed match is left to the next scrape rather
+	// than failing the job, its savepoint keeps the scraped product and prices
+	matchErr := session.Attempt(ctx, "match_product", func() error {
+		return session.MatchProduct(ctx, productID, *productRecord.Product)
+	})
+	if matchErr != nil {
+		logging.FromContext(ctx).Warn("product not matched", "product_id", productID, "err", matchErr)
+	}
+
+	if job.OwnerID != "" {
+		if err = session.AddToWishlist(ctx, job.OwnerID, productID); err != nil {
+			session.Rollback()
+			return fmt.Errorf("process job error: %w", err)
+		}
+	}
+
+	// the stored product only keeps the scraped id when this scrape created it
+	var webhooks []domain.WebhookEvent
+	if productID == productRecord.Product.ID {
+		webhooks = append(webhooks, productCreatedWebhook(productID, job.ID, productRecord))
+	}
+	webhooks = append(webhooks, productEventWebhooks(events)...)
+	if len(webhooks) > 0 {
+		if _, err = session.EnqueueWebhooks(ctx, webhooks); err != nil {
+			session.Rollback()
+			return fmt.Errorf("process job error: %w", err)
+		}
+	}
+
+	if err = session.Ack(ctx, job.Lease()); err != nil {
+		session.Rollback()
+		return fmt.Errorf("process job error: %w", err)
+	}
+	publishStatus(ctx, session, jobStatus(job, repository.StatusDone, "", productID))
+	if err = session.Commit(); err != nil {
+		session.Rollback()
+		return fmt.Errorf("process commit error: %w", err)
+	}
+	return nil
+}
 
+// discontinue marks the product whose page the store removed, notifying who wishlisted it, instead of failing the job
+func (w *Worker) discontinue(ctx context.Context, session WorkerSession, job *repository.ScrapeRequest, scrapeErr error) error {
+	found, events, err := session.DiscontinueProduct(ctx, job.URL)
 	if err != nil {
 		session.Rollback()
 		return fmt.Errorf("process job error: %w", err)
 	}
+	if !found {
+		return w.fail(ctx, session, job, JobProductRemoved, scrapeErr)
+	}
+
+	// events are only returned the first time, so wishlisters are notified once
+	for i := range events {
+		events[i].ScrapeRequestID = job.ID
+	}
+	if len(events) > 0 {
+		if err = session.RecordEvents(ctx, events); err != nil {
+			session.Rollback()
+			return fmt.Errorf("process job error: %w", err)
+		}
+	}
+	for _, e := range events {
+		notified, err := session.NotifyWishlisters(ctx, e.ProductID, domain.NotificationProductDiscontinued)
+		if err != nil {
+			session.Rollback()
+			return fmt.Errorf("process job error: %w", err)
+		}
+		logging.FromContext(ctx).Info("product discontinued", "product_id", e.ProductID, "scrape_request_id", job.ID, "notified", notified)
+	}
+	if webhooks := productEventWebhooks(events); len(webhooks) > 0 {
+		if _, err = session.EnqueueWebhooks(ctx, webhooks); err != nil {
+			session.Rollback()
+			return fmt.Errorf("process job error: %w", err)
+		}
+	}
 
-	session.MarkDone(ctx, job.ID)
+	// the discontinued product is only known the first time it is marked
+	productID := uuid.Nil
+	if len(events) > 0 {
+		productID = events[0].ProductID
+	}
+	if err = session.Ack(ctx, job.Lease()); err != nil {
+		session.Rollback()
+		return fmt.Errorf("process job error: %w", err)
+	}
+	publishStatus(ctx, session, jobStatus(job, repository.StatusDone, "", productID))
 	if err = session.Commit(); err != nil {
 		session.Rollback()
 		return fmt.Errorf("process commit error: %w", err)
 	}
 	return nil
 }
+
+// fail dead letters the job and tells integrators and its owner about it, returning err escalated as a JobError
+func (w *Worker) fail(ctx context.Context, session WorkerSession, job *repository.ScrapeRequest, kind JobErrorKind, err error) error {
+	if nackErr := session.Nack(ctx, job.Lease(), repository.Failure{Kind: string(kind), Message: err.Error()}); nackErr != nil {
+		session.Rollback()
+		return fmt.Errorf("dead letter error: %w", nackErr)
+	}
+	// the webhook is not worth retrying the job over, its savepoint keeps a failed insert from undoing the nack
+	webhookErr := session.Attempt(ctx, "scrape_failed_webhook", func() error {
+		_, err := session.EnqueueWebhooks(ctx, []domain.WebhookEvent{scrapeFailedWebhook(job, kind, err)})
+		return err
+	})
+	if webhookErr != nil {
+		logging.FromContext(ctx).Warn("scrape failed webhook not queued", "scrape_request_id", job.ID, "err", webhookErr)
+	}
+	publishStatus(ctx, session, jobStatus(job, repository.StatusFailed, kind, uuid.Nil))
+	if commitErr := session.Commit(); commitErr != nil {
+		session.Rollback()
+		return fmt.Errorf("dead letter commit error: %w", commitErr)
+	}
+	return JobError{JobID: job.ID, Err: err, Kind: kind}
+}
+
+func (w *Worker) mirrorImage(ctx context.Context, imageURL string) *domain.ProductImage {
+	if w.Images == nil || imageURL == "" {
+		return nil
+	}
+	image, err := w.Images.Mirror(ctx, imageURL)
+	if err != nil {
+		// without a mirror the product keeps pointing at the store's image, not worth failing the scrape over
+		logging.FromContext(ctx).Warn("image mirror failed", "image_url", imageURL, "err", err)
+		return nil
+	}
+	return image
+}
+
+func (w *Worker) keepLease(ctx context.Context, lease repository.Lease) func() {
+	// renews the job's lease in its own session until the returned func is called
+	// so long scrapes are not reclaimed by other workers mid-flight
+	if w.LeaseHeartbeat <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(w.LeaseHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				session, err := w.repo.BeginSession(ctx)
+				if err != nil {
+					continue
+				}
+				if err := session.ExtendLease(ctx, lease); err != nil {
+					session.Rollback()
+					continue
+				}
+				session.Commit()
+			}
+		}
+	}()
+	return cancel
+}
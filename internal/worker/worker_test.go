@@ -12,10 +12,16 @@ import (
 	"slices"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"uniwish.com/internal/api/errors"
 	"uniwish.com/internal/api/repository"
+	"uniwish.com/internal/domain"
 	"uniwish.com/internal/scrapers"
 	"uniwish.com/internal/testutil"
+	"uniwish.com/internal/tracing"
 )
 
 func TestRunOnce(t *testing.T) {
@@ -32,11 +38,15 @@ func TestRunOnce(t *testing.T) {
 			name: "success",
 			repo: &DefaultFakeRepo{},
 			repoCalls: []string{
-				"Dequeue",
+				"Claim",
+				"PublishStatus",
 				"Commit",
 				"UpsertProduct",
 				"InsertPrice",
-				"MarkDone",
+				"MatchProduct",
+				"EnqueueWebhooks",
+				"Ack",
+				"PublishStatus",
 				"Commit",
 			},
 			scraperCalls: []string{
@@ -49,7 +59,7 @@ func TestRunOnce(t *testing.T) {
 			repo:        &FakeNoJobsRepo{},
 			expectedErr: ErrNoWork,
 			repoCalls: []string{
-				"Dequeue",
+				"Claim",
 				"Rollback",
 			},
 			scraperCalls: []string{},
@@ -60,9 +70,12 @@ func TestRunOnce(t *testing.T) {
 			scraperRegistryFactory: NewEmptyScraperRegistry,
 			expectedErr:            scrapers.ErrNoScraper,
 			repoCalls: []string{
-				"Dequeue",
+				"Claim",
+				"PublishStatus",
 				"Commit",
-				"MarkFailed",
+				"Nack",
+				"EnqueueWebhooks",
+				"PublishStatus",
 				"Commit",
 			},
 			scraperCalls: []string{},
@@ -72,7 +85,7 @@ func TestRunOnce(t *testing.T) {
 			repo:        &FakeDBErrorRepo{},
 			expectedErr: sql.ErrConnDone,
 			repoCalls: []string{
-				"Dequeue",
+				"Claim",
 				"Rollback",
 			},
 			scraperCalls: []string{},
@@ -82,7 +95,8 @@ func TestRunOnce(t *testing.T) {
 			repo:        &FaultyCommitRepo{},
 			expectedErr: sql.ErrTxDone,
 			repoCalls: []string{
-				"Dequeue",
+				"Claim",
+				"PublishStatus",
 				"Commit",
 				"Rollback",
 			},
@@ -94,9 +108,53 @@ func TestRunOnce(t *testing.T) {
 			scraper:     &FakeFaultyScraper{},
 			expectedErr: errors.ErrScrapeFailed,
 			repoCalls: []string{
-				"Dequeue",
+				"Claim",
+				"PublishStatus",
 				"Commit",
-				"MarkFailed",
+				"Nack",
+				"EnqueueWebhooks",
+				"PublishStatus",
+				"Commit",
+			},
+			scraperCalls: []string{
+				"New",
+				"Scrape",
+			},
+		},
+		{
+			name:    "discontinued",
+			repo:    &DiscontinuedProductRepo{known: true},
+			scraper: &FakeRemovedScraper{},
+			repoCalls: []string{
+				"Claim",
+				"PublishStatus",
+				"Commit",
+				"DiscontinueProduct",
+				"RecordEvents",
+				"NotifyWishlisters",
+				"EnqueueWebhooks",
+				"Ack",
+				"PublishStatus",
+				"Commit",
+			},
+			scraperCalls: []string{
+				"New",
+				"Scrape",
+			},
+		},
+		{
+			name:        "removed_unknown_product",
+			repo:        &DiscontinuedProductRepo{},
+			scraper:     &FakeRemovedScraper{},
+			expectedErr: errors.ErrProductRemoved,
+			repoCalls: []string{
+				"Claim",
+				"PublishStatus",
+				"Commit",
+				"DiscontinueProduct",
+				"Nack",
+				"EnqueueWebhooks",
+				"PublishStatus",
 				"Commit",
 			},
 			scraperCalls: []string{
@@ -139,13 +197,263 @@ func TestProcessJob_FaultyCommit(t *testing.T) {
 	registry := NewFakeScraperRegistry(scraper)
 	worker := NewWorker(repo, registry)
 
-	expectedCalls := []string{"UpsertProduct", "InsertPrice", "MarkDone", "Commit", "Rollback"}
+	expectedCalls := []string{"UpsertProduct", "InsertPrice", "MatchProduct", "EnqueueWebhooks", "Ack", "PublishStatus", "Commit", "Rollback"}
 	worker.ProcessJob(context.Background(), NewFakeJob())
 	if !slices.Equal(repo.Session().Calls(), expectedCalls) {
 		t.Fatalf("Expected %q, received %q", expectedCalls, repo.Session().Calls())
 	}
 }
 
+func TestProcessJob_DeadLetters(t *testing.T) {
+	tests := []struct {
+		name          string
+		repo          FakeRepo
+		expectedKind  JobErrorKind
+		expectedErr   error
+		expectedCalls []string
+	}{
+		{
+			name: "failed", repo: &DefaultFakeRepo{}, expectedKind: JobScrapeFailed, expectedErr: errors.ErrScrapeFailed,
+			expectedCalls: []string{"Nack", "EnqueueWebhooks", "PublishStatus", "Commit"},
+		},
+		{
+			// the webhook is rolled back to its savepoint, the nack is still committed
+			name: "webhook_not_queued", repo: &FaultyWebhookRepo{}, expectedKind: JobScrapeFailed, expectedErr: errors.ErrScrapeFailed,
+			expectedCalls: []string{"Nack", "EnqueueWebhooks", "RollbackToSavepoint", "PublishStatus", "Commit"},
+		},
+		{
+			name: "nack_failed", repo: &FaultyNackRepo{}, expectedErr: sql.ErrConnDone,
+			expectedCalls: []string{"Nack", "Rollback"},
+		},
+		{
+			name: "faulty_commit", repo: &FaultyCommitRepo{}, expectedErr: sql.ErrTxDone,
+			expectedCalls: []string{"Nack", "EnqueueWebhooks", "PublishStatus", "Commit", "Rollback"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worker := NewWorker(tt.repo, NewFakeScraperRegistry(&FakeFaultyScraper{}))
+			err := worker.ProcessJob(context.Background(), NewFakeJob())
+
+			if !goErrors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected err %v, received %v", tt.expectedErr, err)
+			}
+			var jobErr JobError
+			if isJobErr := goErrors.As(err, &jobErr); isJobErr != (tt.expectedKind != "") || jobErr.Kind != tt.expectedKind {
+				t.Fatalf("expected a %q job error, received %v", tt.expectedKind, err)
+			}
+			if !slices.Equal(tt.repo.Session().Calls(), tt.expectedCalls) {
+				t.Fatalf("Expected %q, received %q", tt.expectedCalls, tt.repo.Session().Calls())
+			}
+		})
+	}
+}
+
+func TestProcessJob_RetriesExhausted(t *testing.T) {
+	// a request whose lease lapsed on every earlier claim is dead lettered without scraping it again
+	repo := &DefaultFakeRepo{}
+	scraper := &DefaultFakeScraper{}
+	worker := NewWorker(repo, NewFakeScraperRegistry(scraper))
+	job := NewFakeJob()
+	job.Attempts, job.MaxAttempts = 6, 5
+
+	err := worker.ProcessJob(context.Background(), job)
+
+	var jobErr JobError
+	if !goErrors.As(err, &jobErr) || jobErr.Kind != JobRetriesExhausted || !goErrors.Is(err, ErrRetriesExhausted) {
+		t.Fatalf("expected a %q job error, received %v", JobRetriesExhausted, err)
+	}
+	if len(scraper.Calls()) != 0 {
+		t.Fatalf("expected no scrape, received %q", scraper.Calls())
+	}
+	expectedCalls := []string{"Nack", "EnqueueWebhooks", "PublishStatus", "Commit"}
+	if !slices.Equal(repo.Session().Calls(), expectedCalls) {
+		t.Fatalf("Expected %q, received %q", expectedCalls, repo.Session().Calls())
+	}
+}
+
+func TestProcessJob_RecordsEvents(t *testing.T) {
+	repo := &ChangedProductRepo{}
+	scraper := &DefaultFakeScraper{}
+	worker := NewWorker(repo, NewFakeScraperRegistry(scraper))
+	job := NewFakeJob()
+
+	if err := worker.ProcessJob(context.Background(), job); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	expectedCalls := []string{"UpsertProduct", "InsertPrice", "RecordEvents", "MatchProduct", "EnqueueWebhooks", "Ack", "PublishStatus", "Commit"}
+	if !slices.Equal(repo.Session().Calls(), expectedCalls) {
+		t.Fatalf("Expected %q, received %q", expectedCalls, repo.Session().Calls())
+	}
+	events := repo.Session().(*ChangedProductRepoSession).events
+	if len(events) != 2 {
+		t.Fatalf("expected both events recorded, received %+v", events)
+	}
+	for _, e := range events {
+		if e.ScrapeRequestID != job.ID {
+			t.Fatalf("expected events to link job %v, received %+v", job.ID, e)
+		}
+	}
+}
+
+func TestProcessJob_WishlistsForOwner(t *testing.T) {
+	repo := &DefaultFakeRepo{}
+	worker := NewWorker(repo, NewFakeScraperRegistry(&DefaultFakeScraper{}))
+	job := NewFakeJob()
+	job.OwnerID = "user-1"
+
+	if err := worker.ProcessJob(context.Background(), job); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	expectedCalls := []string{"UpsertProduct", "InsertPrice", "MatchProduct", "AddToWishlist", "EnqueueWebhooks", "Ack", "PublishStatus", "Commit"}
+	if !slices.Equal(repo.Session().Calls(), expectedCalls) {
+		t.Fatalf("Expected %q, received %q", expectedCalls, repo.Session().Calls())
+	}
+}
+
+func TestProcessJob_QueuesWebhooks(t *testing.T) {
+	tests := []struct {
+		name          string
+		repo          FakeRepo
+		scraper       FakeScraper
+		expectedTypes []domain.WebhookEventType
+	}{
+		{name: "new_product", repo: &DefaultFakeRepo{}, scraper: &DefaultFakeScraper{}, expectedTypes: []domain.WebhookEventType{domain.WebhookProductCreated}},
+		{
+			name: "changed_product", repo: &ChangedProductRepo{}, scraper: &DefaultFakeScraper{},
+			expectedTypes: []domain.WebhookEventType{domain.WebhookProductUpdated, domain.WebhookPriceChanged},
+		},
+		{name: "failed_scrape", repo: &DefaultFakeRepo{}, scraper: &FakeFaultyScraper{}, expectedTypes: []domain.WebhookEventType{domain.WebhookScrapeFailed}},
+		{
+			name: "discontinued", repo: &DiscontinuedProductRepo{known: true}, scraper: &FakeRemovedScraper{},
+			expectedTypes: []domain.WebhookEventType{domain.WebhookProductDiscontinued},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worker := NewWorker(tt.repo, NewFakeScraperRegistry(tt.scraper))
+			worker.ProcessJob(context.Background(), NewFakeJob())
+
+			webhooks := tt.repo.Session().Webhooks()
+			types := make([]domain.WebhookEventType, len(webhooks))
+			for i, webhook := range webhooks {
+				types[i] = webhook.Type
+			}
+			if !slices.Equal(types, tt.expectedTypes) {
+				t.Fatalf("expected %q, received %q", tt.expectedTypes, types)
+			}
+		})
+	}
+}
+
+func TestProcessJob_PublishesStatus(t *testing.T) {
+	tests := []struct {
+		name              string
+		repo              FakeRepo
+		scraper           FakeScraper
+		expectedStatus    string
+		expectedErrorKind string
+		expectedProduct   bool
+	}{
+		{name: "done", repo: &DefaultFakeRepo{}, scraper: &DefaultFakeScraper{}, expectedStatus: "done", expectedProduct: true},
+		{name: "failed", repo: &DefaultFakeRepo{}, scraper: &FakeFaultyScraper{}, expectedStatus: "failed", expectedErrorKind: "scrape_failed"},
+		{name: "discontinued", repo: &DiscontinuedProductRepo{known: true}, scraper: &FakeRemovedScraper{}, expectedStatus: "done", expectedProduct: true},
+		{name: "removed_unknown_product", repo: &DiscontinuedProductRepo{}, scraper: &FakeRemovedScraper{}, expectedStatus: "failed", expectedErrorKind: "product_removed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worker := NewWorker(tt.repo, NewFakeScraperRegistry(tt.scraper))
+			job := NewFakeJob()
+			job.OwnerID = "user-1"
+			worker.ProcessJob(context.Background(), job)
+
+			statuses := tt.repo.Session().Statuses()
+			if len(statuses) != 1 {
+				t.Fatalf("expected one status published, received %+v", statuses)
+			}
+			status := statuses[0]
+			if status.ScrapeRequestID != job.ID || status.OwnerID != "user-1" {
+				t.Fatalf("expected the job's status for its owner, received %+v", status)
+			}
+			if status.Status != tt.expectedStatus || status.ErrorKind != tt.expectedErrorKind || (status.ProductID != nil) != tt.expectedProduct {
+				t.Fatalf("expected %s %q product %v, received %+v", tt.expectedStatus, tt.expectedErrorKind, tt.expectedProduct, status)
+			}
+		})
+	}
+}
+
+func TestRunOnce_StatusNotPublished(t *testing.T) {
+	// a failed notify is rolled back to its savepoint, the claim and the scrape are still committed
+	repo := &FaultyStatusRepo{}
+	worker := NewWorker(repo, NewFakeScraperRegistry(&DefaultFakeScraper{}))
+
+	if err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	expectedCalls := []string{
+		"Claim", "PublishStatus", "RollbackToSavepoint", "Commit",
+		"UpsertProduct", "InsertPrice", "MatchProduct", "EnqueueWebhooks", "Ack", "PublishStatus", "RollbackToSavepoint", "Commit",
+	}
+	if !slices.Equal(repo.Session().Calls(), expectedCalls) {
+		t.Fatalf("Expected %q, received %q", expectedCalls, repo.Session().Calls())
+	}
+}
+
+func TestClaimJob_PublishesProcessing(t *testing.T) {
+	repo := &DefaultFakeRepo{}
+	worker := NewWorker(repo, NewFakeScraperRegistry(&DefaultFakeScraper{}))
+
+	job, err := worker.ClaimJob(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	statuses := repo.Session().Statuses()
+	if len(statuses) != 1 || statuses[0].ScrapeRequestID != job.ID || statuses[0].Status != "processing" {
+		t.Fatalf("expected the claimed job published as processing, received %+v", statuses)
+	}
+}
+
+func TestProcessJob_ProductCreatedWebhookCarriesOffers(t *testing.T) {
+	repo := &DefaultFakeRepo{}
+	worker := NewWorker(repo, NewFakeScraperRegistry(&DefaultFakeScraper{}))
+	job := NewFakeJob()
+
+	if err := worker.ProcessJob(context.Background(), job); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	data, ok := repo.Session().Webhooks()[0].Data.(webhookProductData)
+	if !ok || data.ScrapeRequestID != job.ID || len(data.Offers) != 2 {
+		t.Fatalf("expected the job's product with both offers, received %+v", repo.Session().Webhooks()[0].Data)
+	}
+	if data.Offers[0].Price != 45.32 || data.Offers[0].Currency != "EUR" {
+		t.Fatalf("expected offers priced in major units, received %+v", data.Offers[0])
+	}
+}
+
+func TestProcessJob_MatchFailed(t *testing.T) {
+	// a failed match is rolled back to its savepoint, the scraped product and prices are still committed
+	repo := &FaultyMatchRepo{}
+	worker := NewWorker(repo, NewFakeScraperRegistry(&DefaultFakeScraper{}))
+
+	if err := worker.ProcessJob(context.Background(), NewFakeJob()); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+	calls := repo.Session().Calls()
+	match := slices.Index(calls, "MatchProduct")
+	if match == -1 || match+1 == len(calls) || calls[match+1] != "RollbackToSavepoint" {
+		t.Fatalf("expected the match rolled back to its savepoint, received %q", calls)
+	}
+	if slices.Contains(calls, "Rollback") || calls[len(calls)-1] != "Commit" {
+		t.Fatalf("expected the job committed, received %q", calls)
+	}
+}
+
 func TestClaimJob_FaultyTx(t *testing.T) {
 	repo := &FaultyTransactionRepo{}
 	scraper := &DefaultFakeScraper{}
@@ -181,11 +489,12 @@ func TestRunOnce_Integration(t *testing.T) {
 		testutil.TruncateTables(t, testDB)
 	})
 
-	repo := repository.NewPostgresScrapeRequestRepository(testDB)
+	queueFactory := repository.PostgresQueueFactory(repository.DefaultLease)
+	queue := queueFactory(testDB)
 	expectedUrl := "http://store.com"
 	ctx := context.Background()
 
-	id, err := repo.Insert(ctx, expectedUrl)
+	id, err := queue.Enqueue(ctx, repository.ScrapeRequest{URL: expectedUrl})
 
 	if err != nil {
 		t.Fatalf("insert failed: %v", err)
@@ -193,7 +502,7 @@ func TestRunOnce_Integration(t *testing.T) {
 
 	scraper := &DefaultFakeScraper{}
 	registry := NewFakeScraperRegistry(scraper)
-	workerRepo := NewWorkerRepo(testDB)
+	workerRepo := NewWorkerRepo(testDB, queueFactory)
 	newWorker := NewWorker(workerRepo, registry)
 
 	err = newWorker.RunOnce(context.Background())
@@ -212,3 +521,58 @@ func TestRunOnce_Integration(t *testing.T) {
 		t.Fatalf("expected status to be 'done', received, %s", status)
 	}
 }
+
+func TestRunOnce_RecordsMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := NewMetrics(reg)
+
+	worker := NewWorker(&DefaultFakeRepo{}, NewFakeScraperRegistry(&DefaultFakeScraper{}))
+	worker.Metrics = metrics
+	worker.RunOnce(context.Background())
+
+	faultyWorker := NewWorker(&DefaultFakeRepo{}, NewFakeScraperRegistry(&FakeFaultyScraper{}))
+	faultyWorker.Metrics = metrics
+	faultyWorker.RunOnce(context.Background())
+
+	if count := promtestutil.ToFloat64(metrics.jobs.WithLabelValues(OutcomeDone, "")); count != 1 {
+		t.Fatalf("expected 1 done job, received %v", count)
+	}
+	if count := promtestutil.ToFloat64(metrics.jobs.WithLabelValues(OutcomeFailed, string(JobScrapeFailed))); count != 1 {
+		t.Fatalf("expected 1 failed job, received %v", count)
+	}
+	if count := promtestutil.CollectAndCount(metrics.scrapeDuration); count != 1 {
+		t.Fatalf("expected 1 scrape duration series, received %d", count)
+	}
+}
+
+func TestProcessJob_LinksEnqueuingTrace(t *testing.T) {
+	recorder := testutil.RecordSpans(t)
+
+	ctx, apiSpan := otel.Tracer("test").Start(context.Background(), "POST /scrape-requests")
+	job := NewFakeJob()
+	job.TraceContext = tracing.Inject(ctx)
+	apiSpan.End()
+
+	worker := NewWorker(&DefaultFakeRepo{}, NewFakeScraperRegistry(&DefaultFakeScraper{}))
+	if err := worker.ProcessJob(context.Background(), job); err != nil {
+		t.Fatalf("expected nil err, received %v", err)
+	}
+
+	var processSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "Worker.ProcessJob" {
+			processSpan = span
+		}
+	}
+	if processSpan == nil {
+		t.Fatal("no Worker.ProcessJob span recorded")
+	}
+
+	links := processSpan.Links()
+	if len(links) != 1 || links[0].SpanContext.SpanID() != apiSpan.SpanContext().SpanID() {
+		t.Fatalf("expected a link to span %s, received %+v", apiSpan.SpanContext().SpanID(), links)
+	}
+	if processSpan.SpanContext().TraceID() == apiSpan.SpanContext().TraceID() {
+		t.Fatal("expected the job to be processed in its own trace")
+	}
+}
@@ -0,0 +1,9 @@
+DROP INDEX scrape_requests_store_claimed_idx;
+DROP INDEX scrape_requests_owner_claimed_idx;
+DROP INDEX scrape_requests_pending_idx;
+
+ALTER TABLE scrape_requests
+    DROP COLUMN claimed_at,
+    DROP COLUMN store,
+    DROP COLUMN owner_id,
+    DROP COLUMN priority;
@@ -0,0 +1,15 @@
+ALTER TABLE scrape_requests
+    ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN owner_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN store TEXT NOT NULL DEFAULT '',
+    ADD COLUMN claimed_at TIMESTAMPTZ;
+
+CREATE INDEX scrape_requests_pending_idx
+    ON scrape_requests (priority DESC, created_at)
+    WHERE status = 'pending';
+
+CREATE INDEX scrape_requests_owner_claimed_idx
+    ON scrape_requests (owner_id, claimed_at);
+
+CREATE INDEX scrape_requests_store_claimed_idx
+    ON scrape_requests (store, claimed_at);
@@ -0,0 +1,4 @@
+DROP INDEX scrape_requests_lease_idx;
+
+ALTER TABLE scrape_requests
+    DROP COLUMN lease_expires_at;
@@ -0,0 +1,6 @@
+ALTER TABLE scrape_requests
+    ADD COLUMN lease_expires_at TIMESTAMPTZ;
+
+CREATE INDEX scrape_requests_lease_idx
+    ON scrape_requests (lease_expires_at)
+    WHERE status = 'processing';
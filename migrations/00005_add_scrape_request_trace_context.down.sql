@@ -0,0 +1,2 @@
+ALTER TABLE scrape_requests
+    DROP COLUMN trace_context;
@@ -0,0 +1,2 @@
+ALTER TABLE scrape_requests
+    ADD COLUMN trace_context JSONB NOT NULL DEFAULT '{}';
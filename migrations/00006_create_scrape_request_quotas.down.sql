@@ -0,0 +1 @@
+DROP TABLE scrape_request_quotas;
@@ -0,0 +1,6 @@
+CREATE TABLE scrape_request_quotas (
+    owner_id TEXT NOT NULL,
+    day DATE NOT NULL,
+    used INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (owner_id, day)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE fx_rates (
+    base CHAR(3) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+    loaded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (base, currency, rate_date)
+);
+
+CREATE INDEX fx_rates_rate_date_idx
+    ON fx_rates (rate_date DESC);
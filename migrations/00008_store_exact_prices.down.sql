@@ -0,0 +1,2 @@
+ALTER TABLE prices
+    ALTER COLUMN price TYPE FLOAT USING price::float;
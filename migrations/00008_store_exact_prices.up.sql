@@ -0,0 +1,2 @@
+ALTER TABLE prices
+    ALTER COLUMN price TYPE NUMERIC(19, 4) USING round(price::numeric, 4);
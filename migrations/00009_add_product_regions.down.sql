@@ -0,0 +1,6 @@
+DROP INDEX prices_product_region_scraped_at_idx;
+
+ALTER TABLE prices
+    DROP COLUMN region;
+
+DROP TABLE product_regions;
@@ -0,0 +1,16 @@
+CREATE TABLE product_regions (
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    region TEXT NOT NULL,
+    url TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (product_id, region)
+);
+
+INSERT INTO product_regions (product_id, region, url, updated_at)
+SELECT id, '', url, updated_at FROM products;
+
+ALTER TABLE prices
+    ADD COLUMN region TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX prices_product_region_scraped_at_idx
+    ON prices (product_id, region, scraped_at DESC);
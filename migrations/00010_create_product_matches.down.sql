@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS products_name_trgm_idx;
+
+DROP TABLE product_matches;
+
+DROP TABLE product_match_groups;
+
+DROP INDEX products_mpn_idx;
+
+DROP INDEX products_gtin_idx;
+
+ALTER TABLE products
+    DROP COLUMN mpn,
+    DROP COLUMN gtin,
+    DROP COLUMN brand;
@@ -0,0 +1,48 @@
+ALTER TABLE products
+    ADD COLUMN brand TEXT NOT NULL DEFAULT '',
+    ADD COLUMN gtin TEXT NOT NULL DEFAULT '',
+    ADD COLUMN mpn TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX products_gtin_idx
+    ON products (gtin)
+    WHERE gtin <> '';
+
+CREATE INDEX products_mpn_idx
+    ON products (mpn)
+    WHERE mpn <> '';
+
+CREATE TABLE product_match_groups (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE product_matches (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    group_id UUID NOT NULL REFERENCES product_match_groups(id) ON DELETE CASCADE,
+    confidence REAL NOT NULL CHECK (confidence > 0 AND confidence <= 1),
+    method TEXT NOT NULL,
+    matched_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX product_matches_group_id_idx
+    ON product_matches (group_id);
+
+-- the fuzzy name match filters on lower(name) LIKE, which only a trigram index serves. pg_trgm is trusted
+-- since postgres 13 so a database owner may create it, a migrator that may not is warned and matching scans
+-- instead, see README.md
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN insufficient_privilege OR undefined_file THEN
+    RAISE WARNING 'pg_trgm not created, products_name_trgm_idx is skipped: %', SQLERRM;
+END
+$$;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX products_name_trgm_idx
+            ON products USING gin (lower(name) gin_trgm_ops);
+    END IF;
+END
+$$;
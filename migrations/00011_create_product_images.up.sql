@@ -0,0 +1,11 @@
+CREATE TABLE product_images (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    source_url TEXT NOT NULL,
+    image_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    phash BIGINT NOT NULL,
+    mirrored_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,7 @@
+DROP INDEX scrape_requests_finished_idx;
+DROP INDEX scrape_requests_status_store_idx;
+
+ALTER TABLE scrape_requests
+    DROP COLUMN finished_at,
+    DROP COLUMN error,
+    DROP COLUMN error_kind;
@@ -0,0 +1,11 @@
+ALTER TABLE scrape_requests
+    ADD COLUMN error_kind TEXT NOT NULL DEFAULT '',
+    ADD COLUMN error TEXT NOT NULL DEFAULT '',
+    ADD COLUMN finished_at TIMESTAMPTZ;
+
+CREATE INDEX scrape_requests_status_store_idx
+    ON scrape_requests (status, store, created_at DESC);
+
+CREATE INDEX scrape_requests_finished_idx
+    ON scrape_requests (finished_at)
+    WHERE status = 'done';
@@ -0,0 +1,4 @@
+DROP TABLE product_events;
+
+ALTER TABLE prices
+    DROP COLUMN availability;
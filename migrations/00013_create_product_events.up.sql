@@ -0,0 +1,16 @@
+ALTER TABLE prices
+    ADD COLUMN availability TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE product_events (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    scrape_request_id UUID,
+    kind TEXT NOT NULL,
+    region TEXT NOT NULL DEFAULT '',
+    before TEXT NOT NULL,
+    after TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX product_events_product_occurred_at_idx
+    ON product_events (product_id, occurred_at DESC);
@@ -0,0 +1,5 @@
+DROP TABLE notifications;
+DROP TABLE wishlist_items;
+
+ALTER TABLE products
+    DROP COLUMN discontinued_at;
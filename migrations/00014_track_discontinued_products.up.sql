@@ -0,0 +1,23 @@
+ALTER TABLE products
+    ADD COLUMN discontinued_at TIMESTAMPTZ;
+
+CREATE TABLE wishlist_items (
+    owner_id TEXT NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (owner_id, product_id)
+);
+
+CREATE INDEX wishlist_items_product_idx
+    ON wishlist_items (product_id);
+
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX notifications_owner_created_at_idx
+    ON notifications (owner_id, created_at DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE scrape_requests
+    DROP COLUMN claim_token;
@@ -0,0 +1,2 @@
+ALTER TABLE scrape_requests
+    ADD COLUMN claim_token UUID;
@@ -0,0 +1,3 @@
+ALTER TABLE scrape_requests
+    DROP COLUMN max_attempts,
+    DROP COLUMN attempts;
@@ -0,0 +1,3 @@
+ALTER TABLE scrape_requests
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN max_attempts INT NOT NULL DEFAULT 5;
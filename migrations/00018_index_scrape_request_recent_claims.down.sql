@@ -0,0 +1,7 @@
+CREATE INDEX scrape_requests_owner_claimed_idx
+    ON scrape_requests (owner_id, claimed_at);
+
+CREATE INDEX scrape_requests_store_claimed_idx
+    ON scrape_requests (store, claimed_at);
+
+DROP INDEX scrape_requests_recent_claims_idx;
@@ -0,0 +1,9 @@
+-- the fairness lookups in Claim range over the last hour of claimed_at and group by owner and store,
+-- one index led by claimed_at serves both as an index-only scan, the (owner_id, claimed_at) and
+-- (store, claimed_at) pair could not be range scanned on claimed_at
+CREATE INDEX scrape_requests_recent_claims_idx
+    ON scrape_requests (claimed_at) INCLUDE (owner_id, store)
+    WHERE claimed_at IS NOT NULL;
+
+DROP INDEX scrape_requests_owner_claimed_idx;
+DROP INDEX scrape_requests_store_claimed_idx;
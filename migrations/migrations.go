@@ -0,0 +1,11 @@
+/*
+uniwish.com/migrations
+
+embeds the sql migrations so the binaries can apply them without the files alongside
+*/
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS